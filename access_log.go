@@ -0,0 +1,154 @@
+package autohttp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fortytw2/lounge"
+	"github.com/jwfriese/autohttp/internal/httpsnoop"
+)
+
+// DefaultAccessLogSampling logs every error and request slower than 500ms,
+// and up to 100 other requests a second
+var DefaultAccessLogSampling = AccessLogSampling{
+	SlowThreshold: 500 * time.Millisecond,
+	PerSecond:     100,
+}
+
+// AccessLogSampling keeps access log volume sane at high load. Errors and slow
+// requests are always logged, while fast successful requests are logged up to
+// PerSecond, so quiet services log everything and busy ones a steady trickle
+type AccessLogSampling struct {
+	// SlowThreshold is how long a request takes before it's always logged,
+	// zero logs no request for being slow
+	SlowThreshold time.Duration
+	// PerSecond is how many fast successful requests are logged each second,
+	// a negative number logs all of them
+	PerSecond int
+}
+
+// AccessLogReason is why a request was or wasn't access logged
+type AccessLogReason string
+
+const (
+	AccessLogError   AccessLogReason = "error"
+	AccessLogSlow    AccessLogReason = "slow"
+	AccessLogSampled AccessLogReason = "sampled"
+	AccessLogDropped AccessLogReason = "dropped"
+)
+
+// An AccessLogDecision describes a request and whether it was access logged
+type AccessLogDecision struct {
+	Method   string
+	Route    string
+	Tags     []string
+	Status   int
+	Duration time.Duration
+	Logged   bool
+	Reason   AccessLogReason
+}
+
+// An AccessLogRecorder is told every access log sampling decision. A
+// MetricsSink implementing it can count the requests left out of the logs
+type AccessLogRecorder interface {
+	RecordAccessLog(d AccessLogDecision)
+}
+
+// WithAccessLog logs served requests at info level, sampled as configured
+func WithAccessLog(sampling AccessLogSampling) func(r *Router) error {
+	return func(r *Router) error {
+		r.accessLog = &accessLog{sampling: sampling, now: time.Now}
+		return nil
+	}
+}
+
+type accessLog struct {
+	sampling AccessLogSampling
+
+	mu          sync.Mutex
+	windowStart time.Time
+	logged      int
+	now         func() time.Time
+}
+
+// decide picks whether a request is logged
+func (al *accessLog) decide(status int, duration time.Duration) AccessLogReason {
+	if status >= http.StatusBadRequest {
+		return AccessLogError
+	}
+
+	if al.sampling.SlowThreshold > 0 && duration >= al.sampling.SlowThreshold {
+		return AccessLogSlow
+	}
+
+	if al.sampling.PerSecond < 0 {
+		return AccessLogSampled
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	now := al.now()
+	if now.Sub(al.windowStart) >= time.Second {
+		al.windowStart = now
+		al.logged = 0
+	}
+
+	if al.logged >= al.sampling.PerSecond {
+		return AccessLogDropped
+	}
+
+	al.logged++
+	return AccessLogSampled
+}
+
+// record logs req, if sampled, and reports the decision to sink
+func (al *accessLog) record(log lounge.Log, sink MetricsSink, req *http.Request, m httpsnoop.Metrics, format AccessLogFormatter, fields []AccessLogField) {
+	route := RoutePattern(req.Context())
+	if route == "" {
+		route = req.URL.Path
+	}
+
+	reason := al.decide(m.Code, m.Duration)
+	d := AccessLogDecision{
+		Method:   req.Method,
+		Route:    route,
+		Tags:     RouteTags(req.Context()),
+		Status:   m.Code,
+		Duration: m.Duration,
+		Logged:   reason != AccessLogDropped,
+		Reason:   reason,
+	}
+
+	if d.Logged {
+		if format == nil {
+			format = TextAccessLog
+		}
+
+		if len(fields) == 0 {
+			fields = DefaultAccessLogFields
+		}
+
+		log.Infof("%s", format(AccessLogEntry{
+			Time:      time.Now().Add(-m.Duration),
+			Method:    req.Method,
+			Path:      req.URL.Path,
+			URI:       req.URL.RequestURI(),
+			Proto:     req.Proto,
+			Route:     route,
+			Status:    m.Code,
+			Bytes:     m.Written,
+			Latency:   m.Duration,
+			RemoteIP:  remoteIP(req),
+			UserAgent: req.UserAgent(),
+			Referer:   req.Referer(),
+			RequestID: RequestIDFromContext(req.Context()),
+			Reason:    reason,
+		}, fields))
+	}
+
+	if alr, ok := sink.(AccessLogRecorder); ok {
+		alr.RecordAccessLog(d)
+	}
+}
@@ -0,0 +1,186 @@
+package autohttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An AccessLogField is an optional part of an access log line
+type AccessLogField string
+
+const (
+	AccessLogBytes     AccessLogField = "bytes"
+	AccessLogLatency   AccessLogField = "latency"
+	AccessLogRemoteIP  AccessLogField = "remote_ip"
+	AccessLogUserAgent AccessLogField = "user_agent"
+	AccessLogReferer   AccessLogField = "referer"
+	AccessLogRoute     AccessLogField = "route"
+	AccessLogRequestID AccessLogField = "request_id"
+)
+
+// DefaultAccessLogFields are logged when WithAccessLogFormat selects none
+var DefaultAccessLogFields = []AccessLogField{AccessLogBytes, AccessLogLatency, AccessLogRequestID}
+
+// An AccessLogEntry is everything known about a served request
+type AccessLogEntry struct {
+	Time      time.Time
+	Method    string
+	Path      string
+	URI       string
+	Proto     string
+	Route     string
+	Status    int
+	Bytes     int64
+	Latency   time.Duration
+	RemoteIP  string
+	UserAgent string
+	Referer   string
+	RequestID string
+	Reason    AccessLogReason
+}
+
+// An AccessLogFormatter renders an entry as a log line, including the
+// selected fields where the format allows it
+type AccessLogFormatter func(e AccessLogEntry, fields []AccessLogField) string
+
+// WithAccessLogFormat renders access log lines with f, including fields, or
+// DefaultAccessLogFields if none are given. It requires WithAccessLog
+func WithAccessLogFormat(f AccessLogFormatter, fields ...AccessLogField) func(r *Router) error {
+	return func(r *Router) error {
+		if f == nil {
+			return errors.New("autohttp: WithAccessLogFormat needs a formatter")
+		}
+
+		r.accessLogFormat = f
+		r.accessLogFields = fields
+		return nil
+	}
+}
+
+// AccessLogOptIn only access logs routes registered with WithRouteAccessLog(true)
+func AccessLogOptIn(r *Router) error {
+	r.accessLogOptIn = true
+	return nil
+}
+
+// WithRouteAccessLog turns access logging on or off for the route, whatever
+// the router does by default. Star routes can't be switched
+func WithRouteAccessLog(enabled bool) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.accessLog = &enabled
+		return nil
+	}
+}
+
+// accessLogEnabled reports whether req's route is access logged
+func (r *Router) accessLogEnabled(req *http.Request) bool {
+	if enabled, ok := r.accessLogRoutes[req.Method+" "+RoutePattern(req.Context())]; ok {
+		return enabled
+	}
+
+	return !r.accessLogOptIn
+}
+
+// TextAccessLog formats entries as "METHOD /path STATUS" followed by the
+// fields and the sampling reason
+func TextAccessLog(e AccessLogEntry, fields []AccessLogField) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %d", e.Method, e.Path, e.Status)
+
+	for _, f := range fields {
+		switch f {
+		case AccessLogBytes:
+			fmt.Fprintf(&b, " %dB", e.Bytes)
+		case AccessLogLatency:
+			fmt.Fprintf(&b, " %s", e.Latency)
+		case AccessLogUserAgent, AccessLogReferer:
+			if v := e.field(f); v != "" {
+				fmt.Fprintf(&b, " %s=%q", f, v)
+			}
+		default:
+			if v := e.field(f); v != "" {
+				fmt.Fprintf(&b, " %s=%s", f, v)
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, " reason=%s", e.Reason)
+	return b.String()
+}
+
+// JSONAccessLog formats entries as JSON objects, with latency in seconds
+func JSONAccessLog(e AccessLogEntry, fields []AccessLogField) string {
+	obj := map[string]interface{}{
+		"time":   e.Time.UTC().Format(time.RFC3339Nano),
+		"method": e.Method,
+		"path":   e.Path,
+		"status": e.Status,
+		"reason": e.Reason,
+	}
+
+	for _, f := range fields {
+		switch f {
+		case AccessLogBytes:
+			obj[string(f)] = e.Bytes
+		case AccessLogLatency:
+			obj[string(f)] = e.Latency.Seconds()
+		default:
+			if v := e.field(f); v != "" {
+				obj[string(f)] = v
+			}
+		}
+	}
+
+	b, _ := json.Marshal(obj)
+	return string(b)
+}
+
+// CommonAccessLog formats entries in the Common Log Format read by most log
+// analyzers. The format is fixed, so fields are ignored
+func CommonAccessLog(e AccessLogEntry, fields []AccessLogField) string {
+	remote := e.RemoteIP
+	if remote == "" {
+		remote = "-"
+	}
+
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+
+	return fmt.Sprintf("%s - - [%s] %q %d %s", remote, e.Time.Format("02/Jan/2006:15:04:05 -0700"), e.Method+" "+e.URI+" "+e.Proto, e.Status, bytes)
+}
+
+// field returns the string fields of e
+func (e AccessLogEntry) field(f AccessLogField) string {
+	switch f {
+	case AccessLogRemoteIP:
+		return e.RemoteIP
+	case AccessLogUserAgent:
+		return e.UserAgent
+	case AccessLogReferer:
+		return e.Referer
+	case AccessLogRoute:
+		return e.Route
+	case AccessLogRequestID:
+		return e.RequestID
+	}
+
+	return ""
+}
+
+// remoteIP is the address the request came from, without its port
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
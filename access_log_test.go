@@ -0,0 +1,213 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestAccessLogSampling(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	al := &accessLog{
+		sampling: AccessLogSampling{SlowThreshold: time.Second, PerSecond: 2},
+		now:      func() time.Time { return now },
+	}
+
+	cases := []struct {
+		Name     string
+		Advance  time.Duration
+		Status   int
+		Duration time.Duration
+		Expect   AccessLogReason
+	}{
+		{"first", 0, http.StatusOK, time.Millisecond, AccessLogSampled},
+		{"second", 0, http.StatusOK, time.Millisecond, AccessLogSampled},
+		{"over-budget", 0, http.StatusOK, time.Millisecond, AccessLogDropped},
+		{"client-error", 0, http.StatusNotFound, time.Millisecond, AccessLogError},
+		{"server-error", 0, http.StatusInternalServerError, time.Millisecond, AccessLogError},
+		{"slow", 0, http.StatusOK, 2 * time.Second, AccessLogSlow},
+		{"still-over-budget", 500 * time.Millisecond, http.StatusOK, time.Millisecond, AccessLogDropped},
+		{"next-second", 500 * time.Millisecond, http.StatusOK, time.Millisecond, AccessLogSampled},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+		got := al.decide(c.Status, c.Duration)
+		if got != c.Expect {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.Expect, got)
+		}
+	}
+}
+
+type accessLogSink struct {
+	mu        sync.Mutex
+	decisions []AccessLogDecision
+}
+
+func (als *accessLogSink) RecordSample(s RequestSample) {}
+
+func (als *accessLogSink) RecordAccessLog(d AccessLogDecision) {
+	als.mu.Lock()
+	defer als.mu.Unlock()
+	als.decisions = append(als.decisions, d)
+}
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	sink := &accessLogSink{}
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)),
+		WithAccessLog(AccessLogSampling{PerSecond: 1}),
+		WithMetricsSink(sink),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	logged := logs.String()
+	if !strings.Contains(logged, "GET /users/1 200") || strings.Contains(logged, "/users/2") || !strings.Contains(logged, "GET /missing 404") {
+		t.Errorf("unexpected access log %q", logged)
+	}
+
+	expect := []AccessLogDecision{
+		{Method: http.MethodGet, Route: "/users/{id}", Status: http.StatusOK, Logged: true, Reason: AccessLogSampled},
+		{Method: http.MethodGet, Route: "/users/{id}", Status: http.StatusOK, Logged: false, Reason: AccessLogDropped},
+		{Method: http.MethodGet, Route: "/missing", Status: http.StatusNotFound, Logged: true, Reason: AccessLogError},
+	}
+	if len(sink.decisions) != len(expect) {
+		t.Fatalf("expected %d decisions got %d", len(expect), len(sink.decisions))
+	}
+	for i, d := range sink.decisions {
+		d.Duration = 0
+		if !reflect.DeepEqual(d, expect[i]) {
+			t.Errorf("case[%d] expected %+v got %+v", i, expect[i], d)
+		}
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name          string
+		Options       []RouterOption
+		RouteOptions  []RouteOption
+		Path          string
+		ExpectLogged  []string
+		ExpectMissing []string
+	}{
+		{
+			"text-default",
+			nil,
+			nil,
+			"/users/1",
+			[]string{"GET /users/1 200 3B ", " reason=sampled"},
+			[]string{"{"},
+		},
+		{
+			"text-fields",
+			[]RouterOption{WithAccessLogFormat(TextAccessLog, AccessLogRoute, AccessLogRemoteIP, AccessLogUserAgent)},
+			nil,
+			"/users/1",
+			[]string{`GET /users/1 200 route=/users/{id} remote_ip=192.0.2.1 user_agent="probe/1.0 (test)" reason=sampled`},
+			[]string{"3B"},
+		},
+		{
+			"json",
+			[]RouterOption{WithAccessLogFormat(JSONAccessLog, AccessLogBytes, AccessLogRoute)},
+			nil,
+			"/users/1",
+			[]string{`"bytes":3`, `"method":"GET"`, `"route":"/users/{id}"`, `"status":200`},
+			[]string{"user_agent"},
+		},
+		{
+			"common",
+			[]RouterOption{WithAccessLogFormat(CommonAccessLog)},
+			nil,
+			"/users/1?full=true",
+			[]string{`192.0.2.1 - - [`, `] "GET /users/1?full=true HTTP/1.1" 200 3`},
+			nil,
+		},
+		{
+			"route-disabled",
+			nil,
+			[]RouteOption{WithRouteAccessLog(false)},
+			"/users/1",
+			nil,
+			[]string{"/users/1"},
+		},
+		{
+			"opt-in-without-route",
+			[]RouterOption{AccessLogOptIn},
+			nil,
+			"/users/1",
+			nil,
+			[]string{"/users/1"},
+		},
+		{
+			"opt-in-route",
+			[]RouterOption{AccessLogOptIn},
+			[]RouteOption{WithRouteAccessLog(true)},
+			"/users/1",
+			[]string{"GET /users/1 200"},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		var logs syncBuffer
+		opts := append([]RouterOption{WithAccessLog(AccessLogSampling{PerSecond: -1})}, c.Options...)
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.Register(http.MethodGet, "/users/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("rex"))
+		}), nil, c.RouteOptions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, c.Path, nil)
+		req.Header.Set("User-Agent", "probe/1.0 (test)")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		logged := logs.String()
+		for _, s := range c.ExpectLogged {
+			if !strings.Contains(logged, s) {
+				t.Errorf("case[%s] expected %q in %q", c.Name, s, logged)
+			}
+		}
+
+		for _, s := range c.ExpectMissing {
+			if strings.Contains(logged, s) {
+				t.Errorf("case[%s] expected no %q in %q", c.Name, s, logged)
+			}
+		}
+	}
+
+	_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithAccessLogFormat(JSONAccessLog))
+	if err == nil {
+		t.Error("expected WithAccessLogFormat without WithAccessLog to be refused")
+	}
+}
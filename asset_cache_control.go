@@ -0,0 +1,58 @@
+package autohttp
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// CacheControlImmutable lets clients keep an asset for a year without
+	// revalidating it, for files whose names change with their content
+	CacheControlImmutable = "public, max-age=31536000, immutable"
+	// CacheControlNoCache makes clients revalidate an asset on every use,
+	// for entry points such as index.html
+	CacheControlNoCache = "no-cache"
+)
+
+type assetCacheRule struct {
+	pattern string
+	value   string
+}
+
+// WithAssetCacheControl sets the Cache-Control header of assets whose URL
+// path, relative to the mount, matches pattern, as used by path.Match. It
+// can be given more than once, and the first matching pattern wins, e.g.
+//
+//	WithAssetCacheControl("/assets/*", CacheControlImmutable),
+//	WithAssetCacheControl("/index.html", CacheControlNoCache),
+//
+// Requests answered with the root index.html, such as client side routes,
+// match as /index.html. Assets matching no pattern get no Cache-Control
+func WithAssetCacheControl(pattern, value string) AssetOption {
+	return func(ea *embeddedAssets) error {
+		if !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("autohttp: asset cache pattern %q must start with /", pattern)
+		}
+
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("autohttp: asset cache pattern %q: %w", pattern, err)
+		}
+
+		ea.cacheRules = append(ea.cacheRules, assetCacheRule{pattern: pattern, value: value})
+		return nil
+	}
+}
+
+// cacheControlFor returns the Cache-Control of the first rule matching the
+// asset called name, or ""
+func (ea *embeddedAssets) cacheControlFor(name string) string {
+	for _, rule := range ea.cacheRules {
+		if matched, _ := path.Match(rule.pattern, "/"+name); matched {
+			return rule.value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,82 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestAssetCacheControl(t *testing.T) {
+	t.Parallel()
+
+	assets := fstest.MapFS{
+		"dist/index.html":             {Data: []byte("<p>index</p>")},
+		"dist/assets/app-3f2a9c.js":   {Data: []byte(strings.Repeat("console.log('autohttp');\n", 100))},
+		"dist/assets/site-81bd0e.css": {Data: []byte("body {}")},
+		"dist/robots.txt":             {Data: []byte("User-agent: *")},
+	}
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEmbeddedAssets(assets, "dist",
+			WithAssetCompression(),
+			WithAssetCacheControl("/assets/*", CacheControlImmutable),
+			WithAssetCacheControl("/index.html", CacheControlNoCache),
+			WithAssetCacheControl("/*", "public, max-age=60"),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		ifNoneMatch    bool
+
+		code         int
+		cacheControl string
+	}{
+		{name: "hashed", path: "/assets/app-3f2a9c.js", code: http.StatusOK, cacheControl: CacheControlImmutable},
+		{name: "hashed compressed", path: "/assets/app-3f2a9c.js", acceptEncoding: "gzip", code: http.StatusOK, cacheControl: CacheControlImmutable},
+		{name: "hashed not modified", path: "/assets/site-81bd0e.css", ifNoneMatch: true, code: http.StatusNotModified, cacheControl: CacheControlImmutable},
+		{name: "index", path: "/", code: http.StatusOK, cacheControl: CacheControlNoCache},
+		{name: "client side route", path: "/pets/1", code: http.StatusOK, cacheControl: CacheControlNoCache},
+		{name: "fallback pattern", path: "/robots.txt", code: http.StatusOK, cacheControl: "public, max-age=60"},
+		{name: "directory redirect", path: "/assets", code: http.StatusMovedPermanently},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+		if c.ifNoneMatch {
+			req.Header.Set("If-None-Match", r.AssetManifest().ETags[c.path])
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if got := w.Header().Get("Cache-Control"); got != c.cacheControl {
+			t.Errorf("case[%s] expected Cache-Control %q, got %q", c.name, c.cacheControl, got)
+		}
+	}
+
+	for _, pattern := range []string{"assets/*", "/assets/[", ""} {
+		_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEmbeddedAssets(assets, "dist", WithAssetCacheControl(pattern, CacheControlNoCache)))
+		if err == nil {
+			t.Errorf("case[%s] expected the pattern to be rejected", pattern)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package autohttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// MinCompressedAssetBytes is the smallest asset WithAssetCompression
+// compresses, below it the savings don't pay for the headers
+const MinCompressedAssetBytes = 1024
+
+// WithAssetCompression serves compressible assets, such as scripts,
+// stylesheets, HTML, JSON and SVG, compressed to clients that accept it.
+// Precompressed variants shipped alongside an asset, e.g. app.js.gz for gzip
+// or app.js.br for a Compressor whose Encoding is "br", are used as they are.
+// Any other compressible asset is compressed once, when the router is built,
+// and kept in memory so requests cost no compression CPU. compressors are in
+// server preference order and default to gzip at its best compression
+func WithAssetCompression(compressors ...Compressor) AssetOption {
+	return func(ea *embeddedAssets) error {
+		if len(compressors) == 0 {
+			compressors = []Compressor{GzipCompressor{Level: gzip.BestCompression}}
+		}
+
+		ea.compression = &assetCompression{compressors: compressors}
+		return nil
+	}
+}
+
+// compressedAsset holds an asset's compressed variants by encoding
+type compressedAsset struct {
+	contentType string
+	modTime     time.Time
+	variants    map[string][]byte
+	// compressors are those with a variant, in preference order
+	compressors []Compressor
+}
+
+type assetCompression struct {
+	compressors []Compressor
+	assets      map[string]*compressedAsset
+}
+
+// precompressedExt is the file extension of precompressed variants for encoding
+func precompressedExt(encoding string) string {
+	switch encoding {
+	case "gzip":
+		return ".gz"
+	}
+
+	return "." + encoding
+}
+
+// build compresses, or loads the precompressed variants of, every
+// compressible asset in assets
+func (ac *assetCompression) build(assets fs.FS) error {
+	ac.assets = make(map[string]*compressedAsset)
+
+	return fs.WalkDir(assets, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || isHiddenAsset(name) || isPrecompressedVariant(name, ac.compressors) {
+			return nil
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if !isCompressibleType(contentType) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		b, err := fs.ReadFile(assets, name)
+		if err != nil {
+			return err
+		}
+
+		asset := &compressedAsset{contentType: contentType, modTime: info.ModTime(), variants: make(map[string][]byte)}
+		for _, c := range ac.compressors {
+			variant, err := fs.ReadFile(assets, name+precompressedExt(c.Encoding()))
+			if err != nil {
+				if len(b) < MinCompressedAssetBytes {
+					continue
+				}
+
+				variant, err = compressBytes(c, b)
+				if err != nil {
+					return err
+				}
+
+				if len(variant) >= len(b) {
+					continue
+				}
+			}
+
+			asset.variants[c.Encoding()] = variant
+			asset.compressors = append(asset.compressors, c)
+		}
+
+		if len(asset.variants) > 0 {
+			ac.assets[name] = asset
+		}
+
+		return nil
+	})
+}
+
+// serve writes the compressed variant of the requested asset, if the client
+// accepts one, and reports whether it did
+func (ac *assetCompression) serve(w http.ResponseWriter, req *http.Request) bool {
+	asset, ok := ac.asset(req.URL.Path)
+	if !ok {
+		return false
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	compressor := ac.negotiate(req, asset)
+	if compressor == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", asset.contentType)
+	w.Header().Set("Content-Encoding", compressor.Encoding())
+	http.ServeContent(w, req, req.URL.Path, asset.modTime, bytes.NewReader(asset.variants[compressor.Encoding()]))
+	return true
+}
+
+// asset returns the compressed variants of the asset at urlPath, if it has any
+func (ac *assetCompression) asset(urlPath string) (*compressedAsset, bool) {
+	// the file server redirects index.html requests to their directory
+	if strings.HasSuffix(urlPath, "/index.html") {
+		return nil, false
+	}
+
+	name := assetName(urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		name = path.Join(name, "index.html")
+	}
+
+	asset, ok := ac.assets[name]
+	return asset, ok
+}
+
+// negotiate picks the variant of asset the client accepts, or nil
+func (ac *assetCompression) negotiate(req *http.Request, asset *compressedAsset) Compressor {
+	return negotiateCompressor(req.Header.Get("Accept-Encoding"), asset.compressors)
+}
+
+func compressBytes(c Compressor, b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	cw, err := c.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cw.Write(b)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isPrecompressedVariant reports whether name is a precompressed copy of
+// another asset, rather than an asset of its own
+func isPrecompressedVariant(name string, compressors []Compressor) bool {
+	for _, c := range compressors {
+		if strings.HasSuffix(name, precompressedExt(c.Encoding())) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isCompressibleType reports whether content of contentType shrinks when
+// compressed. Images other than SVG, fonts, archives and media already are
+func isCompressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+
+	switch mediaType {
+	case "application/javascript", "application/json", "application/xml", "application/wasm", "application/manifest+json", "image/svg+xml":
+		return true
+	}
+
+	return false
+}
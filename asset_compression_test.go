@@ -0,0 +1,85 @@
+package autohttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestAssetCompression(t *testing.T) {
+	script := strings.Repeat("console.log('autohttp');\n", 100)
+	assets := fstest.MapFS{
+		"dist/index.html":      {Data: []byte(strings.Repeat("<p>index</p>\n", 100))},
+		"dist/app.js":          {Data: []byte(script)},
+		"dist/small.css":       {Data: []byte(`body{}`)},
+		"dist/photo.png":       {Data: bytes.Repeat([]byte{0}, 4096)},
+		"dist/vendor.js":       {Data: []byte(script)},
+		"dist/vendor.js.gz":    {Data: []byte(`precompressed`)},
+		"dist/docs/index.html": {Data: []byte(strings.Repeat("<p>docs</p>\n", 100))},
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEmbeddedAssets(assets, "dist", WithAssetCompression()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Path           string
+		AcceptEncoding string
+		ExpectEncoding string
+		ExpectBody     string
+	}{
+		{"/app.js", "gzip", "gzip", script},
+		{"/app.js", "", "", script},
+		{"/app.js", "br", "", script},
+		{"/small.css", "gzip", "", `body{}`},
+		{"/photo.png", "gzip", "", string(bytes.Repeat([]byte{0}, 4096))},
+		{"/vendor.js", "gzip", "gzip", `precompressed`},
+		{"/docs/", "gzip", "gzip", strings.Repeat("<p>docs</p>\n", 100)},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.Path, nil)
+		if c.AcceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.AcceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s %s] expected 200 got %d", c.Path, c.AcceptEncoding, w.Code)
+			continue
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != c.ExpectEncoding {
+			t.Errorf("case[%s %s] expected encoding %q got %q", c.Path, c.AcceptEncoding, c.ExpectEncoding, got)
+			continue
+		}
+
+		body := w.Body.Bytes()
+		if c.ExpectEncoding == "gzip" && c.ExpectBody != `precompressed` {
+			gr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, err = io.ReadAll(gr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if string(body) != c.ExpectBody {
+			t.Errorf("case[%s %s] unexpected body %q", c.Path, c.AcceptEncoding, body)
+		}
+	}
+}
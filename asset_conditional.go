@@ -0,0 +1,160 @@
+package autohttp
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// assetValidators are the precomputed ETags and modification time of an
+// asset, so conditional requests are answered without hashing or formatting
+type assetValidators struct {
+	etag string
+	// variantETags maps content codings to the ETags of compressed variants
+	variantETags map[string]string
+
+	modTime      time.Time
+	lastModified string
+	cacheControl string
+}
+
+// buildValidators indexes the assets and their validators. It runs after
+// compression, so compressed variants get ETags of their own
+func (ea *embeddedAssets) buildValidators() error {
+	ea.validators = make(map[string]*assetValidators)
+	ea.dirs = make(map[string]bool)
+
+	return fs.WalkDir(ea.staticDir, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			ea.dirs[name] = true
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		modTime := info.ModTime()
+		if modTime.IsZero() {
+			modTime = ea.startedAt
+		}
+
+		etag := ea.manifest.ETags["/"+name]
+		av := &assetValidators{
+			etag:         etag,
+			modTime:      modTime,
+			lastModified: modTime.UTC().Format(http.TimeFormat),
+			cacheControl: ea.cacheControlFor(name),
+		}
+
+		if ea.compression != nil {
+			if asset, ok := ea.compression.assets[name]; ok {
+				av.variantETags = make(map[string]string, len(asset.compressors))
+				for _, c := range asset.compressors {
+					av.variantETags[c.Encoding()] = strings.TrimSuffix(etag, `"`) + "-" + c.Encoding() + `"`
+				}
+			}
+		}
+
+		ea.validators[name] = av
+		return nil
+	})
+}
+
+// serveNotModified sets the ETag, Last-Modified and Cache-Control of the
+// asset requested, answering the request with a 304 if the client's copy is
+// still current. It reports whether it did
+func (ea *embeddedAssets) serveNotModified(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	name, ok := ea.servedAssetName(req.URL.Path)
+	if !ok {
+		return false
+	}
+
+	av := ea.validators[name]
+	etag := av.etag
+	if av.variantETags != nil {
+		if asset, ok := ea.compression.asset(req.URL.Path); ok {
+			if c := ea.compression.negotiate(req, asset); c != nil {
+				etag = av.variantETags[c.Encoding()]
+			}
+		}
+	}
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Last-Modified", av.lastModified)
+	if av.cacheControl != "" {
+		h.Set("Cache-Control", av.cacheControl)
+	}
+
+	if !assetNotModified(req, etag, av.modTime) {
+		return false
+	}
+
+	if av.variantETags != nil {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	// a 304 carries the validators, not the headers describing a body
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	h.Del("Last-Modified")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// servedAssetName is the name of the file the file server answers urlPath
+// with, or false for redirects and directory listings
+func (ea *embeddedAssets) servedAssetName(urlPath string) (string, bool) {
+	// the file server redirects index.html requests to their directory
+	if strings.HasSuffix(urlPath, "/index.html") {
+		return "", false
+	}
+
+	name := assetName(urlPath)
+	if _, ok := ea.validators[name]; ok {
+		return name, true
+	}
+
+	if !ea.dirs[name] {
+		// unknown paths get the root index.html
+		_, ok := ea.validators["index.html"]
+		return "index.html", ok
+	}
+
+	// directories are redirected to a trailing slash first
+	if name != "." && !strings.HasSuffix(urlPath, "/") {
+		return "", false
+	}
+
+	name = path.Join(name, "index.html")
+	_, ok := ea.validators[name]
+	return name, ok
+}
+
+// assetNotModified evaluates the request's preconditions, where
+// If-None-Match takes precedence over If-Modified-Since
+func assetNotModified(req *http.Request, etag string, modTime time.Time) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package autohttp
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestAssetConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	script := strings.Repeat("console.log('autohttp');\n", 100)
+	assets := fstest.MapFS{
+		"dist/index.html": {Data: []byte("<p>index</p>")},
+		"dist/app.js":     {Data: []byte(script), ModTime: modTime},
+		"dist/logo.svg":   {Data: []byte("<svg/>")},
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEmbeddedAssets(assets, "dist", WithAssetCompression()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte(script))
+	appETag := fmt.Sprintf(`"%x"`, sum[:16])
+	if got := r.AssetManifest().ETags["/app.js"]; got != appETag {
+		t.Fatalf("expected the manifest ETag %s, got %s", appETag, got)
+	}
+	indexETag := r.AssetManifest().ETags["/index.html"]
+	gzipETag := strings.TrimSuffix(appETag, `"`) + `-gzip"`
+
+	cases := []struct {
+		name            string
+		path            string
+		acceptEncoding  string
+		ifNoneMatch     string
+		ifModifiedSince time.Time
+
+		code int
+		etag string
+	}{
+		{name: "unconditional", path: "/app.js", code: http.StatusOK, etag: appETag},
+		{name: "current", path: "/app.js", ifNoneMatch: appETag, code: http.StatusNotModified, etag: appETag},
+		{name: "weakly current", path: "/app.js", ifNoneMatch: `"other", W/` + appETag, code: http.StatusNotModified, etag: appETag},
+		{name: "stale", path: "/app.js", ifNoneMatch: `"other"`, code: http.StatusOK, etag: appETag},
+		{name: "not modified since", path: "/app.js", ifModifiedSince: modTime, code: http.StatusNotModified, etag: appETag},
+		{name: "modified since", path: "/app.js", ifModifiedSince: modTime.Add(-time.Hour), code: http.StatusOK, etag: appETag},
+		{name: "if-none-match wins", path: "/app.js", ifNoneMatch: `"other"`, ifModifiedSince: modTime, code: http.StatusOK, etag: appETag},
+		{name: "compressed", path: "/app.js", acceptEncoding: "gzip", code: http.StatusOK, etag: gzipETag},
+		{name: "compressed current", path: "/app.js", acceptEncoding: "gzip", ifNoneMatch: gzipETag, code: http.StatusNotModified, etag: gzipETag},
+		{name: "other encoding", path: "/app.js", acceptEncoding: "gzip", ifNoneMatch: appETag, code: http.StatusOK, etag: gzipETag},
+		{name: "embedded without a modification time", path: "/logo.svg", ifModifiedSince: time.Now().Add(time.Minute), code: http.StatusNotModified, etag: r.AssetManifest().ETags["/logo.svg"]},
+		{name: "index", path: "/", ifNoneMatch: indexETag, code: http.StatusNotModified, etag: indexETag},
+		{name: "client side route", path: "/settings", code: http.StatusOK, etag: indexETag},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+		if c.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", c.ifNoneMatch)
+		}
+		if !c.ifModifiedSince.IsZero() {
+			req.Header.Set("If-Modified-Since", c.ifModifiedSince.Format(http.TimeFormat))
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if got := w.Header().Get("ETag"); got != c.etag {
+			t.Errorf("case[%s] expected ETag %s, got %s", c.name, c.etag, got)
+		}
+
+		if c.code == http.StatusNotModified && w.Body.Len() > 0 {
+			t.Errorf("case[%s] expected no body, got %d bytes", c.name, w.Body.Len())
+		}
+
+		if c.code == http.StatusOK && w.Header().Get("Last-Modified") == "" {
+			t.Errorf("case[%s] expected a Last-Modified header", c.name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package autohttp
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"path"
+)
+
+// sriExtensions are the asset types browsers verify with Subresource Integrity
+var sriExtensions = map[string]bool{
+	".js":  true,
+	".mjs": true,
+	".css": true,
+}
+
+// An AssetManifest describes the embedded assets, computed once at startup
+type AssetManifest struct {
+	// Integrity maps asset URL paths, such as /app.js, to their
+	// Subresource Integrity hashes
+	Integrity map[string]string
+
+	// Hashes maps asset URL paths to the hex SHA-256 of their content
+	Hashes map[string]string
+
+	// ETags maps asset URL paths to strong ETags derived from their Hashes
+	ETags map[string]string
+
+	// Critical lists the assets every page load needs, see WithCriticalAssets
+	Critical []string
+}
+
+func newAssetManifest(assets fs.FS) (*AssetManifest, error) {
+	am := &AssetManifest{
+		Integrity: make(map[string]string),
+		Hashes:    make(map[string]string),
+		ETags:     make(map[string]string),
+	}
+
+	err := fs.WalkDir(assets, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		b, err := fs.ReadFile(assets, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(b)
+		hash := hex.EncodeToString(sum[:])
+		am.Hashes["/"+name] = hash
+		am.ETags["/"+name] = `"` + hash[:32] + `"`
+
+		if sriExtensions[path.Ext(name)] {
+			sri := sha512.Sum384(b)
+			am.Integrity["/"+name] = "sha384-" + base64.StdEncoding.EncodeToString(sri[:])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return am, nil
+}
+
+// TemplateFuncs returns template functions exposing the manifest.
+// {{ integrity "/app.js" }} renders the SRI hash for an asset, and
+// {{ hash "/app.js" }} its content hash, e.g. for cache busting URLs
+func (am *AssetManifest) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"integrity": func(urlPath string) string {
+			return am.Integrity[urlPath]
+		},
+		"hash": func(urlPath string) string {
+			return am.Hashes[urlPath]
+		},
+	}
+}
+
+// AssetManifest returns the manifest of the embedded assets,
+// or nil if the router has none
+func (r *Router) AssetManifest() *AssetManifest {
+	if r.embeddedAssets == nil {
+		return nil
+	}
+
+	return r.embeddedAssets.manifest
+}
@@ -0,0 +1,72 @@
+package autohttp
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestAssetManifestIntegrity(t *testing.T) {
+	js := []byte(`console.log("hi")`)
+	sum := sha512.Sum384(js)
+	expected := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	assets := fstest.MapFS{
+		"dist/index.html":      {Data: []byte(`<script src="/js/app.js?v={{ hash "/js/app.js" }}" integrity="{{ integrity "/js/app.js" }}"></script>`)},
+		"dist/js/app.js":       {Data: js},
+		"dist/styles/site.css": {Data: []byte(`body {}`)},
+		"dist/logo.png":        {Data: []byte(`png`)},
+	}
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEmbeddedAssets(assets, "dist"),
+		WithCSPNonces(DefaultCSPNoncePolicy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := r.AssetManifest()
+	if manifest.Integrity["/js/app.js"] != expected {
+		t.Errorf("expected %q got %q", expected, manifest.Integrity["/js/app.js"])
+	}
+
+	if _, ok := manifest.Integrity["/styles/site.css"]; !ok {
+		t.Error("expected an integrity hash for css")
+	}
+
+	if _, ok := manifest.Integrity["/logo.png"]; ok {
+		t.Error("expected no integrity hash for images")
+	}
+
+	hash := sha256.Sum256(js)
+	expectedHash := hex.EncodeToString(hash[:])
+	if manifest.Hashes["/js/app.js"] != expectedHash {
+		t.Errorf("expected hash %q got %q", expectedHash, manifest.Hashes["/js/app.js"])
+	}
+
+	if _, ok := manifest.Hashes["/logo.png"]; !ok {
+		t.Error("expected a content hash for every asset")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("integrity hash not rendered into %q", w.Body.String())
+	}
+
+	if !strings.Contains(w.Body.String(), "?v="+expectedHash) {
+		t.Errorf("content hash not rendered into %q", w.Body.String())
+	}
+}
@@ -0,0 +1,68 @@
+package autohttp
+
+import (
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// an assetMount serves embedded assets under a path prefix, ahead of the routes
+type assetMount struct {
+	prefix  string
+	assets  *embeddedAssets
+	handler http.Handler
+}
+
+func (am *assetMount) matches(urlPath string) bool {
+	return hasPathPrefix(urlPath, am.prefix)
+}
+
+// MountAssets serves the assets found at dir within assets under prefix.
+// Mounts are matched before routes, so a catch-all mount at "/" should use
+// AssetNotFoundFallThrough to let routes such as /api coexist with it.
+// Longer prefixes are matched first
+func (r *Router) MountAssets(prefix string, assets fs.FS, dir string, opts ...AssetOption) error {
+	ea, err := newEmbeddedAssets(assets, dir, opts...)
+	if err != nil {
+		return err
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	ea.urlPrefix = prefix
+
+	r.assetMounts = append(r.assetMounts, &assetMount{
+		prefix:  prefix,
+		assets:  ea,
+		handler: http.StripPrefix(prefix, ea),
+	})
+
+	sort.SliceStable(r.assetMounts, func(i, j int) bool {
+		return len(r.assetMounts[i].prefix) > len(r.assetMounts[j].prefix)
+	})
+
+	return nil
+}
+
+// serveAssetMount serves req from the first matching mount, and reports
+// whether it did so
+func (r *Router) serveAssetMount(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	for _, am := range r.assetMounts {
+		if !am.matches(req.URL.Path) {
+			continue
+		}
+
+		if am.assets.notFound == AssetNotFoundFallThrough && !am.assets.resolves(strings.TrimPrefix(req.URL.Path, am.prefix)) {
+			return false
+		}
+
+		am.handler.ServeHTTP(w, req)
+		return true
+	}
+
+	return false
+}
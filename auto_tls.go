@@ -0,0 +1,103 @@
+package autohttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// DefaultChallengeAddr is where WithAutoTLS answers ACME HTTP-01 challenges
+// and redirects plain HTTP traffic to HTTPS
+const DefaultChallengeAddr = ":80"
+
+// errTLSConfigConflict is returned for servers given both WithAutoTLS and a
+// TLSConfig of their own, which WithAutoTLS would replace
+var errTLSConfigConflict = errors.New("autohttp: WithAutoTLS can't be combined with a TLSConfig set by another option")
+
+// A CertManager obtains and renews TLS certificates, answering the ACME
+// HTTP-01 challenges for them. *autocert.Manager from
+// golang.org/x/crypto/acme/autocert satisfies it, e.g.
+//
+//	&autocert.Manager{
+//		Prompt:     autocert.AcceptTOS,
+//		HostPolicy: autocert.HostWhitelist("example.com"),
+//		Cache:      autocert.DirCache("certs"),
+//	}
+type CertManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	// HTTPHandler answers ACME challenges and hands every other request to fallback
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// WithAutoTLS serves HTTPS on :443 with certificates from m, and plain HTTP
+// on DefaultChallengeAddr answering ACME challenges and redirecting all other
+// traffic to HTTPS. It pairs with EnableHSTS on the router, and can't be
+// combined with options setting the server's TLSConfig
+func WithAutoTLS(m CertManager) ServerOption {
+	return WithAutoTLSAddrs(m, ":443", DefaultChallengeAddr)
+}
+
+// WithAutoTLSAddrs is WithAutoTLS serving HTTPS on addr and challenges and
+// redirects on challengeAddr
+func WithAutoTLSAddrs(m CertManager, addr, challengeAddr string) ServerOption {
+	return func(s *Server) error {
+		if m == nil {
+			return errors.New("autohttp: WithAutoTLS needs a CertManager")
+		}
+
+		_, httpsPort, err := net.SplitHostPort(addr)
+		if err != nil {
+			return errors.New("autohttp: WithAutoTLS needs addresses of the form host:port")
+		}
+
+		_, _, err = net.SplitHostPort(challengeAddr)
+		if err != nil {
+			return errors.New("autohttp: WithAutoTLS needs addresses of the form host:port")
+		}
+
+		if s.TLSConfig != nil {
+			return errTLSConfigConflict
+		}
+
+		s.Addr = addr
+		s.TLSConfig = &tls.Config{
+			GetCertificate: m.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+			// acme-tls/1 lets the manager answer TLS-ALPN-01 challenges too
+			NextProtos: []string{"h2", "http/1.1", "acme-tls/1"},
+		}
+		s.autoTLSConfig = s.TLSConfig
+		s.httpServer = &http.Server{
+			Addr:              challengeAddr,
+			Handler:           m.HTTPHandler(httpsRedirect{port: httpsPort}),
+			ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		}
+
+		return nil
+	}
+}
+
+// httpsRedirect sends requests to the same URL over HTTPS
+type httpsRedirect struct {
+	port string
+}
+
+func (hr httpsRedirect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if hr.port != "" && hr.port != "443" {
+		host = net.JoinHostPort(host, hr.port)
+	}
+
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		// keep the method and body of anything other than a plain fetch
+		code = http.StatusPermanentRedirect
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+}
@@ -0,0 +1,204 @@
+package autohttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+// staticCertManager serves a single certificate and a fixed challenge token
+type staticCertManager struct {
+	cert *tls.Certificate
+}
+
+func (scm staticCertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return scm.cert, nil
+}
+
+func (scm staticCertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+			io.WriteString(w, "token")
+			return
+		}
+
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+func selfSignedCert(t *testing.T) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestAutoTLSChallengesAndRedirects(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewServer(r, WithAutoTLS(nil))
+	if err == nil {
+		t.Fatal("expected an error without a CertManager")
+	}
+
+	cases := []struct {
+		Name           string
+		Addr           string
+		Method         string
+		Target         string
+		ExpectCode     int
+		ExpectLocation string
+	}{
+		{"challenge", ":443", http.MethodGet, "http://example.com/.well-known/acme-challenge/abc", http.StatusOK, ""},
+		{"redirect", ":443", http.MethodGet, "http://example.com/pets?limit=1", http.StatusMovedPermanently, "https://example.com/pets?limit=1"},
+		{"redirect-post", ":443", http.MethodPost, "http://example.com:80/pets", http.StatusPermanentRedirect, "https://example.com/pets"},
+		{"redirect-port", ":8443", http.MethodGet, "http://example.com/", http.StatusMovedPermanently, "https://example.com:8443/"},
+	}
+
+	for _, c := range cases {
+		s, err := NewServer(r, WithAutoTLSAddrs(staticCertManager{}, c.Addr, ":8080"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, httptest.NewRequest(c.Method, c.Target, nil))
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if w.Header().Get("Location") != c.ExpectLocation {
+			t.Errorf("case[%s] expected Location %q got %q", c.Name, c.ExpectLocation, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestAutoTLSServesHTTPS(t *testing.T) {
+	t.Parallel()
+
+	cert := selfSignedCert(t)
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "pong")
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(r, WithAutoTLS(staticCertManager{cert: cert}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}}}
+	resp, err := client.Get("https://" + l.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "pong" {
+		t.Errorf("expected pong got %q", body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-served; err != nil {
+		t.Fatalf("expected nil from Serve after shutdown got %v", err)
+	}
+}
+
+func TestAutoTLSConflictsWithTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := staticCertManager{cert: selfSignedCert(t)}
+	withTLSConfig := func(s *Server) error {
+		s.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS13}
+		return nil
+	}
+
+	cases := []struct {
+		Name string
+		Opts []ServerOption
+	}{
+		{"before", []ServerOption{withTLSConfig, WithAutoTLS(m)}},
+		{"after", []ServerOption{WithAutoTLS(m), withTLSConfig}},
+	}
+
+	for _, c := range cases {
+		_, err := NewServer(r, c.Opts...)
+		if err != errTLSConfigConflict {
+			t.Errorf("case[%s] expected the TLSConfig conflict got %v", c.Name, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package autohttptest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fortytw2/lounge"
+	"github.com/jwfriese/autohttp"
+)
+
+// A PerformanceCase compares an autohttp handler function against the
+// net/http handler you would have written by hand instead
+type PerformanceCase struct {
+	Name string
+
+	// Method defaults to POST, Body is sent as application/json
+	Method string
+	Body   string
+
+	Fn       interface{}
+	Baseline http.Handler
+}
+
+// A Measurement is the average cost of serving one request
+type Measurement struct {
+	NsPerOp     int64
+	AllocsPerOp uint64
+	BytesPerOp  uint64
+}
+
+// A PerformanceResult holds both sides of a PerformanceCase
+type PerformanceResult struct {
+	Name     string
+	Autohttp Measurement
+	NetHTTP  Measurement
+}
+
+// Overhead is the extra time per request autohttp spends over the baseline
+func (pr PerformanceResult) Overhead() time.Duration {
+	return time.Duration(pr.Autohttp.NsPerOp - pr.NetHTTP.NsPerOp)
+}
+
+type PerformanceResults []PerformanceResult
+
+func (prs PerformanceResults) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "case\tautohttp ns/op\tnet/http ns/op\toverhead\tautohttp allocs/op\tnet/http allocs/op")
+	for _, pr := range prs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%d\t%d\n", pr.Name, pr.Autohttp.NsPerOp, pr.NetHTTP.NsPerOp, pr.Overhead(), pr.Autohttp.AllocsPerOp, pr.NetHTTP.AllocsPerOp)
+	}
+	tw.Flush()
+
+	return b.String()
+}
+
+type performanceInput struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Owner struct {
+		Login string `json:"login"`
+		Admin bool   `json:"admin"`
+	} `json:"owner"`
+	Stars int     `json:"stars"`
+	Score float64 `json:"score"`
+}
+
+const performancePayload = `{"id": 4215, "name": "autohttp", "tags": ["http", "reflection", "json"], "owner": {"login": "jwfriese", "admin": true}, "stars": 128, "score": 0.87}`
+
+// DefaultPerformanceCases cover the common handler shapes, a bare function
+// and a JSON round trip, used by PerformanceReport when given no cases
+var DefaultPerformanceCases = []PerformanceCase{
+	{
+		Name: "empty",
+		Fn:   func() {},
+		Baseline: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	},
+	{
+		Name: "json-round-trip",
+		Body: performancePayload,
+		Fn: func(in performanceInput) performanceInput {
+			return in
+		},
+		Baseline: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var in performanceInput
+			err := json.NewDecoder(r.Body).Decode(&in)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(in)
+		}),
+	},
+}
+
+// PerformanceReport serves every case iterations times through a Router and
+// through its net/http baseline, so the overhead autohttp adds for a given
+// handler shape can be quantified. Both sides pay for an httptest recorder
+// and request per iteration, so the difference is what autohttp costs.
+// Allocations are read from the process wide memory stats, so the numbers
+// are only meaningful when nothing else is running, e.g. not alongside
+// parallel tests
+func PerformanceReport(iterations int, cases ...PerformanceCase) (PerformanceResults, error) {
+	if iterations <= 0 {
+		return nil, errors.New("autohttptest: iterations must be positive")
+	}
+
+	if len(cases) == 0 {
+		cases = DefaultPerformanceCases
+	}
+
+	results := make(PerformanceResults, 0, len(cases))
+	for _, c := range cases {
+		method := c.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+
+		r, err := autohttp.NewRouter(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)))
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.Register(method, "/bench", c.Fn, nil)
+		if err != nil {
+			return nil, fmt.Errorf("autohttptest: case %s: %w", c.Name, err)
+		}
+
+		newRequest := func() *http.Request {
+			req := httptest.NewRequest(method, "/bench", strings.NewReader(c.Body))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		}
+
+		result := PerformanceResult{
+			Name:     c.Name,
+			Autohttp: measureHandler(r, newRequest, iterations),
+		}
+		if c.Baseline != nil {
+			result.NetHTTP = measureHandler(c.Baseline, newRequest, iterations)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func measureHandler(h http.Handler, newRequest func() *http.Request, iterations int) Measurement {
+	// warm up pools and caches before measuring
+	h.ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	n := uint64(iterations)
+	return Measurement{
+		NsPerOp:     elapsed.Nanoseconds() / int64(iterations),
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / n,
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / n,
+	}
+}
@@ -0,0 +1,65 @@
+package autohttptest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+	"github.com/jwfriese/autohttp"
+)
+
+func TestPerformanceReport(t *testing.T) {
+	results, err := PerformanceReport(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(DefaultPerformanceCases) {
+		t.Fatalf("expected %d results got %d", len(DefaultPerformanceCases), len(results))
+	}
+
+	for _, pr := range results {
+		if pr.Autohttp.NsPerOp <= 0 || pr.NetHTTP.NsPerOp <= 0 {
+			t.Errorf("case[%s] expected both sides to be measured", pr.Name)
+		}
+	}
+
+	if !strings.Contains(results.String(), "json-round-trip") {
+		t.Errorf("expected the report to name every case, got\n%s", results)
+	}
+
+	_, err = PerformanceReport(0)
+	if err == nil {
+		t.Error("expected zero iterations to fail")
+	}
+}
+
+func BenchmarkPerformanceCases(b *testing.B) {
+	for _, c := range DefaultPerformanceCases {
+		c := c
+		r, err := autohttp.NewRouter(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		err = r.Register(http.MethodPost, "/bench", c.Fn, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for name, h := range map[string]http.Handler{"autohttp": r, "net-http": c.Baseline} {
+			h := h
+			b.Run(c.Name+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					req := httptest.NewRequest(http.MethodPost, "/bench", strings.NewReader(c.Body))
+					req.Header.Set("Content-Type", "application/json")
+					h.ServeHTTP(httptest.NewRecorder(), req)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package autohttptest holds helpers for testing autohttp routers
+package autohttptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// DefaultSoakConcurrency is the number of workers of a Soak that doesn't set one
+const DefaultSoakConcurrency = 8
+
+// DefaultSoakRequests is the number of requests sent by a Soak that sets
+// neither Requests nor Duration
+const DefaultSoakRequests = 1000
+
+// A Soak drives a handler in-process with concurrent requests, so routing and
+// codec regressions show up in go test. It stops after Requests requests or
+// once Duration has passed, whichever comes first
+type Soak struct {
+	// Concurrency is the number of concurrent workers
+	Concurrency int
+	Requests    int
+	Duration    time.Duration
+
+	// NewRequest builds the i-th request. Requests are served directly, so
+	// they don't need a host
+	NewRequest func(i int) *http.Request
+
+	// IsError reports whether a response counts against the error budget,
+	// by default any 5xx
+	IsError func(statusCode int) bool
+
+	Budget SoakBudget
+}
+
+// A SoakBudget is the latency and error rate a Soak must stay within. Zero
+// values aren't checked
+type SoakBudget struct {
+	P50 time.Duration
+	P99 time.Duration
+	Max time.Duration
+	// ErrorRate is the fraction of requests, from 0 to 1, allowed to error
+	ErrorRate float64
+}
+
+// A SoakResult summarizes the requests served during a Soak
+type SoakResult struct {
+	Requests    int
+	Errors      int
+	Elapsed     time.Duration
+	StatusCodes map[int]int
+
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// ErrorRate is the fraction of requests that errored
+func (sr SoakResult) ErrorRate() float64 {
+	if sr.Requests == 0 {
+		return 0
+	}
+
+	return float64(sr.Errors) / float64(sr.Requests)
+}
+
+// Throughput is the requests served per second
+func (sr SoakResult) Throughput() float64 {
+	if sr.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(sr.Requests) / sr.Elapsed.Seconds()
+}
+
+func (sr SoakResult) String() string {
+	return fmt.Sprintf("%d requests in %s (%.0f/s), %d errors, p50=%s p95=%s p99=%s max=%s",
+		sr.Requests, sr.Elapsed, sr.Throughput(), sr.Errors, sr.P50, sr.P95, sr.P99, sr.Max)
+}
+
+// Violations lists the ways sr breaks budget, if any
+func (sr SoakResult) Violations(budget SoakBudget) []string {
+	var violations []string
+	check := func(name string, got, limit time.Duration) {
+		if limit > 0 && got > limit {
+			violations = append(violations, fmt.Sprintf("%s %s over budget of %s", name, got, limit))
+		}
+	}
+
+	check("p50", sr.P50, budget.P50)
+	check("p99", sr.P99, budget.P99)
+	check("max", sr.Max, budget.Max)
+
+	if budget.ErrorRate > 0 && sr.ErrorRate() > budget.ErrorRate || budget.ErrorRate == 0 && sr.Errors > 0 {
+		violations = append(violations, fmt.Sprintf("error rate %.4f over budget of %.4f", sr.ErrorRate(), budget.ErrorRate))
+	}
+
+	return violations
+}
+
+// Run serves the soak's requests with h and summarizes them
+func (s Soak) Run(h http.Handler) SoakResult {
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSoakConcurrency
+	}
+
+	isError := s.IsError
+	if isError == nil {
+		isError = func(statusCode int) bool { return statusCode >= http.StatusInternalServerError }
+	}
+
+	requests := s.Requests
+	if requests <= 0 && s.Duration <= 0 {
+		requests = DefaultSoakRequests
+	}
+
+	var deadline time.Time
+	start := time.Now()
+	if s.Duration > 0 {
+		deadline = start.Add(s.Duration)
+	}
+
+	type worker struct {
+		latencies []time.Duration
+		codes     map[int]int
+		errors    int
+	}
+
+	var next int64 = -1
+	workers := make([]*worker, concurrency)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wk := &worker{codes: make(map[int]int)}
+		workers[w] = wk
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if requests > 0 && i >= requests || !deadline.IsZero() && time.Now().After(deadline) {
+					return
+				}
+
+				rec := httptest.NewRecorder()
+				req := s.NewRequest(i)
+
+				began := time.Now()
+				h.ServeHTTP(rec, req)
+				wk.latencies = append(wk.latencies, time.Since(began))
+
+				wk.codes[rec.Code]++
+				if isError(rec.Code) {
+					wk.errors++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := SoakResult{Elapsed: time.Since(start), StatusCodes: make(map[int]int)}
+	var latencies []time.Duration
+	for _, wk := range workers {
+		latencies = append(latencies, wk.latencies...)
+		result.Errors += wk.errors
+		for code, n := range wk.codes {
+			result.StatusCodes[code] += n
+		}
+	}
+
+	result.Requests = len(latencies)
+	if len(latencies) == 0 {
+		return result
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+
+	result.P50 = percentile(0.50)
+	result.P95 = percentile(0.95)
+	result.P99 = percentile(0.99)
+	result.Max = latencies[len(latencies)-1]
+
+	return result
+}
+
+// Assert runs the soak against h and fails t for every budget it breaks
+func (s Soak) Assert(t testing.TB, h http.Handler) SoakResult {
+	t.Helper()
+
+	result := s.Run(h)
+	if result.Requests == 0 {
+		t.Errorf("soak served no requests")
+		return result
+	}
+
+	violations := result.Violations(s.Budget)
+	if len(violations) > 0 {
+		t.Errorf("soak over budget: %s\n%s", strings.Join(violations, ", "), result)
+	}
+
+	return result
+}
@@ -0,0 +1,92 @@
+package autohttptest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+	"github.com/jwfriese/autohttp"
+)
+
+type soakPet struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestSoak(t *testing.T) {
+	r, err := autohttp.NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/pets/{id}", func(ctx context.Context, p soakPet) (soakPet, error) {
+		p.ID, _ = strconv.Atoi(autohttp.PathParam(ctx, "id"))
+		return p, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := Soak{
+		Concurrency: 4,
+		Requests:    500,
+		NewRequest: func(i int) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/pets/"+strconv.Itoa(i), strings.NewReader(`{"name": "rex"}`))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		},
+		Budget: SoakBudget{P99: time.Second},
+	}.Assert(t, r)
+
+	if result.Requests != 500 || result.StatusCodes[http.StatusOK] != 500 {
+		t.Errorf("expected 500 successful requests got %s, codes %v", result, result.StatusCodes)
+	}
+}
+
+func TestSoakViolations(t *testing.T) {
+	t.Parallel()
+
+	result := SoakResult{Requests: 100, Errors: 2, P50: time.Millisecond, P99: 20 * time.Millisecond, Max: 50 * time.Millisecond}
+
+	cases := []struct {
+		Name   string
+		Budget SoakBudget
+		Expect int
+	}{
+		{"within", SoakBudget{P50: time.Millisecond, P99: 25 * time.Millisecond, ErrorRate: 0.05}, 0},
+		{"p99", SoakBudget{P99: 10 * time.Millisecond, ErrorRate: 0.05}, 1},
+		{"errors", SoakBudget{ErrorRate: 0.01}, 1},
+		{"no-errors-allowed", SoakBudget{}, 1},
+		{"everything", SoakBudget{P50: time.Microsecond, P99: time.Millisecond, Max: time.Millisecond, ErrorRate: 0.001}, 4},
+	}
+
+	for _, c := range cases {
+		got := result.Violations(c.Budget)
+		if len(got) != c.Expect {
+			t.Errorf("case[%s] expected %d violations got %v", c.Name, c.Expect, got)
+		}
+	}
+}
+
+func TestSoakDuration(t *testing.T) {
+	t.Parallel()
+
+	result := Soak{
+		Duration:   20 * time.Millisecond,
+		NewRequest: func(i int) *http.Request { return httptest.NewRequest(http.MethodGet, "/", nil) },
+	}.Run(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	if result.Requests == 0 || result.Errors != result.Requests || result.ErrorRate() != 1 {
+		t.Errorf("expected every request to error got %s", result)
+	}
+}
@@ -0,0 +1,112 @@
+package autohttp
+
+import (
+	"context"
+	"sync"
+)
+
+type backgroundTask struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// background runs the router's lifecycle-managed tasks
+type background struct {
+	mu      sync.Mutex
+	tasks   []backgroundTask
+	started bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Go registers a background task, such as a cache refresher or cleanup loop, that
+// runs for as long as the router is serving. The task's context is canceled on
+// StopBackground, after in-flight requests have drained. Tasks registered once
+// the router has started run immediately
+func (r *Router) Go(name string, fn func(ctx context.Context) error) {
+	r.background.mu.Lock()
+	defer r.background.mu.Unlock()
+
+	task := backgroundTask{name: name, fn: fn}
+	r.background.tasks = append(r.background.tasks, task)
+
+	if r.background.started {
+		r.runTask(task)
+	}
+}
+
+// StartBackground starts the registered background tasks. Calling it again has no effect
+func (r *Router) StartBackground() {
+	r.background.mu.Lock()
+	defer r.background.mu.Unlock()
+
+	if r.background.started {
+		return
+	}
+
+	r.background.ctx, r.background.cancel = context.WithCancel(context.Background())
+	r.background.started = true
+	for _, task := range r.background.tasks {
+		r.runTask(task)
+	}
+}
+
+// StopBackground cancels the background tasks and waits for them to return,
+// or for ctx to end
+func (r *Router) StopBackground(ctx context.Context) error {
+	r.background.mu.Lock()
+	if !r.background.started {
+		r.background.mu.Unlock()
+		return nil
+	}
+
+	r.background.cancel()
+	r.background.started = false
+	r.background.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.background.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runOnce runs fn as a one off background task, waited for and recovered
+// like any other. Before StartBackground its context is never canceled
+func (r *Router) runOnce(name string, fn func(ctx context.Context) error) {
+	r.background.mu.Lock()
+	defer r.background.mu.Unlock()
+
+	r.runTask(backgroundTask{name: name, fn: fn})
+}
+
+// runTask must be called with background.mu held
+func (r *Router) runTask(task backgroundTask) {
+	ctx := r.background.ctx
+	if !r.background.started {
+		ctx = context.Background()
+	}
+
+	r.background.wg.Add(1)
+	go func() {
+		defer r.background.wg.Done()
+		defer func() {
+			if rec := recover(); rec != nil {
+				r.log.Errorf("panic in background task %s: %v", task.name, rec)
+			}
+		}()
+
+		err := task.fn(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Errorf("background task %s failed: %s", task.name, err)
+		}
+	}()
+}
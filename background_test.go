@@ -0,0 +1,84 @@
+package autohttp
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestBackgroundTasks(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, stopped int32
+	task := func(ctx context.Context) error {
+		atomic.AddInt32(&running, 1)
+		<-ctx.Done()
+		atomic.AddInt32(&stopped, 1)
+		return nil
+	}
+
+	r.Go("before-start", task)
+	r.Go("panics", func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&running) != 0 {
+		t.Fatal("task ran before the router started")
+	}
+
+	r.StartBackground()
+	r.StartBackground()
+	r.Go("after-start", task)
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&running) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 running tasks, got %d", atomic.LoadInt32(&running))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = r.StopBackground(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&stopped) != 2 {
+		t.Fatalf("expected 2 stopped tasks, got %d", atomic.LoadInt32(&stopped))
+	}
+}
+
+func TestStopBackgroundTimeout(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	r.Go("stubborn", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	r.StartBackground()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = r.StopBackground(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
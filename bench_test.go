@@ -0,0 +1,154 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type benchInput struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Owner struct {
+		Login string `json:"login"`
+		Admin bool   `json:"admin"`
+	} `json:"owner"`
+	Stars int     `json:"stars"`
+	Score float64 `json:"score"`
+}
+
+func benchPayloadFromTestdata(b *testing.B) []byte {
+	payload, err := os.ReadFile("testdata/bench_payload.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return payload
+}
+
+func BenchmarkRouterMatch(b *testing.B) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 100; i++ {
+		err = r.Register(http.MethodGet, fmt.Sprintf("/api/v1/resource-%d", i), noop, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/resource-99", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkHandlerReflection(b *testing.B) {
+	h, err := NewHandler(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)), NoOpDecoder{}, NoOpEncoder{}, nil, nil, func() {})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkJSONDecode(b *testing.B) {
+	payload := benchPayloadFromTestdata(b)
+	fn := func(ctx context.Context, in benchInput) {}
+	jsd := NewJSONDecoder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+
+		_, err := jsd.Decode(fn, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONEncode(b *testing.B) {
+	in := benchInput{ID: 4215, Name: "autohttp", Tags: []string{"http", "reflection", "json"}}
+	jse := &JSONEncoder{}
+	hw := func(k, v string) {}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := jse.Encode(in, hw)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTypedHandler(b *testing.B) {
+	payload := benchPayloadFromTestdata(b)
+
+	reflective := func(ctx context.Context, in benchInput) (benchInput, error) { return in, nil }
+	routers := map[string]func(r *Router) error{
+		"reflect": func(r *Router) error { return r.Register(http.MethodPost, "/bench", reflective, nil) },
+		"typed":   func(r *Router) error { return RegisterTyped(r, http.MethodPost, "/bench", reflective) },
+	}
+
+	for name, register := range routers {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		err = register(r)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/bench", bytes.NewReader(payload))
+				req.Header.Set("Content-Type", "application/json")
+				r.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
+
+func BenchmarkMsgpackEncode(b *testing.B) {
+	in := benchInput{ID: 4215, Name: "autohttp", Tags: []string{"http", "reflection", "json"}}
+	mpe := &MsgpackEncoder{}
+	hw := func(k, v string) {}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := mpe.Encode(in, hw)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+)
+
+// BodyDrainPolicy controls what happens to any unread request body once a route has served a request
+type BodyDrainPolicy int
+
+const (
+	// DrainBody reads and discards the rest of the body before closing it.
+	// Some reverse proxies misbehave when the body isn't fully read. This is the default
+	DrainBody BodyDrainPolicy = iota
+	// CloseBody closes the body without reading it, leaving net/http to decide
+	// whether the connection can be reused
+	CloseBody
+	// SkipBody leaves the body untouched. Routes that hijack the connection
+	// or stream the body, such as websockets, must use this
+	SkipBody
+)
+
+// WithBodyDrainPolicy sets the BodyDrainPolicy of a single route
+func WithBodyDrainPolicy(p BodyDrainPolicy) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.drainPolicy = p
+		rc.drainPolicySet = true
+		return nil
+	}
+}
+
+// WithDefaultBodyDrainPolicy sets the BodyDrainPolicy of routes that don't set their own.
+// Star routes default to SkipBody regardless
+func WithDefaultBodyDrainPolicy(p BodyDrainPolicy) func(r *Router) error {
+	return func(r *Router) error {
+		r.defaultDrainPolicy = p
+		return nil
+	}
+}
+
+// drainingHandler applies a BodyDrainPolicy once the next handler returns
+type drainingHandler struct {
+	policy BodyDrainPolicy
+	next   http.Handler
+}
+
+func (dh *drainingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dh.next.ServeHTTP(w, r)
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	switch dh.policy {
+	case DrainBody:
+		drainBody(r.Body)
+	case CloseBody:
+		r.Body.Close()
+	}
+}
+
+// maxDrainBytes bounds how much of a body is read to be discarded, as
+// net/http does before reusing a connection. Connections left with unread
+// bodies are closed rather than reused
+const maxDrainBytes = 256 << 10
+
+// drainBody reads and discards up to maxDrainBytes of body and closes it
+func drainBody(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrainBytes)
+	body.Close()
+}
@@ -0,0 +1,68 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (tb *trackingBody) Close() error {
+	tb.closed = true
+	return nil
+}
+
+func TestBodyDrainPolicy(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Path         string
+		Options      []RouteOption
+		ExpectRead   bool
+		ExpectClosed bool
+	}{
+		{"default", "/upload", nil, true, true},
+		{"drain", "/upload", []RouteOption{WithBodyDrainPolicy(DrainBody)}, true, true},
+		{"close", "/upload", []RouteOption{WithBodyDrainPolicy(CloseBody)}, false, true},
+		{"skip", "/upload", []RouteOption{WithBodyDrainPolicy(SkipBody)}, false, false},
+		{"star-route", "/ws/*", nil, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			err = r.Register(http.MethodPost, c.Path, noop, nil, c.Options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body := &trackingBody{Reader: strings.NewReader("unread body")}
+			req := httptest.NewRequest(http.MethodPost, strings.TrimSuffix(c.Path, "*"), nil)
+			req.Body = body
+
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			rest, _ := io.ReadAll(body.Reader)
+			if read := len(rest) == 0; read != c.ExpectRead {
+				t.Errorf("expected body read %t", c.ExpectRead)
+			}
+
+			if body.closed != c.ExpectClosed {
+				t.Errorf("expected body closed %t", c.ExpectClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// ErrBodyTooLarge is returned for requests with bodies over their route's limit
+var ErrBodyTooLarge = errors.New("autohttp: request body too large")
+
+// WithMaxBodyBytes caps the request bodies of every route but star routes,
+// which commonly proxy or stream uploads, at n bytes. Requests declaring a
+// larger Content-Length are rejected before they're served, and bodies found
+// to be larger while they're read fail with a 413 through the route's error
+// handler. Routes can override it with WithRouteMaxBodyBytes
+func WithMaxBodyBytes(n int64) func(r *Router) error {
+	return func(r *Router) error {
+		if n < 0 {
+			return errors.New("autohttp: body limit can't be negative")
+		}
+
+		r.maxBodyBytes = n
+		return nil
+	}
+}
+
+// WithRouteMaxBodyBytes caps the route's request bodies at n bytes instead of
+// the router's WithMaxBodyBytes, zero leaving them uncapped
+func WithRouteMaxBodyBytes(n int64) RouteOption {
+	return func(rc *routeConfig) error {
+		if n < 0 {
+			return errors.New("autohttp: body limit can't be negative")
+		}
+
+		rc.maxBodyBytes = n
+		rc.maxBodyBytesSet = true
+		return nil
+	}
+}
+
+// withBodyLimit wraps handler in the route's body limit, if it has one.
+// Star routes only get limits set on the route. Rejections are handled
+// by errorHandler
+func (r *Router) withBodyLimit(handler http.Handler, rc routeConfig, star bool, errorHandler func(w http.ResponseWriter, req *http.Request, err error)) http.Handler {
+	limit := r.bodyLimit(rc, star)
+	if limit == 0 {
+		return handler
+	}
+
+	return &bodyLimitHandler{limit: limit, next: handler, errorHandler: errorHandler}
+}
+
+// bodyLimit returns the route's body limit, or zero when it has none
+func (r *Router) bodyLimit(rc routeConfig, star bool) int64 {
+	if rc.maxBodyBytesSet {
+		return rc.maxBodyBytes
+	}
+
+	if star {
+		return 0
+	}
+
+	return r.maxBodyBytes
+}
+
+// decoderBodyLimit returns the MaxBytesToRead of decoders that have one,
+// like JSONDecoder, or zero
+func decoderBodyLimit(decoder Decoder) int64 {
+	v := reflect.Indirect(reflect.ValueOf(decoder))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+
+	max := v.FieldByName("MaxBytesToRead")
+	if !max.IsValid() || max.Kind() != reflect.Int64 {
+		return 0
+	}
+
+	return max.Int()
+}
+
+type bodyLimitCtxKey struct{}
+
+type bodyLimitHandler struct {
+	limit        int64
+	next         http.Handler
+	errorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+func (blh *bodyLimitHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.ContentLength > blh.limit {
+		blh.errorHandler(w, req, ErrorWithCode{Err: ErrBodyTooLarge, StatusCode: http.StatusRequestEntityTooLarge})
+		return
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		blh.next.ServeHTTP(w, req)
+		return
+	}
+
+	// the connection is closed once the limit is hit, like http.MaxBytesReader
+	lb := &limitedBody{body: http.MaxBytesReader(w, req.Body, blh.limit), limit: blh.limit}
+	req = req.WithContext(context.WithValue(req.Context(), bodyLimitCtxKey{}, lb))
+	req.Body = lb
+
+	blh.next.ServeHTTP(w, req)
+}
+
+// limitedBody records whether a request body was cut off at its limit
+type limitedBody struct {
+	body     io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	n, err := lb.body.Read(p)
+	lb.read += int64(n)
+	if err != nil && err != io.EOF && lb.read >= lb.limit {
+		lb.exceeded = true
+		return n, ErrorWithCode{Err: ErrBodyTooLarge, StatusCode: http.StatusRequestEntityTooLarge}
+	}
+
+	return n, err
+}
+
+func (lb *limitedBody) Close() error {
+	return lb.body.Close()
+}
+
+// bodyLimitExceeded reports whether the request body was cut off at its
+// route's limit. Decoders report failing reads as bad requests, so errors
+// raised after the limit was hit are answered with a 413 instead
+func bodyLimitExceeded(ctx context.Context) bool {
+	lb, ok := ctx.Value(bodyLimitCtxKey{}).(*limitedBody)
+	return ok && lb.exceeded
+}
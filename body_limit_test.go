@@ -0,0 +1,137 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestBodyLimit(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithMaxBodyBytes(32),
+		WithDefaultErrorHandler(func(w http.ResponseWriter, err error) {
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte("custom: " + err.Error()))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := func(in struct{ Name string }) (map[string]string, error) {
+		return map[string]string{"name": in.Name}, nil
+	}
+
+	err = r.Register(http.MethodPost, "/pets", echo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/unlimited", echo, nil, WithRouteMaxBodyBytes(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(statusCodeForError(err))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err = r.Register(http.MethodPost, "/raw", upload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/proxy/*", upload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/limited/*", upload, nil, WithRouteMaxBodyBytes(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := `{"Name":"rex"}`
+	large := `{"Name":"` + strings.Repeat("x", 64) + `"}`
+
+	cases := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+
+		code         int
+		responseBody string
+	}{
+		{name: "within the limit", path: "/pets", body: small, code: http.StatusOK, responseBody: `"name":"rex"`},
+		{name: "declared too large", path: "/pets", body: large, code: http.StatusRequestEntityTooLarge, responseBody: "custom: autohttp: request body too large"},
+		{name: "read too large", path: "/pets", body: large, chunked: true, code: http.StatusRequestEntityTooLarge, responseBody: "custom: autohttp: request body too large"},
+		{name: "route override", path: "/unlimited", body: large, chunked: true, code: http.StatusOK, responseBody: `"name":"xxxx`},
+		{name: "http.Handler declared too large", path: "/raw", body: large, code: http.StatusRequestEntityTooLarge, responseBody: "custom: autohttp: request body too large"},
+		{name: "http.Handler read too large", path: "/raw", body: large, chunked: true, code: http.StatusRequestEntityTooLarge},
+		{name: "star routes aren't limited by the router", path: "/proxy/a", body: large, code: http.StatusCreated},
+		{name: "star route limit", path: "/limited/a", body: small, chunked: true, code: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		var body io.Reader = strings.NewReader(c.body)
+		if c.chunked {
+			// hide the length, so the body is only found too large as it's read
+			body = io.MultiReader(body)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, c.path, body)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d: %s", c.name, c.code, w.Code, w.Body.String())
+		}
+
+		if !strings.Contains(w.Body.String(), c.responseBody) {
+			t.Errorf("case[%s] expected body containing %q, got %q", c.name, c.responseBody, w.Body.String())
+		}
+	}
+}
+
+// endlessBody never runs out
+type endlessBody struct {
+	read   int64
+	closed bool
+}
+
+func (eb *endlessBody) Read(p []byte) (int, error) {
+	eb.read += int64(len(p))
+	return len(p), nil
+}
+
+func (eb *endlessBody) Close() error {
+	eb.closed = true
+	return nil
+}
+
+func TestDrainBodyIsBounded(t *testing.T) {
+	t.Parallel()
+
+	eb := &endlessBody{}
+	drainBody(eb)
+
+	if eb.read < maxDrainBytes || eb.read > 2*maxDrainBytes || !eb.closed {
+		t.Errorf("expected about %d bytes drained and the body closed, got %d %t", maxDrainBytes, eb.read, eb.closed)
+	}
+}
@@ -0,0 +1,94 @@
+package autohttp
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// A SpooledBody is a request body held by a BodyStore. Decoders can rely
+// on it implementing io.ReadSeeker
+type SpooledBody interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// A BodyStore holds request bodies so decoders can seek over them. Bodies up to
+// threshold bytes should be kept in memory
+type BodyStore interface {
+	Store(body io.Reader, threshold int64) (SpooledBody, error)
+}
+
+// TempFileBodyStore spools bodies over the threshold to temporary files in Dir,
+// or the default temp directory when Dir is empty. Files are removed once the
+// request has been served
+type TempFileBodyStore struct {
+	Dir string
+}
+
+func (tfs TempFileBodyStore) Store(body io.Reader, threshold int64) (SpooledBody, error) {
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, body, threshold+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if n <= threshold {
+		return memoryBody{bytes.NewReader(buf.Bytes())}, nil
+	}
+
+	f, err := os.CreateTemp(tfs.Dir, "autohttp-body-*")
+	if err != nil {
+		return nil, err
+	}
+
+	tb := &tempFileBody{f}
+	_, err = io.Copy(f, io.MultiReader(&buf, body))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		tb.Close()
+		return nil, err
+	}
+
+	return tb, nil
+}
+
+type memoryBody struct {
+	*bytes.Reader
+}
+
+func (mb memoryBody) Close() error {
+	return nil
+}
+
+type tempFileBody struct {
+	*os.File
+}
+
+func (tb *tempFileBody) Close() error {
+	err := tb.File.Close()
+	os.Remove(tb.File.Name())
+	return err
+}
+
+type bodySpooling struct {
+	threshold int64
+	store     BodyStore
+}
+
+// WithBodySpooling stores the route's request bodies in store before decoding,
+// protecting memory on routes that legitimately receive large payloads.
+// A nil store spools to temporary files
+func WithBodySpooling(threshold int64, store BodyStore) RouteOption {
+	return func(rc *routeConfig) error {
+		if store == nil {
+			store = TempFileBodyStore{}
+		}
+
+		rc.bodySpooling = &bodySpooling{threshold: threshold, store: store}
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package autohttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestTempFileBodyStore(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name       string
+		Body       string
+		Threshold  int64
+		ExpectFile bool
+	}{
+		{"in-memory", "small", 16, false},
+		{"at-threshold", "exactly16bytes!!", 16, false},
+		{"spooled", strings.Repeat("a", 64), 16, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			body, err := TempFileBodyStore{Dir: dir}.Store(strings.NewReader(c.Body), c.Threshold)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, isFile := body.(*tempFileBody)
+			if isFile != c.ExpectFile {
+				t.Fatalf("expected spooled to file %t", c.ExpectFile)
+			}
+
+			for i := 0; i < 2; i++ {
+				b, err := io.ReadAll(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(b) != c.Body {
+					t.Fatalf("expected %q got %q", c.Body, b)
+				}
+
+				_, err = body.Seek(0, io.SeekStart)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(entries) != 0 {
+				t.Errorf("expected temp files to be removed, found %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestBodySpoolingRoute(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seekable bool
+	err = r.Register(http.MethodPost, "/upload", func(ctx context.Context, in struct{ Data string }) map[string]int {
+		return map[string]int{"length": len(in.Data)}
+	}, nil, WithBodySpooling(8, nil), WithRequestTransform(func(r *http.Request) error {
+		_, seekable = r.Body.(io.ReadSeeker)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"Data": "`+strings.Repeat("a", 100)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	if strings.TrimSpace(w.Body.String()) != `{"length":100}` {
+		t.Errorf("unexpected response %q", w.Body.String())
+	}
+
+	if !seekable {
+		t.Error("expected the body to be seekable")
+	}
+}
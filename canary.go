@@ -0,0 +1,94 @@
+package autohttp
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+const (
+	// VariantStable is the variant served to clients outside the canary
+	VariantStable = "stable"
+	// VariantCanary is the variant served to clients in the canary
+	VariantCanary = "canary"
+
+	// DefaultCanaryCookieName is the cookie used to persist variant assignment
+	DefaultCanaryCookieName = "autohttp_variant"
+)
+
+// DefaultCanaryCookieMaxAge keeps a client on its variant for a week
+var DefaultCanaryCookieMaxAge = 7 * 24 * time.Hour
+
+type canaryVariantCtxKey struct{}
+
+// CanaryVariantFromContext returns the variant a Canary assigned to the request
+func CanaryVariantFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(canaryVariantCtxKey{}).(string)
+	return v
+}
+
+// A Canary splits traffic for a route between a stable and a canary
+// implementation. Assignment is persisted in a cookie so a client consistently
+// hits the same implementation across requests
+type Canary struct {
+	Stable http.Handler
+	Canary http.Handler
+
+	// Percent of new clients assigned to the canary, 0-100
+	Percent int
+
+	CookieName   string
+	CookieMaxAge time.Duration
+}
+
+// NewCanary sends percent of new clients to canary, and the rest to stable
+func NewCanary(stable, canary http.Handler, percent int) *Canary {
+	return &Canary{
+		Stable:       stable,
+		Canary:       canary,
+		Percent:      percent,
+		CookieName:   DefaultCanaryCookieName,
+		CookieMaxAge: DefaultCanaryCookieMaxAge,
+	}
+}
+
+func (c *Canary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	variant := ""
+	cookie, err := r.Cookie(c.CookieName)
+	if err == nil && (cookie.Value == VariantStable || cookie.Value == VariantCanary) {
+		variant = cookie.Value
+	} else {
+		variant = c.assign()
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.CookieName,
+			Value:    variant,
+			Path:     "/",
+			MaxAge:   int(c.CookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), canaryVariantCtxKey{}, variant))
+	if variant == VariantCanary {
+		c.Canary.ServeHTTP(w, r)
+		return
+	}
+
+	c.Stable.ServeHTTP(w, r)
+}
+
+func (c *Canary) assign() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(100))
+	if err != nil {
+		return VariantStable
+	}
+
+	if int(n.Int64()) < c.Percent {
+		return VariantCanary
+	}
+
+	return VariantStable
+}
@@ -0,0 +1,61 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanaryStickyAssignment(t *testing.T) {
+	t.Parallel()
+
+	variantHandler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if CanaryVariantFromContext(r.Context()) != name {
+				t.Errorf("expected variant %q in context", name)
+			}
+			w.Write([]byte(name))
+		})
+	}
+
+	cases := []struct {
+		Name         string
+		Percent      int
+		Cookie       string
+		ExpectBody   string
+		ExpectCookie bool
+	}{
+		{"new-client-stable", 0, "", VariantStable, true},
+		{"new-client-canary", 100, "", VariantCanary, true},
+		{"sticky-canary", 0, VariantCanary, VariantCanary, false},
+		{"sticky-stable", 100, VariantStable, VariantStable, false},
+		{"invalid-cookie", 100, "bogus", VariantCanary, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			canary := NewCanary(variantHandler(VariantStable), variantHandler(VariantCanary), c.Percent)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.Cookie != "" {
+				r.AddCookie(&http.Cookie{Name: DefaultCanaryCookieName, Value: c.Cookie})
+			}
+
+			canary.ServeHTTP(w, r)
+
+			if w.Body.String() != c.ExpectBody {
+				t.Errorf("expected %q got %q", c.ExpectBody, w.Body.String())
+			}
+
+			setCookie := w.Result().Cookies()
+			if c.ExpectCookie {
+				if len(setCookie) != 1 || setCookie[0].Value != c.ExpectBody {
+					t.Errorf("expected variant cookie %q, got %v", c.ExpectBody, setCookie)
+				}
+			} else if len(setCookie) != 0 {
+				t.Errorf("expected no cookie, got %v", setCookie)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package autohttp
+
+import "errors"
+
+// An EncoderWrapper decorates an Encoder, e.g. to record payload sizes or
+// encrypt fields, the way Middleware decorates a handler
+type EncoderWrapper func(next Encoder) Encoder
+
+// A DecoderWrapper decorates a Decoder, e.g. to validate requests against a
+// schema or decrypt fields, the way Middleware decorates a handler
+type DecoderWrapper func(next Decoder) Decoder
+
+// WrapEncoder wraps the encoder of every function route, including encoders
+// picked by content negotiation, with ws. The first wrapper is outermost
+func WrapEncoder(ws ...EncoderWrapper) func(r *Router) error {
+	return func(r *Router) error {
+		for _, w := range ws {
+			if w == nil {
+				return errors.New("autohttp: WrapEncoder needs non-nil wrappers")
+			}
+		}
+
+		r.encoderWrappers = append(r.encoderWrappers, ws...)
+		return nil
+	}
+}
+
+// WrapDecoder wraps the decoder of every function route with ws. The first
+// wrapper is outermost. Typed routes decode without a Decoder call, so they
+// aren't wrapped
+func WrapDecoder(ws ...DecoderWrapper) func(r *Router) error {
+	return func(r *Router) error {
+		for _, w := range ws {
+			if w == nil {
+				return errors.New("autohttp: WrapDecoder needs non-nil wrappers")
+			}
+		}
+
+		r.decoderWrappers = append(r.decoderWrappers, ws...)
+		return nil
+	}
+}
+
+// wrappedEncoder remembers the encoder it decorates, so content negotiation
+// still knows the media type it produces
+type wrappedEncoder struct {
+	Encoder
+	inner Encoder
+}
+
+// Unwrap returns the encoder before wrapping
+func (we wrappedEncoder) Unwrap() Encoder {
+	return we.inner
+}
+
+func (r *Router) wrapEncoder(e Encoder) Encoder {
+	if e == nil || len(r.encoderWrappers) == 0 {
+		return e
+	}
+
+	wrapped := e
+	for i := len(r.encoderWrappers) - 1; i >= 0; i-- {
+		wrapped = r.encoderWrappers[i](wrapped)
+	}
+
+	return wrappedEncoder{Encoder: wrapped, inner: e}
+}
+
+func (r *Router) wrapDecoder(d Decoder) Decoder {
+	if d == nil {
+		return d
+	}
+
+	for i := len(r.decoderWrappers) - 1; i >= 0; i-- {
+		d = r.decoderWrappers[i](d)
+	}
+
+	return d
+}
+
+// wrapNegotiatedEncoders wraps the encoders added with WithEncoderFor, once
+// every router option has been applied
+func (r *Router) wrapNegotiatedEncoders() {
+	if r.negotiation == nil {
+		return
+	}
+
+	for i, me := range r.negotiation.encoders {
+		r.negotiation.encoders[i].encoder = r.wrapEncoder(me.encoder)
+	}
+}
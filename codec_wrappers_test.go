@@ -0,0 +1,136 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+// sizeRecorder is an EncoderWrapper and DecoderWrapper recording payload sizes
+type sizeRecorder struct {
+	mu      sync.Mutex
+	name    string
+	calls   *[]string
+	encoded []int
+}
+
+type sizeRecordingEncoder struct {
+	Encoder
+	sr *sizeRecorder
+}
+
+func (sre sizeRecordingEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	n, body, err := sre.Encoder.Encode(value, hw)
+	if err != nil {
+		return n, body, err
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return n, nil, err
+	}
+
+	sre.sr.mu.Lock()
+	sre.sr.encoded = append(sre.sr.encoded, len(b))
+	*sre.sr.calls = append(*sre.sr.calls, sre.sr.name)
+	sre.sr.mu.Unlock()
+
+	return n, strings.NewReader(string(b)), nil
+}
+
+type recordingDecoder struct {
+	Decoder
+	sr *sizeRecorder
+}
+
+func (rd recordingDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	rd.sr.mu.Lock()
+	*rd.sr.calls = append(*rd.sr.calls, rd.sr.name)
+	rd.sr.mu.Unlock()
+
+	return rd.Decoder.Decode(fn, r)
+}
+
+func (sr *sizeRecorder) encoder(next Encoder) Encoder {
+	return sizeRecordingEncoder{Encoder: next, sr: sr}
+}
+
+func (sr *sizeRecorder) decoder(next Decoder) Decoder {
+	return recordingDecoder{Decoder: next, sr: sr}
+}
+
+func TestCodecWrappers(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	outer := &sizeRecorder{name: "outer", calls: &calls}
+	inner := &sizeRecorder{name: "inner", calls: &calls}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEncoderFor("application/xml", &XMLEncoder{}),
+		WrapEncoder(outer.encoder, inner.encoder),
+		WrapDecoder(outer.decoder, inner.decoder),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/pet", func(p negotiatedPet) negotiatedPet { return p }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name              string
+		Accept            string
+		ExpectContentType string
+		ExpectBody        string
+	}{
+		{"default", "", "application/json", `{"name":"rex"}`},
+		{"json", "application/json", "application/json", `{"name":"rex"}`},
+		{"negotiated", "application/xml", "application/xml; charset=utf-8", `<negotiatedPet><name>rex</name></negotiatedPet>`},
+	}
+
+	for _, c := range cases {
+		calls = nil
+		outer.encoded = nil
+
+		req := httptest.NewRequest(http.MethodPost, "/pet", strings.NewReader(`{"name":"rex"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if c.Accept != "" {
+			req.Header.Set("Accept", c.Accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s] expected 200 got %d: %s", c.Name, w.Code, w.Body.String())
+			continue
+		}
+
+		if w.Header().Get("Content-Type") != c.ExpectContentType {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.ExpectContentType, w.Header().Get("Content-Type"))
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) {
+			t.Errorf("case[%s] expected %q in %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		// decoders run outer first, encoders return through inner first
+		expectCalls := []string{"outer", "inner", "inner", "outer"}
+		if !reflect.DeepEqual(calls, expectCalls) {
+			t.Errorf("case[%s] expected calls %v got %v", c.Name, expectCalls, calls)
+		}
+
+		if len(outer.encoded) != 1 || outer.encoded[0] != w.Body.Len() {
+			t.Errorf("case[%s] expected one recorded size of %d got %v", c.Name, w.Body.Len(), outer.encoded)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package autohttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCompressionCacheEntries is the number of compressed responses kept
+// by WithCompressionCache when no size is given
+const DefaultCompressionCacheEntries = 256
+
+// A Compressor implements a Content-Encoding. Gzip is built in, other encodings
+// such as br or zstd can be plugged in by wrapping their writers
+type Compressor interface {
+	// Encoding is the Content-Encoding token, e.g. "gzip"
+	Encoding() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipCompressor compresses responses with gzip at Level
+type GzipCompressor struct {
+	Level int
+}
+
+func (gc GzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+func (gc GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, gc.Level)
+}
+
+// WithCompression compresses handler responses using the first of compressors
+// accepted by the client's Accept-Encoding
+func WithCompression(compressors ...Compressor) func(r *Router) error {
+	return func(r *Router) error {
+		if r.compression == nil {
+			r.compression = &compression{}
+		}
+
+		r.compression.compressors = compressors
+		return nil
+	}
+}
+
+// WithCompressionCache keeps the compressed variants of up to entries
+// responses, keyed by content hash, so repeated identical responses such
+// as config blobs are not recompressed on every request
+func WithCompressionCache(entries int) func(r *Router) error {
+	return func(r *Router) error {
+		if r.compression == nil {
+			r.compression = &compression{}
+		}
+
+		r.compression.cache = newCompressionCache(entries)
+		return nil
+	}
+}
+
+// WithCompressionMinSize leaves responses under n bytes uncompressed, where
+// the encoding overhead outweighs the savings
+func WithCompressionMinSize(n int) func(r *Router) error {
+	return func(r *Router) error {
+		if n < 0 {
+			return errors.New("autohttp: compression minimum size can't be negative")
+		}
+
+		if r.compression == nil {
+			r.compression = &compression{}
+		}
+
+		r.compression.minSize = n
+		return nil
+	}
+}
+
+// WithCompressionContentTypes only compresses responses in mediaTypes, such
+// as "application/json" or "text/*", leaving already compressed formats like
+// images alone. By default responses of any type are compressed
+func WithCompressionContentTypes(mediaTypes ...string) func(r *Router) error {
+	return func(r *Router) error {
+		if r.compression == nil {
+			r.compression = &compression{}
+		}
+
+		r.compression.contentTypes = nil
+		for _, mt := range mediaTypes {
+			parsed, _, err := mime.ParseMediaType(mt)
+			if err != nil {
+				return fmt.Errorf("autohttp: invalid media type %q: %w", mt, err)
+			}
+
+			r.compression.contentTypes = append(r.compression.contentTypes, parsed)
+		}
+
+		return nil
+	}
+}
+
+type compression struct {
+	compressors  []Compressor
+	cache        *compressionCache
+	minSize      int
+	contentTypes []string
+}
+
+// compressible reports whether responses with header are compressed
+func (c *compression) compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	if len(c.contentTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaTypeAllowed(c.contentTypes, mediaType)
+}
+
+// apply compresses body for the request, setting the response headers to match
+func (c *compression) apply(r *http.Request, header http.Header, body io.Reader) (io.Reader, error) {
+	header.Add("Vary", "Accept-Encoding")
+
+	compressor := negotiateCompressor(r.Header.Get("Accept-Encoding"), c.compressors)
+	if compressor == nil || !c.compressible(header) {
+		return body, nil
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < c.minSize {
+		return bytes.NewReader(b), nil
+	}
+
+	var key compressionCacheKey
+	if c.cache != nil {
+		key = compressionCacheKey{encoding: compressor.Encoding(), sum: sha256.Sum256(b)}
+		if compressed, ok := c.cache.get(key); ok {
+			setCompressionHeaders(header, compressor, len(compressed))
+			return bytes.NewReader(compressed), nil
+		}
+	}
+
+	var buf bytes.Buffer
+	cw, err := compressor.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cw.Write(b)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := buf.Bytes()
+	if c.cache != nil {
+		c.cache.put(key, compressed)
+	}
+
+	setCompressionHeaders(header, compressor, len(compressed))
+	return bytes.NewReader(compressed), nil
+}
+
+func setCompressionHeaders(header http.Header, compressor Compressor, length int) {
+	header.Set("Content-Encoding", compressor.Encoding())
+	header.Set("Content-Length", strconv.Itoa(length))
+}
+
+// negotiateCompressor picks the first compressor, in server preference order,
+// that the Accept-Encoding header allows
+func negotiateCompressor(acceptEncoding string, compressors []Compressor) Compressor {
+	if acceptEncoding == "" {
+		return nil
+	}
+
+	accepted := make(map[string]bool)
+	wildcard := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, q := parseQualityValue(part)
+		if coding == "*" {
+			wildcard = q > 0
+			continue
+		}
+
+		accepted[coding] = q > 0
+	}
+
+	for _, c := range compressors {
+		ok, listed := accepted[c.Encoding()]
+		if ok || (!listed && wildcard) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// parseQualityValue splits a header element such as "gzip;q=0.5" into its
+// lowercased value and quality
+func parseQualityValue(s string) (string, float64) {
+	parts := strings.Split(s, ";")
+	value := strings.ToLower(strings.TrimSpace(parts[0]))
+
+	q := 1.0
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+	}
+
+	return value, q
+}
+
+type compressionCacheKey struct {
+	encoding string
+	sum      [sha256.Size]byte
+}
+
+type compressionCacheEntry struct {
+	key        compressionCacheKey
+	compressed []byte
+}
+
+// compressionCache is a fixed size LRU of compressed response bodies
+type compressionCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[compressionCacheKey]*list.Element
+}
+
+func newCompressionCache(size int) *compressionCache {
+	if size <= 0 {
+		size = DefaultCompressionCacheEntries
+	}
+
+	return &compressionCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[compressionCacheKey]*list.Element),
+	}
+}
+
+func (cc *compressionCache) get(key compressionCacheKey) ([]byte, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	el, ok := cc.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	cc.order.MoveToFront(el)
+	return el.Value.(*compressionCacheEntry).compressed, true
+}
+
+func (cc *compressionCache) put(key compressionCacheKey, compressed []byte) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if el, ok := cc.entries[key]; ok {
+		cc.order.MoveToFront(el)
+		return
+	}
+
+	cc.entries[key] = cc.order.PushFront(&compressionCacheEntry{key: key, compressed: compressed})
+	if cc.order.Len() > cc.size {
+		oldest := cc.order.Back()
+		cc.order.Remove(oldest)
+		delete(cc.entries, oldest.Value.(*compressionCacheEntry).key)
+	}
+}
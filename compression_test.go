@@ -0,0 +1,206 @@
+package autohttp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type fakeCompressor struct {
+	encoding string
+}
+
+func (fc fakeCompressor) Encoding() string {
+	return fc.encoding
+}
+
+func (fc fakeCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nil, nil
+}
+
+func TestNegotiateCompressor(t *testing.T) {
+	t.Parallel()
+
+	compressors := []Compressor{fakeCompressor{"br"}, fakeCompressor{"zstd"}, GzipCompressor{}}
+
+	cases := []struct {
+		Name           string
+		AcceptEncoding string
+		Expect         string
+	}{
+		{"none", "", ""},
+		{"gzip", "gzip, deflate", "gzip"},
+		{"server-preference", "gzip, br", "br"},
+		{"rejected", "br;q=0, gzip", "gzip"},
+		{"wildcard", "*", "br"},
+		{"wildcard-with-rejection", "br;q=0, *;q=0.1", "zstd"},
+		{"unsupported", "deflate", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			got := ""
+			if compressor := negotiateCompressor(c.AcceptEncoding, compressors); compressor != nil {
+				got = compressor.Encoding()
+			}
+
+			if got != c.Expect {
+				t.Errorf("expected %q got %q", c.Expect, got)
+			}
+		})
+	}
+}
+
+type countingCompressor struct {
+	GzipCompressor
+	count *int
+}
+
+func (cc countingCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	*cc.count++
+	return cc.GzipCompressor.NewWriter(w)
+}
+
+func TestCompressionCache(t *testing.T) {
+	var compressions int
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithCompression(countingCompressor{GzipCompressor{Level: gzip.BestSpeed}, &compressions}),
+		WithCompressionCache(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/config", func(ctx context.Context, in struct{ Name string }) map[string]string {
+		return map[string]string{"name": in.Name}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name              string
+		Input             string
+		AcceptEncoding    string
+		ExpectEncoding    string
+		ExpectCompression int
+	}{
+		{"first", "a", "gzip", "gzip", 1},
+		{"cached", "a", "gzip", "gzip", 1},
+		{"uncompressed", "a", "", "", 1},
+		{"second-entry", "b", "gzip", "gzip", 2},
+		{"third-entry-evicts-first", "c", "gzip", "gzip", 3},
+		{"evicted", "a", "gzip", "gzip", 4},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/config", strings.NewReader(`{"Name": "`+c.Input+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Encoding", c.AcceptEncoding)
+
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != c.ExpectEncoding {
+			t.Fatalf("case[%s] expected encoding %q got %q", c.Name, c.ExpectEncoding, got)
+		}
+
+		if compressions != c.ExpectCompression {
+			t.Fatalf("case[%s] expected %d compressions got %d", c.Name, c.ExpectCompression, compressions)
+		}
+
+		body := io.Reader(w.Body)
+		if c.ExpectEncoding == "gzip" {
+			body, err = gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		b, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.TrimSpace(string(b)) != `{"name":"`+c.Input+`"}` {
+			t.Fatalf("case[%s] unexpected body %q", c.Name, b)
+		}
+	}
+}
+
+func TestCompressionFilters(t *testing.T) {
+	t.Parallel()
+
+	var written []int64
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(io.Discard)),
+		WithCompression(GzipCompressor{Level: gzip.BestSpeed}),
+		WithCompressionMinSize(64),
+		WithCompressionContentTypes("application/json", "text/*"),
+		WithAccessLog(AccessLogSampling{PerSecond: -1}),
+		WithAccessLogFormat(func(e AccessLogEntry, fields []AccessLogField) string {
+			written = append(written, e.Bytes)
+			return ""
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/echo", func(ctx context.Context, in struct{ Name string }) map[string]string {
+		return map[string]string{"name": in.Name}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/png", func(ctx context.Context, in struct{ Name string }) (*Response, error) {
+		return &Response{Body: strings.Repeat(in.Name, 100), ContentType: "image/png"}, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := strings.Repeat("x", 200)
+
+	cases := []struct {
+		Name           string
+		Path           string
+		Input          string
+		ExpectEncoding string
+	}{
+		{"large-json", "/echo", long, "gzip"},
+		{"under-min-size", "/echo", "rex", ""},
+		{"excluded-type", "/png", long, ""},
+	}
+
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(`{"Name": "`+c.Input+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("case[%s] expected 200 got %d: %s", c.Name, w.Code, w.Body.String())
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != c.ExpectEncoding {
+			t.Errorf("case[%s] expected encoding %q got %q", c.Name, c.ExpectEncoding, got)
+		}
+
+		// metrics count the bytes on the wire
+		if len(written) != i+1 || written[i] != int64(w.Body.Len()) {
+			t.Errorf("case[%s] expected %d bytes recorded, got %v", c.Name, w.Body.Len(), written)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package autohttp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A RequestPredicate decides, per request, whether conditional middleware runs
+type RequestPredicate func(r *http.Request) bool
+
+// OnlyIf runs mw only for requests matching pred, so middleware such as
+// authentication can skip a few endpoints without splitting route groups.
+// If mw is an AfterMiddleware, pred is checked again before After
+func OnlyIf(pred RequestPredicate, mw Middleware) Middleware {
+	cm := conditionalMiddleware{pred: pred, mw: mw}
+	if amw, ok := mw.(AfterMiddleware); ok {
+		return conditionalAfterMiddleware{conditionalMiddleware: cm, amw: amw}
+	}
+
+	return cm
+}
+
+// Unless runs mw for every request that doesn't match pred
+func Unless(pred RequestPredicate, mw Middleware) Middleware {
+	return OnlyIf(Not(pred), mw)
+}
+
+type conditionalMiddleware struct {
+	pred RequestPredicate
+	mw   Middleware
+}
+
+func (cm conditionalMiddleware) Before(r *http.Request, h *Handler) error {
+	if !cm.pred(r) {
+		return nil
+	}
+
+	return cm.mw.Before(r, h)
+}
+
+type conditionalAfterMiddleware struct {
+	conditionalMiddleware
+	amw AfterMiddleware
+}
+
+func (cam conditionalAfterMiddleware) After(r *http.Request, statusCode int, header http.Header) {
+	if cam.pred(r) {
+		cam.amw.After(r, statusCode, header)
+	}
+}
+
+// Not matches the requests pred doesn't
+func Not(pred RequestPredicate) RequestPredicate {
+	return func(r *http.Request) bool {
+		return !pred(r)
+	}
+}
+
+// PathIs matches requests for any of paths exactly
+func PathIs(paths ...string) RequestPredicate {
+	return func(r *http.Request) bool {
+		for _, p := range paths {
+			if r.URL.Path == p {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// PathHasPrefix matches requests whose path starts with prefix
+func PathHasPrefix(prefix string) RequestPredicate {
+	return func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, prefix)
+	}
+}
+
+// MethodIs matches requests made with any of methods
+func MethodIs(methods ...string) RequestPredicate {
+	return func(r *http.Request) bool {
+		for _, m := range methods {
+			if strings.EqualFold(r.Method, m) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// HasHeader matches requests carrying header, with any value
+func HasHeader(header string) RequestPredicate {
+	return func(r *http.Request) bool {
+		return r.Header.Get(header) != ""
+	}
+}
@@ -0,0 +1,98 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type afterRecorder struct {
+	calls *[]string
+}
+
+func (ar afterRecorder) Before(r *http.Request, h *Handler) error {
+	*ar.calls = append(*ar.calls, "before "+r.URL.Path)
+	return nil
+}
+
+func (ar afterRecorder) After(r *http.Request, statusCode int, header http.Header) {
+	*ar.calls = append(*ar.calls, "after "+r.URL.Path)
+}
+
+func TestConditionalMiddleware(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	auth := NewBasicAuthMiddleware("user", "pass")
+	r.Use(
+		Unless(PathIs("/login", "/health"), auth),
+		OnlyIf(HasHeader("X-Trace"), afterRecorder{&calls}),
+	)
+
+	fn := func(ctx context.Context) {}
+	for _, path := range []string{"/login", "/health", "/account"} {
+		err = r.Register(http.MethodPost, path, fn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		Trace       bool
+		ExpectCode  int
+		ExpectCalls []string
+	}{
+		{"skipped-auth", "/login", false, http.StatusOK, nil},
+		{"skipped-auth-traced", "/health", true, http.StatusOK, []string{"before /health", "after /health"}},
+		{"auth-required", "/account", true, http.StatusForbidden, nil},
+	}
+
+	for _, c := range cases {
+		calls = nil
+		req := httptest.NewRequest(http.MethodPost, c.Path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		if c.Trace {
+			req.Header.Set("X-Trace", "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if strings.Join(calls, ",") != strings.Join(c.ExpectCalls, ",") {
+			t.Errorf("case[%s] expected calls %v got %v", c.Name, c.ExpectCalls, calls)
+		}
+	}
+
+	predicates := []struct {
+		Name   string
+		Pred   RequestPredicate
+		Expect bool
+	}{
+		{"method", MethodIs("get", http.MethodPost), true},
+		{"prefix", PathHasPrefix("/api/"), true},
+		{"not", Not(PathHasPrefix("/api/")), false},
+		{"header", HasHeader("Authorization"), false},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/items", nil)
+	for _, p := range predicates {
+		if got := p.Pred(req); got != p.Expect {
+			t.Errorf("case[%s] expected %t got %t", p.Name, p.Expect, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package autohttp
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+// Duration is a time.Duration that reads and writes as a string such as "30s",
+// so it can be set from JSON, YAML and environment variables
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config is a plain struct alternative to RouterOptions, for deployments that
+// tune the router from a config file instead of code. Zero values keep the
+// router defaults. It carries json and yaml tags, so any YAML library can load it
+type Config struct {
+	// Port is where the router is meant to be served, see Router.Port
+	Port string `json:"port" yaml:"port"`
+
+	// LogLevel is one of debug, info or error, see WithLogLevel
+	LogLevel string `json:"log_level" yaml:"log_level"`
+
+	// RequestTimeout bounds every request's context, see WithRequestTimeout
+	RequestTimeout Duration `json:"request_timeout" yaml:"request_timeout"`
+
+	// MaxBodyBytes limits JSON request bodies, defaulting to DefaultMaxBytesToRead
+	MaxBodyBytes        int64 `json:"max_body_bytes" yaml:"max_body_bytes"`
+	StrictRequests      bool  `json:"strict_requests" yaml:"strict_requests"`
+	MaxHeaderValueBytes int   `json:"max_header_value_bytes" yaml:"max_header_value_bytes"`
+
+	HSTS               bool `json:"hsts" yaml:"hsts"`
+	NormalizePaths     bool `json:"normalize_paths" yaml:"normalize_paths"`
+	CanonicalRedirects bool `json:"canonical_redirects" yaml:"canonical_redirects"`
+
+	RequestIDs             bool `json:"request_ids" yaml:"request_ids"`
+	HideRequestIDsInErrors bool `json:"hide_request_ids_in_errors" yaml:"hide_request_ids_in_errors"`
+	RouteMetrics           bool `json:"route_metrics" yaml:"route_metrics"`
+
+	// Compression enables gzip, CompressionCacheEntries caches its output and
+	// responses under CompressionMinSize bytes are left uncompressed
+	Compression             bool `json:"compression" yaml:"compression"`
+	CompressionCacheEntries int  `json:"compression_cache_entries" yaml:"compression_cache_entries"`
+	CompressionMinSize      int  `json:"compression_min_size" yaml:"compression_min_size"`
+}
+
+// LoadConfig reads a JSON encoded Config, rejecting unknown fields so typos
+// don't silently fall back to defaults
+func LoadConfig(r io.Reader) (Config, error) {
+	var cfg Config
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("autohttp: invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Options converts the Config into the equivalent RouterOptions
+func (c Config) Options() []RouterOption {
+	var opts []RouterOption
+
+	if c.Port != "" {
+		opts = append(opts, WithPort(c.Port))
+	}
+
+	if c.LogLevel != "" {
+		// validate has already checked the level parses
+		level, _ := ParseLogLevel(c.LogLevel)
+		opts = append(opts, WithLogLevel(level))
+	}
+
+	if c.RequestTimeout != 0 {
+		opts = append(opts, WithRequestTimeout(time.Duration(c.RequestTimeout)))
+	}
+
+	if c.MaxBodyBytes != 0 {
+		opts = append(opts, WithDefaultDecoder(&JSONDecoder{
+			MaxBytesToRead:        c.MaxBodyBytes,
+			DisallowUnknownFields: true,
+		}))
+	}
+
+	if c.StrictRequests {
+		opts = append(opts, EnableStrictRequests)
+	}
+	if c.MaxHeaderValueBytes != 0 {
+		opts = append(opts, WithMaxHeaderValueBytes(c.MaxHeaderValueBytes))
+	}
+
+	if c.HSTS {
+		opts = append(opts, EnableHSTS)
+	}
+	if c.NormalizePaths {
+		opts = append(opts, EnablePathNormalization)
+	}
+	if c.CanonicalRedirects {
+		opts = append(opts, EnableCanonicalRedirects)
+	}
+
+	if c.RequestIDs {
+		opts = append(opts, EnableRequestIDs)
+	}
+	if c.HideRequestIDsInErrors {
+		opts = append(opts, HideRequestIDsInErrors)
+	}
+	if c.RouteMetrics {
+		opts = append(opts, EnableRouteMetrics)
+	}
+
+	if c.Compression {
+		opts = append(opts, WithCompression(GzipCompressor{Level: gzip.DefaultCompression}))
+	}
+	if c.CompressionCacheEntries != 0 {
+		opts = append(opts, WithCompressionCache(c.CompressionCacheEntries))
+	}
+	if c.CompressionMinSize != 0 {
+		opts = append(opts, WithCompressionMinSize(c.CompressionMinSize))
+	}
+
+	return opts
+}
+
+// validate catches values that can't be expressed as options
+func (c Config) validate() error {
+	if c.MaxBodyBytes < 0 {
+		return errors.New("autohttp: max_body_bytes can't be negative")
+	}
+
+	if c.LogLevel != "" {
+		_, err := ParseLogLevel(c.LogLevel)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewRouterFromConfig builds a Router from cfg. opts are applied after the
+// config, so code can still set what a config file can't, such as assets
+func NewRouterFromConfig(log lounge.Log, cfg Config, opts ...RouterOption) (*Router, error) {
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRouter(log, append(cfg.Options(), opts...)...)
+}
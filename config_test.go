@@ -0,0 +1,105 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		JSON      string
+		Expect    Config
+		ShouldErr bool
+	}{
+		{"empty", `{}`, Config{}, false},
+		{
+			"full",
+			`{"request_timeout": "1.5s", "max_body_bytes": 1024, "hsts": true, "route_metrics": true}`,
+			Config{RequestTimeout: Duration(1500 * time.Millisecond), MaxBodyBytes: 1024, HSTS: true, RouteMetrics: true},
+			false,
+		},
+		{"bad-duration", `{"request_timeout": "soon"}`, Config{}, true},
+		{"unknown-field", `{"hstss": true}`, Config{}, true},
+	}
+
+	for _, c := range cases {
+		cfg, err := LoadConfig(strings.NewReader(c.JSON))
+		if err != nil && !c.ShouldErr {
+			t.Errorf("case[%s] failed unexpectedly: %s", c.Name, err)
+		}
+
+		if err == nil && c.ShouldErr {
+			t.Errorf("case[%s] did not fail when it should have", c.Name)
+		}
+
+		if cfg != c.Expect {
+			t.Errorf("case[%s] expected %+v got %+v", c.Name, c.Expect, cfg)
+		}
+	}
+}
+
+func TestNewRouterFromConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		RequestTimeout: Duration(time.Minute),
+		MaxBodyBytes:   16,
+		HSTS:           true,
+	}
+
+	r, err := NewRouterFromConfig(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deadline time.Time
+	err = r.Register(http.MethodPost, "/", func(ctx context.Context, in struct{ Name string }) {
+		deadline, _ = ctx.Deadline()
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Body         string
+		ExpectStatus int
+	}{
+		{"within-limit", `{"Name": "a"}`, http.StatusOK},
+		{"over-limit", `{"Name": "abcdefghijklmn"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+		}
+
+		if w.Header().Get("Strict-Transport-Security") != HSTSPolicy {
+			t.Errorf("case[%s] expected the HSTS header", c.Name)
+		}
+	}
+
+	if until := time.Until(deadline); until <= 0 || until > time.Minute {
+		t.Errorf("expected the request timeout to set a deadline, got %s", until)
+	}
+
+	_, err = NewRouterFromConfig(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), Config{MaxBodyBytes: -1})
+	if err == nil {
+		t.Error("expected a negative body limit to fail")
+	}
+}
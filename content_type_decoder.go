@@ -0,0 +1,58 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// ContentTypeDecoder picks a Decoder by the request's Content-Type, so clients
+// speaking different formats can share a route, e.g.
+//
+//	autohttp.ContentTypeDecoder{
+//		"application/json":           autohttp.NewJSONDecoder(),
+//		autohttp.ProtobufContentType: autohttp.NewProtobufDecoder(unmarshal),
+//	}
+//
+// Every decoder must accept the route's function
+type ContentTypeDecoder map[string]Decoder
+
+func (ctd ContentTypeDecoder) ValidateType(fn interface{}) error {
+	if len(ctd) == 0 {
+		return errors.New("content type decoder has no decoders")
+	}
+
+	mediaTypes := make([]string, 0, len(ctd))
+	for mediaType := range ctd {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+
+	for _, mediaType := range mediaTypes {
+		err := ctd[mediaType].ValidateType(fn)
+		if err != nil {
+			return fmt.Errorf("%s: %w", mediaType, err)
+		}
+	}
+
+	return nil
+}
+
+// Decode returns the reflect values needed to call the fn
+// from the *http.Request
+func (ctd ContentTypeDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	d, ok := ctd[mediaType]
+	if !ok {
+		return nil, ErrorWithCode{Err: fmt.Errorf("unsupported mime type %s", mediaType), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	return d.Decode(fn, r)
+}
@@ -0,0 +1,89 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// WithContentTypes restricts the route to requests whose body is one of
+// mediaTypes, such as "application/json" or "image/*". Other requests get a
+// 415 listing the accepted types before they're decoded. Requests without a
+// body aren't checked. Generated OpenAPI documents list the types as the
+// route's request body content
+func WithContentTypes(mediaTypes ...string) RouteOption {
+	return func(rc *routeConfig) error {
+		if len(mediaTypes) == 0 {
+			return errors.New("autohttp: WithContentTypes needs at least one media type")
+		}
+
+		rc.contentTypes = nil
+		for _, mt := range mediaTypes {
+			parsed, _, err := mime.ParseMediaType(mt)
+			if err != nil {
+				return fmt.Errorf("autohttp: invalid media type %q: %w", mt, err)
+			}
+
+			rc.contentTypes = append(rc.contentTypes, parsed)
+		}
+
+		return nil
+	}
+}
+
+// withContentTypes wraps handler to reject requests in other content types
+// than the route's, if it restricts them. Rejections are handled by errorHandler
+func withContentTypes(handler http.Handler, rc routeConfig, errorHandler func(w http.ResponseWriter, req *http.Request, err error)) http.Handler {
+	if len(rc.contentTypes) == 0 {
+		return handler
+	}
+
+	return &contentTypesHandler{mediaTypes: rc.contentTypes, next: handler, errorHandler: errorHandler}
+}
+
+type contentTypesHandler struct {
+	mediaTypes   []string
+	next         http.Handler
+	errorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+func (cth *contentTypesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ct := req.Header.Get("Content-Type")
+	if ct == "" && (req.ContentLength == 0 || req.Body == nil || req.Body == http.NoBody) {
+		cth.next.ServeHTTP(w, req)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err == nil && mediaTypeAllowed(cth.mediaTypes, mediaType) {
+		cth.next.ServeHTTP(w, req)
+		return
+	}
+
+	// 415s list the types that would have been accepted
+	w.Header().Set("Accept", strings.Join(cth.mediaTypes, ", "))
+	if err != nil {
+		cth.errorHandler(w, req, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType})
+		return
+	}
+
+	cth.errorHandler(w, req, ErrorWithCode{Err: fmt.Errorf("unsupported mime type %s", mediaType), StatusCode: http.StatusUnsupportedMediaType})
+}
+
+// mediaTypeAllowed matches mediaType against allowed, which may hold
+// wildcards such as "image/*" or "*/*"
+func mediaTypeAllowed(allowed []string, mediaType string) bool {
+	for _, a := range allowed {
+		if a == "*/*" || a == mediaType {
+			return true
+		}
+
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
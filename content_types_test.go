@@ -0,0 +1,114 @@
+package autohttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestContentTypes(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithOpenAPI("/openapi.json", OpenAPIInfo{Title: "Pets", Version: "1.0.0"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/pets", func(p specPet) (*specPet, error) { return &p, nil }, nil,
+		WithContentTypes("application/json; charset=utf-8", "application/merge-patch+json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err = r.Register(http.MethodPut, "/photos/{id}", upload, nil, WithContentTypes("image/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/uploads/*", upload, nil, WithContentTypes("application/octet-stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/bad", upload, nil, WithContentTypes("not a type/"))
+	if err == nil {
+		t.Error("expected invalid media types to be rejected")
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+
+		code   int
+		accept string
+	}{
+		{name: "allowed", method: http.MethodPost, path: "/pets", contentType: "application/json", body: `{"name":"rex"}`, code: http.StatusOK},
+		{name: "rejected before decoding", method: http.MethodPost, path: "/pets", contentType: "text/plain", body: "rex", code: http.StatusUnsupportedMediaType, accept: "application/json, application/merge-patch+json"},
+		{name: "invalid", method: http.MethodPost, path: "/pets", contentType: "/", body: "rex", code: http.StatusUnsupportedMediaType, accept: "application/json, application/merge-patch+json"},
+		{name: "wildcard", method: http.MethodPut, path: "/photos/1", contentType: "image/png", body: "png", code: http.StatusCreated},
+		{name: "wildcard rejected", method: http.MethodPut, path: "/photos/1", contentType: "text/png", body: "png", code: http.StatusUnsupportedMediaType, accept: "image/*"},
+		{name: "no body", method: http.MethodPut, path: "/photos/1", code: http.StatusCreated},
+		{name: "body without a type", method: http.MethodPut, path: "/photos/1", body: "png", code: http.StatusUnsupportedMediaType, accept: "image/*"},
+		{name: "star route", method: http.MethodPost, path: "/uploads/a", contentType: "application/octet-stream", body: "bytes", code: http.StatusCreated},
+		{name: "star route rejected", method: http.MethodPost, path: "/uploads/a", contentType: "image/png", body: "png", code: http.StatusUnsupportedMediaType, accept: "application/octet-stream"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		if c.contentType != "" {
+			req.Header.Set("Content-Type", c.contentType)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if got := w.Header().Get("Accept"); got != c.accept {
+			t.Errorf("case[%s] expected Accept %q, got %q", c.name, c.accept, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var doc OpenAPIDocument
+	err = json.NewDecoder(w.Body).Decode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := doc.Paths["/pets"]["post"]
+	if create == nil || create.RequestBody == nil {
+		t.Fatalf("expected POST /pets with a request body, got %+v", doc.Paths["/pets"])
+	}
+
+	for _, ct := range []string{"application/json", "application/merge-patch+json"} {
+		if ref := create.RequestBody.Content[ct].Schema; ref == nil || ref.Ref != "#/components/schemas/specPet" {
+			t.Errorf("expected a %s request body, got %+v", ct, create.RequestBody.Content)
+		}
+	}
+
+	if create.Responses["415"] == nil {
+		t.Error("expected a 415 response")
+	}
+}
@@ -0,0 +1,312 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A CORSPolicy says which cross-origin requests browsers may make
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to make requests, such as
+	// "https://app.example.com". "*" allows every origin, and a single "*"
+	// within an origin, as in "https://*.example.com", allows any subdomain
+	AllowedOrigins []string
+	// AllowedMethods limits the methods allowed across origins, empty allows
+	// every method registered for the path
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed across origins beyond
+	// the CORS-safelisted ones, "*" allows any
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read beyond the
+	// CORS-safelisted ones
+	ExposedHeaders []string
+	// AllowCredentials lets requests carry cookies and authorization
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response, zero
+	// leaves it to the browser
+	MaxAge time.Duration
+}
+
+// WithCORS answers CORS preflight requests and adds CORS headers to the
+// responses of cross-origin requests allowed by p. Routes can override it
+// with WithRouteCORS
+func WithCORS(p CORSPolicy) func(r *Router) error {
+	return func(r *Router) error {
+		policy, err := newCORSPolicy(p)
+		if err != nil {
+			return err
+		}
+
+		r.cors = policy
+		return nil
+	}
+}
+
+// WithRouteCORS applies p to the route instead of the router's WithCORS policy
+func WithRouteCORS(p CORSPolicy) RouteOption {
+	return func(rc *routeConfig) error {
+		policy, err := newCORSPolicy(p)
+		if err != nil {
+			return err
+		}
+
+		rc.cors = policy
+		return nil
+	}
+}
+
+type corsPolicy struct {
+	anyOrigin bool
+	origins   []string
+
+	methods map[string]bool
+
+	anyHeader bool
+	headers   map[string]bool
+
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORSPolicy(p CORSPolicy) (*corsPolicy, error) {
+	if len(p.AllowedOrigins) == 0 {
+		return nil, errors.New("autohttp: a CORS policy needs at least one allowed origin")
+	}
+
+	cp := &corsPolicy{
+		allowCredentials: p.AllowCredentials,
+		exposedHeaders:   strings.Join(p.ExposedHeaders, ", "),
+	}
+
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			cp.anyOrigin = true
+			continue
+		}
+
+		if strings.Count(o, "*") > 1 {
+			return nil, fmt.Errorf("autohttp: CORS origin %q can only have one wildcard", o)
+		}
+		cp.origins = append(cp.origins, strings.ToLower(o))
+	}
+
+	// browsers refuse credentialed responses allowed for any origin
+	if cp.anyOrigin && p.AllowCredentials {
+		return nil, errors.New("autohttp: CORS credentials can't be allowed for every origin")
+	}
+
+	if len(p.AllowedMethods) > 0 {
+		cp.methods = make(map[string]bool)
+		for _, m := range p.AllowedMethods {
+			m = strings.ToUpper(m)
+			if !validMethods[m] {
+				return nil, fmt.Errorf("autohttp: invalid CORS method %q", m)
+			}
+			cp.methods[m] = true
+		}
+	}
+
+	cp.headers = make(map[string]bool)
+	for _, h := range p.AllowedHeaders {
+		if h == "*" {
+			cp.anyHeader = true
+			continue
+		}
+		cp.headers[strings.ToLower(h)] = true
+	}
+
+	if p.MaxAge > 0 {
+		cp.maxAge = strconv.Itoa(int(p.MaxAge / time.Second))
+	}
+
+	return cp, nil
+}
+
+// allowsOrigin reports whether origin may make requests
+func (cp *corsPolicy) allowsOrigin(origin string) bool {
+	if cp.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	for _, o := range cp.origins {
+		prefix, suffix, wildcard := strings.Cut(o, "*")
+		if !wildcard {
+			if o == origin {
+				return true
+			}
+			continue
+		}
+
+		if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowOrigin sets the headers every allowed cross-origin response carries
+func (cp *corsPolicy) allowOrigin(h http.Header, origin string) {
+	if cp.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if cp.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// applySimple adds CORS headers to the response of an actual request
+func (cp *corsPolicy) applySimple(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+
+	if !cp.anyOrigin {
+		w.Header().Add("Vary", "Origin")
+	}
+
+	if !cp.allowsOrigin(origin) {
+		return
+	}
+
+	cp.allowOrigin(w.Header(), origin)
+	if cp.exposedHeaders != "" {
+		w.Header().Set("Access-Control-Expose-Headers", cp.exposedHeaders)
+	}
+}
+
+// preflight answers a preflight request for a path serving methods, leaving
+// out the CORS headers, so the browser blocks the request, if it isn't allowed
+func (cp *corsPolicy) preflight(w http.ResponseWriter, req *http.Request, methods []string) {
+	w.Header().Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+	origin := req.Header.Get("Origin")
+	if !cp.allowsOrigin(origin) {
+		return
+	}
+
+	requested := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	var allowed []string
+	var found bool
+	for _, m := range methods {
+		if cp.methods == nil || cp.methods[m] {
+			allowed = append(allowed, m)
+			found = found || m == requested
+		}
+	}
+
+	if !found {
+		return
+	}
+
+	headers := splitHeaderList(req.Header.Get("Access-Control-Request-Headers"))
+	if !cp.anyHeader {
+		for _, h := range headers {
+			if !cp.headers[strings.ToLower(h)] {
+				return
+			}
+		}
+	}
+
+	cp.allowOrigin(w.Header(), origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if cp.maxAge != "" {
+		w.Header().Set("Access-Control-Max-Age", cp.maxAge)
+	}
+}
+
+// serveOptions answers an OPTIONS request with the methods the path serves,
+// and as a CORS preflight if it is one
+func (r *Router) serveOptions(w http.ResponseWriter, req *http.Request) {
+	methods, patterns, star := r.methodsFor(req.URL.Path)
+	if len(methods) == 0 {
+		r.serveNotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(append(methods, http.MethodOptions), ", "))
+
+	requested := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	if req.Header.Get("Origin") != "" && requested != "" {
+		policy := r.cors
+		if !star {
+			policy = r.corsPolicyFor(requested, patterns[requested])
+		}
+
+		if policy != nil {
+			policy.preflight(w, req, methods)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// methodsFor returns the sorted methods routed for path and the pattern each
+// matched. A star route serves every method
+func (r *Router) methodsFor(path string) ([]string, map[string]string, bool) {
+	for _, sr := range r.starRoutes {
+		if strings.HasPrefix(path, sr.prefix) {
+			var methods []string
+			for m := range validMethods {
+				methods = append(methods, m)
+			}
+			sort.Strings(methods)
+			return methods, nil, true
+		}
+	}
+
+	var methods []string
+	patterns := make(map[string]string)
+	for method, routes := range r.Routes {
+		if _, pattern, _, ok := r.lookup(method, routes, path); ok {
+			methods = append(methods, method)
+			patterns[method] = pattern
+		}
+	}
+
+	// GET routes answer HEAD requests too
+	if _, ok := patterns[http.MethodGet]; ok {
+		if _, ok := patterns[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+			patterns[http.MethodHead] = patterns[http.MethodGet]
+		}
+	}
+	sort.Strings(methods)
+
+	return methods, patterns, false
+}
+
+// corsPolicyFor returns the CORS policy of a route, or the router's
+func (r *Router) corsPolicyFor(method, pattern string) *corsPolicy {
+	if cp, ok := r.corsRoutes[method+" "+pattern]; ok {
+		return cp
+	}
+
+	return r.cors
+}
+
+func splitHeaderList(v string) []string {
+	var headers []string
+	for _, h := range strings.Split(v, ",") {
+		h = strings.TrimSpace(h)
+		if h != "" {
+			headers = append(headers, h)
+		}
+	}
+
+	return headers
+}
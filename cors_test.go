@@ -0,0 +1,245 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithCORS(CORSPolicy{
+			AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization"},
+			ExposedHeaders:   []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           10 * time.Minute,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		err = r.Register(method, "/widgets/{id}", ok, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Register(http.MethodGet, "/public", ok, nil, WithRouteCORS(CORSPolicy{AllowedOrigins: []string{"*"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+
+		code    int
+		expect  map[string]string
+		without []string
+	}{
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			path:   "/widgets/1",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "content-type",
+			},
+			code: http.StatusNoContent,
+			expect: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "GET, HEAD, PUT",
+				"Access-Control-Allow-Headers":     "content-type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+				"Allow":                            "GET, HEAD, PUT, OPTIONS",
+			},
+		},
+		{
+			name:   "wildcard subdomain preflight",
+			method: http.MethodOptions,
+			path:   "/widgets/1",
+			headers: map[string]string{
+				"Origin":                        "https://pr-12.preview.example.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			code: http.StatusNoContent,
+			expect: map[string]string{
+				"Access-Control-Allow-Origin": "https://pr-12.preview.example.com",
+			},
+		},
+		{
+			name:   "disallowed origin",
+			method: http.MethodOptions,
+			path:   "/widgets/1",
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": "PUT",
+			},
+			code:    http.StatusNoContent,
+			without: []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods"},
+		},
+		{
+			name:   "unregistered method",
+			method: http.MethodOptions,
+			path:   "/widgets/1",
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			code:    http.StatusNoContent,
+			without: []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:   "disallowed header",
+			method: http.MethodOptions,
+			path:   "/widgets/1",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "x-secret",
+			},
+			code:    http.StatusNoContent,
+			without: []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:   "plain options",
+			method: http.MethodOptions,
+			path:   "/widgets/1",
+			code:   http.StatusNoContent,
+			expect: map[string]string{
+				"Allow": "GET, HEAD, PUT, OPTIONS",
+			},
+			without: []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:   "options for unknown path",
+			method: http.MethodOptions,
+			path:   "/nope",
+			code:   http.StatusNotFound,
+		},
+		{
+			name:    "simple request",
+			method:  http.MethodGet,
+			path:    "/widgets/1",
+			headers: map[string]string{"Origin": "https://app.example.com"},
+			code:    http.StatusOK,
+			expect: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:    "simple request from disallowed origin",
+			method:  http.MethodGet,
+			path:    "/widgets/1",
+			headers: map[string]string{"Origin": "https://evil.example.com"},
+			code:    http.StatusOK,
+			expect:  map[string]string{"Vary": "Origin"},
+			without: []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:    "route policy",
+			method:  http.MethodGet,
+			path:    "/public",
+			headers: map[string]string{"Origin": "https://evil.example.com"},
+			code:    http.StatusOK,
+			expect: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+			without: []string{"Access-Control-Allow-Credentials", "Vary"},
+		},
+		{
+			name:   "route policy preflight",
+			method: http.MethodOptions,
+			path:   "/public",
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			code: http.StatusNoContent,
+			expect: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET, HEAD",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		for k, v := range c.expect {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("case[%s] expected %s %q, got %q", c.name, k, v, got)
+			}
+		}
+
+		for _, k := range c.without {
+			if got := w.Header().Get(k); got != "" {
+				t.Errorf("case[%s] expected no %s, got %q", c.name, k, got)
+			}
+		}
+	}
+}
+
+func TestCORSPolicyValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		policy CORSPolicy
+	}{
+		{name: "no origins", policy: CORSPolicy{}},
+		{name: "credentials for any origin", policy: CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{name: "two wildcards", policy: CORSPolicy{AllowedOrigins: []string{"https://*.*.example.com"}}},
+		{name: "bad method", policy: CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"FETCH"}}},
+	}
+
+	for _, c := range cases {
+		_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithCORS(c.policy))
+		if err == nil {
+			t.Errorf("case[%s] expected an error", c.name)
+		}
+	}
+}
+
+func TestOptionsWithoutCORS(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/anything", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Allow") != "" {
+		t.Errorf("expected the empty OPTIONS response, got %d %v", w.Code, w.Header())
+	}
+}
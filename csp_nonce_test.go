@@ -0,0 +1,82 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestCSPNonces(t *testing.T) {
+	assets := fstest.MapFS{
+		"dist/index.html": {Data: []byte(`<script nonce="{{ .CSPNonce }}" src="/app.js"></script>`)},
+		"dist/app.js":     {Data: []byte(`console.log("hi")`)},
+	}
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEmbeddedAssets(assets, "dist"),
+		WithCSPNonces(DefaultCSPNoncePolicy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		ExpectNonce bool
+	}{
+		{"root", "/", true},
+		{"spa-fallback", "/some/client/route", true},
+		{"asset", "/app.js", false},
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+			}
+
+			csp := w.Header().Get("Content-Security-Policy")
+			if !c.ExpectNonce {
+				if csp != "" {
+					t.Fatalf("expected no csp header, got %q", csp)
+				}
+				return
+			}
+
+			start := strings.Index(csp, "'nonce-")
+			if start < 0 {
+				t.Fatalf("no nonce in csp header %q", csp)
+			}
+			nonce := strings.SplitN(csp[start+len("'nonce-"):], "'", 2)[0]
+
+			if !strings.Contains(w.Body.String(), `nonce="`+nonce+`"`) {
+				t.Errorf("nonce %q not injected into body %q", nonce, w.Body.String())
+			}
+
+			if seen[nonce] {
+				t.Errorf("nonce %q was reused", nonce)
+			}
+			seen[nonce] = true
+		})
+	}
+}
+
+func TestCSPNoncesRequireAssets(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithCSPNonces(DefaultCSPNoncePolicy))
+	if err == nil {
+		t.Fatal("expected an error without embedded assets")
+	}
+}
@@ -0,0 +1,125 @@
+package autohttp
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeHeader carries the client-supplied dedupe token
+const DefaultDedupeHeader = "X-Dedupe-Token"
+
+// DuplicateSubmissionError is returned for a request carrying a dedupe token
+// that was already seen within the dedupe window
+type DuplicateSubmissionError struct {
+	// Location of the resource created by the original request, if known
+	Location string
+}
+
+func (dse *DuplicateSubmissionError) Error() string {
+	if dse.Location == "" {
+		return "duplicate submission"
+	}
+
+	return fmt.Sprintf("duplicate submission, original resource at %s", dse.Location)
+}
+
+func (dse *DuplicateSubmissionError) ResponseHeaders() http.Header {
+	h := make(http.Header)
+	if dse.Location != "" {
+		h.Set("Location", dse.Location)
+	}
+
+	return h
+}
+
+type dedupeEntry struct {
+	expires  time.Time
+	location string
+}
+
+// DedupeMiddleware rejects duplicate submissions, such as double-clicked form
+// posts, that carry the same dedupe token within a window. Duplicates get a 409
+// pointing at the resource created by the original request.
+//
+// Unlike idempotency replay the original response is not stored, and a token
+// is released if the original request fails so the client can retry
+type DedupeMiddleware struct {
+	header string
+	window time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*dedupeEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewDedupeMiddleware(window time.Duration) *DedupeMiddleware {
+	return &DedupeMiddleware{
+		header:  DefaultDedupeHeader,
+		window:  window,
+		entries: make(map[string]*dedupeEntry),
+		now:     time.Now,
+	}
+}
+
+func (dm *DedupeMiddleware) Before(r *http.Request, h *Handler) error {
+	token := r.Header.Get(dm.header)
+	if token == "" {
+		return nil
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	now := dm.now()
+	dm.sweep(now)
+
+	entry, ok := dm.entries[token]
+	if ok && now.Before(entry.expires) {
+		return MiddlewareError{
+			StatusCode: http.StatusConflict,
+			Err:        &DuplicateSubmissionError{Location: entry.location},
+		}
+	}
+
+	dm.entries[token] = &dedupeEntry{expires: now.Add(dm.window)}
+	return nil
+}
+
+func (dm *DedupeMiddleware) After(r *http.Request, statusCode int, header http.Header) {
+	token := r.Header.Get(dm.header)
+	if token == "" {
+		return
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	// failed submissions can be retried
+	if statusCode >= http.StatusBadRequest {
+		delete(dm.entries, token)
+		return
+	}
+
+	entry, ok := dm.entries[token]
+	if ok {
+		entry.location = header.Get("Location")
+	}
+}
+
+// sweep drops expired entries at most once per window. Must be called with mu held
+func (dm *DedupeMiddleware) sweep(now time.Time) {
+	if now.Sub(dm.lastSweep) < dm.window {
+		return
+	}
+
+	for token, entry := range dm.entries {
+		if !now.Before(entry.expires) {
+			delete(dm.entries, token)
+		}
+	}
+
+	dm.lastSweep = now
+}
@@ -0,0 +1,100 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestDedupeMiddleware(t *testing.T) {
+	now := time.Now()
+	dm := NewDedupeMiddleware(10 * time.Second)
+	dm.now = func() time.Time { return now }
+
+	fail := false
+	h, err := NewHandler(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		NewJSONDecoder(),
+		&JSONEncoder{},
+		[]Middleware{dm},
+		DefaultErrorHandler,
+		func(ctx context.Context, in struct{ Name string }) (map[string]string, error) {
+			if fail {
+				return nil, errors.New("failed")
+			}
+			return map[string]string{"name": in.Name}, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(token string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name": "x"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(DefaultDedupeHeader, token)
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	cases := []struct {
+		Name         string
+		Token        string
+		Fail         bool
+		Advance      time.Duration
+		ExpectStatus int
+	}{
+		{"first", "a", false, 0, http.StatusOK},
+		{"duplicate", "a", false, time.Second, http.StatusConflict},
+		{"other-token", "b", false, 0, http.StatusOK},
+		{"after-window", "a", false, 10 * time.Second, http.StatusOK},
+		{"failed", "c", true, 0, http.StatusInternalServerError},
+		{"retry-after-failure", "c", false, 0, http.StatusOK},
+		{"no-token", "", false, 0, http.StatusOK},
+		{"no-token-again", "", false, 0, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+		fail = c.Fail
+
+		if code := serve(c.Token); code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, code)
+		}
+	}
+}
+
+func TestDedupeMiddlewareLocation(t *testing.T) {
+	t.Parallel()
+
+	dm := NewDedupeMiddleware(time.Minute)
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(DefaultDedupeHeader, "token")
+
+	err := dm.Before(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := make(http.Header)
+	header.Set("Location", "/orders/1")
+	dm.After(r, http.StatusCreated, header)
+
+	err = dm.Before(r, nil)
+
+	var dse *DuplicateSubmissionError
+	if !errors.As(err, &dse) {
+		t.Fatalf("expected duplicate submission error, got %v", err)
+	}
+
+	if dse.ResponseHeaders().Get("Location") != "/orders/1" {
+		t.Errorf("expected location of original resource, got %q", dse.Location)
+	}
+}
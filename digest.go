@@ -0,0 +1,198 @@
+package autohttp
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrDigestMismatch is returned for requests whose body doesn't match
+	// their Digest or Content-MD5 header
+	ErrDigestMismatch = errors.New("autohttp: request body doesn't match its digest")
+	// ErrDigestMissing is returned for requests without a digest on routes
+	// requiring one
+	ErrDigestMissing = errors.New("autohttp: request digest required")
+)
+
+// digestHashes are the RFC 3230 digest algorithms supported, by their
+// lowercased names
+var digestHashes = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// WithRequestDigest verifies the route's request bodies against their RFC 3230
+// Digest and Content-MD5 headers before they're decoded, answering mismatches
+// with a 400. When required, requests without a supported digest are rejected
+// too. Bodies that aren't spooled with WithBodySpooling are held in memory,
+// up to the route's WithRouteMaxBodyBytes or WithMaxBodyBytes limit, or else
+// its decoder's MaxBytesToRead, or DefaultMaxBytesToRead
+func WithRequestDigest(required bool) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.requestDigest = &requestDigest{required: required}
+		return nil
+	}
+}
+
+// WithResponseDigest sets a Digest header on the route's responses, computed
+// over the body as it's sent, after compression, with each of algs: "md5",
+// "sha-256" or "sha-512". Listing "md5" also sets Content-MD5. Without algs
+// it uses "sha-256". Streamed responses aren't digested
+func WithResponseDigest(algs ...string) RouteOption {
+	return func(rc *routeConfig) error {
+		if len(algs) == 0 {
+			algs = []string{"sha-256"}
+		}
+
+		rc.responseDigest = nil
+		for _, alg := range algs {
+			alg = strings.ToLower(alg)
+			if _, ok := digestHashes[alg]; !ok {
+				return fmt.Errorf("autohttp: unsupported digest algorithm %q", alg)
+			}
+
+			rc.responseDigest = append(rc.responseDigest, alg)
+		}
+
+		return nil
+	}
+}
+
+type requestDigest struct {
+	required bool
+	// limit caps the bodies held in memory to be verified
+	limit int64
+}
+
+// verify checks the body of r against its digests, leaving the body
+// to be read again
+func (rd *requestDigest) verify(r *http.Request) error {
+	wants, err := requestDigests(r.Header)
+	if err != nil {
+		return err
+	}
+
+	if len(wants) == 0 {
+		if rd.required {
+			return ErrorWithCode{Err: ErrDigestMissing, StatusCode: http.StatusBadRequest}
+		}
+		return nil
+	}
+
+	hashes := make(map[string]hash.Hash, len(wants))
+	writers := make([]io.Writer, 0, len(wants))
+	for alg := range wants {
+		hashes[alg] = digestHashes[alg]()
+		writers = append(writers, hashes[alg])
+	}
+	dst := io.MultiWriter(writers...)
+
+	if r.Body != nil && r.Body != http.NoBody {
+		if seeker, ok := r.Body.(io.Seeker); ok {
+			// spooled bodies are hashed in place and rewound
+			_, err = io.Copy(dst, r.Body)
+			if err == nil {
+				_, err = seeker.Seek(0, io.SeekStart)
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			var buf bytes.Buffer
+			n, err := io.Copy(io.MultiWriter(dst, &buf), io.LimitReader(r.Body, rd.limit+1))
+			if err != nil {
+				return err
+			}
+
+			if n > rd.limit {
+				return ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", rd.limit), StatusCode: http.StatusRequestEntityTooLarge}
+			}
+
+			r.Body.Close()
+			r.Body = io.NopCloser(&buf)
+		}
+	}
+
+	for alg, want := range wants {
+		if !bytes.Equal(hashes[alg].Sum(nil), want) {
+			return ErrorWithCode{Err: ErrDigestMismatch, StatusCode: http.StatusBadRequest}
+		}
+	}
+
+	return nil
+}
+
+// requestDigests returns the supported digests a request carries, by
+// algorithm. Unsupported algorithms are ignored
+func requestDigests(header http.Header) (map[string][]byte, error) {
+	wants := make(map[string][]byte)
+	mismatch := ErrorWithCode{Err: ErrDigestMismatch, StatusCode: http.StatusBadRequest}
+
+	if v := header.Get("Content-MD5"); v != "" {
+		sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			return nil, mismatch
+		}
+		wants["md5"] = sum
+	}
+
+	for _, v := range header.Values("Digest") {
+		for _, part := range strings.Split(v, ",") {
+			alg, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok {
+				continue
+			}
+
+			alg = strings.ToLower(alg)
+			if _, ok := digestHashes[alg]; !ok {
+				continue
+			}
+
+			sum, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, mismatch
+			}
+
+			if prev, ok := wants[alg]; ok && !bytes.Equal(prev, sum) {
+				return nil, mismatch
+			}
+			wants[alg] = sum
+		}
+	}
+
+	return wants, nil
+}
+
+// digestBody buffers body and sets its Digest, and Content-MD5 if md5 is
+// among algs, in header
+func digestBody(algs []string, header http.Header, body io.Reader) (io.Reader, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make([]string, 0, len(algs))
+	for _, alg := range algs {
+		h := digestHashes[alg]()
+		h.Write(b)
+		sum := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		if alg == "md5" {
+			header.Set("Content-MD5", sum)
+		}
+		digests = append(digests, alg+"="+sum)
+	}
+
+	header.Set("Digest", strings.Join(digests, ","))
+	return bytes.NewReader(b), nil
+}
@@ -0,0 +1,226 @@
+package autohttp
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestDigest(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := func(in struct{ Name string }) (map[string]string, error) {
+		return map[string]string{"name": in.Name}, nil
+	}
+
+	err = r.Register(http.MethodPost, "/optional", echo, nil, WithRequestDigest(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/required", echo, nil, WithRequestDigest(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/spooled", echo, nil, WithRequestDigest(true), WithBodySpooling(4, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"Name":"rex"}`
+	sha := sha256.Sum256([]byte(body))
+	md := md5.Sum([]byte(body))
+	shaDigest := "SHA-256=" + base64.StdEncoding.EncodeToString(sha[:])
+	md5Digest := base64.StdEncoding.EncodeToString(md[:])
+
+	cases := []struct {
+		name      string
+		path      string
+		digest    string
+		contentMD string
+		code      int
+	}{
+		{name: "no digest", path: "/optional", code: http.StatusOK},
+		{name: "sha-256", path: "/optional", digest: shaDigest, code: http.StatusOK},
+		{name: "content-md5", path: "/optional", contentMD: md5Digest, code: http.StatusOK},
+		{name: "both", path: "/required", digest: "unknown=abc, " + shaDigest, contentMD: md5Digest, code: http.StatusOK},
+		{name: "mismatch", path: "/optional", digest: "sha-256=" + md5Digest, code: http.StatusBadRequest},
+		{name: "invalid base64", path: "/optional", contentMD: "not base64!", code: http.StatusBadRequest},
+		{name: "required but missing", path: "/required", code: http.StatusBadRequest},
+		{name: "required but unsupported", path: "/required", digest: "unknown=abc", code: http.StatusBadRequest},
+		{name: "spooled", path: "/spooled", digest: shaDigest, code: http.StatusOK},
+		{name: "spooled mismatch", path: "/spooled", contentMD: base64.StdEncoding.EncodeToString(sha[:]), code: http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if c.digest != "" {
+			req.Header.Set("Digest", c.digest)
+		}
+		if c.contentMD != "" {
+			req.Header.Set("Content-MD5", c.contentMD)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d: %s", c.name, c.code, w.Code, w.Body.String())
+		}
+
+		if c.code == http.StatusOK && !strings.Contains(w.Body.String(), `"name":"rex"`) {
+			t.Errorf("case[%s] expected the verified body to be decoded, got %q", c.name, w.Body.String())
+		}
+	}
+}
+
+func TestRequestDigestBodyLimit(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := func(in struct{ Name string }) (map[string]string, error) {
+		return map[string]string{"name": in.Name}, nil
+	}
+
+	large := NewJSONDecoder()
+	large.MaxBytesToRead = 4 * DefaultMaxBytesToRead
+
+	err = r.Register(http.MethodPost, "/default", echo, nil, WithRequestDigest(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/route", echo, nil, WithRequestDigest(true), WithRouteMaxBodyBytes(3*DefaultMaxBytesToRead), WithDecoder(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/decoder", echo, nil, WithRequestDigest(true), WithDecoder(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"Name":"` + strings.Repeat("x", int(2*DefaultMaxBytesToRead)) + `"}`
+	sha := sha256.Sum256([]byte(body))
+
+	cases := []struct {
+		name string
+		path string
+		code int
+	}{
+		{name: "default limit", path: "/default", code: http.StatusRequestEntityTooLarge},
+		{name: "route limit", path: "/route", code: http.StatusOK},
+		{name: "decoder limit", path: "/decoder", code: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Digest", "sha-256="+base64.StdEncoding.EncodeToString(sha[:]))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+	}
+}
+
+func TestResponseDigest(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithCompression(GzipCompressor{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pet := func() (map[string]string, error) {
+		return map[string]string{"name": "rex"}, nil
+	}
+
+	err = r.Register(http.MethodGet, "/sha", pet, nil, WithDecoder(NoOpDecoder{}), WithResponseDigest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/both", pet, nil, WithDecoder(NoOpDecoder{}), WithResponseDigest("MD5", "sha-256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/bad", pet, nil, WithDecoder(NoOpDecoder{}), WithResponseDigest("crc32"))
+	if err == nil {
+		t.Error("expected an unsupported digest algorithm to be rejected")
+	}
+
+	cases := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		algs           []string
+	}{
+		{name: "default", path: "/sha", algs: []string{"sha-256"}},
+		{name: "compressed", path: "/sha", acceptEncoding: "gzip", algs: []string{"sha-256"}},
+		{name: "md5 and sha-256", path: "/both", algs: []string{"md5", "sha-256"}},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s] expected code 200, got %d", c.name, w.Code)
+			continue
+		}
+
+		// digests cover the body as sent
+		b := w.Body.Bytes()
+		sha := sha256.Sum256(b)
+		md := md5.Sum(b)
+		sums := map[string]string{
+			"md5":     base64.StdEncoding.EncodeToString(md[:]),
+			"sha-256": base64.StdEncoding.EncodeToString(sha[:]),
+		}
+
+		var expected []string
+		for _, alg := range c.algs {
+			expected = append(expected, alg+"="+sums[alg])
+		}
+
+		if got := w.Header().Get("Digest"); got != strings.Join(expected, ",") {
+			t.Errorf("case[%s] expected Digest %q, got %q", c.name, strings.Join(expected, ","), got)
+		}
+
+		expectedMD5 := ""
+		if len(c.algs) > 1 {
+			expectedMD5 = sums["md5"]
+		}
+		if got := w.Header().Get("Content-MD5"); got != expectedMD5 {
+			t.Errorf("case[%s] expected Content-MD5 %q, got %q", c.name, expectedMD5, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrEarlyHintsUnavailable is returned when early hints can't be sent for a request
+var ErrEarlyHintsUnavailable = errors.New("autohttp: early hints unavailable for this request")
+
+type earlyHintsCtxKey struct{}
+
+// earlyHinter lets code without access to the ResponseWriter send early hints
+type earlyHinter struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+func withEarlyHints(w http.ResponseWriter, r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), earlyHintsCtxKey{}, &earlyHinter{w: w, r: r}))
+}
+
+// PreloadLink formats a Link header value preloading url as the given type,
+// e.g. PreloadLink("/app.js", "script")
+func PreloadLink(url, as string) string {
+	return fmt.Sprintf("<%s>; rel=preload; as=%s", url, as)
+}
+
+// WriteEarlyHints sends a 103 Early Hints response with links as Link headers,
+// before the final response is written. The links are kept on the final response.
+// Early hints are not sent to HTTP/1.0 clients, nor by builds before Go 1.19,
+// where net/http takes a 103 for the final response
+func WriteEarlyHints(w http.ResponseWriter, r *http.Request, links ...string) error {
+	if !earlyHintsSupported || !r.ProtoAtLeast(1, 1) {
+		return ErrEarlyHintsUnavailable
+	}
+
+	for _, link := range links {
+		w.Header().Add("Link", link)
+	}
+
+	w.WriteHeader(http.StatusEarlyHints)
+	return nil
+}
+
+// EarlyHints sends a 103 Early Hints response from within a handler function
+// or middleware, using the context of a request served by a Handler
+func EarlyHints(ctx context.Context, links ...string) error {
+	eh, ok := ctx.Value(earlyHintsCtxKey{}).(*earlyHinter)
+	if !ok {
+		return ErrEarlyHintsUnavailable
+	}
+
+	return WriteEarlyHints(eh.w, eh.r, links...)
+}
+
+// WithEarlyHints sends links as early hints ahead of serving index.html, so
+// browsers can start fetching critical assets while the page is prepared
+func WithEarlyHints(links ...string) AssetOption {
+	return func(ea *embeddedAssets) error {
+		ea.earlyHints = links
+		return nil
+	}
+}
@@ -0,0 +1,7 @@
+//go:build go1.19
+
+package autohttp
+
+// earlyHintsSupported is set from Go 1.19, whose net/http sends 1xx
+// responses ahead of the final one
+const earlyHintsSupported = true
@@ -0,0 +1,106 @@
+//go:build go1.19
+
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestEarlyHints(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/page", func(ctx context.Context, in struct{ Name string }) (map[string]string, error) {
+		err := EarlyHints(ctx, PreloadLink("/app.js", "script"))
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]string{"name": in.Name}, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	var hints []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				hints = append(hints, header.Values("Link")...)
+			}
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(
+		httptrace.WithClientTrace(context.Background(), trace),
+		http.MethodPost,
+		srv.URL+"/page",
+		strings.NewReader(`{"Name": "x"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, res.StatusCode)
+	}
+
+	if len(hints) != 1 || hints[0] != "</app.js>; rel=preload; as=script" {
+		t.Errorf("unexpected early hints %v", hints)
+	}
+}
+
+func TestEarlyHintsMetrics(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs), lounge.WithDebugEnabled()), EnableRouteMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/page", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		WriteEarlyHints(w, req, PreloadLink("/app.js", "script"))
+		w.Write([]byte("page"))
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	if !strings.Contains(logs.String(), "with code 200") || strings.Contains(logs.String(), "with code 103") {
+		t.Errorf("expected the final status to be recorded, got %q", logs.String())
+	}
+}
+
+func TestEarlyHintsUnavailable(t *testing.T) {
+	t.Parallel()
+
+	err := EarlyHints(context.Background(), PreloadLink("/app.js", "script"))
+	if err != ErrEarlyHintsUnavailable {
+		t.Fatalf("expected ErrEarlyHintsUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !go1.19
+
+package autohttp
+
+// earlyHintsSupported is unset before Go 1.19, whose net/http takes any
+// status written for the final one
+const earlyHintsSupported = false
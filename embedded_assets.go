@@ -0,0 +1,293 @@
+package autohttp
+
+import (
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+type embeddedAssets struct {
+	staticDir fs.FS
+	manifest  *AssetManifest
+
+	indexTemplate  *template.Template
+	cspNoncePolicy string
+
+	directoryListing bool
+	notFound         AssetNotFoundBehavior
+	earlyHints       []string
+	serverPush       bool
+	errorPages       *errorPages
+	compression      *assetCompression
+	cacheRules       []assetCacheRule
+
+	// urlPrefix is the path assets are mounted under
+	urlPrefix string
+
+	// startedAt stands in for the modification time of assets without one,
+	// such as those embedded with embed.FS
+	startedAt  time.Time
+	validators map[string]*assetValidators
+	dirs       map[string]bool
+}
+
+// AssetNotFoundBehavior controls what happens to requests for assets that don't exist
+type AssetNotFoundBehavior int
+
+const (
+	// AssetNotFoundSPA serves the root index.html, so client side routes
+	// of single page apps resolve. This is the default
+	AssetNotFoundSPA AssetNotFoundBehavior = iota
+	// AssetNotFoundStrict responds with a 404
+	AssetNotFoundStrict
+	// AssetNotFoundFallThrough hands the request on to the router's routes.
+	// It only applies to assets registered with MountAssets
+	AssetNotFoundFallThrough
+)
+
+// WithNotFoundBehavior sets what happens to requests for assets that don't exist
+func WithNotFoundBehavior(b AssetNotFoundBehavior) AssetOption {
+	return func(ea *embeddedAssets) error {
+		ea.notFound = b
+		return nil
+	}
+}
+
+// AssetOption configures how a set of embedded assets is served
+type AssetOption func(ea *embeddedAssets) error
+
+// EnableDirectoryListing serves a styled index for directories that have no
+// index.html of their own, instead of falling back to the root index.html.
+// Hidden files are left out of the listing
+func EnableDirectoryListing(ea *embeddedAssets) error {
+	ea.directoryListing = true
+	return nil
+}
+
+func newEmbeddedAssets(assets fs.FS, distDir string, opts ...AssetOption) (*embeddedAssets, error) {
+	staticFS, err := fs.Sub(assets, distDir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := newAssetManifest(staticFS)
+	if err != nil {
+		return nil, err
+	}
+
+	ea := &embeddedAssets{
+		staticDir: staticFS,
+		manifest:  manifest,
+		startedAt: time.Now(),
+	}
+
+	for _, opt := range opts {
+		err = opt(ea)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ea.compression != nil {
+		err = ea.compression.build(ea.staticDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ea.buildValidators()
+	if err != nil {
+		return nil, err
+	}
+
+	return ea, nil
+}
+
+func (ea *embeddedAssets) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if ea.notFound != AssetNotFoundSPA && !ea.resolves(req.URL.Path) {
+		if ea.errorPages == nil || !ea.errorPages.serve(w, req, http.StatusNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	if ea.directoryListing && ea.servesDirectoryListing(req.URL.Path) {
+		ea.serveDirectoryListing(w, req)
+		return
+	}
+
+	if len(ea.earlyHints) > 0 && ea.servesIndex(req.URL.Path) {
+		WriteEarlyHints(w, req, ea.earlyHints...)
+	}
+
+	if ea.serverPush && ea.servesIndex(req.URL.Path) {
+		ea.pushCriticalAssets(w, req)
+	}
+
+	if ea.indexTemplate != nil && ea.servesIndex(req.URL.Path) {
+		ea.serveIndexTemplate(w, req)
+		return
+	}
+
+	if ea.serveNotModified(w, req) {
+		return
+	}
+
+	if ea.compression != nil && ea.compression.serve(w, req) {
+		return
+	}
+
+	// Handling the FileServer Code Snippet was taken from here:
+	// https://golang.org/pkg/embed/#hdr-File_Systems
+	nfs := indexOnNotFoundFS{fs: ea.staticDir}
+
+	embeddedFileServer := http.FileServer(http.FS(nfs))
+	embeddedFileServer.ServeHTTP(w, req)
+}
+
+// assetName converts a URL path into a name within the assets FS
+func assetName(urlPath string) string {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+func isHiddenAsset(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolves reports whether urlPath maps to an asset, without any fallback
+func (ea *embeddedAssets) resolves(urlPath string) bool {
+	name := assetName(urlPath)
+	stat, err := fs.Stat(ea.staticDir, name)
+	if err != nil {
+		return false
+	}
+
+	if !stat.IsDir() {
+		return true
+	}
+
+	if ea.directoryListing && !isHiddenAsset(name) {
+		return true
+	}
+
+	_, err = fs.Stat(ea.staticDir, path.Join(name, "index.html"))
+	return err == nil
+}
+
+// servesDirectoryListing reports whether urlPath is a directory without an index.html
+func (ea *embeddedAssets) servesDirectoryListing(urlPath string) bool {
+	name := assetName(urlPath)
+	if isHiddenAsset(name) {
+		return false
+	}
+
+	stat, err := fs.Stat(ea.staticDir, name)
+	if err != nil || !stat.IsDir() {
+		return false
+	}
+
+	_, err = fs.Stat(ea.staticDir, path.Join(name, "index.html"))
+	return err != nil
+}
+
+type directoryListingEntry struct {
+	Name  string
+	Href  string
+	IsDir bool
+	Size  int64
+}
+
+type directoryListingData struct {
+	Path    string
+	Parent  string
+	Entries []directoryListingEntry
+}
+
+var directoryListingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index of {{ .Path }}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+table { border-collapse: collapse; min-width: 40rem; }
+th, td { text-align: left; padding: 0.3rem 1rem 0.3rem 0; border-bottom: 1px solid #eee; }
+td.size { text-align: right; color: #666; }
+a { color: #0057b8; text-decoration: none; }
+a:hover { text-decoration: underline; }
+</style>
+</head>
+<body>
+<h1>Index of {{ .Path }}</h1>
+<table>
+<tr><th>Name</th><th>Size</th></tr>
+{{ if .Parent }}<tr><td><a href="{{ .Parent }}">../</a></td><td></td></tr>{{ end }}
+{{ range .Entries }}<tr><td><a href="{{ .Href }}">{{ .Name }}{{ if .IsDir }}/{{ end }}</a></td><td class="size">{{ if not .IsDir }}{{ .Size }}{{ end }}</td></tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+func (ea *embeddedAssets) serveDirectoryListing(w http.ResponseWriter, req *http.Request) {
+	// relative links only resolve correctly from a trailing slash
+	if !strings.HasSuffix(req.URL.Path, "/") {
+		http.Redirect(w, req, path.Base(req.URL.Path)+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	name := assetName(req.URL.Path)
+	dirEntries, err := fs.ReadDir(ea.staticDir, name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	data := directoryListingData{Path: req.URL.Path}
+	if name != "." {
+		data.Parent = "../"
+	}
+
+	for _, de := range dirEntries {
+		if isHiddenAsset(de.Name()) {
+			continue
+		}
+
+		entry := directoryListingEntry{
+			Name:  de.Name(),
+			Href:  de.Name(),
+			IsDir: de.IsDir(),
+		}
+		if de.IsDir() {
+			entry.Href += "/"
+		} else if info, err := de.Info(); err == nil {
+			entry.Size = info.Size()
+		}
+
+		data.Entries = append(data.Entries, entry)
+	}
+
+	sort.Slice(data.Entries, func(i, j int) bool {
+		if data.Entries[i].IsDir != data.Entries[j].IsDir {
+			return data.Entries[i].IsDir
+		}
+		return data.Entries[i].Name < data.Entries[j].Name
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	directoryListingTemplate.Execute(w, data)
+}
@@ -0,0 +1,218 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestDirectoryListing(t *testing.T) {
+	assets := fstest.MapFS{
+		"dist/index.html":          {Data: []byte(`spa`)},
+		"dist/docs/guide.pdf":      {Data: []byte(`pdf`)},
+		"dist/docs/.secret":        {Data: []byte(`secret`)},
+		"dist/docs/v2/notes.txt":   {Data: []byte(`notes`)},
+		"dist/site/index.html":     {Data: []byte(`site`)},
+		"dist/.hidden/archive.zip": {Data: []byte(`zip`)},
+	}
+
+	cases := []struct {
+		Name           string
+		Options        []AssetOption
+		Path           string
+		ExpectContains []string
+		ExpectMissing  []string
+	}{
+		{
+			"disabled-by-default",
+			nil,
+			"/docs/",
+			[]string{"spa"},
+			[]string{"guide.pdf"},
+		},
+		{
+			"listing",
+			[]AssetOption{EnableDirectoryListing},
+			"/docs/",
+			[]string{"Index of /docs/", "guide.pdf", `href="v2/"`, `href="../"`},
+			[]string{".secret"},
+		},
+		{
+			"dir-with-index",
+			[]AssetOption{EnableDirectoryListing},
+			"/site/",
+			[]string{"site"},
+			[]string{"Index of"},
+		},
+		{
+			"hidden-dir",
+			[]AssetOption{EnableDirectoryListing},
+			"/.hidden/",
+			nil,
+			[]string{"archive.zip"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEmbeddedAssets(assets, "dist", c.Options...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+			for _, s := range c.ExpectContains {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Errorf("expected %q in body %q", s, w.Body.String())
+				}
+			}
+
+			for _, s := range c.ExpectMissing {
+				if strings.Contains(w.Body.String(), s) {
+					t.Errorf("expected no %q in body %q", s, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAssetMountNotFoundBehavior(t *testing.T) {
+	assets := fstest.MapFS{
+		"dist/index.html": {Data: []byte(`spa`)},
+		"dist/app.js":     {Data: []byte(`js`)},
+	}
+
+	cases := []struct {
+		Name         string
+		Behavior     AssetNotFoundBehavior
+		Path         string
+		ExpectStatus int
+		ExpectBody   string
+	}{
+		{"spa-hit", AssetNotFoundSPA, "/app.js", http.StatusOK, "js"},
+		{"spa-miss", AssetNotFoundSPA, "/client/route", http.StatusOK, "spa"},
+		{"spa-shadows-routes", AssetNotFoundSPA, "/api/ping", http.StatusOK, "spa"},
+		{"strict-hit", AssetNotFoundStrict, "/app.js", http.StatusOK, "js"},
+		{"strict-miss", AssetNotFoundStrict, "/client/route", http.StatusNotFound, ""},
+		{"fallthrough-hit", AssetNotFoundFallThrough, "/app.js", http.StatusOK, "js"},
+		{"fallthrough-route", AssetNotFoundFallThrough, "/api/ping", http.StatusOK, "pong"},
+		{"fallthrough-miss", AssetNotFoundFallThrough, "/client/route", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.MountAssets("/", assets, "dist", WithNotFoundBehavior(c.Behavior))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Register(http.MethodGet, "/api/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("pong"))
+			}), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("expected %d got %d", c.ExpectStatus, w.Code)
+			}
+
+			if c.ExpectBody != "" && w.Body.String() != c.ExpectBody {
+				t.Errorf("expected %q got %q", c.ExpectBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAssetMountPrefix(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.MountAssets("/static/", fstest.MapFS{"public/app.js": {Data: []byte(`js`)}}, "public", WithNotFoundBehavior(AssetNotFoundStrict))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "js" {
+		t.Errorf("expected asset, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/missing.js", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (pr *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	pr.pushed = append(pr.pushed, target)
+	return nil
+}
+
+func TestServerPush(t *testing.T) {
+	assets := fstest.MapFS{
+		"dist/index.html": {Data: []byte(`spa`)},
+		"dist/app.js":     {Data: []byte(`js`)},
+		"dist/app.css":    {Data: []byte(`css`)},
+	}
+
+	cases := []struct {
+		Name       string
+		ProtoMajor int
+		Path       string
+		ExpectPush []string
+	}{
+		{"http2-index", 2, "/app/", []string{"/app/app.js", "/app/app.css"}},
+		{"http2-spa-fallback", 2, "/app/settings", []string{"/app/app.js", "/app/app.css"}},
+		{"http2-asset", 2, "/app/app.js", nil},
+		{"http1", 1, "/app/", nil},
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.MountAssets("/app", assets, "dist", WithCriticalAssets("/app.js", "/app.css"), EnableServerPush)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+			req := httptest.NewRequest(http.MethodGet, c.Path, nil)
+			req.ProtoMajor = c.ProtoMajor
+
+			r.ServeHTTP(w, req)
+
+			if strings.Join(w.pushed, ",") != strings.Join(c.ExpectPush, ",") {
+				t.Errorf("expected pushes %v got %v", c.ExpectPush, w.pushed)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package autohttp
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultEnvPrefix is used by WithEnvDefaults and ConfigFromEnv when no
+// prefix is given, reading AUTOHTTP_MAX_BODY_BYTES and friends
+const DefaultEnvPrefix = "AUTOHTTP"
+
+// ConfigFromEnv reads a Config from environment variables named after its
+// json tags, e.g. AUTOHTTP_REQUEST_TIMEOUT=30s or AUTOHTTP_ROUTE_METRICS=true.
+// The port falls back to PORT, as set by platforms such as Heroku
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+
+	var cfg Config
+	v := reflect.ValueOf(&cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		name := prefix + "_" + strings.ToUpper(tag)
+
+		value, ok := os.LookupEnv(name)
+		if !ok && tag == "port" {
+			value, ok = os.LookupEnv("PORT")
+		}
+		if !ok {
+			continue
+		}
+
+		err := setFromEnv(v.Field(i), value)
+		if err != nil {
+			return Config{}, fmt.Errorf("autohttp: invalid %s: %w", name, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+func setFromEnv(field reflect.Value, value string) error {
+	if tu, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported config field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// WithEnvDefaults configures the router from environment variables, see
+// ConfigFromEnv. Options after it override what the environment set
+func WithEnvDefaults(prefix string) func(r *Router) error {
+	return func(r *Router) error {
+		cfg, err := ConfigFromEnv(prefix)
+		if err != nil {
+			return err
+		}
+
+		err = cfg.validate()
+		if err != nil {
+			return err
+		}
+
+		for _, opt := range cfg.Options() {
+			err := opt(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
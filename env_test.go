@@ -0,0 +1,78 @@
+package autohttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("PORT", "5000")
+	t.Setenv("MYAPP_REQUEST_TIMEOUT", "2s")
+	t.Setenv("MYAPP_MAX_BODY_BYTES", "4096")
+	t.Setenv("MYAPP_ROUTE_METRICS", "true")
+	t.Setenv("MYAPP_LOG_LEVEL", "error")
+
+	cfg, err := ConfigFromEnv("MYAPP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := Config{
+		Port:           "5000",
+		LogLevel:       "error",
+		RequestTimeout: Duration(2 * time.Second),
+		MaxBodyBytes:   4096,
+		RouteMetrics:   true,
+	}
+	if cfg != expect {
+		t.Errorf("expected %+v got %+v", expect, cfg)
+	}
+
+	t.Setenv("MYAPP_PORT", "6000")
+	t.Setenv("MYAPP_HSTS", "maybe")
+	_, err = ConfigFromEnv("MYAPP")
+	if err == nil || !strings.Contains(err.Error(), "MYAPP_HSTS") {
+		t.Errorf("expected an error naming the bad variable, got %v", err)
+	}
+}
+
+func TestWithEnvDefaults(t *testing.T) {
+	t.Setenv("AUTOHTTP_PORT", "9000")
+	t.Setenv("AUTOHTTP_ROUTE_METRICS", "1")
+	t.Setenv("AUTOHTTP_LOG_LEVEL", "info")
+
+	var logs bytes.Buffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs), lounge.WithDebugEnabled()),
+		WithEnvDefaults(""),
+		// later options override the environment
+		WithPort("9100"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Port() != "9100" {
+		t.Errorf("expected the explicit port to win, got %s", r.Port())
+	}
+
+	if !r.enableRouteMetrics {
+		t.Error("expected route metrics to be enabled from the environment")
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if logs.Len() != 0 {
+		t.Errorf("expected the info level to drop debug metrics, got %q", logs.String())
+	}
+
+	t.Setenv("AUTOHTTP_LOG_LEVEL", "loud")
+	_, err = NewRouter(lounge.NewDefaultLog(), WithEnvDefaults(""))
+	if err == nil {
+		t.Error("expected an unknown log level to fail")
+	}
+}
@@ -2,6 +2,7 @@ package autohttp
 
 import (
 	"errors"
+	"net/http"
 	"reflect"
 )
 
@@ -11,6 +12,13 @@ func errTypeInvalidAtIndex(idx int, t reflect.Type) error {
 	return errors.New("type is invalid at input idx")
 }
 
+// ErrorWithHeaders is implemented by errors that need headers set on
+// the error response, such as Location or Retry-After
+type ErrorWithHeaders interface {
+	error
+	ResponseHeaders() http.Header
+}
+
 type ErrorWithCode struct {
 	Err        error
 	StatusCode int
@@ -26,3 +34,28 @@ func NewErrorWithCode(err error, code int) error {
 func (ewc ErrorWithCode) Error() string {
 	return ewc.Err.Error()
 }
+
+func (ewc ErrorWithCode) Unwrap() error {
+	return ewc.Err
+}
+
+// statusCodeForError returns the status code an error should be reported with,
+// falling back to a 500 for errors that don't carry one
+func statusCodeForError(err error) int {
+	var ewc ErrorWithCode
+	if errors.As(err, &ewc) {
+		return ewc.StatusCode
+	}
+
+	var ewcPtr *ErrorWithCode
+	if errors.As(err, &ewcPtr) {
+		return ewcPtr.StatusCode
+	}
+
+	var mwe MiddlewareError
+	if errors.As(err, &mwe) {
+		return mwe.StatusCode
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,98 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StageRouting is the Stage of errors raised by the router itself, before a
+// route's handler runs, such as rejected or read-only requests
+const StageRouting Stage = "routing"
+
+// ErrorContext describes the request an error was raised on
+type ErrorContext struct {
+	Request *http.Request
+	// Route is the matched route pattern, or "" before a route is matched
+	Route string
+	Stage Stage
+
+	// Start is when the router began serving the request, and Elapsed how
+	// long it has taken so far. Both are zero outside a Router
+	Start   time.Time
+	Elapsed time.Duration
+
+	RequestID string
+	// TraceParent is the trace context the request was sent with, see WithTracing
+	TraceParent    TraceParent
+	HasTraceParent bool
+}
+
+// A RequestErrorHandler writes error responses like an ErrorHandler, but also
+// sees the request, so errors can vary by request or carry trace IDs
+type RequestErrorHandler func(w http.ResponseWriter, ec ErrorContext, err error)
+
+// WithRequestErrorHandler handles every error with reh, in place of the
+// ErrorHandler. It can't be combined with WithDefaultErrorHandler
+func WithRequestErrorHandler(reh RequestErrorHandler) func(r *Router) error {
+	return func(r *Router) error {
+		if reh == nil {
+			return errors.New("autohttp: WithRequestErrorHandler needs a handler")
+		}
+
+		r.requestErrorHandler = reh
+		return nil
+	}
+}
+
+// newErrorContext describes req, which failed in stage
+func newErrorContext(req *http.Request, stage Stage) ErrorContext {
+	ctx := req.Context()
+	ec := ErrorContext{
+		Request:   req,
+		Route:     RoutePattern(ctx),
+		Stage:     stage,
+		RequestID: RequestIDFromContext(ctx),
+	}
+
+	if rm := requestMetaFromContext(ctx); rm != nil {
+		ec.Start = rm.start
+		ec.Elapsed = time.Since(rm.start)
+	}
+
+	ec.TraceParent, ec.HasTraceParent = TraceParentFromContext(ctx)
+	return ec
+}
+
+// serveError hands err to reh, if there is one, or to eh
+func serveError(w http.ResponseWriter, req *http.Request, stage Stage, err error, eh ErrorHandler, reh RequestErrorHandler) {
+	if reh != nil {
+		reh(w, newErrorContext(req, stage), err)
+		return
+	}
+
+	eh(w, err)
+}
+
+// serveRouterError handles errors the router raises before any route runs
+func (r *Router) serveRouterError(w http.ResponseWriter, req *http.Request, err error) {
+	r.errorShaping().serve(w, req, StageRouting, err, r.errorHandler(), r.requestErrorHandler)
+}
+
+// routeErrorHandler handles errors raised around a route's handler. Function
+// routes handle them like their own errors, in stage, so they reach the
+// route's error pages and logging
+func (r *Router) routeErrorHandler(handler http.Handler, stage Stage) func(w http.ResponseWriter, req *http.Request, err error) {
+	if h, ok := handler.(*Handler); ok {
+		return func(w http.ResponseWriter, req *http.Request, err error) {
+			h.handleError(w, req, stage, err)
+		}
+	}
+
+	return r.serveRouterError
+}
+
+// errorShaping shapes the router's own error responses
+func (r *Router) errorShaping() errorShaping {
+	return errorShaping{compression: r.compression, negotiation: r.negotiation}
+}
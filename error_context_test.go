@@ -0,0 +1,97 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	var seen []ErrorContext
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		EnableRequestIDs,
+		EnableStrictRequests,
+		WithTracing(&recordingTracer{}),
+		WithRequestErrorHandler(func(w http.ResponseWriter, ec ErrorContext, err error) {
+			seen = append(seen, ec)
+
+			w.WriteHeader(statusCodeForError(err))
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":    err.Error(),
+				"route":    ec.Route,
+				"trace_id": ec.TraceParent.String(),
+			})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/users/{id}", func(ctx context.Context, in struct{}) error {
+		return errors.New("boom")
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	cases := []struct {
+		Name        string
+		Body        string
+		Header      map[string]string
+		ExpectStage Stage
+		ExpectRoute string
+		ExpectCode  int
+	}{
+		{"handler", `{}`, map[string]string{"Content-Type": "application/json", TraceParentHeader: traceparent}, StageHandler, "/users/{id}", http.StatusInternalServerError},
+		{"decode", `[]`, map[string]string{"Content-Type": "application/json"}, StageDecode, "/users/{id}", http.StatusBadRequest},
+		{"routing", `{}`, map[string]string{"Content-Type": "application/json", "Content-Length": "2", "Transfer-Encoding": "chunked"}, StageRouting, "", http.StatusBadRequest},
+	}
+
+	for i, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader(c.Body))
+		for k, v := range c.Header {
+			req.Header.Set(k, v)
+		}
+		if c.Header["Transfer-Encoding"] != "" {
+			req.TransferEncoding = []string{"chunked"}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if len(seen) != i+1 {
+			t.Fatalf("case[%s] expected the request error handler to be called", c.Name)
+		}
+
+		ec := seen[i]
+		if ec.Stage != c.ExpectStage || ec.Route != c.ExpectRoute || ec.Request == nil || ec.RequestID == "" || ec.Start.IsZero() {
+			t.Errorf("case[%s] unexpected error context %+v", c.Name, ec)
+		}
+
+		if c.Header[TraceParentHeader] != "" && (!ec.HasTraceParent || !strings.Contains(w.Body.String(), traceparent)) {
+			t.Errorf("case[%s] expected the trace parent in %s", c.Name, w.Body.String())
+		}
+	}
+
+	_, err = NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithDefaultErrorHandler(DefaultErrorHandler),
+		WithRequestErrorHandler(func(w http.ResponseWriter, ec ErrorContext, err error) {}),
+	)
+	if err == nil {
+		t.Error("expected both error handlers to conflict")
+	}
+}
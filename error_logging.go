@@ -0,0 +1,133 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+// requestMeta is what the router learns about a request as it's served, kept
+// behind a pointer so middleware can fill in the principal without replacing
+// the request
+type requestMeta struct {
+	start   time.Time
+	pattern string
+	tags    []string
+
+	mu        sync.Mutex
+	principal string
+}
+
+type requestMetaCtxKey struct{}
+
+func withRequestMeta(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestMetaCtxKey{}, &requestMeta{start: time.Now()}))
+}
+
+func requestMetaFromContext(ctx context.Context) *requestMeta {
+	rm, _ := ctx.Value(requestMetaCtxKey{}).(*requestMeta)
+	return rm
+}
+
+// SetPrincipal records who is making the request, for error logs. It is meant
+// for authentication middleware and does nothing outside a Router
+func SetPrincipal(ctx context.Context, principal string) {
+	if rm := requestMetaFromContext(ctx); rm != nil {
+		rm.mu.Lock()
+		rm.principal = principal
+		rm.mu.Unlock()
+	}
+}
+
+// PrincipalFromContext returns the principal set by SetPrincipal, or ""
+func PrincipalFromContext(ctx context.Context) string {
+	rm := requestMetaFromContext(ctx)
+	if rm == nil {
+		return ""
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.principal
+}
+
+// RoutePattern returns the pattern of the route serving the request, such as
+// "/users/{id}", or "" outside a Router
+func RoutePattern(ctx context.Context) string {
+	if rm := requestMetaFromContext(ctx); rm != nil {
+		return rm.pattern
+	}
+
+	return ""
+}
+
+// errorLogging logs server errors with what's known about their request
+type errorLogging struct {
+	level LogLevel
+}
+
+// WithErrorLogLevel sets the level server errors are logged at, LogError by default
+func WithErrorLogLevel(level LogLevel) func(r *Router) error {
+	return func(r *Router) error {
+		r.errorLogging = &errorLogging{level: level}
+		return nil
+	}
+}
+
+// DisableErrorLogging stops the router logging server errors, for ErrorHandlers
+// that already do
+func DisableErrorLogging(r *Router) error {
+	r.errorLogging = nil
+	return nil
+}
+
+// log writes err along with the request's method, route pattern, request ID,
+// principal, duration and the stage that failed
+func (el *errorLogging) log(log lounge.Log, r *http.Request, code int, stage Stage, err error) {
+	var b strings.Builder
+	b.WriteString("method=")
+	b.WriteString(r.Method)
+
+	pattern := r.URL.Path
+	var duration time.Duration
+	var principal string
+	if rm := requestMetaFromContext(r.Context()); rm != nil {
+		if rm.pattern != "" {
+			pattern = rm.pattern
+		}
+		duration = time.Since(rm.start)
+		principal = PrincipalFromContext(r.Context())
+	}
+
+	b.WriteString(" route=")
+	b.WriteString(pattern)
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		b.WriteString(" request_id=")
+		b.WriteString(id)
+	}
+	if principal != "" {
+		b.WriteString(" principal=")
+		b.WriteString(principal)
+	}
+	if duration > 0 {
+		b.WriteString(" duration=")
+		b.WriteString(duration.String())
+	}
+	if stage != "" {
+		b.WriteString(" stage=")
+		b.WriteString(string(stage))
+	}
+
+	switch el.level {
+	case LogDebug:
+		log.Debugf("%s status=%d error=%q", b.String(), code, err.Error())
+	case LogInfo:
+		log.Infof("%s status=%d error=%q", b.String(), code, err.Error())
+	default:
+		log.Errorf("%s status=%d error=%q", b.String(), code, err.Error())
+	}
+}
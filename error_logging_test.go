@@ -0,0 +1,63 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestErrorLogging(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		Options   []RouterOption
+		Err       error
+		ExpectLog []string
+	}{
+		{"server-error", []RouterOption{EnableRequestIDs}, errors.New("db down"), []string{
+			"ERROR", "method=POST", "route=/users/{id}", "request_id=abc-123", "principal=ada", "duration=", "stage=handler", "status=500", `error="db down"`,
+		}},
+		{"info-level", []RouterOption{WithErrorLogLevel(LogInfo)}, errors.New("db down"), []string{"INFO", "route=/users/{id}", "principal=ada"}},
+		{"client-error", nil, ErrorWithCode{Err: errors.New("bad id"), StatusCode: http.StatusBadRequest}, nil},
+		{"disabled", []RouterOption{DisableErrorLogging}, errors.New("db down"), nil},
+	}
+
+	for _, c := range cases {
+		var logs syncBuffer
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)), c.Options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fail := c.Err
+		err = r.Register(http.MethodPost, "/users/{id}", func(ctx context.Context) error {
+			return fail
+		}, []Middleware{NewBasicAuthMiddleware("ada", "hunter2")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/users/42", nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(RequestIDHeader, "abc-123")
+		req.SetBasicAuth("ada", "hunter2")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		logged := logs.String()
+		if c.ExpectLog == nil && logged != "" {
+			t.Errorf("case[%s] expected nothing logged, got %q", c.Name, logged)
+		}
+
+		for _, want := range c.ExpectLog {
+			if !strings.Contains(logged, want) {
+				t.Errorf("case[%s] expected %q in %q", c.Name, want, logged)
+			}
+		}
+	}
+}
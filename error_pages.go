@@ -0,0 +1,147 @@
+package autohttp
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithErrorPages serves HTML error pages from dir within the assets, such as
+// errors/404.html, to clients that accept text/html. Pages can be localized
+// as errors/404.de.html or errors/404.de-at.html and are picked by the
+// request's Accept-Language, trying "de-AT" as de-at then de, then each of
+// fallbackLocales, then the unlocalized page. A class page such as 5xx.html
+// covers statuses without a page of their own. Locales in file names are
+// lowercase
+func WithErrorPages(dir string, fallbackLocales ...string) AssetOption {
+	return func(ea *embeddedAssets) error {
+		stat, err := fs.Stat(ea.staticDir, dir)
+		if err != nil || !stat.IsDir() {
+			return fmt.Errorf("autohttp: error pages directory %q not found in assets", dir)
+		}
+
+		fallbacks := make([]string, len(fallbackLocales))
+		for i, locale := range fallbackLocales {
+			fallbacks[i] = strings.ToLower(locale)
+		}
+
+		ea.errorPages = &errorPages{fs: ea.staticDir, dir: dir, fallbacks: fallbacks}
+		return nil
+	}
+}
+
+type errorPages struct {
+	fs        fs.FS
+	dir       string
+	fallbacks []string
+}
+
+// serve writes the error page for code, reporting false if the client doesn't
+// accept HTML or there is no page for code
+func (ep *errorPages) serve(w http.ResponseWriter, r *http.Request, code int) bool {
+	if !acceptsHTML(r) {
+		return false
+	}
+
+	b, locale, ok := ep.page(code, requestLocales(r))
+	if !ok {
+		return false
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if locale != "" {
+		w.Header().Set("Content-Language", locale)
+	}
+	w.WriteHeader(code)
+	w.Write(b)
+
+	return true
+}
+
+// page finds the most preferred page for code along the locale chain, the
+// last resort being the unlocalized page
+func (ep *errorPages) page(code int, locales []string) ([]byte, string, bool) {
+	names := []string{strconv.Itoa(code), strconv.Itoa(code/100) + "xx"}
+
+	chain := append(locales, ep.fallbacks...)
+	chain = append(chain, "")
+
+	for _, locale := range chain {
+		for _, name := range names {
+			file := name + ".html"
+			if locale != "" {
+				file = name + "." + locale + ".html"
+			}
+
+			b, err := fs.ReadFile(ep.fs, path.Join(ep.dir, file))
+			if err == nil {
+				return b, locale, true
+			}
+		}
+	}
+
+	return nil, "", false
+}
+
+// requestLocales lists the locales of the Accept-Language header by
+// preference, each followed by its less specific forms
+func requestLocales(r *http.Request) []string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return nil
+	}
+
+	type accepted struct {
+		locale string
+		q      float64
+	}
+
+	var ranges []accepted
+	for _, part := range strings.Split(header, ",") {
+		locale, q := parseQualityValue(part)
+		if locale != "" && locale != "*" && q > 0 {
+			ranges = append(ranges, accepted{locale, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	seen := make(map[string]bool)
+	var locales []string
+	for _, ar := range ranges {
+		locale := ar.locale
+		for locale != "" {
+			if !seen[locale] {
+				seen[locale] = true
+				locales = append(locales, locale)
+			}
+
+			i := strings.LastIndexByte(locale, '-')
+			if i < 0 {
+				break
+			}
+			locale = locale[:i]
+		}
+	}
+
+	return locales
+}
+
+// acceptsHTML reports whether the client explicitly asks for HTML, as
+// browsers do, rather than accepting anything
+func acceptsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, q := parseQualityValue(part)
+		if q > 0 && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+			return true
+		}
+	}
+
+	return false
+}
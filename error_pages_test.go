@@ -0,0 +1,88 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestErrorPages(t *testing.T) {
+	t.Parallel()
+
+	assets := fstest.MapFS{
+		"dist/index.html":            {Data: []byte(`spa`)},
+		"dist/errors/404.html":       {Data: []byte(`not found`)},
+		"dist/errors/404.de.html":    {Data: []byte(`nicht gefunden`)},
+		"dist/errors/404.de-at.html": {Data: []byte(`ned gfundn`)},
+		"dist/errors/404.fr.html":    {Data: []byte(`introuvable`)},
+		"dist/errors/5xx.html":       {Data: []byte(`server error`)},
+		"dist/errors/5xx.de.html":    {Data: []byte(`serverfehler`)},
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEmbeddedAssets(assets, "dist", WithNotFoundBehavior(AssetNotFoundStrict), WithErrorPages("errors", "fr")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/fail", func() error {
+		return ErrorWithCode{Err: errors.New("unavailable"), StatusCode: http.StatusServiceUnavailable}
+	}, nil, WithDecoder(NoOpDecoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const html = "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8"
+	cases := []struct {
+		Name           string
+		Path           string
+		Accept         string
+		AcceptLanguage string
+		ExpectCode     int
+		ExpectBody     string
+		ExpectLanguage string
+	}{
+		{"exact-locale", "/missing", html, "de-AT", http.StatusNotFound, "ned gfundn", "de-at"},
+		{"region-fallback", "/missing", html, "de-CH,en;q=0.5", http.StatusNotFound, "nicht gefunden", "de"},
+		{"preference-order", "/missing", html, "es, de;q=0.4, fr;q=0.8", http.StatusNotFound, "introuvable", "fr"},
+		{"configured-fallback", "/missing", html, "ja", http.StatusNotFound, "introuvable", "fr"},
+		{"no-language", "/missing", html, "", http.StatusNotFound, "introuvable", "fr"},
+		{"class-page", "/fail", html, "de-DE", http.StatusServiceUnavailable, "serverfehler", "de"},
+		{"class-unlocalized", "/fail", html, "it", http.StatusServiceUnavailable, "server error", ""},
+		{"not-html", "/fail", "application/json", "de", http.StatusServiceUnavailable, `{"error":"unavailable"}` + "\n", ""},
+		{"not-html-asset", "/missing", "*/*", "de", http.StatusNotFound, "", ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.Path, nil)
+		req.Header.Set("Accept", c.Accept)
+		if c.AcceptLanguage != "" {
+			req.Header.Set("Accept-Language", c.AcceptLanguage)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if w.Body.String() != c.ExpectBody {
+			t.Errorf("case[%s] expected body %q got %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		if got := w.Header().Get("Content-Language"); got != c.ExpectLanguage {
+			t.Errorf("case[%s] expected Content-Language %q got %q", c.Name, c.ExpectLanguage, got)
+		}
+	}
+
+	_, err = NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEmbeddedAssets(assets, "dist", WithErrorPages("pages")))
+	if err == nil {
+		t.Error("expected a missing error pages directory to fail")
+	}
+}
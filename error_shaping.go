@@ -0,0 +1,111 @@
+package autohttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// errorShaping gives error responses the shaping handler responses get, so
+// errors raised by the router and by handlers alike are negotiated, see
+// WithEncoderFor, and compressed, see WithCompression
+type errorShaping struct {
+	compression *compression
+	negotiation *negotiation
+}
+
+// jsonErrors stands in for DefaultErrorHandler when negotiating error bodies
+var jsonErrors Encoder = &JSONEncoder{}
+
+// serve writes err like serveError, shaped
+func (es errorShaping) serve(w http.ResponseWriter, req *http.Request, stage Stage, err error, eh ErrorHandler, reh RequestErrorHandler) {
+	if es.negotiation != nil && reh == nil && isDefaultErrorHandler(eh) {
+		w.Header().Add("Vary", "Accept")
+
+		// clients accepting JSON, or nothing the router produces, get the
+		// usual JSON body rather than an error about their error
+		encoder, nerr := es.negotiation.encoderFor(req, jsonErrors)
+		if nerr == nil && encoderMediaType(encoder) != encoderMediaType(jsonErrors) {
+			eh = encodedErrorHandler(encoder)
+		}
+	}
+
+	if es.compression == nil {
+		serveError(w, req, stage, err, eh, reh)
+		return
+	}
+
+	bw := &bufferedErrorWriter{w: w}
+	serveError(bw, req, stage, err, eh, reh)
+	bw.flush(req, es.compression)
+}
+
+func isDefaultErrorHandler(eh ErrorHandler) bool {
+	return eh != nil && reflect.ValueOf(eh).Pointer() == reflect.ValueOf(DefaultErrorHandler).Pointer()
+}
+
+// encodedErrorHandler writes an ErrorResponse with encoder, falling back to
+// DefaultErrorHandler if it can't be encoded
+func encodedErrorHandler(encoder Encoder) ErrorHandler {
+	return func(w http.ResponseWriter, err error) {
+		code, body := NewErrorResponse(w, err)
+
+		_, encoded, encErr := encoder.Encode(body, w.Header().Set)
+		if encErr != nil {
+			w.Header().Del("Content-Type")
+			DefaultErrorHandler(w, err)
+			return
+		}
+
+		w.WriteHeader(code)
+		if encoded != nil {
+			io.Copy(w, encoded)
+		}
+	}
+}
+
+// bufferedErrorWriter holds an error response back so its body can be
+// compressed before it is written
+type bufferedErrorWriter struct {
+	w    http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (bw *bufferedErrorWriter) Header() http.Header {
+	return bw.w.Header()
+}
+
+func (bw *bufferedErrorWriter) WriteHeader(code int) {
+	if bw.code == 0 {
+		bw.code = code
+	}
+}
+
+func (bw *bufferedErrorWriter) Write(b []byte) (int, error) {
+	if bw.code == 0 {
+		bw.code = http.StatusOK
+	}
+
+	return bw.body.Write(b)
+}
+
+// flush writes the held back response, compressed if the client accepts it
+func (bw *bufferedErrorWriter) flush(req *http.Request, c *compression) {
+	if bw.code == 0 {
+		// nothing was written, leave that to whoever comes next
+		return
+	}
+
+	var body io.Reader = &bw.body
+	if bw.body.Len() > 0 {
+		compressed, err := c.apply(req, bw.w.Header(), bytes.NewReader(bw.body.Bytes()))
+		if err == nil {
+			body = compressed
+		}
+	}
+
+	bw.w.WriteHeader(bw.code)
+	io.Copy(bw.w, body)
+}
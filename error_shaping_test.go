@@ -0,0 +1,114 @@
+package autohttp
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestErrorShaping(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		EnableHSTS,
+		WithCompression(GzipCompressor{}),
+		WithEncoderFor("application/xml", &XMLEncoder{}),
+		WithMethodNotAllowedHandler(func(w http.ResponseWriter, req *http.Request, allowed []string) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte("try " + strings.Join(allowed, " or ")))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/warming", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil,
+		WithWarmup(func() bool { return false }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/broken", func() (*typedPet, error) {
+		return nil, errors.New("broken")
+	}, nil, WithDecoder(NoOpDecoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name           string
+		method         string
+		path           string
+		accept         string
+		acceptEncoding string
+
+		code        int
+		contentType string
+		encoding    string
+		body        string
+	}{
+		{name: "router error", method: http.MethodGet, path: "/warming", code: http.StatusServiceUnavailable, contentType: "", body: `"error":"autohttp: warming up`},
+		{name: "router error negotiated", method: http.MethodGet, path: "/warming", accept: "application/xml", code: http.StatusServiceUnavailable, contentType: "application/xml", body: "<error>autohttp: warming up"},
+		{name: "router error compressed", method: http.MethodGet, path: "/warming", acceptEncoding: "gzip", code: http.StatusServiceUnavailable, encoding: "gzip", body: `"error":"autohttp: warming up`},
+		{name: "handler error negotiated and compressed", method: http.MethodGet, path: "/broken", accept: "application/xml", acceptEncoding: "gzip", code: http.StatusInternalServerError, contentType: "application/xml", encoding: "gzip", body: "<error>broken</error>"},
+		{name: "unacceptable falls back to JSON", method: http.MethodGet, path: "/broken", accept: "image/png", code: http.StatusNotAcceptable, body: `"error":"autohttp: no acceptable media type"`},
+		{name: "method not allowed compressed", method: http.MethodPost, path: "/warming", acceptEncoding: "gzip", code: http.StatusMethodNotAllowed, encoding: "gzip", body: "try GET or HEAD"},
+		{name: "not found", method: http.MethodGet, path: "/nope", code: http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept", c.accept)
+		}
+		if c.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", c.acceptEncoding)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if w.Header().Get("Strict-Transport-Security") == "" {
+			t.Errorf("case[%s] expected an HSTS header", c.name)
+		}
+
+		if ct := w.Header().Get("Content-Type"); c.contentType != "" && !strings.HasPrefix(ct, c.contentType) {
+			t.Errorf("case[%s] expected Content-Type %q, got %q", c.name, c.contentType, ct)
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != c.encoding {
+			t.Errorf("case[%s] expected Content-Encoding %q, got %q", c.name, c.encoding, got)
+		}
+
+		var body io.Reader = w.Body
+		if c.encoding == "gzip" {
+			body, err = gzip.NewReader(w.Body)
+			if err != nil {
+				t.Errorf("case[%s] %s", c.name, err)
+				continue
+			}
+		}
+
+		b, err := io.ReadAll(body)
+		if err != nil {
+			t.Errorf("case[%s] %s", c.name, err)
+			continue
+		}
+
+		if !strings.Contains(string(b), c.body) {
+			t.Errorf("case[%s] expected body containing %q, got %q", c.name, c.body, b)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package autohttp
+
+import "errors"
+
+// A Stage is the part of the request pipeline an error came from
+type Stage string
+
+const (
+	StageMiddleware Stage = "middleware"
+	StageDecode     Stage = "decode"
+	StageTransform  Stage = "transform"
+	StageHandler    Stage = "handler"
+	StageEncode     Stage = "encode"
+)
+
+// A StageError overrides the Stage an error is reported in, such as a
+// middleware returning a decode failure. Errors reach ErrorHandlers as they
+// were returned, the stage is reported in ErrorContext and error logs. Error
+// returns the wrapped message unchanged, so clients see the same error bodies
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (se StageError) Error() string {
+	return se.Err.Error()
+}
+
+func (se StageError) Unwrap() error {
+	return se.Err
+}
+
+// errorStage returns the stage err is reported in, stage unless err carries
+// its own
+func errorStage(stage Stage, err error) Stage {
+	if se, ok := ErrorStage(err); ok {
+		return se
+	}
+
+	return stage
+}
+
+// ErrorStage returns the Stage carried by a StageError in err
+func ErrorStage(err error) (Stage, bool) {
+	var se StageError
+	if errors.As(err, &se) {
+		return se.Stage, true
+	}
+
+	return "", false
+}
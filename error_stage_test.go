@@ -0,0 +1,148 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestErrorStages(t *testing.T) {
+	t.Parallel()
+
+	var gotStage Stage
+	var gotErr error
+	reh := func(w http.ResponseWriter, ec ErrorContext, err error) {
+		gotStage = ec.Stage
+		gotErr = err
+		DefaultErrorHandler(w, err)
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithRequestErrorHandler(reh))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errBoom := errors.New("boom")
+	denied := MiddlewareError{StatusCode: http.StatusForbidden, Err: errors.New("denied")}
+	fn := func(ctx context.Context, in struct{ Name string }) (map[string]string, error) {
+		if in.Name == "fail" {
+			return nil, errBoom
+		}
+		return map[string]string{"name": in.Name}, nil
+	}
+
+	routes := []struct {
+		path string
+		fn   interface{}
+		mws  []Middleware
+		opts []RouteOption
+	}{
+		{"/handler", fn, nil, nil},
+		{"/middleware", fn, []Middleware{beforeFunc(func(r *http.Request) error { return denied })}, nil},
+		// middleware errors that already carry a stage keep it
+		{"/restaged", fn, []Middleware{beforeFunc(func(r *http.Request) error { return StageError{Stage: StageDecode, Err: errBoom} })}, nil},
+		{"/transform", fn, nil, []RouteOption{WithRequestTransform(func(r *http.Request) error { return errBoom })}},
+		{"/encode", func(ctx context.Context) chan int { return make(chan int) }, nil, nil},
+	}
+	for _, rt := range routes {
+		err = r.Register(http.MethodPost, rt.path, rt.fn, rt.mws, rt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		Body        string
+		ExpectStage Stage
+		ExpectCode  int
+		ExpectErr   error
+	}{
+		{"middleware", "/middleware", `{}`, StageMiddleware, http.StatusForbidden, denied.Err},
+		{"restaged", "/restaged", `{}`, StageDecode, http.StatusInternalServerError, errBoom},
+		{"transform", "/transform", `{}`, StageTransform, http.StatusInternalServerError, errBoom},
+		{"decode", "/handler", `{"Name": nope}`, StageDecode, http.StatusBadRequest, nil},
+		{"handler", "/handler", `{"Name": "fail"}`, StageHandler, http.StatusInternalServerError, errBoom},
+		{"encode", "/encode", `{}`, StageEncode, http.StatusInternalServerError, nil},
+	}
+
+	for _, c := range cases {
+		gotStage, gotErr = "", nil
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if gotStage != c.ExpectStage {
+			t.Errorf("case[%s] expected stage %q got %q", c.Name, c.ExpectStage, gotStage)
+		}
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if c.ExpectErr != nil && !errors.Is(gotErr, c.ExpectErr) {
+			t.Errorf("case[%s] expected the original error, got %v", c.Name, gotErr)
+		}
+	}
+}
+
+func TestErrorHandlerGetsOriginalError(t *testing.T) {
+	t.Parallel()
+
+	errGone := errors.New("gone")
+	eh := func(w http.ResponseWriter, err error) {
+		if err == errGone {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		ewc := err.(ErrorWithCode)
+		w.WriteHeader(ewc.StatusCode)
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithDefaultErrorHandler(eh))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(ctx context.Context, in struct{ Name string }) (map[string]string, error) {
+		if in.Name == "gone" {
+			return nil, errGone
+		}
+		return nil, ErrorWithCode{Err: errors.New("teapot"), StatusCode: http.StatusTeapot}
+	}
+
+	err = r.Register(http.MethodPost, "/pets", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name       string
+		Body       string
+		ExpectCode int
+	}{
+		{"sentinel", `{"Name": "gone"}`, http.StatusGone},
+		{"type-assertion", `{"Name": "rex"}`, http.StatusTeapot},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+	}
+}
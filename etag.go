@@ -0,0 +1,65 @@
+package autohttp
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithWeakETag tags the route's successful GET and HEAD responses with a weak
+// ETag over the encoded body, answering matching If-None-Match requests with
+// a 304. It suits frequently polled server rendered pages, the page is still
+// rendered but not sent again while it hasn't changed
+func WithWeakETag() RouteOption {
+	return func(rc *routeConfig) error {
+		rc.weakETag = true
+		return nil
+	}
+}
+
+// weakETag hashes body into a weak ETag
+func weakETag(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`W/"%016x"`, h.Sum64())
+}
+
+// etagMatches weakly compares etag against an If-None-Match header
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyWeakETag sets the ETag for body, reporting true if the client already
+// has it. The returned reader replaces body, which has been read
+func applyWeakETag(r *http.Request, header http.Header, body io.Reader) (io.Reader, bool, error) {
+	var b []byte
+	if buf, ok := body.(*bytes.Buffer); ok {
+		b = buf.Bytes()
+	} else {
+		var err error
+		b, err = io.ReadAll(body)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	etag := weakETag(b)
+	header.Set("ETag", etag)
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		return nil, true, nil
+	}
+
+	return bytes.NewReader(b), false, nil
+}
@@ -0,0 +1,77 @@
+package autohttp
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestWeakETag(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := template.Must(template.New("status").Parse(`<p>{{ .Jobs }} jobs queued</p>`))
+	jobs := 3
+	err = r.Register(http.MethodGet, "/status", func() map[string]int { return map[string]int{"Jobs": jobs} }, nil,
+		WithDecoder(NoOpDecoder{}), WithEncoder(NewHTMLEncoder(page, "")), WithWeakETag())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != "<p>3 jobs queued</p>" {
+		t.Fatalf("expected the page, got %d %q", first.Code, first.Body.String())
+	}
+	if len(etag) < 3 || etag[:2] != "W/" {
+		t.Fatalf("expected a weak etag got %q", etag)
+	}
+
+	cases := []struct {
+		Name        string
+		IfNoneMatch string
+		Jobs        int
+		ExpectCode  int
+	}{
+		{"match", etag, 3, http.StatusNotModified},
+		{"strong-form", etag[2:], 3, http.StatusNotModified},
+		{"list", `"other", ` + etag, 3, http.StatusNotModified},
+		{"stale", `W/"0000000000000000"`, 3, http.StatusOK},
+		{"changed", etag, 4, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		jobs = c.Jobs
+		w := get(c.IfNoneMatch)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if c.ExpectCode == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("case[%s] expected no body got %q", c.Name, w.Body.String())
+		}
+
+		if w.Header().Get("ETag") == "" {
+			t.Errorf("case[%s] expected an etag", c.Name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// ScopeTag restricts a response field to requests granted one of the listed
+// scopes, e.g. `scope:"admin,support"`. Fields without it are always visible
+const ScopeTag = "scope"
+
+// A ScopeFunc returns the scopes, such as roles, a request is granted
+type ScopeFunc func(r *http.Request) []string
+
+// FilterFieldsByScope returns a ResponseTransformer that zeroes every field of
+// the response the request isn't scoped to see, through nested structs,
+// pointers, slices and maps, so one handler can serve both the admin and
+// public views of a resource. Tag restricted fields omitempty to leave them
+// out of the encoded response entirely. The handler's value is copied, never
+// modified
+func FilterFieldsByScope(scopes ScopeFunc) ResponseTransformer {
+	return func(r *http.Request, value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+
+		v := reflect.ValueOf(value)
+		if !hasScopedFields(v.Type()) {
+			return value, nil
+		}
+
+		granted := make(map[string]bool)
+		for _, s := range scopes(r) {
+			granted[s] = true
+		}
+
+		return pruneScoped(v, granted).Interface(), nil
+	}
+}
+
+// WithFieldScopes filters the responses of every function route registered
+// after it with FilterFieldsByScope, after any of the route's own response
+// transforms. Routes whose responses have no scoped fields are left alone.
+// A route combining scoped fields with WithResponseCache must key its cache
+// on the request's scopes with WithCacheKey, so views aren't served across
+// scopes
+func WithFieldScopes(scopes ScopeFunc) func(r *Router) error {
+	return func(r *Router) error {
+		if scopes == nil {
+			return errors.New("autohttp: WithFieldScopes needs a ScopeFunc")
+		}
+
+		r.fieldScopes = scopes
+		return nil
+	}
+}
+
+// scopedResponse reports whether any return value of fn has scoped fields
+func scopedResponse(fn interface{}) bool {
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return false
+	}
+
+	for i := 0; i < fnType.NumOut(); i++ {
+		if !isErrorType(fnType.Out(i)) && hasScopedFields(fnType.Out(i)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var scopedTypes sync.Map
+
+// hasScopedFields reports whether values of t may hold scoped fields.
+// Interfaces might hold anything, so they always may
+func hasScopedFields(t reflect.Type) bool {
+	if scoped, ok := scopedTypes.Load(t); ok {
+		return scoped.(bool)
+	}
+
+	scoped := findScopedFields(t, map[reflect.Type]bool{})
+	scopedTypes.Store(t, scoped)
+	return scoped
+}
+
+func findScopedFields(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Interface {
+		return true
+	}
+
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		if _, ok := f.Tag.Lookup(ScopeTag); ok || findScopedFields(f.Type, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pruneScoped copies v with the fields granted doesn't cover zeroed
+func pruneScoped(v reflect.Value, granted map[string]bool) reflect.Value {
+	t := v.Type()
+	if !hasScopedFields(t) {
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.New(t.Elem())
+		out.Elem().Set(pruneScoped(v.Elem(), granted))
+		return out
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.New(t).Elem()
+		out.Set(pruneScoped(v.Elem(), granted))
+		return out
+
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			field := out.Field(i)
+			if !field.CanSet() {
+				if !f.Anonymous {
+					continue
+				}
+
+				// encoding/json promotes the exported fields of unexported
+				// embedded structs, so they're filtered like any other
+				field = reflect.NewAt(f.Type, unsafe.Pointer(field.UnsafeAddr())).Elem()
+			}
+
+			if scopes, ok := f.Tag.Lookup(ScopeTag); ok && !scopeGranted(scopes, granted) {
+				field.Set(reflect.Zero(f.Type))
+				continue
+			}
+
+			field.Set(pruneScoped(field, granted))
+		}
+		return out
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(pruneScoped(v.Index(i), granted))
+		}
+		return out
+
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(pruneScoped(v.Index(i), granted))
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), pruneScoped(iter.Value(), granted))
+		}
+		return out
+	}
+
+	return v
+}
+
+// scopeGranted reports whether granted covers any of the comma separated scopes
+func scopeGranted(scopes string, granted map[string]bool) bool {
+	for _, scope := range strings.Split(scopes, ",") {
+		if granted[strings.TrimSpace(scope)] {
+			return true
+		}
+	}
+
+	return false
+}
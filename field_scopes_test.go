@@ -0,0 +1,167 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+type scopedNote struct {
+	Text     string `json:"text"`
+	Internal string `json:"internal,omitempty" scope:"admin"`
+}
+
+type scopedAccount struct {
+	ID      string       `json:"id"`
+	Email   string       `json:"email,omitempty" scope:"admin,support"`
+	Balance int          `json:"balance,omitempty" scope:"admin"`
+	Notes   []scopedNote `json:"notes"`
+}
+
+func TestFieldScopes(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithFieldScopes(func(r *http.Request) []string {
+		return strings.Split(r.Header.Get("X-Roles"), ",")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := &scopedAccount{
+		ID:      "acct-1",
+		Email:   "a@b.c",
+		Balance: 12,
+		Notes:   []scopedNote{{Text: "hello", Internal: "flagged"}},
+	}
+	err = r.Register(http.MethodPost, "/account", func(ctx context.Context) (*scopedAccount, error) {
+		return account, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name   string
+		Roles  string
+		Expect string
+	}{
+		{"public", "", `{"id":"acct-1","notes":[{"text":"hello"}]}`},
+		{"support", "support", `{"id":"acct-1","email":"a@b.c","notes":[{"text":"hello"}]}`},
+		{"admin", "viewer,admin", `{"id":"acct-1","email":"a@b.c","balance":12,"notes":[{"text":"hello","internal":"flagged"}]}`},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/account", nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Roles", c.Roles)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s] expected 200 got %d: %s", c.Name, w.Code, w.Body.String())
+			continue
+		}
+
+		if strings.TrimSpace(w.Body.String()) != c.Expect {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.Expect, w.Body.String())
+		}
+	}
+
+	if account.Email != "a@b.c" || account.Notes[0].Internal != "flagged" {
+		t.Errorf("expected the handler's value to be left alone got %+v", account)
+	}
+
+	err = r.Register(http.MethodPost, "/cached", func(ctx context.Context) (scopedAccount, error) {
+		return *account, nil
+	}, nil, WithResponseCache(time.Minute))
+	if err == nil {
+		t.Error("expected caching scoped responses under the default key to fail registration")
+	}
+}
+
+func TestFilterFieldsByScopeInterfaces(t *testing.T) {
+	t.Parallel()
+
+	filter := FilterFieldsByScope(func(r *http.Request) []string { return nil })
+	value := map[string]interface{}{
+		"account": scopedAccount{ID: "acct-2", Balance: 5},
+		"count":   1,
+	}
+
+	got, err := filter(httptest.NewRequest(http.MethodGet, "/", nil), value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered := got.(map[string]interface{})
+	if acct := filtered["account"].(scopedAccount); acct.Balance != 0 || acct.ID != "acct-2" {
+		t.Errorf("expected the balance to be pruned from %+v", acct)
+	}
+
+	if filtered["count"] != 1 || value["account"].(scopedAccount).Balance != 5 {
+		t.Errorf("expected unscoped values to survive and the original to be left alone got %+v", filtered)
+	}
+}
+
+type scopedSecret struct {
+	SSN string `json:"ssn,omitempty" scope:"admin"`
+}
+
+func TestFieldScopesEmbeddedUnexported(t *testing.T) {
+	t.Parallel()
+
+	type embedded struct {
+		scopedSecret
+		Name string `json:"name"`
+	}
+
+	type embeddedPointer struct {
+		*scopedSecret
+		Name string `json:"name"`
+	}
+
+	secret := &scopedSecret{SSN: "123-45"}
+	cases := []struct {
+		Name   string
+		Value  interface{}
+		Roles  string
+		Expect string
+	}{
+		{"embedded public", embedded{scopedSecret: *secret, Name: "ada"}, "", `{"name":"ada"}`},
+		{"embedded admin", embedded{scopedSecret: *secret, Name: "ada"}, "admin", `{"ssn":"123-45","name":"ada"}`},
+		{"embedded pointer public", embeddedPointer{scopedSecret: secret, Name: "ada"}, "", `{"name":"ada"}`},
+		{"embedded pointer admin", &embeddedPointer{scopedSecret: secret, Name: "ada"}, "admin", `{"ssn":"123-45","name":"ada"}`},
+		{"embedded nil pointer", embeddedPointer{Name: "ada"}, "", `{"name":"ada"}`},
+	}
+
+	for _, c := range cases {
+		filter := FilterFieldsByScope(func(r *http.Request) []string { return strings.Split(c.Roles, ",") })
+		got, err := filter(httptest.NewRequest(http.MethodGet, "/", nil), c.Value)
+		if err != nil {
+			t.Errorf("case[%s] %s", c.Name, err)
+			continue
+		}
+
+		b, err := json.Marshal(got)
+		if err != nil {
+			t.Errorf("case[%s] %s", c.Name, err)
+			continue
+		}
+
+		if string(b) != c.Expect {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.Expect, b)
+		}
+	}
+
+	if secret.SSN != "123-45" {
+		t.Errorf("expected the handler's value to be left alone got %+v", secret)
+	}
+}
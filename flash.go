@@ -0,0 +1,106 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// flashSessionKey is the session value pending flashes are kept in
+const flashSessionKey = "_flash"
+
+// A Flash is a one-off message for the next page a client sees, such as
+// "Pet saved" after a form post
+type Flash struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// AddFlash queues a message in the session, to be read with Flashes on the
+// next request. It returns ErrNoSession without WithSessions
+func AddFlash(ctx context.Context, kind, message string) error {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return ErrNoSession
+	}
+
+	flashes := decodeFlashes(session.Get(flashSessionKey))
+	b, err := json.Marshal(append(flashes, Flash{Kind: kind, Message: message}))
+	if err != nil {
+		return err
+	}
+
+	session.Set(flashSessionKey, string(b))
+	return nil
+}
+
+// Flashes returns the queued messages and removes them from the session, so
+// each is only shown once
+func Flashes(ctx context.Context) []Flash {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return nil
+	}
+
+	flashes := decodeFlashes(session.Get(flashSessionKey))
+	session.Delete(flashSessionKey)
+	return flashes
+}
+
+func decodeFlashes(value string) []Flash {
+	if value == "" {
+		return nil
+	}
+
+	var flashes []Flash
+	err := json.Unmarshal([]byte(value), &flashes)
+	if err != nil {
+		return nil
+	}
+
+	return flashes
+}
+
+// Redirect is returned by a handler to redirect the client instead of
+// encoding a body. Status defaults to 303 See Other, so a form post is
+// followed by a GET
+type Redirect struct {
+	URL    string
+	Status int
+}
+
+// RedirectWithFlash queues a flash message and redirects to url, for
+// post/redirect/get flows:
+//
+//	func(ctx context.Context, pet Pet) (autohttp.Redirect, error) {
+//		...
+//		return autohttp.RedirectWithFlash(ctx, "/pets", "success", "Pet saved")
+//	}
+func RedirectWithFlash(ctx context.Context, url, kind, message string) (Redirect, error) {
+	err := AddFlash(ctx, kind, message)
+	if err != nil {
+		return Redirect{}, err
+	}
+
+	return Redirect{URL: url}, nil
+}
+
+// HTTPRedirectWithFlash is RedirectWithFlash for http.Handlers
+func HTTPRedirectWithFlash(w http.ResponseWriter, r *http.Request, url, kind, message string) error {
+	rd, err := RedirectWithFlash(r.Context(), url, kind, message)
+	if err != nil {
+		return err
+	}
+
+	rd.serve(w, r)
+	return nil
+}
+
+func (rd Redirect) serve(w http.ResponseWriter, r *http.Request) {
+	status := rd.Status
+	if status == 0 {
+		status = http.StatusSeeOther
+	}
+
+	http.Redirect(w, r, rd.URL, status)
+}
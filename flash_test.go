@@ -0,0 +1,117 @@
+package autohttp
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRedirectWithFlash(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithSessions("s3cr3t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	save := func(ctx context.Context, in struct{ Name string }) (Redirect, error) {
+		return RedirectWithFlash(ctx, "/pets", "success", in.Name+" saved")
+	}
+
+	err = r.Register(http.MethodPost, "/pets", save, nil, WithDecoder(NewFormDecoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/raw", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		HTTPRedirectWithFlash(w, req, "/pets", "info", "raw")
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := template.Must(template.New("pets").Parse(`{{ range . }}[{{ .Kind }}: {{ .Message }}]{{ end }}`))
+	list := func(ctx context.Context) ([]Flash, error) {
+		return Flashes(ctx), nil
+	}
+
+	err = r.Register(http.MethodGet, "/pets", list, nil, WithDecoder(NewFormDecoder()), WithEncoder(NewHTMLEncoder(page, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path, body string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	get := func(cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/pets", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/pets", "Name=rex", nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/pets" {
+		t.Fatalf("expected a 303 to /pets, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = post("/raw", "", w.Result().Cookies())
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected a 303 from the http.Handler, got %d", w.Code)
+	}
+	cookies := w.Result().Cookies()
+
+	w = get(cookies)
+	if w.Body.String() != "[success: rex saved][info: raw]" {
+		t.Errorf("expected both flashes, got %q", w.Body.String())
+	}
+
+	deleted := w.Result().Cookies()
+	if len(deleted) != 1 || deleted[0].MaxAge >= 0 {
+		t.Errorf("expected the emptied session cookie to be deleted, got %v", deleted)
+	}
+
+	w = get(nil)
+	if w.Body.String() != "" {
+		t.Errorf("expected no flashes once read, got %q", w.Body.String())
+	}
+
+	tampered := *cookies[0]
+	tampered.Value = strings.Replace(tampered.Value, "a", "b", 1)
+	w = get([]*http.Cookie{&tampered})
+	if w.Body.String() != "" {
+		t.Errorf("expected a tampered session to be ignored, got %q", w.Body.String())
+	}
+}
+
+func TestFlashWithoutSessions(t *testing.T) {
+	t.Parallel()
+
+	_, err := RedirectWithFlash(context.Background(), "/", "info", "hi")
+	if err != ErrNoSession {
+		t.Errorf("expected ErrNoSession, got %v", err)
+	}
+
+	if flashes := Flashes(context.Background()); flashes != nil {
+		t.Errorf("expected no flashes, got %v", flashes)
+	}
+}
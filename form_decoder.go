@@ -0,0 +1,322 @@
+package autohttp
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormTag names the form field a struct field is read from, falling back to
+// the json tag and then the field name
+const FormTag = "form"
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// FormDecoder decodes application/x-www-form-urlencoded bodies, or the query
+// of GET requests, into a struct argument. Fields may be strings, bools,
+// numbers, times in RFC 3339, encoding.TextUnmarshalers, pointers to any of
+// them, or slices of them for repeated fields
+type FormDecoder struct {
+	MaxBytesToRead        int64
+	DisallowUnknownFields bool
+}
+
+func NewFormDecoder() *FormDecoder {
+	return &FormDecoder{
+		MaxBytesToRead: DefaultMaxBytesToRead,
+	}
+}
+
+func (fd *FormDecoder) isFormDecodable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+func (fd *FormDecoder) ValidateType(fn interface{}) error {
+	_, _, decodeIdx, err := bodyInputIndices(fn, fd.isFormDecodable)
+	if err != nil {
+		return err
+	}
+
+	if decodeIdx == uIdx {
+		return nil
+	}
+
+	t := reflect.TypeOf(fn).In(decodeIdx)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, f := range formFields(t) {
+		if !isFormValueType(f.Type) {
+			return fmt.Errorf("form decoder cannot decode field %s of type %s", f.Name, f.Type)
+		}
+	}
+
+	return checkValidationTags(t)
+}
+
+// Decode returns the reflect values needed to call the fn
+// from the *http.Request
+func (fd *FormDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	var values url.Values
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		values = r.URL.Query()
+	} else {
+		if !strings.Contains(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+		}
+
+		b, err := io.ReadAll(io.LimitReader(r.Body, fd.MaxBytesToRead+1))
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+		if int64(len(b)) > fd.MaxBytesToRead {
+			return nil, ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", fd.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+		}
+
+		values, err = url.ParseQuery(string(b))
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+	}
+
+	ctxIdx, hdrIdx, decodeIdx, err := bodyInputIndices(fn, fd.isFormDecodable)
+	if err != nil {
+		return nil, err
+	}
+
+	fnReflectType := reflect.ValueOf(fn).Type()
+	callValues := make([]reflect.Value, fnReflectType.NumIn())
+
+	if ctxIdx != uIdx {
+		callValues[ctxIdx] = reflect.ValueOf(r.Context())
+	}
+
+	if hdrIdx != uIdx {
+		callValues[hdrIdx] = reflect.ValueOf(requestHeader(r))
+	}
+
+	if decodeIdx != uIdx {
+		inArg := fnReflectType.In(decodeIdx)
+
+		var object reflect.Value
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			object = reflect.New(inArg.Elem())
+		default:
+			object = reflect.New(inArg)
+		}
+
+		err = fd.decodeValues(values, object.Elem())
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		err = bindRequest(r, object, boundFields(inArg))
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateValue(object)
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			callValues[decodeIdx] = object
+		default:
+			callValues[decodeIdx] = object.Elem()
+		}
+	}
+
+	return callValues, nil
+}
+
+func (fd *FormDecoder) decodeValues(values url.Values, v reflect.Value) error {
+	known := make(map[string]bool)
+	for _, f := range formFields(v.Type()) {
+		if isBoundField(f) {
+			// GET forms share the query with query bound fields
+			name, _, _ := strings.Cut(f.Tag.Get(QueryTag), ",")
+			known[name] = true
+			continue
+		}
+
+		name := formFieldName(f)
+		known[name] = true
+
+		vals, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		err := setFormValue(v.FieldByIndex(f.Index), vals)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if fd.DisallowUnknownFields {
+		for name := range values {
+			if !known[name] {
+				return fmt.Errorf("unknown field %q", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// formFields lists the exported fields of t, flattening embedded structs
+func formFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get(FormTag) == "-" {
+			continue
+		}
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get(FormTag) == "" {
+			for _, inner := range formFields(f.Type) {
+				inner.Index = append([]int{i}, inner.Index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+func formFieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get(FormTag), ","); name != "" {
+		return name
+	}
+
+	return fieldName(f)
+}
+
+func isFormValueType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+func setFormValue(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !v.Addr().Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			err := setFormScalar(slice.Index(i), s)
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	// the first value wins, as with url.Values.Get
+	return setFormScalar(v, vals[0])
+}
+
+func setFormScalar(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		err := setFormScalar(ptr.Elem(), s)
+		if err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == timeType {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		// checkboxes submit "on"
+		if s == "on" {
+			v.SetBool(true)
+			return nil
+		}
+		if s == "" || s == "off" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot decode into %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+type signupForm struct {
+	Email    string    `form:"email" validate:"required"`
+	Age      int       `form:"age"`
+	Ratio    float64   `json:"ratio"`
+	Terms    bool      `form:"terms"`
+	Interest []string  `form:"interest"`
+	Referrer *string   `form:"referrer"`
+	Birthday time.Time `form:"birthday"`
+	Ignored  string    `form:"-"`
+}
+
+func TestFormDecoder(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got signupForm
+	fn := func(ctx context.Context, f signupForm) error {
+		got = f
+		return nil
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		err = r.Register(method, "/signup", fn, nil, WithDecoder(&FormDecoder{MaxBytesToRead: 256, DisallowUnknownFields: true}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	friend := "friend"
+	cases := []struct {
+		Name        string
+		Method      string
+		ContentType string
+		Query       string
+		Body        string
+		ExpectCode  int
+		Expect      signupForm
+	}{
+		{"post", http.MethodPost, "application/x-www-form-urlencoded", "",
+			"email=a%40b.c&age=30&ratio=0.5&terms=on&interest=go&interest=http&referrer=friend&birthday=1990-01-02T00:00:00Z",
+			http.StatusOK, signupForm{Email: "a@b.c", Age: 30, Ratio: 0.5, Terms: true, Interest: []string{"go", "http"}, Referrer: &friend, Birthday: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		{"get-query", http.MethodGet, "", "?email=q%40b.c&age=5", "", http.StatusOK, signupForm{Email: "q@b.c", Age: 5}},
+		{"mime-type", http.MethodPost, "application/json", "", `{"email":"a"}`, http.StatusUnsupportedMediaType, signupForm{}},
+		{"bad-number", http.MethodPost, "application/x-www-form-urlencoded", "", "email=a&age=old", http.StatusBadRequest, signupForm{}},
+		{"validation", http.MethodPost, "application/x-www-form-urlencoded", "", "age=3", http.StatusBadRequest, signupForm{}},
+		{"unknown-field", http.MethodPost, "application/x-www-form-urlencoded", "", "email=a&Ignored=x", http.StatusBadRequest, signupForm{}},
+		{"too-large", http.MethodPost, "application/x-www-form-urlencoded", "", "email=" + strings.Repeat("a", 300), http.StatusRequestEntityTooLarge, signupForm{}},
+	}
+
+	for _, c := range cases {
+		got = signupForm{}
+		req := httptest.NewRequest(c.Method, "/signup"+c.Query, strings.NewReader(c.Body))
+		if c.ContentType != "" {
+			req.Header.Set("Content-Type", c.ContentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+		}
+
+		if !reflect.DeepEqual(got, c.Expect) {
+			t.Errorf("case[%s] expected %+v got %+v", c.Name, c.Expect, got)
+		}
+	}
+}
+
+func TestFormDecoderValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		Fn        interface{}
+		ShouldErr bool
+	}{
+		{"struct", func(ctx context.Context, f signupForm) {}, false},
+		{"pointer", func(f *signupForm) {}, false},
+		{"map", func(f map[string]string) {}, true},
+		{"nested-struct", func(f struct{ Inner struct{ X int } }) {}, true},
+	}
+
+	for _, c := range cases {
+		err := NewFormDecoder().ValidateType(c.Fn)
+		if (err != nil) != c.ShouldErr {
+			t.Errorf("case[%s] expected error %t got %v", c.Name, c.ShouldErr, err)
+		}
+	}
+}
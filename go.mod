@@ -1,5 +1,5 @@
 module github.com/jwfriese/autohttp
 
-go 1.17
+go 1.18
 
 require github.com/fortytw2/lounge v0.0.0-20211222193458-766d5beb419b
@@ -0,0 +1,65 @@
+package autohttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrGone is reported for requests to endpoints declared with Router.Gone
+var ErrGone = errors.New("autohttp: this endpoint has been removed")
+
+// GoneResponse is the body written for requests to removed endpoints
+type GoneResponse struct {
+	Error string `json:"error"`
+	Path  string `json:"path"`
+	// Successor is where the endpoint's replacement lives, if it has one
+	Successor string `json:"successor,omitempty"`
+}
+
+// Gone declares that the endpoint at path, which may contain {name} segments
+// and end with a * wildcard, has been removed. Requests for it, with any
+// method, get a 410 and a GoneResponse instead of a 404. A successor, if not
+// empty, is also linked with rel="successor-version". Live routes matching
+// the same path take precedence
+func (r *Router) Gone(path string, successor string) error {
+	if r.gone == nil {
+		r.gone = newRouteNode()
+	}
+
+	return r.gone.insert(path, &goneHandler{successor: successor})
+}
+
+type goneHandler struct {
+	successor string
+}
+
+func (gh *goneHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if gh.successor != "" {
+		w.Header().Add("Link", "<"+gh.successor+`>; rel="successor-version"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGone)
+	json.NewEncoder(w).Encode(GoneResponse{
+		Error:     ErrGone.Error(),
+		Path:      req.URL.Path,
+		Successor: gh.successor,
+	})
+}
+
+// serveGone serves a 410 if req is for a removed endpoint
+func (r *Router) serveGone(w http.ResponseWriter, req *http.Request) bool {
+	if r.gone == nil {
+		return false
+	}
+
+	entry, _ := r.gone.lookup(req.URL.Path, nil)
+	if entry == nil {
+		return false
+	}
+
+	entry.handler.ServeHTTP(w, req)
+	r.cleanLeftovers(req)
+	return true
+}
@@ -0,0 +1,83 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestGone(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/v2/users/{id}", func(ctx context.Context) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Gone("/v1/users/{id}", "/v2/users/{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Gone("/legacy/*", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name            string
+		Method          string
+		Path            string
+		ExpectCode      int
+		ExpectSuccessor string
+		ExpectLink      string
+	}{
+		{"successor", http.MethodGet, "/v1/users/12", http.StatusGone, "/v2/users/{id}", `</v2/users/{id}>; rel="successor-version"`},
+		{"any-method", http.MethodDelete, "/v1/users/12", http.StatusGone, "/v2/users/{id}", `</v2/users/{id}>; rel="successor-version"`},
+		{"method-with-routes", http.MethodPost, "/legacy/reports/3", http.StatusGone, "", ""},
+		{"live-route", http.MethodPost, "/v2/users/12", http.StatusUnsupportedMediaType, "", ""},
+		{"not-found", http.MethodGet, "/v3/users/12", http.StatusNotFound, "", ""},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.Method, c.Path, nil))
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+			continue
+		}
+
+		if got := w.Header().Get("Link"); got != c.ExpectLink {
+			t.Errorf("case[%s] expected Link %q got %q", c.Name, c.ExpectLink, got)
+		}
+
+		if c.ExpectCode != http.StatusGone {
+			continue
+		}
+
+		var body GoneResponse
+		err := json.NewDecoder(w.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Path != c.Path || body.Successor != c.ExpectSuccessor || body.Error == "" {
+			t.Errorf("case[%s] unexpected body %+v", c.Name, body)
+		}
+	}
+
+	if err := r.Gone("/v1/users/{id}", ""); err == nil {
+		t.Error("expected declaring the same removed endpoint twice to fail")
+	}
+}
@@ -0,0 +1,81 @@
+package autohttp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// A Group registers routes under a shared path prefix, applying
+// the same middleware and response policy to every member route
+type Group struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+	headers     Header
+}
+
+// Group returns a Group for registering routes under prefix, running
+// middlewares before those passed to each Register call
+func (r *Router) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		router:      r,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: middlewares,
+		headers:     make(Header),
+	}
+}
+
+// Group returns a nested Group under prefix. It inherits the parent's
+// middleware, which runs before its own, and the parent's headers as they
+// are when it is created
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	composed := make([]Middleware, 0, len(g.middlewares)+len(middlewares))
+	composed = append(composed, g.middlewares...)
+	composed = append(composed, middlewares...)
+
+	return &Group{
+		router:      g.router,
+		prefix:      g.prefix + strings.TrimSuffix(prefix, "/"),
+		middlewares: composed,
+		headers:     copyHeader(g.headers),
+	}
+}
+
+// SetHeader sets a header on every response from routes in the group.
+// Handlers may still override it
+func (g *Group) SetHeader(key, val string) {
+	g.headers[http.CanonicalHeaderKey(key)] = val
+}
+
+// Register registers fn at the group prefix joined with path. The group's
+// middleware runs first, outermost group first, then middlewares
+func (g *Group) Register(method string, path string, fn interface{}, middlewares []Middleware, opts ...RouteOption) error {
+	composed := make([]Middleware, 0, len(g.middlewares)+len(middlewares))
+	composed = append(composed, g.middlewares...)
+	composed = append(composed, middlewares...)
+
+	return g.router.register(method, g.prefix+path, fn, composed, routeConfig{headers: copyHeader(g.headers)}, opts)
+}
+
+func copyHeader(h Header) Header {
+	copied := make(Header, len(h))
+	for k, v := range h {
+		copied[k] = v
+	}
+
+	return copied
+}
+
+// headersHandler sets response headers before calling the next handler
+type headersHandler struct {
+	headers Header
+	next    http.Handler
+}
+
+func (hh *headersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for k, v := range hh.headers {
+		w.Header().Set(k, v)
+	}
+
+	hh.next.ServeHTTP(w, r)
+}
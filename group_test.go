@@ -0,0 +1,174 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestGroupHeaders(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := r.Group("/api/v1/")
+	g.SetHeader("x-api-version", "1")
+	g.SetHeader("Cache-Control", "no-store")
+
+	err = g.Register(http.MethodPost, "/ping", func(ctx context.Context, in struct{ Name string }) map[string]string {
+		return map[string]string{"name": in.Name}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/outside", func(ctx context.Context, in struct{ Name string }) map[string]string {
+		return map[string]string{"name": in.Name}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name          string
+		Path          string
+		ExpectVersion string
+	}{
+		{"member", "/api/v1/ping", "1"},
+		{"non-member", "/outside", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(`{"Name": "x"}`))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+			}
+
+			if got := w.Header().Get("X-Api-Version"); got != c.ExpectVersion {
+				t.Errorf("expected version header %q got %q", c.ExpectVersion, got)
+			}
+		})
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(ctx context.Context, in struct{ Name string }) map[string]string {
+		return map[string]string{"name": in.Name}
+	}
+
+	g := r.Group("/billing")
+	err = g.Register(http.MethodPost, "/charge", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/users", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"Name": "x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	g.SetReadOnly(true)
+	if code := serve("/billing/charge"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, code)
+	}
+	if code := serve("/users"); code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, code)
+	}
+
+	g.SetReadOnly(false)
+	r.SetReadOnly("/", true)
+	if code := serve("/users"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, code)
+	}
+
+	r.SetReadOnly("/", false)
+	if code := serve("/billing/charge"); code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, code)
+	}
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	record := func(name string) Middleware {
+		return beforeFunc(func(r *http.Request) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	api := r.Group("/api", record("api"))
+	v1 := api.Group("/v1/", record("v1"))
+	admin := v1.Group("/admin", record("admin"), record("admin-2"))
+
+	fn := func(ctx context.Context) {}
+	for _, reg := range []struct {
+		g    *Group
+		path string
+	}{
+		{api, "/status"},
+		{v1, "/users"},
+		{admin, "/audit"},
+	} {
+		err = reg.g.Register(http.MethodPost, reg.path, fn, []Middleware{record("route")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		ExpectOrder []string
+	}{
+		{"top", "/api/status", []string{"api", "route"}},
+		{"nested", "/api/v1/users", []string{"api", "v1", "route"}},
+		{"deeply-nested", "/api/v1/admin/audit", []string{"api", "v1", "admin", "admin-2", "route"}},
+	}
+
+	for _, c := range cases {
+		order = nil
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, c.Path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s] expected 200 got %d", c.Name, w.Code)
+		}
+
+		if strings.Join(order, ",") != strings.Join(c.ExpectOrder, ",") {
+			t.Errorf("case[%s] expected middleware order %v got %v", c.Name, c.ExpectOrder, order)
+		}
+	}
+}
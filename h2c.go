@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package autohttp
+
+import "net/http"
+
+// enableH2C serves HTTP/2 without TLS alongside HTTP/1
+func enableH2C(s *http.Server) error {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	s.Protocols = &protocols
+	return nil
+}
@@ -0,0 +1,91 @@
+//go:build go1.24
+
+package autohttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestH2C(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/proto", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewServer(r, EnableH2C, WithAutoTLS(staticCertManager{}))
+	if err == nil {
+		t.Fatal("expected EnableH2C and WithAutoTLS to conflict")
+	}
+
+	s, err := NewServer(r, EnableH2C)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	h2c := &http.Protocols{}
+	h2c.SetUnencryptedHTTP2(true)
+	h1 := &http.Protocols{}
+	h1.SetHTTP1(true)
+
+	cases := []struct {
+		Name        string
+		Protocols   *http.Protocols
+		ExpectProto string
+	}{
+		{"h2c", h2c, "HTTP/2.0"},
+		{"http1", h1, "HTTP/1.1"},
+	}
+
+	for _, c := range cases {
+		client := &http.Client{Transport: &http.Transport{Protocols: c.Protocols}}
+		resp, err := client.Get("http://" + l.Addr().String() + "/proto")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != c.ExpectProto {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.ExpectProto, body)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
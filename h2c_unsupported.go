@@ -0,0 +1,12 @@
+//go:build !go1.24
+
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+)
+
+func enableH2C(s *http.Server) error {
+	return errors.New("autohttp: EnableH2C needs Go 1.24 or later")
+}
@@ -9,6 +9,7 @@ import (
 	"reflect"
 
 	"github.com/fortytw2/lounge"
+	"github.com/jwfriese/autohttp/internal/httpsnoop"
 )
 
 var (
@@ -39,21 +40,38 @@ type Encoder interface {
 
 type ErrorHandler func(w http.ResponseWriter, err error)
 
-func DefaultErrorHandler(w http.ResponseWriter, err error) {
-	ewc, ok := err.(ErrorWithCode)
-	if ok {
-		w.WriteHeader(ewc.StatusCode)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+// ErrorResponse is the body DefaultErrorHandler writes
+type ErrorResponse struct {
+	Error     string           `json:"error" xml:"error"`
+	Fields    ValidationErrors `json:"fields,omitempty" xml:"field,omitempty"`
+	RequestID string           `json:"request_id,omitempty" xml:"request_id,omitempty"`
+}
 
-		return
+// NewErrorResponse describes err, to be written with the returned status code.
+// Validation failures list the fields at fault and server errors include the
+// request ID, if one was attached to the response, so they can be traced
+func NewErrorResponse(w http.ResponseWriter, err error) (int, ErrorResponse) {
+	code := statusCodeForError(err)
+	body := ErrorResponse{Error: err.Error()}
+
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		body.Fields = ve
 	}
 
-	w.WriteHeader(http.StatusInternalServerError)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": err.Error(),
-	})
+	if code >= http.StatusInternalServerError {
+		body.RequestID = w.Header().Get(RequestIDHeader)
+	}
+
+	return code, body
+}
+
+// DefaultErrorHandler writes the error as a JSON ErrorResponse
+func DefaultErrorHandler(w http.ResponseWriter, err error) {
+	code, body := NewErrorResponse(w, err)
+
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
 }
 
 // An Handler is an http.Handler generated from any function
@@ -66,7 +84,33 @@ type Handler struct {
 	errorHandler ErrorHandler
 	middlewares  []Middleware
 
+	// requestErrorHandler, when set, replaces errorHandler
+	requestErrorHandler RequestErrorHandler
+
 	hideFromIntrospectors bool
+	hideRequestIDs        bool
+	hasAfterMiddleware    bool
+	weakETag              bool
+	responseSigner        ResponseSigner
+	requestDigest         *requestDigest
+	responseDigest        []string
+	tags                  []string
+
+	compression *compression
+	negotiation *negotiation
+	// errorNegotiation picks encoders for error bodies, which any may encode
+	errorNegotiation     *negotiation
+	requestTransformers  []RequestTransformer
+	responseTransformers []ResponseTransformer
+	bodySpooling         *bodySpooling
+	responseCache        *responseCache
+	memo                 *memo
+	sampling             *sampling
+	errorLogging         *errorLogging
+	errorPages           *errorPages
+
+	// typed routes skip the decoder and reflection, see RegisterTyped
+	typed typedCaller
 }
 
 func NewHandler(
@@ -78,7 +122,11 @@ func NewHandler(
 	fn interface{},
 ) (*Handler, error) {
 	if decoder == nil || encoder == nil {
-		return nil, errors.New("a decoder and encoder must be supplied. use httpz.NoOpDecoder")
+		return nil, errors.New("a decoder and encoder must be supplied. use NoOpDecoder or PassthroughDecoder and NoOpEncoder for raw routes")
+	}
+
+	if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		return nil, fmt.Errorf("handlers must be functions or http.Handlers, got %T", fn)
 	}
 
 	err := decoder.ValidateType(fn)
@@ -86,9 +134,12 @@ func NewHandler(
 		return nil, err
 	}
 
-	err = encoder.ValidateType(fn)
-	if err != nil {
-		return nil, err
+	// streamed responses never reach the encoder
+	if !isStreamFunc(fn) {
+		err = encoder.ValidateType(fn)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// extra autoroute rule
@@ -96,14 +147,39 @@ func NewHandler(
 		return nil, errors.New("a function can only have up to 2 return values")
 	}
 
+	return newHandler(log, decoder, encoder, middlewares, errorHandler, fn), nil
+}
+
+// newHandler builds a Handler for an already validated fn
+func newHandler(
+	log lounge.Log,
+	decoder Decoder,
+	encoder Encoder,
+	middlewares []Middleware,
+	errorHandler ErrorHandler,
+	fn interface{},
+) *Handler {
+	if errorHandler == nil {
+		errorHandler = DefaultErrorHandler
+	}
+
+	hasAfterMiddleware := false
+	for _, mw := range middlewares {
+		if _, ok := mw.(AfterMiddleware); ok {
+			hasAfterMiddleware = true
+		}
+	}
+
 	return &Handler{
 		fn:                    fn,
+		log:                   log,
 		encoder:               encoder,
 		decoder:               decoder,
-		errorHandler:          DefaultErrorHandler,
+		errorHandler:          errorHandler,
 		middlewares:           middlewares,
 		hideFromIntrospectors: false,
-	}, nil
+		hasAfterMiddleware:    hasAfterMiddleware,
+	}
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -114,23 +190,198 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	r = withEarlyHints(w, r)
+
+	sample := h.sampling.start(r)
+	defer sample.finish()
+
+	ran := 0
+	if h.hasAfterMiddleware {
+		status := http.StatusOK
+		w = httpsnoop.Wrap(w, httpsnoop.Hooks{
+			WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+				return func(code int) {
+					status = code
+					next(code)
+				}
+			},
+		})
+
+		defer func() {
+			h.runAfterMiddleware(ran, r, status, w.Header())
+		}()
+	}
+
 	for _, mw := range h.middlewares {
+		sample.mark()
 		err := mw.Before(r, h)
+		sample.lapMiddleware(mw)
+		if err != nil {
+			h.handleError(w, r, StageMiddleware, err)
+			return
+		}
+		ran++
+	}
+
+	if h.bodySpooling != nil && r.Body != nil && r.Body != http.NoBody {
+		spooled, err := h.bodySpooling.store.Store(r.Body, h.bodySpooling.threshold)
+		if err != nil {
+			h.handleError(w, r, StageDecode, err)
+			return
+		}
+		defer spooled.Close()
+
+		r.Body.Close()
+		r.Body = spooled
+	}
+
+	if h.requestDigest != nil {
+		err := h.requestDigest.verify(r)
+		if err != nil {
+			h.handleError(w, r, StageDecode, err)
+			return
+		}
+	}
+
+	for _, transform := range h.requestTransformers {
+		err := transform(r)
+		if err != nil {
+			h.handleError(w, r, StageTransform, err)
+			return
+		}
+	}
+
+	encoder := h.encoder
+	if h.negotiation != nil {
+		w.Header().Add("Vary", "Accept")
+
+		var err error
+		encoder, err = h.negotiation.encoderFor(r, h.encoder)
+		if err != nil {
+			h.handleError(w, r, StageEncode, err)
+			return
+		}
+	}
+
+	var (
+		encodableValue interface{}
+		cacheKey       string
+		ok             bool
+	)
+	if h.typed != nil {
+		encodableValue, cacheKey, ok = h.typed.call(h, w, r, sample)
+	} else {
+		encodableValue, cacheKey, ok = h.callReflect(w, r, sample)
+	}
+	if !ok {
+		return
+	}
+
+	encodableValue, resp := unwrapResponse(encodableValue)
+
+	var err error
+	for _, transform := range h.responseTransformers {
+		encodableValue, err = transform(r, encodableValue)
+		if err != nil {
+			h.handleError(w, r, StageTransform, err)
+			return
+		}
+	}
+
+	if rd, ok := encodableValue.(Redirect); ok {
+		if resp != nil {
+			resp.apply(w.Header(), 0)
+		}
+		rd.serve(w, r)
+		sample.lap(phaseEncode)
+		return
+	}
+
+	if rd, ok := encodableValue.(io.Reader); ok {
+		h.stream(w, r, rd, resp)
+		sample.lap(phaseEncode)
+		return
+	}
+
+	hw := HeaderWriter(w.Header().Set)
+	var cacheHeader http.Header
+	if h.responseCache != nil {
+		cacheHeader, hw = recordHeaders(w.Header())
+	}
+
+	responseCode, body, err := encoder.Encode(encodableValue, hw)
+	if sm, ok := encodableValue.(*StatusMonitor); ok && sm != nil && err == nil {
+		// accepted work points the client at its status
+		hw("Location", sm.Location())
+		responseCode = http.StatusAccepted
+	}
+	if err != nil {
+		h.handleError(w, r, StageEncode, err)
+		return
+	}
+
+	if resp != nil {
+		if cacheHeader != nil {
+			resp.apply(cacheHeader, responseCode)
+		}
+		responseCode = resp.apply(w.Header(), responseCode)
+	}
+
+	if h.responseCache != nil && cacheKey != "" {
+		body, err = h.responseCache.store(cacheKey, responseCode, cacheHeader, body)
 		if err != nil {
-			h.errorHandler(w, err)
+			h.handleError(w, r, StageEncode, err)
 			return
 		}
+		w.Header().Set("X-Cache", "MISS")
 	}
 
+	h.writeResponse(w, r, responseCode, body)
+	sample.lap(phaseEncode)
+}
+
+// callReflect decodes the request and calls fn through reflection, returning
+// its encodable result. It reports false if a response was already written
+func (h *Handler) callReflect(w http.ResponseWriter, r *http.Request, sample *sampleTimer) (interface{}, string, bool) {
+	defer func() {
+		// uploads spooled to disk don't outlive the call
+		if r.MultipartForm != nil {
+			r.MultipartForm.RemoveAll()
+		}
+	}()
+
+	sample.mark()
 	callValues, err := h.decoder.Decode(h.fn, r)
+	sample.lap(phaseDecode)
 	if err != nil {
 		// encode the parsing error cleanly
-		h.errorHandler(w, err)
-		return
+		h.handleError(w, r, StageDecode, err)
+		return nil, "", false
+	}
+
+	var cacheKey string
+	if h.responseCache != nil {
+		cacheKey = h.responseCache.key(r, callValues)
+	}
+	if cacheKey != "" {
+		if cached, hit := h.responseCache.lookup(cacheKey); hit != "" {
+			h.serveCached(w, r, cached, hit)
+			return nil, "", false
+		}
+	}
+
+	var memoKey interface{}
+	if h.memo != nil {
+		memoKey = h.memo.callKey(r.Context(), callValues)
+		if result, ok := h.memo.get(memoKey); ok {
+			return result, cacheKey, true
+		}
 	}
 
 	// call the handler function using reflection
+	sample.mark()
 	returnValues := reflect.ValueOf(h.fn).Call(callValues)
+	sample.lap(phaseHandler)
 
 	// split out the error value and the return value
 	var encodableValue interface{} = nil
@@ -138,21 +389,120 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if isErrorType(rv.Type()) && !rv.IsNil() && !rv.IsZero() {
 			err = rv.Interface().(error)
 			// encode the parsing error cleanly
-			h.errorHandler(w, err)
-			return
+			h.handleError(w, r, StageHandler, err)
+			return nil, "", false
 		} else if !isErrorType(rv.Type()) {
 			encodableValue = rv.Interface()
 		}
 	}
 
-	responseCode, body, err := h.encoder.Encode(encodableValue, w.Header().Set)
-	if err != nil {
-		h.errorHandler(w, err)
-	} else {
-		w.WriteHeader(responseCode)
-		_, err = io.Copy(w, body)
+	if h.memo != nil {
+		h.memo.put(memoKey, encodableValue)
+	}
+
+	return encodableValue, cacheKey, true
+}
+
+// writeResponse tags and compresses, if enabled, and writes an encoded response
+func (h *Handler) writeResponse(w http.ResponseWriter, r *http.Request, code int, body io.Reader) {
+	var err error
+	if h.responseSigner != nil && body != nil && code < http.StatusBadRequest {
+		body, err = signBody(h.responseSigner, w.Header(), body)
 		if err != nil {
-			h.log.Errorf("error copying response body to writer: %s", err)
+			h.handleError(w, r, StageEncode, err)
+			return
 		}
 	}
+
+	if h.weakETag && body != nil && code == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		var notModified bool
+		body, notModified, err = applyWeakETag(r, w.Header(), body)
+		if err != nil {
+			h.handleError(w, r, StageEncode, err)
+			return
+		}
+
+		if notModified {
+			// a 304 carries no body, or the headers describing one
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if body != nil && h.compression != nil {
+		body, err = h.compression.apply(r, w.Header(), body)
+		if err != nil {
+			h.handleError(w, r, StageEncode, err)
+			return
+		}
+	}
+
+	if body != nil && h.responseDigest != nil {
+		body, err = digestBody(h.responseDigest, w.Header(), body)
+		if err != nil {
+			h.handleError(w, r, StageEncode, err)
+			return
+		}
+	}
+
+	w.WriteHeader(code)
+	if body == nil {
+		return
+	}
+
+	_, err = io.Copy(w, body)
+	if err != nil {
+		h.log.Errorf("error copying response body to writer: %s", err)
+	}
+}
+
+// runAfterMiddleware calls After, in reverse order, on the first n middlewares
+func (h *Handler) runAfterMiddleware(n int, r *http.Request, status int, header http.Header) {
+	for i := n - 1; i >= 0; i-- {
+		if amw, ok := h.middlewares[i].(AfterMiddleware); ok {
+			amw.After(r, status, header)
+		}
+	}
+}
+
+// handleError attaches the request ID to server errors and logs them with the
+// stage they came from before handing off to the ErrorHandler
+func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, stage Stage, err error) {
+	if bodyLimitExceeded(r.Context()) && statusCodeForError(err) != http.StatusRequestEntityTooLarge {
+		err = ErrorWithCode{Err: ErrBodyTooLarge, StatusCode: http.StatusRequestEntityTooLarge}
+	}
+	stage = errorStage(stage, err)
+
+	var ewh ErrorWithHeaders
+	if errors.As(err, &ewh) {
+		for k, vals := range ewh.ResponseHeaders() {
+			for _, v := range vals {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+
+	if span := spanFromContext(r.Context()); span != nil {
+		span.RecordError(err)
+	}
+
+	code := statusCodeForError(err)
+	if code >= http.StatusInternalServerError {
+		if !h.hideRequestIDs {
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				w.Header().Set(RequestIDHeader, id)
+			}
+		}
+
+		if h.errorLogging != nil {
+			h.errorLogging.log(h.log, r, code, stage, err)
+		}
+	}
+
+	if h.errorPages != nil && h.errorPages.serve(w, r, code) {
+		return
+	}
+
+	errorShaping{compression: h.compression, negotiation: h.errorNegotiation}.serve(w, r, stage, err, h.errorHandler, h.requestErrorHandler)
 }
@@ -0,0 +1,90 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/jwfriese/autohttp/internal/httpsnoop"
+)
+
+// headWriter answers a HEAD request served by a GET route. The body is
+// counted rather than written, and the status held back until the handler
+// returns, so the response gets the Content-Length the GET would have had
+type headWriter struct {
+	w http.ResponseWriter
+
+	code      int
+	written   int64
+	committed bool
+}
+
+// serveHead calls serve to answer req, a HEAD request, as its GET route would
+func serveHead(w http.ResponseWriter, req *http.Request, serve func(w http.ResponseWriter, req *http.Request)) {
+	hw := &headWriter{w: w}
+	serve(httpsnoop.Wrap(w, httpsnoop.Hooks{
+		WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+			return func(code int) {
+				// informational responses, such as early hints, go out as they are
+				if code < http.StatusOK {
+					next(code)
+					return
+				}
+
+				if hw.code == 0 {
+					hw.code = code
+				}
+			}
+		},
+		Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+			return hw.write
+		},
+		ReadFrom: func(next httpsnoop.ReadFromFunc) httpsnoop.ReadFromFunc {
+			return func(src io.Reader) (int64, error) {
+				n, err := io.Copy(io.Discard, src)
+				hw.count(n)
+				return n, err
+			}
+		},
+		Flush: func(next httpsnoop.FlushFunc) httpsnoop.FlushFunc {
+			return func() {
+				hw.commit(false)
+				next()
+			}
+		},
+	}), req)
+
+	hw.commit(true)
+}
+
+func (hw *headWriter) write(b []byte) (int, error) {
+	hw.count(int64(len(b)))
+	return len(b), nil
+}
+
+func (hw *headWriter) count(n int64) {
+	if hw.code == 0 {
+		hw.code = http.StatusOK
+	}
+
+	hw.written += n
+}
+
+// commit writes the held back status, with the counted Content-Length if the
+// whole body was seen and the handler didn't set one
+func (hw *headWriter) commit(done bool) {
+	if hw.committed {
+		return
+	}
+	hw.committed = true
+
+	if hw.code == 0 {
+		hw.code = http.StatusOK
+	}
+
+	if done && hw.written > 0 && hw.w.Header().Get("Content-Length") == "" && hw.w.Header().Get("Transfer-Encoding") == "" {
+		hw.w.Header().Set("Content-Length", strconv.FormatInt(hw.written, 10))
+	}
+
+	hw.w.WriteHeader(hw.code)
+}
@@ -0,0 +1,96 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestHead(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/pets/{id}", func() (map[string]string, error) {
+		return map[string]string{"name": "rex"}, nil
+	}, nil, WithDecoder(NoOpDecoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/explicit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("get"))
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodHead, "/explicit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Explicit", "true")
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/pets", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := httptest.NewRecorder()
+	r.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/pets/1", nil))
+
+	head := httptest.NewRecorder()
+	r.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/pets/1", nil))
+
+	if get.Code != http.StatusOK || head.Code != get.Code {
+		t.Errorf("expected HEAD to get the GET status %d, got %d", get.Code, head.Code)
+	}
+
+	if head.Body.Len() != 0 {
+		t.Errorf("expected no body, got %q", head.Body.String())
+	}
+
+	if ct := head.Header().Get("Content-Type"); ct != get.Header().Get("Content-Type") {
+		t.Errorf("expected Content-Type %q, got %q", get.Header().Get("Content-Type"), ct)
+	}
+
+	if cl := head.Header().Get("Content-Length"); cl == "" || cl != strconv.Itoa(get.Body.Len()) {
+		t.Errorf("expected Content-Length %d, got %q", get.Body.Len(), cl)
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		code   int
+		header string
+	}{
+		{name: "explicit HEAD route", path: "/explicit", code: http.StatusOK, header: "X-Explicit"},
+		{name: "POST only path", path: "/pets", code: http.StatusMethodNotAllowed, header: "Allow"},
+		{name: "unknown path", path: "/nope", code: http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodHead, c.path, nil))
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if c.header != "" && w.Header().Get(c.header) == "" {
+			t.Errorf("case[%s] expected a %s header", c.name, c.header)
+		}
+
+		if w.Body.Len() != 0 {
+			t.Errorf("case[%s] expected no body, got %q", c.name, w.Body.String())
+		}
+	}
+}
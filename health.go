@@ -0,0 +1,160 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+// HealthContentType is the media type of the draft health check response
+// format, see https://datatracker.ietf.org/doc/html/draft-inadarei-api-health-check
+const HealthContentType = "application/health+json"
+
+// A HealthStatus is pass, warn or fail
+type HealthStatus string
+
+const (
+	HealthPass HealthStatus = "pass"
+	HealthWarn HealthStatus = "warn"
+	HealthFail HealthStatus = "fail"
+)
+
+// severity orders statuses so the worst one wins
+func (hs HealthStatus) severity() int {
+	switch hs {
+	case HealthPass:
+		return 0
+	case HealthWarn:
+		return 1
+	}
+
+	return 2
+}
+
+// A HealthCheckResult is the outcome of one HealthCheck
+type HealthCheckResult struct {
+	Status        HealthStatus `json:"status"`
+	ObservedValue interface{}  `json:"observedValue,omitempty"`
+	ObservedUnit  string       `json:"observedUnit,omitempty"`
+	Output        string       `json:"output,omitempty"`
+}
+
+// A HealthCheck reports on one component. Name is the draft's
+// "component:measurement" key, such as "postgres:responseTime"
+type HealthCheck struct {
+	Name          string
+	ComponentType string
+	Check         func(ctx context.Context) HealthCheckResult
+}
+
+// Health describes the service in health responses
+type Health struct {
+	Version     string
+	ReleaseID   string
+	ServiceID   string
+	Description string
+
+	Checks []HealthCheck
+}
+
+type healthCheckEntry struct {
+	ComponentType string `json:"componentType,omitempty"`
+	HealthCheckResult
+	Time string `json:"time"`
+}
+
+type healthResponse struct {
+	Status      HealthStatus                  `json:"status"`
+	Version     string                        `json:"version,omitempty"`
+	ReleaseID   string                        `json:"releaseId,omitempty"`
+	ServiceID   string                        `json:"serviceId,omitempty"`
+	Description string                        `json:"description,omitempty"`
+	Checks      map[string][]healthCheckEntry `json:"checks,omitempty"`
+}
+
+// WithHealth serves h's checks at path as application/health+json. Checks run
+// concurrently, the response status is the worst of theirs, and failing
+// services answer with a 503 so load balancers take them out of rotation
+func WithHealth(path string, h Health) func(r *Router) error {
+	return func(r *Router) error {
+		for _, c := range h.Checks {
+			if c.Name == "" || c.Check == nil {
+				return errors.New("autohttp: health checks need a name and a check function")
+			}
+		}
+
+		return r.registerQuiet(path, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			resp := h.run(req.Context(), r.log)
+
+			w.Header().Set("Content-Type", HealthContentType)
+			w.Header().Set("Cache-Control", "no-store")
+			if resp.Status == HealthFail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+
+			json.NewEncoder(w).Encode(resp)
+		}))
+	}
+}
+
+// run runs the checks, logging to log the panics of checks that panic, which
+// are reported as failing without the panic value
+func (h Health) run(ctx context.Context, log lounge.Log) healthResponse {
+	resp := healthResponse{
+		Status:      HealthPass,
+		Version:     h.Version,
+		ReleaseID:   h.ReleaseID,
+		ServiceID:   h.ServiceID,
+		Description: h.Description,
+	}
+
+	if len(h.Checks) == 0 {
+		return resp
+	}
+
+	entries := make([]healthCheckEntry, len(h.Checks))
+	var wg sync.WaitGroup
+	for i, c := range h.Checks {
+		wg.Add(1)
+		go func(i int, c HealthCheck) {
+			defer wg.Done()
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Errorf("autohttp: health check %s panicked: %v", c.Name, rec)
+					entries[i] = healthCheckEntry{
+						ComponentType:     c.ComponentType,
+						HealthCheckResult: HealthCheckResult{Status: HealthFail, Output: "check panicked"},
+						Time:              time.Now().UTC().Format(time.RFC3339),
+					}
+				}
+			}()
+
+			result := c.Check(ctx)
+			if result.Status == "" {
+				result.Status = HealthPass
+			}
+
+			entries[i] = healthCheckEntry{
+				ComponentType:     c.ComponentType,
+				HealthCheckResult: result,
+				Time:              time.Now().UTC().Format(time.RFC3339),
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	resp.Checks = make(map[string][]healthCheckEntry)
+	for i, c := range h.Checks {
+		resp.Checks[c.Name] = append(resp.Checks[c.Name], entries[i])
+		if entries[i].Status.severity() > resp.Status.severity() {
+			resp.Status = entries[i].Status
+		}
+	}
+
+	return resp
+}
@@ -0,0 +1,120 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	check := func(name string, status HealthStatus) HealthCheck {
+		return HealthCheck{
+			Name:          name,
+			ComponentType: "datastore",
+			Check: func(ctx context.Context) HealthCheckResult {
+				return HealthCheckResult{Status: status, ObservedValue: 12, ObservedUnit: "ms"}
+			},
+		}
+	}
+
+	cases := []struct {
+		Name         string
+		Checks       []HealthCheck
+		ExpectCode   int
+		ExpectStatus HealthStatus
+	}{
+		{"no-checks", nil, http.StatusOK, HealthPass},
+		{"passing", []HealthCheck{check("db:responseTime", HealthPass)}, http.StatusOK, HealthPass},
+		{"warning", []HealthCheck{check("db:responseTime", HealthPass), check("cache:responseTime", HealthWarn)}, http.StatusOK, HealthWarn},
+		{"failing", []HealthCheck{check("db:responseTime", HealthWarn), check("cache:responseTime", HealthFail)}, http.StatusServiceUnavailable, HealthFail},
+		{"panicking", []HealthCheck{{Name: "queue:depth", Check: func(ctx context.Context) HealthCheckResult { panic("boom") }}}, http.StatusServiceUnavailable, HealthFail},
+	}
+
+	for _, c := range cases {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithHealth("/health", Health{
+			Version:   "1",
+			ReleaseID: "1.2.0",
+			Checks:    c.Checks,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if w.Header().Get("Content-Type") != HealthContentType {
+			t.Errorf("case[%s] expected %s got %s", c.Name, HealthContentType, w.Header().Get("Content-Type"))
+		}
+
+		var body struct {
+			Status    HealthStatus `json:"status"`
+			ReleaseID string       `json:"releaseId"`
+			Checks    map[string][]struct {
+				ComponentType string       `json:"componentType"`
+				Status        HealthStatus `json:"status"`
+				ObservedValue float64      `json:"observedValue"`
+				Time          string       `json:"time"`
+			} `json:"checks"`
+		}
+		err = json.NewDecoder(w.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Status != c.ExpectStatus {
+			t.Errorf("case[%s] expected status %s got %s", c.Name, c.ExpectStatus, body.Status)
+		}
+
+		if body.ReleaseID != "1.2.0" {
+			t.Errorf("case[%s] expected the release ID got %q", c.Name, body.ReleaseID)
+		}
+
+		for _, hc := range c.Checks {
+			entries := body.Checks[hc.Name]
+			if len(entries) != 1 || entries[0].Time == "" {
+				t.Errorf("case[%s] expected one timed entry for %s got %+v", c.Name, hc.Name, entries)
+			}
+		}
+	}
+
+	_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithHealth("/health", Health{Checks: []HealthCheck{{Name: "x"}}}))
+	if err == nil {
+		t.Error("expected a check without a function to fail")
+	}
+}
+
+func TestHealthHidesPanics(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)), WithHealth("/health", Health{
+		Checks: []HealthCheck{{Name: "db", Check: func(ctx context.Context) HealthCheckResult { panic("postgres://admin:hunter2@db") }}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if strings.Contains(w.Body.String(), "hunter2") || !strings.Contains(w.Body.String(), "check panicked") {
+		t.Errorf("expected a generic output for the panic, got %s", w.Body.String())
+	}
+
+	if !strings.Contains(logs.String(), "health check db panicked: postgres://admin:hunter2@db") {
+		t.Errorf("expected the panic to be logged, got %q", logs.String())
+	}
+}
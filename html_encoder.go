@@ -0,0 +1,51 @@
+package autohttp
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+// HTMLEncoder renders return values through an html/template, for server
+// rendered pages
+type HTMLEncoder struct {
+	Template *template.Template
+	// Name of the template to execute, the root template if empty
+	Name string
+}
+
+// NewHTMLEncoder renders return values with the template called name in t
+func NewHTMLEncoder(t *template.Template, name string) *HTMLEncoder {
+	return &HTMLEncoder{Template: t, Name: name}
+}
+
+func (he *HTMLEncoder) ValidateType(fn interface{}) error {
+	if he.Template == nil {
+		return errors.New("html encoder needs a template")
+	}
+
+	if he.Name != "" && he.Template.Lookup(he.Name) == nil {
+		return errors.New("html encoder template " + he.Name + " not found")
+	}
+
+	return nil
+}
+
+func (he *HTMLEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	hw("Content-Type", "text/html; charset=utf-8")
+
+	var b bytes.Buffer
+	var err error
+	if he.Name != "" {
+		err = he.Template.ExecuteTemplate(&b, he.Name, value)
+	} else {
+		err = he.Template.Execute(&b, value)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, &b, nil
+}
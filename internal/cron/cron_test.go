@@ -0,0 +1,86 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+
+	for _, spec := range cases {
+		_, err := Parse(spec)
+		if err == nil {
+			t.Errorf("expected %q to fail to parse", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	// a wednesday
+	from := time.Date(2021, 12, 22, 10, 30, 15, 0, time.UTC)
+
+	cases := []struct {
+		Spec   string
+		Expect time.Time
+	}{
+		{"* * * * *", time.Date(2021, 12, 22, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 12, 22, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2021, 12, 22, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 12, 22, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, 12, 23, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2021, 12, 23, 9, 30, 0, 0, time.UTC)},
+		{"0 12 * * 0", time.Date(2021, 12, 26, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2021, 12, 26, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"15,45 8-9 * * *", time.Date(2021, 12, 23, 8, 15, 0, 0, time.UTC)},
+		// either day field matches when both are restricted
+		{"0 0 1 * 5", time.Date(2021, 12, 24, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.Spec)
+		if err != nil {
+			t.Fatalf("%q: %s", c.Spec, err)
+		}
+
+		next, err := s.Next(from)
+		if err != nil {
+			t.Fatalf("%q: %s", c.Spec, err)
+		}
+
+		if !next.Equal(c.Expect) {
+			t.Errorf("%q: expected %s got %s", c.Spec, c.Expect, next)
+		}
+	}
+}
+
+func TestNextNeverRuns(t *testing.T) {
+	t.Parallel()
+
+	s, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Next(time.Now())
+	if err != ErrNoNextRun {
+		t.Fatalf("expected ErrNoNextRun, got %v", err)
+	}
+}
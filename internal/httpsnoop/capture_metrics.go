@@ -45,6 +45,13 @@ func CaptureMetricsFn(w http.ResponseWriter, fn func(http.ResponseWriter)) Metri
 			WriteHeader: func(next WriteHeaderFunc) WriteHeaderFunc {
 				return func(code int) {
 					next(code)
+
+					// informational responses, such as 103 Early Hints, are
+					// followed by the final one, except for protocol switches
+					if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+						return
+					}
+
 					lock.Lock()
 					defer lock.Unlock()
 					if !headerWritten {
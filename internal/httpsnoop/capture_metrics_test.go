@@ -48,6 +48,21 @@ func TestCaptureMetrics(t *testing.T) {
 			}),
 			WantCode: http.StatusOK,
 		},
+		{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusCreated)
+			}),
+			WantCode: http.StatusCreated,
+		},
+		{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.Write([]byte("foo"))
+			}),
+			WantCode:    http.StatusOK,
+			WantWritten: 3,
+		},
 		{
 			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				panic("oh no")
@@ -0,0 +1,858 @@
+// Package msgpack is a small reflection based MessagePack codec, covering
+// the types encoding/json does. Structs are encoded as maps keyed by their
+// msgpack tag, or json tag, names and times as the timestamp extension
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// the timestamp extension type
+const extTimestamp = -1
+
+// Marshal returns the MessagePack encoding of v
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encoder{}
+	err := e.encode(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) byte1(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) raw32(n uint32) {
+	e.buf = append(e.buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (e *encoder) raw64(n uint64) {
+	e.raw32(uint32(n >> 32))
+	e.raw32(uint32(n))
+}
+
+func (e *encoder) uint16(code byte, n uint16) {
+	e.buf = append(e.buf, code, byte(n>>8), byte(n))
+}
+
+func (e *encoder) uint32(code byte, n uint32) {
+	e.byte1(code)
+	e.raw32(n)
+}
+
+func (e *encoder) uint64(code byte, n uint64) {
+	e.byte1(code)
+	e.raw64(n)
+}
+
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.byte1(0xc0)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		e.encodeTime(v.Interface().(time.Time))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.byte1(0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.byte1(0xc3)
+		} else {
+			e.byte1(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.uint32(0xca, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.uint64(0xcb, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.byte1(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.byte1(0xc0)
+			return nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.byte1(byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.uint16(0xd1, uint16(n))
+	case n >= math.MinInt32:
+		e.uint32(0xd2, uint32(n))
+	default:
+		e.uint64(0xd3, uint64(n))
+	}
+}
+
+func (e *encoder) encodeUint(n uint64) {
+	switch {
+	case n <= math.MaxInt8:
+		e.byte1(byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xcd, uint16(n))
+	case n <= math.MaxUint32:
+		e.uint32(0xce, uint32(n))
+	default:
+		e.uint64(0xcf, n)
+	}
+}
+
+func (e *encoder) encodeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.byte1(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xda, uint16(n))
+	default:
+		e.uint32(0xdb, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) encodeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xc5, uint16(n))
+	default:
+		e.uint32(0xc6, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) arrayHeader(n int) {
+	switch {
+	case n < 16:
+		e.byte1(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xdc, uint16(n))
+	default:
+		e.uint32(0xdd, uint32(n))
+	}
+}
+
+func (e *encoder) mapHeader(n int) {
+	switch {
+	case n < 16:
+		e.byte1(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		e.uint16(0xde, uint16(n))
+	default:
+		e.uint32(0xdf, uint32(n))
+	}
+}
+
+func (e *encoder) encodeArray(v reflect.Value) error {
+	e.arrayHeader(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		err := e.encode(v.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	// string keys are sorted so equal maps encode to equal bytes
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+
+	e.mapHeader(len(keys))
+	for _, k := range keys {
+		err := e.encode(k)
+		if err != nil {
+			return err
+		}
+
+		err = e.encode(v.MapIndex(k))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	fields := cachedFields(v.Type())
+
+	n := 0
+	for _, f := range fields {
+		if !f.omitEmpty || !v.FieldByIndex(f.index).IsZero() {
+			n++
+		}
+	}
+
+	e.mapHeader(n)
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		e.encodeString(f.name)
+		err := e.encode(fv)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) encodeTime(t time.Time) {
+	// the 96 bit timestamp covers every time.Time
+	e.buf = append(e.buf, 0xc7, 12, 0xff) // 0xff is extTimestamp
+	e.raw32(uint32(t.Nanosecond()))
+	e.raw64(uint64(t.Unix()))
+}
+
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+func cachedFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := structFields(t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func structFields(t reflect.Type, index []int) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+
+		tag, ok := sf.Tag.Lookup("msgpack")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(sf.Type, idx)...)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			name:      name,
+			index:     idx,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	return fields
+}
+
+// Unmarshal decodes the MessagePack data in b into v, which must be a
+// non-nil pointer
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("msgpack: Unmarshal needs a non-nil pointer")
+	}
+
+	d := &decoder{b: b}
+	err := d.decode(rv.Elem())
+	if err != nil {
+		return err
+	}
+
+	if d.i != len(d.b) {
+		return errors.New("msgpack: trailing data")
+	}
+
+	return nil
+}
+
+var errShort = errors.New("msgpack: unexpected end of data")
+
+type decoder struct {
+	b []byte
+	i int
+}
+
+func (d *decoder) peek() (byte, error) {
+	if d.i >= len(d.b) {
+		return 0, errShort
+	}
+
+	return d.b[d.i], nil
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.b)-d.i < n {
+		return nil, errShort
+	}
+
+	b := d.b[d.i : d.i+n]
+	d.i += n
+	return b, nil
+}
+
+func (d *decoder) uintN(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// lengthFor reads a length of n bytes
+func (d *decoder) lengthFor(n int) (int, error) {
+	l, err := d.uintN(n)
+	if err != nil {
+		return 0, err
+	}
+
+	if l > uint64(len(d.b)) {
+		// no length can exceed the data left, whatever its elements
+		return 0, errShort
+	}
+
+	return int(l), nil
+}
+
+// decodeAny reads the next value as the generic Go type encoding/json would use
+func (d *decoder) decodeAny() (interface{}, error) {
+	var v interface{}
+	err := d.decode(reflect.ValueOf(&v).Elem())
+	return v, err
+}
+
+func (d *decoder) decode(v reflect.Value) error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+
+	if c == 0xc0 {
+		d.i++
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	if v.Type() == timeType {
+		return d.decodeTime(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return fmt.Errorf("msgpack: cannot decode into %s", v.Type())
+		}
+		generic, err := d.decodeGeneric()
+		if err != nil {
+			return err
+		}
+		if generic == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(generic))
+		}
+		return nil
+	}
+
+	switch {
+	case c <= 0x7f || c >= 0xe0 || (c >= 0xcc && c <= 0xd3):
+		return d.decodeInteger(v)
+	case c == 0xc2 || c == 0xc3:
+		d.i++
+		if v.Kind() != reflect.Bool {
+			return typeError("bool", v)
+		}
+		v.SetBool(c == 0xc3)
+		return nil
+	case c == 0xca || c == 0xcb:
+		return d.decodeFloat(v)
+	case c >= 0xa0 && c <= 0xbf, c == 0xd9, c == 0xda, c == 0xdb, c == 0xc4, c == 0xc5, c == 0xc6:
+		return d.decodeRaw(v)
+	case c >= 0x90 && c <= 0x9f, c == 0xdc, c == 0xdd:
+		return d.decodeArray(v)
+	case c >= 0x80 && c <= 0x8f, c == 0xde, c == 0xdf:
+		return d.decodeMap(v)
+	}
+
+	return fmt.Errorf("msgpack: unsupported type code 0x%x", c)
+}
+
+func (d *decoder) decodeGeneric() (interface{}, error) {
+	c, err := d.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c == 0xc0:
+		d.i++
+		return nil, nil
+	case c == 0xc2 || c == 0xc3:
+		var b bool
+		err = d.decode(reflect.ValueOf(&b).Elem())
+		return b, err
+	case c <= 0x7f || (c >= 0xcc && c <= 0xcf):
+		var n uint64
+		err = d.decodeInteger(reflect.ValueOf(&n).Elem())
+		if err == nil && n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, err
+	case c >= 0xe0 || (c >= 0xd0 && c <= 0xd3):
+		var n int64
+		err = d.decodeInteger(reflect.ValueOf(&n).Elem())
+		return n, err
+	case c == 0xca || c == 0xcb:
+		var f float64
+		err = d.decodeFloat(reflect.ValueOf(&f).Elem())
+		return f, err
+	case c >= 0xa0 && c <= 0xbf, c == 0xd9, c == 0xda, c == 0xdb:
+		var s string
+		err = d.decodeRaw(reflect.ValueOf(&s).Elem())
+		return s, err
+	case c == 0xc4, c == 0xc5, c == 0xc6:
+		var b []byte
+		err = d.decodeRaw(reflect.ValueOf(&b).Elem())
+		return b, err
+	case c >= 0x90 && c <= 0x9f, c == 0xdc, c == 0xdd:
+		var a []interface{}
+		err = d.decodeArray(reflect.ValueOf(&a).Elem())
+		return a, err
+	case c >= 0x80 && c <= 0x8f, c == 0xde, c == 0xdf:
+		m := map[string]interface{}{}
+		err = d.decodeMap(reflect.ValueOf(&m).Elem())
+		return m, err
+	case c == 0xd6 || c == 0xd7 || c == 0xc7:
+		var t time.Time
+		err = d.decodeTime(reflect.ValueOf(&t).Elem())
+		return t, err
+	}
+
+	return nil, fmt.Errorf("msgpack: unsupported type code 0x%x", c)
+}
+
+func (d *decoder) decodeInteger(v reflect.Value) error {
+	c, _ := d.peek()
+	d.i++
+
+	var signed int64
+	var unsigned uint64
+	isSigned := false
+
+	switch {
+	case c <= 0x7f:
+		unsigned = uint64(c)
+	case c >= 0xe0:
+		signed, isSigned = int64(int8(c)), true
+	case c >= 0xcc && c <= 0xcf:
+		n, err := d.uintN(1 << (c - 0xcc))
+		if err != nil {
+			return err
+		}
+		unsigned = n
+	default:
+		size := 1 << (c - 0xd0)
+		n, err := d.uintN(size)
+		if err != nil {
+			return err
+		}
+		switch size {
+		case 1:
+			signed = int64(int8(n))
+		case 2:
+			signed = int64(int16(n))
+		case 4:
+			signed = int64(int32(n))
+		default:
+			signed = int64(n)
+		}
+		isSigned = true
+	}
+
+	if isSigned && signed >= 0 {
+		unsigned, isSigned = uint64(signed), false
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := signed
+		if !isSigned {
+			if unsigned > math.MaxInt64 {
+				return typeError("uint64", v)
+			}
+			n = int64(unsigned)
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("msgpack: %d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isSigned || v.OverflowUint(unsigned) {
+			return fmt.Errorf("msgpack: integer overflows %s", v.Type())
+		}
+		v.SetUint(unsigned)
+	case reflect.Float32, reflect.Float64:
+		if isSigned {
+			v.SetFloat(float64(signed))
+		} else {
+			v.SetFloat(float64(unsigned))
+		}
+	default:
+		return typeError("integer", v)
+	}
+
+	return nil
+}
+
+func (d *decoder) decodeFloat(v reflect.Value) error {
+	c, _ := d.peek()
+	d.i++
+
+	var f float64
+	if c == 0xca {
+		n, err := d.uintN(4)
+		if err != nil {
+			return err
+		}
+		f = float64(math.Float32frombits(uint32(n)))
+	} else {
+		n, err := d.uintN(8)
+		if err != nil {
+			return err
+		}
+		f = math.Float64frombits(n)
+	}
+
+	if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+		return typeError("float", v)
+	}
+
+	v.SetFloat(f)
+	return nil
+}
+
+// decodeRaw reads a str or bin into a string or []byte
+func (d *decoder) decodeRaw(v reflect.Value) error {
+	c, _ := d.peek()
+	d.i++
+
+	var n int
+	var err error
+	switch {
+	case c >= 0xa0 && c <= 0xbf:
+		n = int(c & 0x1f)
+	case c == 0xd9 || c == 0xc4:
+		n, err = d.lengthFor(1)
+	case c == 0xda || c == 0xc5:
+		n, err = d.lengthFor(2)
+	default:
+		n, err = d.lengthFor(4)
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := d.next(n)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(b))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte(nil), b...))
+	default:
+		return typeError("string", v)
+	}
+
+	return nil
+}
+
+func (d *decoder) collectionLength(fix, code16, code32 byte) (int, error) {
+	c, _ := d.peek()
+	d.i++
+
+	switch c {
+	case code16:
+		return d.lengthFor(2)
+	case code32:
+		return d.lengthFor(4)
+	}
+
+	return int(c - fix), nil
+}
+
+func (d *decoder) decodeArray(v reflect.Value) error {
+	n, err := d.collectionLength(0x90, 0xdc, 0xdd)
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	case reflect.Array:
+		if n > v.Len() {
+			return fmt.Errorf("msgpack: %d elements overflow %s", n, v.Type())
+		}
+		v.Set(reflect.Zero(v.Type()))
+	default:
+		return typeError("array", v)
+	}
+
+	for i := 0; i < n; i++ {
+		err = d.decode(v.Index(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *decoder) decodeMap(v reflect.Value) error {
+	n, err := d.collectionLength(0x80, 0xde, 0xdf)
+	if err != nil {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), n))
+		}
+
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			err = d.decode(key)
+			if err != nil {
+				return err
+			}
+
+			val := reflect.New(v.Type().Elem()).Elem()
+			err = d.decode(val)
+			if err != nil {
+				return err
+			}
+
+			v.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		for i := 0; i < n; i++ {
+			var key string
+			err = d.decode(reflect.ValueOf(&key).Elem())
+			if err != nil {
+				return err
+			}
+
+			target := fieldNamed(fields, key)
+			if target == nil {
+				// unknown fields are skipped, like encoding/json
+				_, err = d.decodeAny()
+			} else {
+				err = d.decode(fieldByIndexAlloc(v, target.index))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return typeError("map", v)
+	}
+
+	return nil
+}
+
+func fieldNamed(fields []field, name string) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+
+	return nil
+}
+
+// fieldByIndexAlloc is FieldByIndex, allocating embedded struct pointers
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+
+	return v
+}
+
+func (d *decoder) decodeTime(v reflect.Value) error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	d.i++
+
+	var size int
+	switch c {
+	case 0xd6:
+		size = 4
+	case 0xd7:
+		size = 8
+	case 0xc7:
+		l, err := d.uintN(1)
+		if err != nil {
+			return err
+		}
+		size = int(l)
+	default:
+		return typeError("timestamp", v)
+	}
+
+	ext, err := d.next(1)
+	if err != nil {
+		return err
+	}
+	if int8(ext[0]) != extTimestamp {
+		return fmt.Errorf("msgpack: unsupported extension %d", int8(ext[0]))
+	}
+
+	b, err := d.next(size)
+	if err != nil {
+		return err
+	}
+
+	var t time.Time
+	switch size {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(b)), 0)
+	case 8:
+		n := binary.BigEndian.Uint64(b)
+		t = time.Unix(int64(n&0x3ffffffff), int64(n>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b)))
+	default:
+		return fmt.Errorf("msgpack: invalid timestamp length %d", size)
+	}
+
+	if v.Type() != timeType {
+		return typeError("timestamp", v)
+	}
+
+	v.Set(reflect.ValueOf(t.UTC()))
+	return nil
+}
+
+func typeError(what string, v reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot decode %s into %s", what, v.Type())
+}
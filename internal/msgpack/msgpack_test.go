@@ -0,0 +1,127 @@
+package msgpack
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Flag bool `msgpack:"flag"`
+}
+
+type Embedded struct {
+	Kind string `json:"kind"`
+}
+
+type record struct {
+	Embedded
+	ID      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Score   float64           `json:"score"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]int    `json:"attrs"`
+	Blob    []byte            `json:"blob"`
+	Inner   *inner            `json:"inner"`
+	When    time.Time         `json:"when"`
+	Skipped string            `json:"-"`
+	Empty   string            `json:"empty,omitempty"`
+	Any     interface{}       `json:"any"`
+	Nested  map[string][]uint `json:"nested"`
+}
+
+func TestKnownEncodings(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name   string
+		Value  interface{}
+		Expect []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"true", true, []byte{0xc3}},
+		{"fixint", 7, []byte{0x07}},
+		{"negative-fixint", -3, []byte{0xfd}},
+		{"uint8", 200, []byte{0xcc, 200}},
+		{"int16", -300, []byte{0xd1, 0xfe, 0xd4}},
+		{"uint32", 70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{"fixstr", "hi", []byte{0xa2, 'h', 'i'}},
+		{"bin", []byte{1, 2}, []byte{0xc4, 2, 1, 2}},
+		{"fixarray", []int{1, 2}, []byte{0x92, 1, 2}},
+		{"sorted-map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 1, 0xa1, 'b', 2}},
+		{"struct", inner{Flag: true}, []byte{0x81, 0xa4, 'f', 'l', 'a', 'g', 0xc3}},
+		{"float64", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, c := range cases {
+		got, err := Marshal(c.Value)
+		if err != nil {
+			t.Fatalf("case[%s] %s", c.Name, err)
+		}
+
+		if !bytes.Equal(got, c.Expect) {
+			t.Errorf("case[%s] expected % x got % x", c.Name, c.Expect, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := record{
+		Embedded: Embedded{Kind: "pet"},
+		ID:       math.MinInt64,
+		Name:     string(bytes.Repeat([]byte("x"), 300)),
+		Score:    -2.25,
+		Tags:     []string{"a", "b"},
+		Attrs:    map[string]int{"legs": 4},
+		Blob:     []byte{0, 1, 2},
+		Inner:    &inner{Flag: true},
+		When:     time.Date(2024, 2, 29, 12, 30, 0, 123456789, time.UTC),
+		Skipped:  "never",
+		Any:      map[string]interface{}{"n": int64(-1), "list": []interface{}{"x", true, nil}},
+		Nested:   map[string][]uint{"big": {math.MaxUint32 + 1}},
+	}
+
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out record
+	err = Unmarshal(b, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in.Skipped = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected %+v got %+v", in, out)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name   string
+		Data   []byte
+		Target interface{}
+	}{
+		{"short", []byte{0xa5, 'h'}, new(string)},
+		{"wrong-type", []byte{0xa1, 'h'}, new(int)},
+		{"overflow", []byte{0xcd, 0x01, 0x00}, new(int8)},
+		{"negative-unsigned", []byte{0xff}, new(uint)},
+		{"trailing", []byte{0x01, 0x02}, new(int)},
+		{"huge-length", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}, new([]int)},
+	}
+
+	for _, c := range cases {
+		err := Unmarshal(c.Data, c.Target)
+		if err == nil {
+			t.Errorf("case[%s] expected an error", c.Name)
+		}
+	}
+}
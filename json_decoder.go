@@ -29,11 +29,31 @@ func NewJSONDecoder() *JSONDecoder {
 }
 
 func (jsd *JSONDecoder) ValidateType(fn interface{}) error {
-	_, _, _, err := jsd.inputsAtIndices(fn)
-	return err
+	_, _, decodeIdx, err := jsd.inputsAtIndices(fn)
+	if err != nil {
+		return err
+	}
+
+	if decodeIdx != uIdx {
+		t := reflect.TypeOf(fn).In(decodeIdx)
+		err = checkBoundFields(t)
+		if err != nil {
+			return err
+		}
+
+		return checkValidationTags(t)
+	}
+
+	return nil
 }
 
 func (jsd *JSONDecoder) inputsAtIndices(fn interface{}) (int, int, int, error) {
+	return bodyInputIndices(fn, jsd.isJSONDecodable)
+}
+
+// bodyInputIndices finds the context, Header and body arguments of fn, where
+// decodable reports which types the body can be decoded into
+func bodyInputIndices(fn interface{}, decodable func(reflect.Type) bool) (int, int, int, error) {
 	reflectFn := reflect.ValueOf(fn)
 
 	inputArgCount := reflectFn.Type().NumIn()
@@ -72,7 +92,7 @@ func (jsd *JSONDecoder) inputsAtIndices(fn interface{}) (int, int, int, error) {
 			foundHeaderIdx = i
 		}
 
-		if jsd.isJSONDecodable(typeAtInputIdx) {
+		if decodable(typeAtInputIdx) {
 			if foundDecodeTargetIdx != uIdx {
 				return uIdx, uIdx, uIdx, ErrDuplicateType
 			}
@@ -109,26 +129,27 @@ func (jsd *JSONDecoder) isJSONDecodable(t reflect.Type) bool {
 // Decode returns the reflect values needed to call the fn
 // from the *http.Request
 func (jsd *JSONDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
-	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
-		return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	ctxIdx, hdrIdx, decodeIdx, err := jsd.inputsAtIndices(fn)
+	if err != nil {
+		return nil, err
 	}
 
-	if r.Method == http.MethodGet {
-		return nil, ErrorWithCode{Err: errors.New("GET requests prohibited for this endpoint"), StatusCode: http.StatusMethodNotAllowed}
-	}
+	fnReflectType := reflect.ValueOf(fn).Type()
 
-	limitedReader := io.LimitReader(r.Body, int64(jsd.MaxBytesToRead))
-	dec := json.NewDecoder(limitedReader)
-	if jsd.DisallowUnknownFields {
-		dec.DisallowUnknownFields()
+	var bound []boundField
+	if decodeIdx != uIdx {
+		bound = boundFields(fnReflectType.In(decodeIdx))
 	}
 
-	ctxIdx, hdrIdx, decodeIdx, err := jsd.inputsAtIndices(fn)
-	if err != nil {
-		return nil, err
+	// GET requests to routes with bound fields have no body to decode
+	fromRequest := len(bound) > 0 && isQueryMethod(r.Method)
+	if !fromRequest {
+		err = jsd.checkRequest(r)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	fnReflectType := reflect.ValueOf(fn).Type()
 	callValues := make([]reflect.Value, fnReflectType.NumIn())
 
 	if ctxIdx != uIdx {
@@ -137,13 +158,7 @@ func (jsd *JSONDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value
 
 	// add the httpz.Header to the call args
 	if hdrIdx != uIdx {
-		header := make(Header)
-		for k := range r.Header {
-			hVal := r.Header.Get(k)
-			header[http.CanonicalHeaderKey(k)] = hVal
-		}
-
-		callValues[hdrIdx] = reflect.ValueOf(header)
+		callValues[hdrIdx] = reflect.ValueOf(requestHeader(r))
 	}
 
 	// JSON decode and add to call values
@@ -160,11 +175,22 @@ func (jsd *JSONDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value
 		}
 
 		oi := object.Interface()
-		err = dec.Decode(&oi)
-		if err != nil {
-			if err == io.ErrUnexpectedEOF {
-				return nil, ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", jsd.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+		if !fromRequest {
+			err = jsd.decodeBody(r, &oi)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(bound) > 0 && oi != nil {
+			err = bindRequest(r, reflect.ValueOf(oi), bound)
+			if err != nil {
+				return nil, err
 			}
+		}
+
+		err = validateValue(object)
+		if err != nil {
 			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
 		}
 
@@ -178,3 +204,46 @@ func (jsd *JSONDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value
 
 	return callValues, nil
 }
+
+// requestHeader flattens the request headers to their first values
+func requestHeader(r *http.Request) Header {
+	header := make(Header)
+	for k := range r.Header {
+		hVal := r.Header.Get(k)
+		header[http.CanonicalHeaderKey(k)] = hVal
+	}
+
+	return header
+}
+
+// checkRequest rejects requests the JSONDecoder can never decode
+func (jsd *JSONDecoder) checkRequest(r *http.Request) error {
+	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		return ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	if isQueryMethod(r.Method) {
+		return ErrorWithCode{Err: errors.New("GET requests prohibited for this endpoint"), StatusCode: http.StatusMethodNotAllowed}
+	}
+
+	return nil
+}
+
+// decodeBody reads the request body into target, up to MaxBytesToRead
+func (jsd *JSONDecoder) decodeBody(r *http.Request, target interface{}) error {
+	limitedReader := io.LimitReader(r.Body, int64(jsd.MaxBytesToRead))
+	dec := json.NewDecoder(limitedReader)
+	if jsd.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(target)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", jsd.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+		}
+		return ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+	}
+
+	return nil
+}
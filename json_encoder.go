@@ -14,6 +14,11 @@ func (jse *JSONEncoder) ValidateType(fn interface{}) error {
 }
 
 func (jse *JSONEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	// Text asks for plain text, strings stay JSON strings
+	if text, ok := value.(Text); ok {
+		return TextEncoder{}.Encode(text, hw)
+	}
+
 	hw("Content-Type", "application/json")
 
 	var b bytes.Buffer
@@ -0,0 +1,183 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A RouteEvent describes a route being registered or deregistered. Star
+// routes, which serve every method, have the Method "*"
+type RouteEvent struct {
+	Method  string
+	Pattern string
+	Tags    []string
+}
+
+// A ServerEvent describes a Server starting or beginning to drain
+type ServerEvent struct {
+	Addr string
+	TLS  bool
+}
+
+// A LifecycleSubscriber is told about route and server lifecycle events, so
+// tooling such as service registries, API gateways or docs pipelines can
+// react to them. Events are delivered synchronously, in order
+type LifecycleSubscriber interface {
+	RouteRegistered(e RouteEvent)
+	RouteDeregistered(e RouteEvent)
+	// ServerStarted is sent just before a Server starts serving
+	ServerStarted(e ServerEvent)
+	// DrainBegun is sent when a Server's Shutdown is called, before its
+	// pre-shutdown hooks run and in-flight requests drain
+	DrainBegun(e ServerEvent)
+}
+
+// LifecycleFuncs is a LifecycleSubscriber calling whichever funcs are set
+type LifecycleFuncs struct {
+	OnRouteRegistered   func(e RouteEvent)
+	OnRouteDeregistered func(e RouteEvent)
+	OnServerStarted     func(e ServerEvent)
+	OnDrainBegun        func(e ServerEvent)
+}
+
+func (lf LifecycleFuncs) RouteRegistered(e RouteEvent) {
+	if lf.OnRouteRegistered != nil {
+		lf.OnRouteRegistered(e)
+	}
+}
+
+func (lf LifecycleFuncs) RouteDeregistered(e RouteEvent) {
+	if lf.OnRouteDeregistered != nil {
+		lf.OnRouteDeregistered(e)
+	}
+}
+
+func (lf LifecycleFuncs) ServerStarted(e ServerEvent) {
+	if lf.OnServerStarted != nil {
+		lf.OnServerStarted(e)
+	}
+}
+
+func (lf LifecycleFuncs) DrainBegun(e ServerEvent) {
+	if lf.OnDrainBegun != nil {
+		lf.OnDrainBegun(e)
+	}
+}
+
+// WithLifecycleSubscriber subscribes s to the router's lifecycle events, see
+// Router.Subscribe
+func WithLifecycleSubscriber(s LifecycleSubscriber) func(r *Router) error {
+	return func(r *Router) error {
+		if s == nil {
+			return errors.New("autohttp: WithLifecycleSubscriber needs a subscriber")
+		}
+
+		r.Subscribe(s)
+		return nil
+	}
+}
+
+// Subscribe sends s the router's lifecycle events. Routes registered before
+// s subscribed are sent first, sorted by pattern and method
+func (r *Router) Subscribe(s LifecycleSubscriber) {
+	r.lifecycleMu.Lock()
+	defer r.lifecycleMu.Unlock()
+
+	var existing []RouteEvent
+	for _, sr := range r.starRoutes {
+		existing = append(existing, r.routeEvent("*", sr.pattern))
+	}
+	for method, routes := range r.Routes {
+		for pattern := range routes {
+			existing = append(existing, r.routeEvent(method, pattern))
+		}
+	}
+	sort.Slice(existing, func(i, j int) bool {
+		if existing[i].Pattern != existing[j].Pattern {
+			return existing[i].Pattern < existing[j].Pattern
+		}
+		return existing[i].Method < existing[j].Method
+	})
+
+	for _, e := range existing {
+		s.RouteRegistered(e)
+	}
+
+	r.subscribers = append(r.subscribers, s)
+}
+
+// Deregister removes the route registered at method and path. Like Register,
+// it must not be called while the router is serving
+func (r *Router) Deregister(method, path string) error {
+	method = strings.ToUpper(method)
+	for i, sr := range r.starRoutes {
+		if sr.pattern == path {
+			r.starRoutes = append(r.starRoutes[:i:i], r.starRoutes[i+1:]...)
+			delete(r.warmups, "* "+path)
+			r.emitLifecycle(func(s LifecycleSubscriber) { s.RouteDeregistered(RouteEvent{Method: "*", Pattern: path}) })
+			return nil
+		}
+	}
+
+	routes := r.Routes[method]
+	if _, ok := routes[path]; !ok {
+		return fmt.Errorf("autohttp: %s %s isn't registered", method, path)
+	}
+
+	e := r.routeEvent(method, path)
+	delete(routes, path)
+
+	// the tree is rebuilt rather than pruned, routes are rarely removed
+	tree := newRouteNode()
+	for pattern, handler := range routes {
+		err := tree.insert(pattern, handler)
+		if err != nil {
+			return err
+		}
+	}
+	r.trees[method] = tree
+
+	if len(routes) == 0 {
+		delete(r.Routes, method)
+		delete(r.trees, method)
+	}
+
+	key := method + " " + path
+	delete(r.routeTags, key)
+	delete(r.accessLogRoutes, key)
+	delete(r.corsRoutes, key)
+	delete(r.warmups, key)
+
+	docs := r.docs[:0]
+	for _, d := range r.docs {
+		if d.method != method || d.pattern != path {
+			docs = append(docs, d)
+		}
+	}
+	r.docs = docs
+
+	r.emitLifecycle(func(s LifecycleSubscriber) { s.RouteDeregistered(e) })
+	return nil
+}
+
+func (r *Router) routeEvent(method, pattern string) RouteEvent {
+	return RouteEvent{Method: method, Pattern: pattern, Tags: r.routeTags[method+" "+pattern]}
+}
+
+// emitLifecycle sends an event to every subscriber
+func (r *Router) emitLifecycle(send func(s LifecycleSubscriber)) {
+	r.lifecycleMu.Lock()
+	subscribers := append([]LifecycleSubscriber(nil), r.subscribers...)
+	r.lifecycleMu.Unlock()
+
+	for _, s := range subscribers {
+		send(s)
+	}
+}
+
+// serverEvent describes s, listening on addr
+func (s *Server) serverEvent(addr string) ServerEvent {
+	return ServerEvent{Addr: addr, TLS: s.httpServer != nil}
+}
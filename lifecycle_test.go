@@ -0,0 +1,154 @@
+package autohttp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+// lifecycleLog records events as strings, in order
+type lifecycleLog []string
+
+func (ll *lifecycleLog) RouteRegistered(e RouteEvent) {
+	*ll = append(*ll, fmt.Sprintf("registered %s %s %v", e.Method, e.Pattern, e.Tags))
+}
+
+func (ll *lifecycleLog) RouteDeregistered(e RouteEvent) {
+	*ll = append(*ll, fmt.Sprintf("deregistered %s %s %v", e.Method, e.Pattern, e.Tags))
+}
+
+func (ll *lifecycleLog) ServerStarted(e ServerEvent) {
+	*ll = append(*ll, "started")
+}
+
+func (ll *lifecycleLog) DrainBegun(e ServerEvent) {
+	*ll = append(*ll, "draining")
+}
+
+func TestLifecycleRoutes(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	err = r.Register(http.MethodGet, "/pets", ok, nil, WithTags("public"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events lifecycleLog
+	r.Subscribe(&events)
+
+	err = r.Register(http.MethodGet, "/pets/{id}", ok, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/files/*", ok, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Deregister(http.MethodGet, "/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Deregister(http.MethodGet, "/files/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Deregister(http.MethodGet, "/pets"); err == nil {
+		t.Error("expected deregistering a missing route to fail")
+	}
+
+	expected := lifecycleLog{
+		"registered GET /pets [public]",
+		"registered GET /pets/{id} []",
+		"registered * /files/* []",
+		"deregistered GET /pets [public]",
+		"deregistered * /files/* []",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %q, got %q", expected, events)
+	}
+
+	cases := []struct {
+		name string
+		path string
+		code int
+	}{
+		{name: "deregistered", path: "/pets", code: http.StatusNotFound},
+		{name: "deregistered star route", path: "/files/a.txt", code: http.StatusNotFound},
+		{name: "still registered", path: "/pets/1", code: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+	}
+}
+
+func TestLifecycleServer(t *testing.T) {
+	var events lifecycleLog
+	addrs := make(chan string, 1)
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithLifecycleSubscriber(&events),
+		WithLifecycleSubscriber(LifecycleFuncs{OnServerStarted: func(e ServerEvent) { addrs <- e.Addr }}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	if addr := <-addrs; addr != l.Addr().String() {
+		t.Errorf("expected the listener address %s, got %s", l.Addr(), addr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(events, lifecycleLog{"started", "draining"}) {
+		t.Errorf("unexpected events %q", events)
+	}
+}
@@ -0,0 +1,96 @@
+package autohttp
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fortytw2/lounge"
+)
+
+// A LogLevel is the least severe level a Router logs
+type LogLevel int32
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogError:
+		return "error"
+	}
+
+	return fmt.Sprintf("LogLevel(%d)", int32(l))
+}
+
+// ParseLogLevel reads debug, info or error
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LogDebug, nil
+	case "info":
+		return LogInfo, nil
+	case "error":
+		return LogError, nil
+	}
+
+	return 0, fmt.Errorf("autohttp: unknown log level %q", s)
+}
+
+// WithLogLevel drops log lines below level. It can only quiet the router's
+// lounge.Log, debug lines still need the log itself to have debug enabled
+func WithLogLevel(level LogLevel) func(r *Router) error {
+	return func(r *Router) error {
+		r.logLevel.set(level)
+		return nil
+	}
+}
+
+// newLevelLog wraps log in a levelLog passing every line. Routers always
+// log through one, so a level set while serving reaches every handler
+func newLevelLog(log lounge.Log) *levelLog {
+	return &levelLog{next: log, level: new(int32)}
+}
+
+// levelLog filters a lounge.Log by a level that can be changed while serving
+type levelLog struct {
+	next  lounge.Log
+	level *int32
+}
+
+func (ll *levelLog) set(level LogLevel) {
+	atomic.StoreInt32(ll.level, int32(level))
+}
+
+func (ll *levelLog) enabled(level LogLevel) bool {
+	return LogLevel(atomic.LoadInt32(ll.level)) <= level
+}
+
+func (ll *levelLog) With(pairs map[string]string) lounge.Log {
+	return &levelLog{next: ll.next.With(pairs), level: ll.level}
+}
+
+func (ll *levelLog) Debugf(format string, args ...interface{}) {
+	if ll.enabled(LogDebug) {
+		ll.next.Debugf(format, args...)
+	}
+}
+
+func (ll *levelLog) Infof(format string, args ...interface{}) {
+	if ll.enabled(LogInfo) {
+		ll.next.Infof(format, args...)
+	}
+}
+
+func (ll *levelLog) Errorf(format string, args ...interface{}) {
+	if ll.enabled(LogError) {
+		ll.next.Errorf(format, args...)
+	}
+}
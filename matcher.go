@@ -0,0 +1,62 @@
+package autohttp
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A Matcher plugs custom matching logic, such as matrix params or
+// tenant-prefixed paths, into the router for the paths under a mount
+type Matcher interface {
+	// Match returns the handler for req, or false to let the router
+	// carry on matching routes as usual
+	Match(req *http.Request) (http.Handler, bool)
+}
+
+// MatcherFunc adapts a function to the Matcher interface
+type MatcherFunc func(req *http.Request) (http.Handler, bool)
+
+func (mf MatcherFunc) Match(req *http.Request) (http.Handler, bool) {
+	return mf(req)
+}
+
+type matcherMount struct {
+	prefix  string
+	matcher Matcher
+}
+
+// Mount consults m for every request under prefix, ahead of the registered
+// routes. Longer prefixes are consulted first
+func (r *Router) Mount(prefix string, m Matcher) {
+	r.matcherMounts = append(r.matcherMounts, &matcherMount{
+		prefix:  strings.TrimSuffix(prefix, "/"),
+		matcher: m,
+	})
+
+	sort.SliceStable(r.matcherMounts, func(i, j int) bool {
+		return len(r.matcherMounts[i].prefix) > len(r.matcherMounts[j].prefix)
+	})
+}
+
+// matchMount returns the handler of the first mounted Matcher to match req
+func (r *Router) matchMount(req *http.Request) (http.Handler, bool) {
+	for _, mm := range r.matcherMounts {
+		if !hasPathPrefix(req.URL.Path, mm.prefix) {
+			continue
+		}
+
+		h, ok := mm.matcher.Match(req)
+		if ok {
+			return h, true
+		}
+	}
+
+	return nil, false
+}
+
+// hasPathPrefix reports whether p is prefix, or is below it. Prefix must not have a
+// trailing slash, the empty prefix matches every path
+func hasPathPrefix(p, prefix string) bool {
+	return prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
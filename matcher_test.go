@@ -0,0 +1,64 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestMountMatcher(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// matches /t/{tenant}/dashboard
+	r.Mount("/t", MatcherFunc(func(req *http.Request) (http.Handler, bool) {
+		parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/t/"), "/")
+		if len(parts) != 2 || parts[1] != "dashboard" {
+			return nil, false
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("dashboard for " + parts[0]))
+		}), true
+	}))
+
+	err = r.Register(http.MethodGet, "/t/settings", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("settings"))
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Path         string
+		ExpectStatus int
+		ExpectBody   string
+	}{
+		{"matched", "/t/acme/dashboard", http.StatusOK, "dashboard for acme"},
+		{"falls-back-to-routes", "/t/settings", http.StatusOK, "settings"},
+		{"unmatched", "/t/acme/unknown", http.StatusNotFound, ""},
+		{"outside-mount", "/tenants/acme/dashboard", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("expected %d got %d", c.ExpectStatus, w.Code)
+			}
+
+			if w.Body.String() != c.ExpectBody {
+				t.Errorf("expected %q got %q", c.ExpectBody, w.Body.String())
+			}
+		})
+	}
+}
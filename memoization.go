@@ -0,0 +1,210 @@
+package autohttp
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMemoizedResults bounds the number of results a memoized route keeps
+const DefaultMemoizedResults = 256
+
+// WithMemoization remembers the results of a pure handler for ttl, keyed on
+// its path parameters and decoded input, so requests decoding to the same
+// input skip the call.
+// It suits lookup and config endpoints whose results rarely change. Unlike
+// WithResponseCache results are kept before encoding, so negotiation and
+// response transformers still apply, and the handler's context and headers
+// are not part of the key. Up to size results are kept, or
+// DefaultMemoizedResults if size isn't positive. Errors aren't remembered,
+// and results are shared between requests so must not be modified
+func WithMemoization(ttl time.Duration, size int) RouteOption {
+	return func(rc *routeConfig) error {
+		if ttl <= 0 {
+			return errors.New("autohttp: WithMemoization needs a positive ttl")
+		}
+
+		if size <= 0 {
+			size = DefaultMemoizedResults
+		}
+
+		rc.memo = newMemo(ttl, size)
+		return nil
+	}
+}
+
+type memoEntry struct {
+	key     interface{}
+	result  interface{}
+	expires time.Time
+}
+
+// memo is a size bounded LRU of handler results keyed on their input
+type memo struct {
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[interface{}]*list.Element
+	now     func() time.Time
+}
+
+func newMemo(ttl time.Duration, size int) *memo {
+	return &memo{
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[interface{}]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// checkMemoization rejects memoizing a route whose input can't be a map key
+// by value
+func checkMemoization(fn interface{}) error {
+	if _, ok := fn.(http.Handler); ok {
+		return errors.New("memoization needs a function route, not an http.Handler")
+	}
+
+	if tc, ok := fn.(typedCaller); ok {
+		fn = tc.handlerFunc()
+	}
+
+	if fn == nil {
+		return errors.New("memoization needs a handler to call")
+	}
+
+	if isStreamFunc(fn) {
+		return errors.New("streamed responses can't be memoized")
+	}
+
+	fnType := reflect.TypeOf(fn)
+	for i := 0; i < fnType.NumIn(); i++ {
+		in := fnType.In(i)
+		if isContextType(in) || isHeaderType(in) {
+			continue
+		}
+
+		if in.Kind() == reflect.Ptr {
+			in = in.Elem()
+		}
+
+		if !memoizable(in) {
+			return fmt.Errorf("memoization needs an input of plain comparable values, %s isn't", fnType.In(i))
+		}
+	}
+
+	return nil
+}
+
+// memoizable reports whether values of t compare equal exactly when their
+// contents do
+func memoizable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return memoizable(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !memoizable(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// memoKey is a memo key, the route's path parameters along with the input
+type memoKey struct {
+	params string
+	input  interface{}
+}
+
+// key derives the memo key from the path parameters in ctx and the decoded
+// input, pointers keyed on what they point to
+func (m *memo) key(ctx context.Context, input interface{}) interface{} {
+	var params strings.Builder
+	captured, _ := ctx.Value(paramsCtxKey{}).([]Param)
+	for _, p := range captured {
+		params.WriteString(strconv.Quote(p.Key))
+		params.WriteString(strconv.Quote(p.Value))
+	}
+
+	if input != nil {
+		v := reflect.ValueOf(input)
+		if v.Kind() == reflect.Ptr {
+			input = nil
+			if !v.IsNil() {
+				input = v.Elem().Interface()
+			}
+		}
+	}
+
+	return memoKey{params: params.String(), input: input}
+}
+
+// callKey derives the memo key from the call values of a reflected handler
+func (m *memo) callKey(ctx context.Context, callValues []reflect.Value) interface{} {
+	for _, cv := range callValues {
+		if !cv.IsValid() || isContextType(cv.Type()) || isHeaderType(cv.Type()) {
+			continue
+		}
+
+		return m.key(ctx, cv.Interface())
+	}
+
+	return m.key(ctx, nil)
+}
+
+func (m *memo) get(key interface{}) (interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*memoEntry)
+	if !m.now().Before(entry.expires) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		return nil, false
+	}
+
+	m.order.MoveToFront(el)
+	return entry.result, true
+}
+
+func (m *memo) put(key, result interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := &memoEntry{key: key, result: result, expires: m.now().Add(m.ttl)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = entry
+		m.order.MoveToFront(el)
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(entry)
+	if m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoEntry).key)
+	}
+}
@@ -0,0 +1,194 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+type memoLookup struct {
+	Region string
+	Tier   int
+}
+
+func TestMemoization(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err = r.Register(http.MethodPost, "/limits", func(ctx context.Context, in *memoLookup) (map[string]int, error) {
+		calls++
+		if in.Region == "fail" {
+			return nil, errors.New("no such region")
+		}
+		return map[string]int{"limit": in.Tier * 10}, nil
+	}, nil, WithMemoization(time.Minute, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Body        string
+		ExpectCode  int
+		ExpectCalls int
+	}{
+		{"miss", `{"Region": "eu", "Tier": 1}`, http.StatusOK, 1},
+		{"hit", `{"Region": "eu", "Tier": 1}`, http.StatusOK, 1},
+		{"same input spelled differently", `{"Tier": 1, "Region": "eu"}`, http.StatusOK, 1},
+		{"different input", `{"Region": "us", "Tier": 1}`, http.StatusOK, 2},
+		{"errors aren't remembered", `{"Region": "fail"}`, http.StatusInternalServerError, 3},
+		{"errors are called again", `{"Region": "fail"}`, http.StatusInternalServerError, 4},
+		{"evicts the least recently used", `{"Region": "ap", "Tier": 1}`, http.StatusOK, 5},
+		{"evicted", `{"Region": "eu", "Tier": 1}`, http.StatusOK, 6},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/limits", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected code %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if calls != c.ExpectCalls {
+			t.Errorf("case[%s] expected %d calls got %d", c.Name, c.ExpectCalls, calls)
+		}
+	}
+}
+
+func TestMemoExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m := newMemo(time.Minute, 2)
+	m.now = func() time.Time { return now }
+
+	m.put(m.key(context.Background(), &memoLookup{Region: "eu"}), "a")
+	if result, ok := m.get(m.key(context.Background(), memoLookup{Region: "eu"})); !ok || result != "a" {
+		t.Fatal("expected pointers and values to share an entry")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := m.get(m.key(context.Background(), memoLookup{Region: "eu"})); ok {
+		t.Fatal("expected the entry to expire")
+	}
+}
+
+func TestMemoizationTyped(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err = RegisterTyped(r, http.MethodPost, "/limits", func(ctx context.Context, in memoLookup) (map[string]int, error) {
+		calls++
+		return map[string]int{"limit": in.Tier * 10}, nil
+	}, WithMemoization(time.Minute, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/limits", strings.NewReader(`{"Region": "eu", "Tier": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if !strings.Contains(w.Body.String(), `"limit":20`) {
+			t.Errorf("request %d got unexpected body %q", i, w.Body.String())
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+}
+
+func TestMemoizationPathParams(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err = r.Register(http.MethodGet, "/items/{id}", func(ctx context.Context) (map[string]string, error) {
+		calls++
+		return map[string]string{"id": PathParam(ctx, "id")}, nil
+	}, nil, WithDecoder(NewFormDecoder()), WithMemoization(time.Minute, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		ExpectBody  string
+		ExpectCalls int
+	}{
+		{"miss", "/items/1", `"id":"1"`, 1},
+		{"hit", "/items/1", `"id":"1"`, 1},
+		{"different param", "/items/2", `"id":"2"`, 2},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) {
+			t.Errorf("case[%s] expected %s in %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		if calls != c.ExpectCalls {
+			t.Errorf("case[%s] expected %d calls got %d", c.Name, c.ExpectCalls, calls)
+		}
+	}
+}
+
+func TestMemoizationRejections(t *testing.T) {
+	t.Parallel()
+
+	type withSlice struct {
+		IDs []int
+	}
+
+	cases := []struct {
+		Name string
+		Fn   interface{}
+		TTL  time.Duration
+	}{
+		{"http.Handler", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), time.Minute},
+		{"slice input", func(ctx context.Context, in withSlice) (string, error) { return "", nil }, time.Minute},
+		{"no ttl", func(ctx context.Context, in memoLookup) (string, error) { return "", nil }, 0},
+	}
+
+	for _, c := range cases {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.Register(http.MethodPost, "/memo", c.Fn, nil, WithMemoization(c.TTL, 0))
+		if err == nil {
+			t.Errorf("case[%s] expected an error", c.Name)
+		}
+	}
+}
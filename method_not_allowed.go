@@ -0,0 +1,51 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// A MethodNotAllowedHandler writes the response to a request for a path only
+// registered under other methods. The Allow header listing them is already set
+type MethodNotAllowedHandler func(w http.ResponseWriter, req *http.Request, allowed []string)
+
+// WithMethodNotAllowedHandler replaces the empty 405 response written for
+// requests to paths registered under other methods
+func WithMethodNotAllowedHandler(h MethodNotAllowedHandler) func(r *Router) error {
+	return func(r *Router) error {
+		if h == nil {
+			return errors.New("autohttp: WithMethodNotAllowedHandler needs a handler")
+		}
+
+		r.methodNotAllowed = h
+		return nil
+	}
+}
+
+// serveMethodNotAllowed serves a 405 if req's path is routed under other methods
+func (r *Router) serveMethodNotAllowed(w http.ResponseWriter, req *http.Request) bool {
+	allowed, _, _ := r.methodsFor(req.URL.Path)
+	if len(allowed) == 0 {
+		return false
+	}
+
+	if r.cors != nil || len(r.corsRoutes) > 0 {
+		// OPTIONS is only answered with a CORS policy in place
+		allowed = append(allowed, http.MethodOptions)
+	}
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if r.methodNotAllowed != nil && r.compression != nil {
+		bw := &bufferedErrorWriter{w: w}
+		r.methodNotAllowed(bw, req, allowed)
+		bw.flush(req, r.compression)
+	} else if r.methodNotAllowed != nil {
+		r.methodNotAllowed(w, req, allowed)
+	} else {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+	r.cleanLeftovers(req)
+	return true
+}
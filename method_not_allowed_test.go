@@ -0,0 +1,103 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		err = r.Register(method, "/widgets/{id}", ok, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Register(http.MethodPost, "/widgets", ok, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Gone("/legacy", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		allow  string
+	}{
+		{name: "registered", method: http.MethodPut, path: "/widgets/1", code: http.StatusOK},
+		{name: "other methods on a param route", method: http.MethodDelete, path: "/widgets/1", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, PUT"},
+		{name: "method without any routes", method: http.MethodPatch, path: "/widgets", code: http.StatusMethodNotAllowed, allow: "POST"},
+		{name: "get on a post route", method: http.MethodGet, path: "/widgets", code: http.StatusMethodNotAllowed, allow: "POST"},
+		{name: "unknown path", method: http.MethodGet, path: "/gadgets", code: http.StatusNotFound},
+		{name: "unknown path and method", method: http.MethodPatch, path: "/gadgets", code: http.StatusNotFound},
+		{name: "gone", method: http.MethodPost, path: "/legacy", code: http.StatusGone},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if got := w.Header().Get("Allow"); got != c.allow {
+			t.Errorf("case[%s] expected Allow %q, got %q", c.name, c.allow, got)
+		}
+	}
+}
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithCORS(CORSPolicy{AllowedOrigins: []string{"*"}}),
+		WithMethodNotAllowedHandler(func(w http.ResponseWriter, req *http.Request, allowed []string) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write([]byte(req.Method + " not in " + strings.Join(allowed, ",")))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/widgets", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/widgets", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected a 405, got %d", w.Code)
+	}
+
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Errorf("expected Allow to list GET, HEAD and OPTIONS, got %q", got)
+	}
+
+	if got := w.Body.String(); got != "DELETE not in GET,HEAD,OPTIONS" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/jwfriese/autohttp/internal/httpsnoop"
+)
+
+// WithoutMetricsCapture serves the route with the raw http.ResponseWriter,
+// for handlers that type assert it to something the metrics wrapper hides.
+// The route is left out of route metrics and access logs, see MetricBlindRoutes
+func WithoutMetricsCapture() RouteOption {
+	return func(rc *routeConfig) error {
+		rc.metricBlind = true
+		return nil
+	}
+}
+
+// MetricBlindRoutes lists, as "METHOD pattern", the routes served without
+// metrics capture, whether configured with WithoutMetricsCapture or found at
+// runtime to need the raw http.ResponseWriter
+func (r *Router) MetricBlindRoutes() []string {
+	r.metricBlindMu.Lock()
+	defer r.metricBlindMu.Unlock()
+
+	routes := make([]string, 0, len(r.metricBlind))
+	for route := range r.metricBlind {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	return routes
+}
+
+func (r *Router) isMetricBlind(route string) bool {
+	r.metricBlindMu.Lock()
+	defer r.metricBlindMu.Unlock()
+
+	return r.metricBlind[route]
+}
+
+func (r *Router) markMetricBlind(route string) {
+	r.metricBlindMu.Lock()
+	defer r.metricBlindMu.Unlock()
+
+	if r.metricBlind == nil {
+		r.metricBlind = make(map[string]bool)
+	}
+	r.metricBlind[route] = true
+}
+
+type rawWriterCtxKey struct{}
+
+// rawWriter keeps hold of the ResponseWriter under the metrics wrapper, so
+// routes that can't work with the wrapper can be served without it
+type rawWriter struct {
+	w http.ResponseWriter
+
+	// wrote is set once anything reaches w through the wrapper
+	wrote bool
+	// bypassed is set when the request was served straight to w, leaving
+	// the captured metrics meaningless
+	bypassed bool
+}
+
+// withRawWriter tracks writes to w, returning the writer to capture metrics
+// around and the request carrying the raw writer
+func withRawWriter(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, *http.Request, *rawWriter) {
+	raw := &rawWriter{w: w}
+	tracked := httpsnoop.Wrap(w, httpsnoop.Hooks{
+		WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+			return func(code int) {
+				raw.wrote = true
+				next(code)
+			}
+		},
+		Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+			return func(b []byte) (int, error) {
+				raw.wrote = true
+				return next(b)
+			}
+		},
+	})
+
+	return tracked, req.WithContext(context.WithValue(req.Context(), rawWriterCtxKey{}, raw)), raw
+}
+
+// errWrapperConflict is served for requests that found a route needing the
+// raw http.ResponseWriter and couldn't safely be served again
+var errWrapperConflict = errors.New("autohttp: route needs the raw http.ResponseWriter")
+
+// trackedBody notes whether a handler read the request body
+type trackedBody struct {
+	io.ReadCloser
+	read bool
+}
+
+func (tb *trackedBody) Read(p []byte) (int, error) {
+	tb.read = true
+	return tb.ReadCloser.Read(p)
+}
+
+// serveRoute serves a matched route, bypassing the metrics wrapper for metric
+// blind routes. A route that panics asserting the wrapped writer before
+// writing anything is marked metric blind, so later requests bypass the
+// wrapper. The request itself is served again unwrapped when it's a read that
+// left its body unread, and gets an error otherwise, as running the handler
+// twice could repeat its side effects
+func (r *Router) serveRoute(w http.ResponseWriter, req *http.Request, route string, handler http.Handler) {
+	raw, _ := req.Context().Value(rawWriterCtxKey{}).(*rawWriter)
+	if raw == nil {
+		handler.ServeHTTP(w, req)
+		return
+	}
+
+	if r.isMetricBlind(route) {
+		raw.bypassed = true
+		handler.ServeHTTP(raw.w, req)
+		return
+	}
+
+	var body *trackedBody
+	if req.Body != nil && req.Body != http.NoBody {
+		body = &trackedBody{ReadCloser: req.Body}
+		req.Body = body
+	}
+
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+
+		if raw.wrote || !isWrapperConflict(p) {
+			panic(p)
+		}
+
+		r.markMetricBlind(route)
+		r.log.Infof("autohttp: warning: %s needs the raw http.ResponseWriter, serving it without metrics: %v", route, p)
+
+		raw.bypassed = true
+		if !isReadMethod(req.Method) || (body != nil && body.read) {
+			r.serveRouterError(raw.w, req, ErrorWithCode{Err: errWrapperConflict, StatusCode: http.StatusInternalServerError})
+			return
+		}
+
+		handler.ServeHTTP(raw.w, req)
+	}()
+
+	handler.ServeHTTP(w, req)
+}
+
+// isWrapperConflict reports whether a panic came from type asserting the
+// metrics wrapper to a type or interface it doesn't satisfy
+func isWrapperConflict(p interface{}) bool {
+	err, ok := p.(*runtime.TypeAssertionError)
+	return ok && strings.Contains(err.Error(), "httpsnoop.Unwrapper")
+}
@@ -0,0 +1,152 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestMetricsCaptureFallback(t *testing.T) {
+	t.Parallel()
+
+	// needsRaw only works with the recorder it was handed, like handlers
+	// asserting the server's own ResponseWriter
+	needsRaw := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec := w.(*httptest.ResponseRecorder)
+		rec.WriteHeader(http.StatusTeapot)
+	})
+	wrapped := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	cases := []struct {
+		Name       string
+		Handler    http.Handler
+		Opts       []RouteOption
+		ExpectCode int
+		ExpectLog  bool
+	}{
+		{"wrapped", wrapped, nil, http.StatusAccepted, true},
+		{"detected", needsRaw, nil, http.StatusTeapot, false},
+		{"configured", needsRaw, []RouteOption{WithoutMetricsCapture()}, http.StatusTeapot, false},
+	}
+
+	for _, c := range cases {
+		sink := &accessLogSink{}
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+			WithMetricsSink(sink),
+			WithAccessLog(AccessLogSampling{PerSecond: 100}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.Register(http.MethodGet, "/raw", c.Handler, nil, c.Opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the second request takes the path the first one settled on
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/raw", nil))
+			if w.Code != c.ExpectCode {
+				t.Errorf("case[%s] request %d expected %d got %d", c.Name, i, c.ExpectCode, w.Code)
+			}
+		}
+
+		if logged := len(sink.decisions) > 0; logged != c.ExpectLog {
+			t.Errorf("case[%s] expected access logs %t got %+v", c.Name, c.ExpectLog, sink.decisions)
+		}
+
+		var expectBlind []string
+		if !c.ExpectLog {
+			expectBlind = []string{"GET /raw"}
+		}
+		if blind := r.MetricBlindRoutes(); len(blind) > 0 || len(expectBlind) > 0 {
+			if !reflect.DeepEqual(blind, expectBlind) {
+				t.Errorf("case[%s] expected metric blind routes %v got %v", c.Name, expectBlind, blind)
+			}
+		}
+	}
+}
+
+func TestMetricsCaptureFallbackAfterWrite(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), EnableRouteMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/late", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = w.(*httptest.ResponseRecorder)
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a handler that already wrote to keep panicking")
+		}
+
+		if blind := r.MetricBlindRoutes(); len(blind) != 0 {
+			t.Errorf("expected no metric blind routes got %v", blind)
+		}
+	}()
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/late", nil))
+}
+
+func TestMetricsCaptureFallbackUnsafeRetry(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name   string
+		Method string
+		Body   string
+		Read   bool
+	}{
+		{"write method", http.MethodPost, "", false},
+		{"read body", http.MethodGet, "q", true},
+	}
+
+	for _, c := range cases {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), EnableRouteMetrics)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calls := 0
+		err = r.Register(c.Method, "/raw", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if c.Read {
+				_, _ = io.ReadAll(req.Body)
+			}
+			w.(*httptest.ResponseRecorder).WriteHeader(http.StatusTeapot)
+		}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.Method, "/raw", strings.NewReader(c.Body)))
+		if w.Code != http.StatusInternalServerError || calls != 1 {
+			t.Errorf("case[%s] expected a 500 without serving again, got %d after %d calls", c.Name, w.Code, calls)
+		}
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.Method, "/raw", strings.NewReader(c.Body)))
+		if w.Code != http.StatusTeapot || calls != 2 {
+			t.Errorf("case[%s] expected later requests to bypass the wrapper, got %d after %d calls", c.Name, w.Code, calls)
+		}
+	}
+}
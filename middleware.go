@@ -15,6 +15,13 @@ type Middleware interface {
 	Before(r *http.Request, h *Handler) error
 }
 
+// AfterMiddleware is implemented by Middleware that also need to observe
+// the outcome of a request. After is called once the response has been written,
+// in reverse registration order, only for middleware whose Before succeeded
+type AfterMiddleware interface {
+	After(r *http.Request, statusCode int, header http.Header)
+}
+
 type MiddlewareError struct {
 	StatusCode int
 	Err        error
@@ -24,6 +31,10 @@ func (mwe MiddlewareError) Error() string {
 	return mwe.Err.Error()
 }
 
+func (mwe MiddlewareError) Unwrap() error {
+	return mwe.Err
+}
+
 // SignedHeadersMiddleware validates that all incoming headers are signed using a certain key
 // if they're set as a header outgoing, they'll also be signed on the way out.
 // this works great for cookies and the like
@@ -94,5 +105,6 @@ func (bam *BasicAuthMiddleware) Before(r *http.Request, h *Handler) error {
 		}
 	}
 
+	SetPrincipal(r.Context(), uname)
 	return nil
 }
@@ -0,0 +1,72 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRouterUse(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithRobotsTxt(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	record := func(name string) Middleware {
+		return beforeFunc(func(r *http.Request) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	// http.Handler routes registered by options don't block Use
+	r.Use(record("global"), record("global-2"))
+
+	fn := func(ctx context.Context) {}
+	err = r.Register(http.MethodPost, "/plain", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Group("/api", record("group")).Register(http.MethodPost, "/grouped", fn, []Middleware{record("route")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		ExpectOrder []string
+	}{
+		{"plain", "/plain", []string{"global", "global-2"}},
+		{"grouped", "/api/grouped", []string{"global", "global-2", "group", "route"}},
+	}
+
+	for _, c := range cases {
+		order = nil
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, c.Path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if strings.Join(order, ",") != strings.Join(c.ExpectOrder, ",") {
+			t.Errorf("case[%s] expected middleware order %v got %v", c.Name, c.ExpectOrder, order)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Use after registering routes to panic")
+		}
+	}()
+	r.Use(record("late"))
+}
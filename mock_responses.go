@@ -0,0 +1,96 @@
+package autohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultMockHeader asks for a route's example response instead of calling its handler
+const DefaultMockHeader = "X-Mock-Response"
+
+// ErrNotImplemented is returned by routes registered with only an example, while mocking is off
+var ErrNotImplemented = errors.New("autohttp: route not implemented")
+
+type mockMode int
+
+const (
+	mockOff mockMode = iota
+	mockOnHeader
+	mockAlways
+)
+
+// WithExample annotates a route with an example response, served in place of the
+// handler when mocking is enabled. Routes may be registered with a nil function
+// and only an example, letting frontends develop against the route table
+// before the handlers exist
+func WithExample(example interface{}) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.example = example
+		rc.hasExample = true
+		return nil
+	}
+}
+
+// EnableMockResponses serves the example response of every route that has one
+func EnableMockResponses(r *Router) error {
+	if r.mockMode == mockOnHeader {
+		r.mockModeConflict = true
+	}
+
+	r.mockMode = mockAlways
+	return nil
+}
+
+// EnableMockResponsesOnHeader serves a route's example response only when the
+// request sets header, such as DefaultMockHeader
+func EnableMockResponsesOnHeader(header string) func(r *Router) error {
+	return func(r *Router) error {
+		if r.mockMode == mockAlways {
+			r.mockModeConflict = true
+		}
+
+		r.mockMode = mockOnHeader
+		r.mockHeader = header
+		return nil
+	}
+}
+
+// mockHandler serves a route's example response when mocking applies
+type mockHandler struct {
+	mode         mockMode
+	header       string
+	example      interface{}
+	encoder      Encoder
+	errorHandler ErrorHandler
+	next         http.Handler
+
+	requestErrorHandler RequestErrorHandler
+	errorShaping        errorShaping
+}
+
+func (mh *mockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mock := mh.mode == mockAlways || (mh.mode == mockOnHeader && r.Header.Get(mh.header) != "")
+	if !mock {
+		if mh.next == nil {
+			mh.errorShaping.serve(w, r, StageHandler, ErrorWithCode{Err: ErrNotImplemented, StatusCode: http.StatusNotImplemented}, mh.errorHandler, mh.requestErrorHandler)
+			return
+		}
+
+		mh.next.ServeHTTP(w, r)
+		return
+	}
+
+	code, body, err := mh.encoder.Encode(mh.example, w.Header().Set)
+	if err != nil {
+		mh.errorShaping.serve(w, r, StageEncode, err, mh.errorHandler, mh.requestErrorHandler)
+		return
+	}
+
+	// mark mocked responses so they're never mistaken for real ones
+	w.Header().Set(DefaultMockHeader, "true")
+	w.WriteHeader(code)
+	if body != nil {
+		io.Copy(w, body)
+	}
+}
@@ -0,0 +1,66 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestMockResponses(t *testing.T) {
+	example := map[string]string{"name": "example"}
+	real := func(ctx context.Context, in struct{ Name string }) map[string]string {
+		return map[string]string{"name": in.Name}
+	}
+
+	cases := []struct {
+		Name         string
+		Options      []RouterOption
+		Fn           interface{}
+		MockHeader   bool
+		ExpectStatus int
+		ExpectRes    string
+	}{
+		{"mocking-off", nil, real, false, http.StatusOK, `{"name":"real"}`},
+		{"always", []RouterOption{EnableMockResponses}, real, false, http.StatusOK, `{"name":"example"}`},
+		{"on-header-without-header", []RouterOption{EnableMockResponsesOnHeader(DefaultMockHeader)}, real, false, http.StatusOK, `{"name":"real"}`},
+		{"on-header-with-header", []RouterOption{EnableMockResponsesOnHeader(DefaultMockHeader)}, real, true, http.StatusOK, `{"name":"example"}`},
+		{"no-handler-mocked", []RouterOption{EnableMockResponses}, nil, false, http.StatusOK, `{"name":"example"}`},
+		{"no-handler-not-mocked", nil, nil, false, http.StatusNotImplemented, `{"error":"autohttp: route not implemented"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), c.Options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Register(http.MethodPost, "/user", c.Fn, nil, WithExample(example))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(`{"Name": "real"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if c.MockHeader {
+				req.Header.Set(DefaultMockHeader, "1")
+			}
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("expected %d got %d", c.ExpectStatus, w.Code)
+			}
+
+			if strings.TrimSpace(w.Body.String()) != c.ExpectRes {
+				t.Errorf("expected %q got %q", c.ExpectRes, w.Body.String())
+			}
+		})
+	}
+}
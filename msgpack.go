@@ -0,0 +1,135 @@
+package autohttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/jwfriese/autohttp/internal/msgpack"
+)
+
+// MsgpackContentType is the media type of MessagePack bodies. The older
+// application/x-msgpack is accepted on requests too
+const MsgpackContentType = "application/msgpack"
+
+// MsgpackEncoder renders return values as MessagePack, naming struct fields
+// by their msgpack or json tags
+type MsgpackEncoder struct{}
+
+func (me *MsgpackEncoder) ValidateType(fn interface{}) error {
+	return nil
+}
+
+func (me *MsgpackEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	hw("Content-Type", MsgpackContentType)
+
+	b, err := msgpack.Marshal(value)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, bytes.NewReader(b), nil
+}
+
+// MsgpackDecoder decodes MessagePack request bodies, taking the same
+// arguments as the JSONDecoder
+type MsgpackDecoder struct {
+	MaxBytesToRead int64
+}
+
+func NewMsgpackDecoder() *MsgpackDecoder {
+	return &MsgpackDecoder{
+		MaxBytesToRead: DefaultMaxBytesToRead,
+	}
+}
+
+func (md *MsgpackDecoder) isDecodable(t reflect.Type) bool {
+	kind := t.Kind()
+
+	return !isHeaderType(t) && (kind == reflect.Ptr || kind == reflect.Map || kind == reflect.Slice || kind == reflect.Struct)
+}
+
+func (md *MsgpackDecoder) ValidateType(fn interface{}) error {
+	_, _, decodeIdx, err := bodyInputIndices(fn, md.isDecodable)
+	if err != nil {
+		return err
+	}
+
+	if decodeIdx != uIdx {
+		return checkValidationTags(reflect.TypeOf(fn).In(decodeIdx))
+	}
+
+	return nil
+}
+
+// Decode returns the reflect values needed to call the fn
+// from the *http.Request
+func (md *MsgpackDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	ct := r.Header.Get("Content-Type")
+	if !strings.Contains(ct, MsgpackContentType) && !strings.Contains(ct, "application/x-msgpack") {
+		return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	if isQueryMethod(r.Method) {
+		return nil, ErrorWithCode{Err: errors.New("GET requests prohibited for this endpoint"), StatusCode: http.StatusMethodNotAllowed}
+	}
+
+	ctxIdx, hdrIdx, decodeIdx, err := bodyInputIndices(fn, md.isDecodable)
+	if err != nil {
+		return nil, err
+	}
+
+	fnReflectType := reflect.ValueOf(fn).Type()
+	callValues := make([]reflect.Value, fnReflectType.NumIn())
+
+	if ctxIdx != uIdx {
+		callValues[ctxIdx] = reflect.ValueOf(r.Context())
+	}
+
+	if hdrIdx != uIdx {
+		callValues[hdrIdx] = reflect.ValueOf(requestHeader(r))
+	}
+
+	if decodeIdx != uIdx {
+		b, err := io.ReadAll(io.LimitReader(r.Body, md.MaxBytesToRead+1))
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+		if int64(len(b)) > md.MaxBytesToRead {
+			return nil, ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", md.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+		}
+
+		inArg := fnReflectType.In(decodeIdx)
+
+		var object reflect.Value
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			object = reflect.New(inArg.Elem())
+		default:
+			object = reflect.New(inArg)
+		}
+
+		err = msgpack.Unmarshal(b, object.Interface())
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		err = validateValue(object)
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			callValues[decodeIdx] = object
+		default:
+			callValues[decodeIdx] = object.Elem()
+		}
+	}
+
+	return callValues, nil
+}
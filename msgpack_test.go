@@ -0,0 +1,97 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+	"github.com/jwfriese/autohttp/internal/msgpack"
+)
+
+type msgpackOrder struct {
+	ID    int      `json:"id"`
+	Items []string `json:"items" validate:"min=1"`
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEncoderFor(MsgpackContentType, &MsgpackEncoder{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := ContentTypeDecoder{
+		"application/json":      NewJSONDecoder(),
+		MsgpackContentType:      NewMsgpackDecoder(),
+		"application/x-msgpack": NewMsgpackDecoder(),
+	}
+	err = r.Register(http.MethodPost, "/orders", func(ctx context.Context, o *msgpackOrder) (*msgpackOrder, error) {
+		o.ID++
+		return o, nil
+	}, nil, WithDecoder(decoder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := func(v interface{}) []byte {
+		b, err := msgpack.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	cases := []struct {
+		Name        string
+		ContentType string
+		Accept      string
+		Body        []byte
+		ExpectCode  int
+		ExpectOrder *msgpackOrder
+	}{
+		{"msgpack", MsgpackContentType, MsgpackContentType, body(msgpackOrder{ID: 1, Items: []string{"tea"}}), http.StatusOK, &msgpackOrder{ID: 2, Items: []string{"tea"}}},
+		{"legacy-content-type", "application/x-msgpack", MsgpackContentType, body(msgpackOrder{ID: 5, Items: []string{"tea"}}), http.StatusOK, &msgpackOrder{ID: 6, Items: []string{"tea"}}},
+		{"json-out", MsgpackContentType, "application/json", body(msgpackOrder{ID: 1, Items: []string{"tea"}}), http.StatusOK, nil},
+		{"validation", MsgpackContentType, MsgpackContentType, body(msgpackOrder{ID: 1}), http.StatusBadRequest, nil},
+		{"garbage", MsgpackContentType, MsgpackContentType, []byte{0xc1}, http.StatusBadRequest, nil},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewReader(c.Body))
+		req.Header.Set("Content-Type", c.ContentType)
+		req.Header.Set("Accept", c.Accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+		}
+
+		if w.Code == http.StatusOK {
+			ct := w.Header().Get("Content-Type")
+			if ct != c.Accept {
+				t.Errorf("case[%s] expected %s got %s", c.Name, c.Accept, ct)
+			}
+		}
+
+		if c.ExpectOrder != nil {
+			var got msgpackOrder
+			err := msgpack.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatalf("case[%s] %s", c.Name, err)
+			}
+
+			if !reflect.DeepEqual(&got, c.ExpectOrder) {
+				t.Errorf("case[%s] expected %+v got %+v", c.Name, c.ExpectOrder, got)
+			}
+		}
+	}
+}
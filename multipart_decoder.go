@@ -0,0 +1,229 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+var (
+	// DefaultMultipartMaxBytes bounds a whole multipart request body, 32MiB
+	DefaultMultipartMaxBytes int64 = 32 << 20
+	// DefaultMultipartMaxMemory is how much of a multipart body is held in
+	// memory before files are spooled to disk, 10MiB
+	DefaultMultipartMaxMemory int64 = 10 << 20
+)
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// MultipartDecoder decodes multipart/form-data bodies into a struct argument.
+// Text fields are bound as with FormDecoder, and uploads to fields of type
+// *multipart.FileHeader, or []*multipart.FileHeader for repeated files.
+// Uploads spooled to disk are removed once the handler returns, so a file
+// must be read, with FileHeader.Open, before then.
+// Each route can be given its own limits with WithDecoder
+type MultipartDecoder struct {
+	// MaxBytesToRead bounds the whole request body
+	MaxBytesToRead int64
+	// MaxMemory is how much of the body is held in memory, files beyond it
+	// are spooled to temporary files
+	MaxMemory int64
+	// MaxFileBytes bounds each uploaded file, zero allows any size that
+	// fits in MaxBytesToRead
+	MaxFileBytes int64
+
+	DisallowUnknownFields bool
+}
+
+func NewMultipartDecoder() *MultipartDecoder {
+	return &MultipartDecoder{
+		MaxBytesToRead: DefaultMultipartMaxBytes,
+		MaxMemory:      DefaultMultipartMaxMemory,
+	}
+}
+
+func (md *MultipartDecoder) isMultipartDecodable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+func (md *MultipartDecoder) ValidateType(fn interface{}) error {
+	_, _, decodeIdx, err := bodyInputIndices(fn, md.isMultipartDecodable)
+	if err != nil {
+		return err
+	}
+
+	if decodeIdx == uIdx {
+		return nil
+	}
+
+	t := reflect.TypeOf(fn).In(decodeIdx)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, f := range formFields(t) {
+		if !isFileType(f.Type) && !isFormValueType(f.Type) {
+			return fmt.Errorf("multipart decoder cannot decode field %s of type %s", f.Name, f.Type)
+		}
+	}
+
+	return checkValidationTags(t)
+}
+
+// Decode returns the reflect values needed to call the fn
+// from the *http.Request
+func (md *MultipartDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	body := &countingReader{r: io.LimitReader(r.Body, md.MaxBytesToRead+1)}
+	form, err := multipart.NewReader(body, params["boundary"]).ReadForm(md.MaxMemory)
+	if body.n > md.MaxBytesToRead {
+		if form != nil {
+			form.RemoveAll()
+		}
+		return nil, ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", md.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+	}
+	if err != nil {
+		return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+	}
+
+	// the handler cleans up after the function returns, see callReflect
+	r.MultipartForm = form
+
+	ctxIdx, hdrIdx, decodeIdx, err := bodyInputIndices(fn, md.isMultipartDecodable)
+	if err != nil {
+		return nil, err
+	}
+
+	fnReflectType := reflect.ValueOf(fn).Type()
+	callValues := make([]reflect.Value, fnReflectType.NumIn())
+
+	if ctxIdx != uIdx {
+		callValues[ctxIdx] = reflect.ValueOf(r.Context())
+	}
+
+	if hdrIdx != uIdx {
+		callValues[hdrIdx] = reflect.ValueOf(requestHeader(r))
+	}
+
+	if decodeIdx != uIdx {
+		inArg := fnReflectType.In(decodeIdx)
+
+		var object reflect.Value
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			object = reflect.New(inArg.Elem())
+		default:
+			object = reflect.New(inArg)
+		}
+
+		err = md.decodeForm(form, object.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		err = bindRequest(r, object, boundFields(inArg))
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateValue(object)
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			callValues[decodeIdx] = object
+		default:
+			callValues[decodeIdx] = object.Elem()
+		}
+	}
+
+	return callValues, nil
+}
+
+func (md *MultipartDecoder) decodeForm(form *multipart.Form, v reflect.Value) error {
+	known := make(map[string]bool)
+	for _, f := range formFields(v.Type()) {
+		if isBoundField(f) {
+			continue
+		}
+
+		name := formFieldName(f)
+		known[name] = true
+
+		if isFileType(f.Type) {
+			files, ok := form.File[name]
+			if !ok {
+				continue
+			}
+
+			for _, fh := range files {
+				if md.MaxFileBytes > 0 && fh.Size > md.MaxFileBytes {
+					return ErrorWithCode{Err: fmt.Errorf("%s: file %q exceeds %d bytes", name, fh.Filename, md.MaxFileBytes), StatusCode: http.StatusRequestEntityTooLarge}
+				}
+			}
+
+			field := v.FieldByIndex(f.Index)
+			if f.Type == fileHeaderType {
+				field.Set(reflect.ValueOf(files[0]))
+			} else {
+				field.Set(reflect.ValueOf(files))
+			}
+			continue
+		}
+
+		vals, ok := form.Value[name]
+		if !ok {
+			continue
+		}
+
+		err := setFormValue(v.FieldByIndex(f.Index), vals)
+		if err != nil {
+			return ErrorWithCode{Err: fmt.Errorf("%s: %w", name, err), StatusCode: http.StatusBadRequest}
+		}
+	}
+
+	if md.DisallowUnknownFields {
+		for name := range form.Value {
+			if !known[name] {
+				return ErrorWithCode{Err: fmt.Errorf("unknown field %q", name), StatusCode: http.StatusBadRequest}
+			}
+		}
+		for name := range form.File {
+			if !known[name] {
+				return ErrorWithCode{Err: fmt.Errorf("unknown file %q", name), StatusCode: http.StatusBadRequest}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isFileType(t reflect.Type) bool {
+	return t == fileHeaderType || (t.Kind() == reflect.Slice && t.Elem() == fileHeaderType)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
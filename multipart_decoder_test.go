@@ -0,0 +1,132 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type uploadForm struct {
+	Title       string                  `form:"title" validate:"required"`
+	Avatar      *multipart.FileHeader   `form:"avatar"`
+	Attachments []*multipart.FileHeader `form:"attachments"`
+}
+
+type uploadPart struct {
+	Field, Filename, Content string
+}
+
+func multipartBody(t *testing.T, parts []uploadPart) (io.Reader, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		var (
+			w   io.Writer
+			err error
+		)
+		if p.Filename != "" {
+			w, err = mw.CreateFormFile(p.Field, p.Filename)
+		} else {
+			w, err = mw.CreateFormField(p.Field)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, p.Content)
+	}
+	mw.Close()
+
+	return &buf, mw.FormDataContentType()
+}
+
+func TestMultipartDecoder(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = r.Register(http.MethodPost, "/upload", func(ctx context.Context, f uploadForm) error {
+		got = []string{f.Title}
+		files := f.Attachments
+		if f.Avatar != nil {
+			files = append([]*multipart.FileHeader{f.Avatar}, files...)
+		}
+		for _, fh := range files {
+			file, err := fh.Open()
+			if err != nil {
+				return err
+			}
+			b, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			got = append(got, fh.Filename+"="+string(b))
+		}
+		return nil
+	}, nil, WithDecoder(&MultipartDecoder{MaxBytesToRead: 1024, MaxMemory: 16, MaxFileBytes: 64, DisallowUnknownFields: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name       string
+		Parts      []uploadPart
+		Expect     []string
+		ExpectCode int
+	}{
+		{"fields-and-files", []uploadPart{
+			{"title", "", "holiday"},
+			{"avatar", "me.png", "png"},
+			{"attachments", "a.txt", "first"},
+			{"attachments", "b.txt", strings.Repeat("b", 32)},
+		}, []string{"holiday", "me.png=png", "a.txt=first", "b.txt=" + strings.Repeat("b", 32)}, http.StatusOK},
+		{"no-files", []uploadPart{{"title", "", "plain"}}, []string{"plain"}, http.StatusOK},
+		{"validation", []uploadPart{{"avatar", "me.png", "png"}}, nil, http.StatusBadRequest},
+		{"unknown-file", []uploadPart{{"title", "", "x"}, {"resume", "cv.pdf", "pdf"}}, nil, http.StatusBadRequest},
+		{"file-too-large", []uploadPart{{"title", "", "x"}, {"avatar", "big.png", strings.Repeat("p", 65)}}, nil, http.StatusRequestEntityTooLarge},
+		{"body-too-large", []uploadPart{{"title", "", strings.Repeat("t", 2048)}}, nil, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		got = nil
+		body, contentType := multipartBody(t, c.Parts)
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+			continue
+		}
+
+		if strings.Join(got, ",") != strings.Join(c.Expect, ",") {
+			t.Errorf("case[%s] expected %v got %v", c.Name, c.Expect, got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("title=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected %d for a urlencoded body got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+
+	err = r.Register(http.MethodPost, "/bad", func(ctx context.Context, f struct{ File *os.File }) error { return nil }, nil, WithDecoder(NewMultipartDecoder()))
+	if err == nil {
+		t.Error("expected an unsupported field type to fail registration")
+	}
+}
@@ -0,0 +1,137 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrNotAcceptable is returned when no encoder produces a media type the client accepts
+var ErrNotAcceptable = errors.New("autohttp: no acceptable media type")
+
+// WithEncoderFor encodes responses with e for requests that Accept mediaType,
+// such as "application/xml". Requests without an Accept header, or accepting
+// anything, get the route's default encoder, and requests accepting nothing
+// the router can produce get a 406
+func WithEncoderFor(mediaType string, e Encoder) func(r *Router) error {
+	return func(r *Router) error {
+		if e == nil {
+			return errors.New("autohttp: WithEncoderFor needs an encoder")
+		}
+
+		if r.negotiation == nil {
+			r.negotiation = &negotiation{}
+		}
+
+		r.negotiation.add(strings.ToLower(mediaType), e)
+		return nil
+	}
+}
+
+type mediaEncoder struct {
+	mediaType string
+	encoder   Encoder
+}
+
+// negotiation picks an Encoder by the request's Accept header
+type negotiation struct {
+	encoders []mediaEncoder
+}
+
+func (n *negotiation) add(mediaType string, e Encoder) {
+	for i, me := range n.encoders {
+		if me.mediaType == mediaType {
+			n.encoders[i].encoder = e
+			return
+		}
+	}
+
+	n.encoders = append(n.encoders, mediaEncoder{mediaType: mediaType, encoder: e})
+}
+
+// forFunc returns the negotiation left after dropping the encoders that
+// can't encode fn's results, so requests for their media types fall through
+// to another encoder or a 406 rather than failing to encode
+func (n *negotiation) forFunc(fn interface{}) *negotiation {
+	if n == nil {
+		return nil
+	}
+
+	valid := &negotiation{}
+	for _, me := range n.encoders {
+		if me.encoder.ValidateType(fn) == nil {
+			valid.encoders = append(valid.encoders, me)
+		}
+	}
+
+	return valid
+}
+
+// encoderFor returns the best encoder for r, where fallback is the route's
+// own encoder and serves requests that accept anything
+func (n *negotiation) encoderFor(r *http.Request, fallback Encoder) (Encoder, error) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return fallback, nil
+	}
+
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []accepted
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, q := parseQualityValue(part)
+		if mediaType != "" && q > 0 {
+			ranges = append(ranges, accepted{mediaType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	// the fallback is matched on its own media type, if it has a known one
+	candidates := n.encoders
+	if mediaType := encoderMediaType(fallback); mediaType != "" {
+		candidates = append([]mediaEncoder{{mediaType, fallback}}, n.encoders...)
+	}
+
+	for _, ar := range ranges {
+		if ar.mediaType == "*/*" {
+			return fallback, nil
+		}
+
+		for _, me := range candidates {
+			if me.mediaType == ar.mediaType ||
+				(strings.HasSuffix(ar.mediaType, "/*") && strings.HasPrefix(me.mediaType, strings.TrimSuffix(ar.mediaType, "*"))) {
+				return me.encoder, nil
+			}
+		}
+	}
+
+	return nil, ErrorWithCode{Err: ErrNotAcceptable, StatusCode: http.StatusNotAcceptable}
+}
+
+// encoderMediaType is the media type produced by the built in encoders
+func encoderMediaType(e Encoder) string {
+	if we, ok := e.(wrappedEncoder); ok {
+		return encoderMediaType(we.inner)
+	}
+
+	switch e.(type) {
+	case *JSONEncoder:
+		return "application/json"
+	case *XMLEncoder:
+		return "application/xml"
+	case TextEncoder, *TextEncoder:
+		return "text/plain"
+	case *ProtobufEncoder:
+		return ProtobufContentType
+	case *MsgpackEncoder:
+		return MsgpackContentType
+	}
+
+	return ""
+}
@@ -0,0 +1,112 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type negotiatedPet struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestContentNegotiation(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEncoderFor("application/xml", &XMLEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/pet", func() negotiatedPet { return negotiatedPet{Name: "rex"} }, nil, WithDecoder(NoOpDecoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name              string
+		Accept            string
+		ExpectCode        int
+		ExpectContentType string
+		ExpectBody        string
+	}{
+		{"no-accept", "", http.StatusOK, "application/json", `{"name":"rex"}`},
+		{"wildcard", "*/*", http.StatusOK, "application/json", `{"name":"rex"}`},
+		{"json", "application/json", http.StatusOK, "application/json", `{"name":"rex"}`},
+		{"xml", "application/xml", http.StatusOK, "application/xml; charset=utf-8", `<negotiatedPet><name>rex</name></negotiatedPet>`},
+		{"quality", "application/json;q=0.5, application/xml", http.StatusOK, "application/xml; charset=utf-8", "<name>rex</name>"},
+		{"subtype-wildcard", "text/html, application/*;q=0.8", http.StatusOK, "application/json", `{"name":"rex"}`},
+		{"refused", "application/xml;q=0, application/json;q=0", http.StatusNotAcceptable, "", ""},
+		{"unknown", "application/msgpack", http.StatusNotAcceptable, "", ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/pet", nil)
+		if c.Accept != "" {
+			req.Header.Set("Accept", c.Accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if c.ExpectContentType != "" && w.Header().Get("Content-Type") != c.ExpectContentType {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.ExpectContentType, w.Header().Get("Content-Type"))
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) {
+			t.Errorf("case[%s] expected %q in %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("case[%s] expected Vary: Accept got %q", c.Name, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestContentNegotiationSkipsInvalidEncoders(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithEncoderFor("application/xml", &XMLEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the xml encoder can't encode maps, so this route only speaks JSON
+	err = r.Register(http.MethodPost, "/counts", func() map[string]int { return map[string]int{"rex": 1} }, nil, WithDecoder(NoOpDecoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name       string
+		Accept     string
+		ExpectCode int
+		ExpectBody string
+	}{
+		{"xml only", "application/xml", http.StatusNotAcceptable, ""},
+		{"falls through", "application/xml, application/json;q=0.5", http.StatusOK, `{"rex":1}`},
+		{"subtype-wildcard", "application/*", http.StatusOK, `{"rex":1}`},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/counts", nil)
+		req.Header.Set("Accept", c.Accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) || strings.Contains(w.Body.String(), "xml:") {
+			t.Errorf("case[%s] expected %q in %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+}
@@ -24,8 +24,12 @@ func (noop NoOpDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value
 type NoOpEncoder struct{}
 
 func (noop NoOpEncoder) ValidateType(fn interface{}) error {
-	if reflect.ValueOf(fn).Type().NumOut() != 0 {
-		return errors.New("noop encoder only works for functions with no return values")
+	fnType := reflect.ValueOf(fn).Type()
+	for i := 0; i < fnType.NumOut(); i++ {
+		// an error is handled before anything is encoded
+		if !isErrorType(fnType.Out(i)) {
+			return errors.New("noop encoder only works for functions with no return values other than an error")
+		}
 	}
 
 	return nil
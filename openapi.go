@@ -0,0 +1,447 @@
+package autohttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the OpenAPI version of generated documents
+const OpenAPIVersion = "3.0.3"
+
+// OpenAPIInfo is the info object of a generated document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIDocument is the subset of OpenAPI 3 generated from the routes
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema  *Schema     `json:"schema,omitempty"`
+	Example interface{} `json:"example,omitempty"`
+}
+
+// Schema is an OpenAPI schema object. Constraints are read from validate tags
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *float64           `json:"minLength,omitempty"`
+	MaxLength            *float64           `json:"maxLength,omitempty"`
+	MinItems             *float64           `json:"minItems,omitempty"`
+	MaxItems             *float64           `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// routeDoc is what the router remembers about a route for documentation
+type routeDoc struct {
+	method     string
+	pattern    string
+	fn         interface{}
+	decoder    Decoder
+	example    interface{}
+	hasExample bool
+	tags       []string
+
+	// contentTypes are the request media types the route accepts, if restricted
+	contentTypes []string
+}
+
+// HideFromIntrospection leaves the route out of generated documents
+func HideFromIntrospection() RouteOption {
+	return func(rc *routeConfig) error {
+		rc.hidden = true
+		return nil
+	}
+}
+
+// WithOpenAPI serves the router's OpenAPI document at path, such as
+// "/openapi.json", describing every route registered at request time
+func WithOpenAPI(path string, info OpenAPIInfo) func(r *Router) error {
+	return func(r *Router) error {
+		r.openAPIInfo = info
+
+		return r.registerQuiet(path, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(r.OpenAPISpec())
+		}))
+	}
+}
+
+// OpenAPISpec describes the function routes registered so far as an OpenAPI 3
+// document, reflecting over their request and response types. http.Handler
+// routes are left out, as there is nothing to reflect over
+func (r *Router) OpenAPISpec() *OpenAPIDocument {
+	info := r.openAPIInfo
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "0.0.0"
+	}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+	sg := &schemaGenerator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+
+	for _, rd := range r.docs {
+		if doc.Paths[rd.pattern] == nil {
+			doc.Paths[rd.pattern] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[rd.pattern][strings.ToLower(rd.method)] = rd.operation(sg)
+	}
+
+	if len(sg.schemas) > 0 {
+		doc.Components.Schemas = sg.schemas
+	}
+
+	return doc
+}
+
+var errorSchema = &Schema{
+	Type:       "object",
+	Properties: map[string]*Schema{"error": {Type: "string"}, "request_id": {Type: "string"}},
+	Required:   []string{"error"},
+}
+
+func (rd routeDoc) operation(sg *schemaGenerator) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: operationID(rd.method, rd.pattern),
+		Tags:        rd.tags,
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+
+	for _, seg := range splitPath(rd.pattern) {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{
+				Name:     seg[1 : len(seg)-1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+
+	var fnType reflect.Type
+	if rd.fn != nil {
+		fnType = reflect.TypeOf(rd.fn)
+	}
+
+	if _, ok := rd.decoder.(*JSONDecoder); ok && fnType != nil {
+		for i := 0; i < fnType.NumIn(); i++ {
+			in := fnType.In(i)
+			if isContextType(in) || isHeaderType(in) {
+				continue
+			}
+
+			bound := boundFields(in)
+			for _, f := range bound {
+				op.Parameters = append(op.Parameters, OpenAPIParameter{
+					Name:   f.name,
+					In:     f.tag,
+					Schema: sg.schema(f.Type),
+				})
+			}
+
+			if len(bound) > 0 && isQueryMethod(rd.method) {
+				continue
+			}
+
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content:  map[string]OpenAPIMediaType{"application/json": {Schema: sg.schema(in)}},
+			}
+		}
+	}
+
+	if op.RequestBody != nil && len(rd.contentTypes) > 0 {
+		schema := op.RequestBody.Content["application/json"].Schema
+		op.RequestBody.Content = make(map[string]OpenAPIMediaType, len(rd.contentTypes))
+		for _, ct := range rd.contentTypes {
+			op.RequestBody.Content[ct] = OpenAPIMediaType{Schema: schema}
+		}
+	}
+
+	if len(rd.contentTypes) > 0 {
+		op.Responses["415"] = &OpenAPIResponse{
+			Description: "Unsupported Media Type",
+			Content:     map[string]OpenAPIMediaType{"application/json": {Schema: errorSchema}},
+		}
+	}
+
+	ok := &OpenAPIResponse{Description: "OK"}
+	op.Responses["200"] = ok
+
+	var out reflect.Type
+	hasError := false
+	if fnType != nil {
+		for i := 0; i < fnType.NumOut(); i++ {
+			if isErrorType(fnType.Out(i)) {
+				hasError = true
+			} else {
+				out = fnType.Out(i)
+			}
+		}
+	}
+
+	switch {
+	case out != nil && isTextType(out):
+		ok.Content = map[string]OpenAPIMediaType{"text/plain": {Schema: &Schema{Type: "string"}}}
+	case out != nil:
+		ok.Content = map[string]OpenAPIMediaType{"application/json": {Schema: sg.schema(out)}}
+	case rd.hasExample:
+		ok.Content = map[string]OpenAPIMediaType{"application/json": {Schema: sg.schema(reflect.TypeOf(rd.example))}}
+	}
+
+	if rd.hasExample {
+		if ok.Content == nil {
+			ok.Content = map[string]OpenAPIMediaType{"application/json": {}}
+		}
+		for ct, mt := range ok.Content {
+			mt.Example = rd.example
+			ok.Content[ct] = mt
+		}
+	}
+
+	if op.RequestBody != nil || hasError {
+		op.Responses["default"] = &OpenAPIResponse{
+			Description: "Error",
+			Content:     map[string]OpenAPIMediaType{"application/json": {Schema: errorSchema}},
+		}
+	}
+
+	return op
+}
+
+// operationID turns "GET /users/{id}" into "get_users_id"
+func operationID(method, pattern string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range splitPath(pattern) {
+		seg = strings.Trim(seg, "{}")
+		if seg == "" {
+			continue
+		}
+		b.WriteByte('_')
+		b.WriteString(strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, seg))
+	}
+
+	return b.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaGenerator builds schemas, placing named structs in components
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func (sg *schemaGenerator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case textType:
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := sg.schema(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: sg.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sg.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sg.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + sg.componentName(t)}
+	}
+
+	// interfaces and anything else can hold any value
+	return &Schema{}
+}
+
+// componentName registers t as a component, once, and returns its name
+func (sg *schemaGenerator) componentName(t reflect.Type) string {
+	if name, ok := sg.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	// same-named types from different packages get a numeric suffix
+	for i := 2; sg.schemas[name] != nil; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+
+	sg.names[t] = name
+	// reserve the name before recursing, for self-referencing types
+	sg.schemas[name] = &Schema{}
+	*sg.schemas[name] = *sg.structSchema(t)
+
+	return name
+}
+
+func (sg *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			embedded := sg.structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		name := fieldName(f)
+		prop := sg.schema(f.Type)
+
+		if tag, ok := f.Tag.Lookup(ValidateTag); ok {
+			rules, err := parseValidationTag(tag)
+			if err == nil {
+				if applySchemaRules(prop, f.Type, rules) {
+					s.Required = append(s.Required, name)
+				}
+			}
+		}
+
+		s.Properties[name] = prop
+	}
+
+	sort.Strings(s.Required)
+	return s
+}
+
+// applySchemaRules copies validate rules onto s, reporting if the field is required
+func applySchemaRules(s *Schema, t reflect.Type, rules []validationRule) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range rules {
+		n := rule.num
+		switch rule.name {
+		case "required":
+			required = true
+		case "pattern":
+			s.Pattern = rule.arg
+		case "min", "max", "len":
+			switch t.Kind() {
+			case reflect.String:
+				if rule.name != "max" {
+					s.MinLength = &n
+				}
+				if rule.name != "min" {
+					s.MaxLength = &n
+				}
+			case reflect.Slice, reflect.Array:
+				if rule.name != "max" {
+					s.MinItems = &n
+				}
+				if rule.name != "min" {
+					s.MaxItems = &n
+				}
+			case reflect.Map:
+			default:
+				if rule.name == "min" {
+					s.Minimum = &n
+				} else if rule.name == "max" {
+					s.Maximum = &n
+				}
+			}
+		}
+	}
+
+	return required
+}
@@ -0,0 +1,145 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+type specPet struct {
+	Name    string            `json:"name" validate:"required,min=2,max=64"`
+	Age     int               `json:"age" validate:"min=0,max=40"`
+	Tags    []string          `json:"tags,omitempty" validate:"max=5"`
+	Owner   *specPet          `json:"owner,omitempty"`
+	Born    time.Time         `json:"born"`
+	Labels  map[string]string `json:"labels"`
+	private string
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithOpenAPI("/openapi.json", OpenAPIInfo{Title: "Pets", Version: "1.0.0"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []struct {
+		Method string
+		Path   string
+		Fn     interface{}
+		Opts   []RouteOption
+	}{
+		{http.MethodPost, "/pets", func(ctx context.Context, p specPet) (*specPet, error) { return &p, nil }, nil},
+		{http.MethodGet, "/pets/{id}/name", func(ctx context.Context) string { return "" }, nil},
+		{http.MethodDelete, "/pets/{id}", func(ctx context.Context) error { return nil }, nil},
+		{http.MethodGet, "/planned", nil, []RouteOption{WithExample(map[string]int{"count": 1})}},
+		{http.MethodGet, "/internal", func() {}, []RouteOption{HideFromIntrospection()}},
+		{http.MethodGet, "/raw", http.NotFoundHandler(), nil},
+	}
+	for _, rt := range routes {
+		err = r.Register(rt.Method, rt.Path, rt.Fn, nil, rt.Opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+
+	var doc OpenAPIDocument
+	err = json.NewDecoder(w.Body).Decode(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != OpenAPIVersion || doc.Info.Title != "Pets" {
+		t.Errorf("unexpected header %s %+v", doc.OpenAPI, doc.Info)
+	}
+
+	for _, p := range []string{"/internal", "/raw", "/openapi.json"} {
+		if _, ok := doc.Paths[p]; ok {
+			t.Errorf("expected %s to be left out", p)
+		}
+	}
+
+	create := doc.Paths["/pets"]["post"]
+	if create == nil || create.RequestBody == nil {
+		t.Fatalf("expected POST /pets with a request body, got %+v", doc.Paths["/pets"])
+	}
+	if ref := create.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/specPet" {
+		t.Errorf("expected request body ref, got %q", ref)
+	}
+	if ref := create.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/specPet" {
+		t.Errorf("expected response ref, got %q", ref)
+	}
+	if create.Responses["default"] == nil {
+		t.Errorf("expected an error response")
+	}
+
+	pet := doc.Components.Schemas["specPet"]
+	if pet == nil {
+		t.Fatal("expected a specPet component")
+	}
+	if !reflect.DeepEqual(pet.Required, []string{"name"}) {
+		t.Errorf("expected name to be required, got %v", pet.Required)
+	}
+
+	props := []struct {
+		Name   string
+		Expect Schema
+	}{
+		{"name", Schema{Type: "string", MinLength: floatPtr(2), MaxLength: floatPtr(64)}},
+		{"age", Schema{Type: "integer", Minimum: floatPtr(0), Maximum: floatPtr(40)}},
+		{"tags", Schema{Type: "array", Items: &Schema{Type: "string"}, MaxItems: floatPtr(5)}},
+		{"owner", Schema{Ref: "#/components/schemas/specPet"}},
+		{"born", Schema{Type: "string", Format: "date-time"}},
+		{"labels", Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}},
+	}
+	for _, p := range props {
+		got := pet.Properties[p.Name]
+		if got == nil || !reflect.DeepEqual(*got, p.Expect) {
+			t.Errorf("case[%s] expected %+v got %+v", p.Name, p.Expect, got)
+		}
+	}
+	if len(pet.Properties) != len(props) {
+		t.Errorf("expected %d properties got %d", len(props), len(pet.Properties))
+	}
+
+	name := doc.Paths["/pets/{id}/name"]["get"]
+	if name == nil || len(name.Parameters) != 1 || name.Parameters[0].Name != "id" || name.Parameters[0].In != "path" {
+		t.Fatalf("expected an id path parameter, got %+v", name)
+	}
+	if _, ok := name.Responses["200"].Content["text/plain"]; !ok {
+		t.Errorf("expected a text/plain response, got %+v", name.Responses["200"].Content)
+	}
+	if name.OperationID != "get_pets_id_name" {
+		t.Errorf("unexpected operation ID %s", name.OperationID)
+	}
+
+	del := doc.Paths["/pets/{id}"]["delete"]
+	if del == nil || del.Responses["200"].Content != nil {
+		t.Errorf("expected an empty response, got %+v", del)
+	}
+
+	planned := doc.Paths["/planned"]["get"]
+	if planned == nil || planned.Responses["200"].Content["application/json"].Example == nil {
+		t.Errorf("expected the example to be documented, got %+v", planned)
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,334 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOperationsPrefix is where operation statuses are served from
+const DefaultOperationsPrefix = "/operations"
+
+const (
+	// DefaultOperationTTL is how long a MemoryOperationStore keeps finished
+	// operations for clients to poll
+	DefaultOperationTTL = 24 * time.Hour
+	// DefaultMaxOperations caps the operations a MemoryOperationStore keeps
+	DefaultMaxOperations = 10000
+	// DefaultOperationSweepInterval is how often a MemoryOperationStore used
+	// with WithOperations drops expired operations
+	DefaultOperationSweepInterval = time.Minute
+)
+
+// OperationState is the lifecycle state of an async operation
+type OperationState string
+
+const (
+	OperationPending   OperationState = "pending"
+	OperationRunning   OperationState = "running"
+	OperationSucceeded OperationState = "succeeded"
+	OperationFailed    OperationState = "failed"
+)
+
+var (
+	ErrOperationNotFound = errors.New("autohttp: operation not found")
+	ErrNoOperationStore  = errors.New("autohttp: operations are not enabled, see WithOperations")
+	// ErrTooManyOperations is returned when a MemoryOperationStore is full of
+	// unfinished operations
+	ErrTooManyOperations = errors.New("autohttp: too many operations in progress")
+)
+
+// An Operation is the status of work accepted by a 202 response
+type Operation struct {
+	ID        string         `json:"id"`
+	State     OperationState `json:"state"`
+	Result    interface{}    `json:"result,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// An OperationStore persists operation statuses. Implementations backed by a
+// database or queue let operations outlive the process that accepted them
+type OperationStore interface {
+	Get(ctx context.Context, id string) (*Operation, error)
+	Put(ctx context.Context, op *Operation) error
+}
+
+// MemoryOperationStore keeps operations in memory. Finished operations are
+// dropped ttl after they finish, and at most max operations are kept: once
+// full, the oldest finished operation makes room for a new one, and new
+// operations are refused with ErrTooManyOperations if none has finished
+type MemoryOperationStore struct {
+	ttl time.Duration
+	max int
+
+	mu  sync.RWMutex
+	ops map[string]Operation
+	now func() time.Time
+}
+
+// NewMemoryOperationStore returns a store keeping finished operations for
+// DefaultOperationTTL, and at most DefaultMaxOperations operations
+func NewMemoryOperationStore() *MemoryOperationStore {
+	return NewMemoryOperationStoreWithLimits(DefaultOperationTTL, DefaultMaxOperations)
+}
+
+// NewMemoryOperationStoreWithLimits returns a store keeping finished
+// operations for ttl, and at most max operations
+func NewMemoryOperationStoreWithLimits(ttl time.Duration, max int) *MemoryOperationStore {
+	return &MemoryOperationStore{
+		ttl: ttl,
+		max: max,
+		ops: make(map[string]Operation),
+		now: time.Now,
+	}
+}
+
+func (mos *MemoryOperationStore) Get(ctx context.Context, id string) (*Operation, error) {
+	mos.mu.RLock()
+	defer mos.mu.RUnlock()
+
+	op, ok := mos.ops[id]
+	if !ok || mos.expired(op, mos.now()) {
+		return nil, ErrOperationNotFound
+	}
+
+	return &op, nil
+}
+
+func (mos *MemoryOperationStore) Put(ctx context.Context, op *Operation) error {
+	mos.mu.Lock()
+	defer mos.mu.Unlock()
+
+	if _, ok := mos.ops[op.ID]; !ok && len(mos.ops) >= mos.max {
+		mos.sweep(mos.now())
+		if len(mos.ops) >= mos.max && !mos.evictOldestFinished() {
+			return ErrorWithCode{Err: ErrTooManyOperations, StatusCode: http.StatusServiceUnavailable}
+		}
+	}
+
+	mos.ops[op.ID] = *op
+	return nil
+}
+
+func (mos *MemoryOperationStore) expired(op Operation, now time.Time) bool {
+	return finished(op.State) && !now.Before(op.UpdatedAt.Add(mos.ttl))
+}
+
+func finished(state OperationState) bool {
+	return state == OperationSucceeded || state == OperationFailed
+}
+
+// sweep drops expired operations. Must be called with mu held
+func (mos *MemoryOperationStore) sweep(now time.Time) {
+	for id, op := range mos.ops {
+		if mos.expired(op, now) {
+			delete(mos.ops, id)
+		}
+	}
+}
+
+// evictOldestFinished drops the operation that finished first, reporting
+// whether there was one. Must be called with mu held
+func (mos *MemoryOperationStore) evictOldestFinished() bool {
+	var oldest string
+	var oldestAt time.Time
+	for id, op := range mos.ops {
+		if finished(op.State) && (oldest == "" || op.UpdatedAt.Before(oldestAt)) {
+			oldest, oldestAt = id, op.UpdatedAt
+		}
+	}
+
+	if oldest == "" {
+		return false
+	}
+
+	delete(mos.ops, oldest)
+	return true
+}
+
+// sweepEvery drops expired operations every interval until ctx ends
+func (mos *MemoryOperationStore) sweepEvery(interval time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				mos.mu.Lock()
+				mos.sweep(mos.now())
+				mos.mu.Unlock()
+			}
+		}
+	}
+}
+
+type operations struct {
+	store  OperationStore
+	prefix string
+	router *Router
+}
+
+type operationsCtxKey struct{}
+
+// WithOperations enables 202 Accepted flows. Handlers create operations with
+// NewOperation or StartOperation and return the StatusMonitor, and clients
+// poll the operation status at prefix/{id}. A MemoryOperationStore is swept
+// of expired operations as a background task
+func WithOperations(store OperationStore, prefix string) func(r *Router) error {
+	return func(r *Router) error {
+		prefix = strings.TrimSuffix(prefix, "/")
+		r.operations = &operations{store: store, prefix: prefix, router: r}
+
+		if mos, ok := store.(*MemoryOperationStore); ok {
+			r.Go("operations sweep", mos.sweepEvery(DefaultOperationSweepInterval))
+		}
+
+		return r.Register(http.MethodGet, prefix+"/*", http.HandlerFunc(r.operations.serveStatus), nil)
+	}
+}
+
+func (ops *operations) serveStatus(w http.ResponseWriter, req *http.Request) {
+	// the status route is a star route, answering every method
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		allowed := []string{http.MethodGet, http.MethodHead}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if ops.router.methodNotAllowed != nil {
+			ops.router.methodNotAllowed(w, req, allowed)
+		} else {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id := strings.TrimPrefix(req.URL.Path, ops.prefix+"/")
+	op, err := ops.store.Get(req.Context(), id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrOperationNotFound) {
+			code = http.StatusNotFound
+		}
+
+		ops.router.errorHandler()(w, ErrorWithCode{Err: err, StatusCode: code})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(op)
+}
+
+// A StatusMonitor tracks an accepted operation. Returned from a handler function,
+// it produces a 202 Accepted pointing at the operation's status
+type StatusMonitor struct {
+	ops *operations
+	op  Operation
+}
+
+// NewOperation records a pending operation for work the handler enqueues elsewhere.
+// The worker reports progress through the returned StatusMonitor
+func NewOperation(ctx context.Context) (*StatusMonitor, error) {
+	ops, ok := ctx.Value(operationsCtxKey{}).(*operations)
+	if !ok {
+		return nil, ErrNoOperationStore
+	}
+
+	now := time.Now()
+	sm := &StatusMonitor{
+		ops: ops,
+		op: Operation{
+			ID:        newRequestID(),
+			State:     OperationPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+
+	err := ops.store.Put(ctx, &sm.op)
+	if err != nil {
+		return nil, err
+	}
+
+	return sm, nil
+}
+
+// StartOperation records an operation and runs work as a background task of
+// the router, recording its outcome when it finishes. work's context is
+// canceled by StopBackground, and an operation whose work panics is failed
+func StartOperation(ctx context.Context, work func(ctx context.Context) (interface{}, error)) (*StatusMonitor, error) {
+	sm, err := NewOperation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.ops.router.runOnce("operation "+sm.op.ID, func(ctx context.Context) (err error) {
+		// statuses are recorded even once the work is canceled
+		bg := context.Background()
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("panic: %v", rec)
+				sm.Fail(bg, err)
+			}
+		}()
+
+		sm.Running(bg)
+		result, workErr := work(ctx)
+		if workErr != nil {
+			// the error is the operation's outcome, not the task's
+			return sm.Fail(bg, workErr)
+		}
+
+		return sm.Succeed(bg, result)
+	})
+
+	return sm, nil
+}
+
+// ID returns the operation ID
+func (sm *StatusMonitor) ID() string {
+	return sm.op.ID
+}
+
+// Location returns the path the operation status is served at
+func (sm *StatusMonitor) Location() string {
+	return sm.ops.prefix + "/" + sm.op.ID
+}
+
+// Running marks the operation as running
+func (sm *StatusMonitor) Running(ctx context.Context) error {
+	return sm.update(ctx, OperationRunning, nil, "")
+}
+
+// Succeed marks the operation as succeeded with result
+func (sm *StatusMonitor) Succeed(ctx context.Context, result interface{}) error {
+	return sm.update(ctx, OperationSucceeded, result, "")
+}
+
+// Fail marks the operation as failed with err
+func (sm *StatusMonitor) Fail(ctx context.Context, err error) error {
+	return sm.update(ctx, OperationFailed, nil, err.Error())
+}
+
+func (sm *StatusMonitor) update(ctx context.Context, state OperationState, result interface{}, errMsg string) error {
+	op := sm.op
+	op.State = state
+	op.Result = result
+	op.Error = errMsg
+	op.UpdatedAt = time.Now()
+
+	return sm.ops.store.Put(ctx, &op)
+}
+
+// MarshalJSON renders the operation as it was accepted
+func (sm *StatusMonitor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sm.op)
+}
@@ -0,0 +1,290 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestOperations(t *testing.T) {
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithOperations(NewMemoryOperationStore(), DefaultOperationsPrefix),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	err = r.Register(http.MethodPost, "/reports", func(ctx context.Context, in struct{ Name string }) (*StatusMonitor, error) {
+		return StartOperation(ctx, func(ctx context.Context) (interface{}, error) {
+			<-release
+			return map[string]string{"report": in.Name}, nil
+		})
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reports", strings.NewReader(`{"Name": "q4"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected %d got %d", http.StatusAccepted, w.Code)
+	}
+
+	location := w.Header().Get("Location")
+	if !strings.HasPrefix(location, DefaultOperationsPrefix+"/") {
+		t.Fatalf("unexpected location %q", location)
+	}
+
+	poll := func() Operation {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+		}
+
+		var op Operation
+		err := json.NewDecoder(w.Body).Decode(&op)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return op
+	}
+
+	if op := poll(); op.State == OperationSucceeded {
+		t.Fatalf("operation finished before it was released")
+	}
+
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		op := poll()
+		if op.State == OperationSucceeded {
+			if op.Result.(map[string]interface{})["report"] != "q4" {
+				t.Errorf("unexpected result %v", op.Result)
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("operation never succeeded, last state %q", op.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, DefaultOperationsPrefix+"/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestNewOperationRequiresStore(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewOperation(context.Background())
+	if err != ErrNoOperationStore {
+		t.Fatalf("expected ErrNoOperationStore, got %v", err)
+	}
+}
+
+func TestStartOperationPanics(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithOperations(NewMemoryOperationStore(), DefaultOperationsPrefix),
+		WithDefaultErrorHandler(func(w http.ResponseWriter, err error) {
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte("custom: " + err.Error()))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.StartBackground()
+
+	started := make(chan struct{})
+	err = r.Register(http.MethodPost, "/panics", func(ctx context.Context) (*StatusMonitor, error) {
+		return StartOperation(ctx, func(ctx context.Context) (interface{}, error) {
+			panic("boom")
+		})
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/waits", func(ctx context.Context) (*StatusMonitor, error) {
+		return StartOperation(ctx, func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(path string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("expected %d got %d", http.StatusAccepted, w.Code)
+		}
+		return w.Header().Get("Location")
+	}
+
+	panicked := start("/panics")
+	waiting := start("/waits")
+	<-started
+
+	// stopping waits for the operation tasks, so their outcomes are recorded
+	err = r.StopBackground(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name   string
+		Path   string
+		Expect string
+	}{
+		{"panicked", panicked, "panic: boom"},
+		{"canceled", waiting, context.Canceled.Error()},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+		var op Operation
+		err := json.NewDecoder(w.Body).Decode(&op)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if op.State != OperationFailed || op.Error != c.Expect {
+			t.Errorf("case[%s] expected a failed operation with %q got %+v", c.Name, c.Expect, op)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, DefaultOperationsPrefix+"/unknown", nil))
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Body.String(), "custom: ") {
+		t.Errorf("expected the router's error handler to answer, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMemoryOperationStoreLimits(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	store := NewMemoryOperationStoreWithLimits(time.Hour, 2)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	put := func(id string, state OperationState) error {
+		return store.Put(ctx, &Operation{ID: id, State: state, UpdatedAt: now})
+	}
+
+	cases := []struct {
+		Name      string
+		Put       string
+		State     OperationState
+		Advance   time.Duration
+		ExpectErr bool
+		ExpectIDs []string
+	}{
+		{"first", "a", OperationSucceeded, 0, false, []string{"a"}},
+		{"second", "b", OperationPending, time.Minute, false, []string{"a", "b"}},
+		{"evicts finished", "c", OperationRunning, time.Minute, false, []string{"b", "c"}},
+		{"refused when unfinished", "d", OperationPending, 0, true, []string{"b", "c"}},
+		{"updates fit", "b", OperationFailed, 0, false, []string{"b", "c"}},
+		{"finished expire", "", "", time.Hour, false, []string{"c"}},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+
+		if c.Put != "" {
+			err := put(c.Put, c.State)
+			if (err != nil) != c.ExpectErr {
+				t.Errorf("case[%s] expected error %t got %v", c.Name, c.ExpectErr, err)
+			}
+			if c.ExpectErr && !errors.Is(err, ErrTooManyOperations) {
+				t.Errorf("case[%s] expected ErrTooManyOperations got %v", c.Name, err)
+			}
+		}
+
+		for _, id := range []string{"a", "b", "c", "d"} {
+			_, err := store.Get(ctx, id)
+			expect := false
+			for _, want := range c.ExpectIDs {
+				expect = expect || want == id
+			}
+
+			if (err == nil) != expect {
+				t.Errorf("case[%s] expected %s kept %t got %v", c.Name, id, expect, err)
+			}
+		}
+	}
+
+	store.mu.Lock()
+	store.sweep(now)
+	kept := len(store.ops)
+	store.mu.Unlock()
+	if kept != 1 {
+		t.Errorf("expected expired operations to be swept, %d kept", kept)
+	}
+}
+
+func TestOperationStatusMethods(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithOperations(NewMemoryOperationStore(), DefaultOperationsPrefix),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Method     string
+		ExpectCode int
+	}{
+		{http.MethodGet, http.StatusNotFound},
+		{http.MethodHead, http.StatusNotFound},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+		{http.MethodDelete, http.StatusMethodNotAllowed},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.Method, DefaultOperationsPrefix+"/missing", nil))
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Method, c.ExpectCode, w.Code)
+		}
+
+		if c.ExpectCode == http.StatusMethodNotAllowed && w.Header().Get("Allow") != "GET, HEAD" {
+			t.Errorf("case[%s] expected Allow GET, HEAD got %q", c.Method, w.Header().Get("Allow"))
+		}
+	}
+}
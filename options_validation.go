@@ -0,0 +1,66 @@
+package autohttp
+
+import (
+	"errors"
+	"strings"
+)
+
+// OptionErrors collects every conflict NewRouter found between its options,
+// so they can all be fixed at once instead of one per restart. Options that
+// fail on their own stop NewRouter, which returns their error as is
+type OptionErrors []error
+
+func (oe OptionErrors) Error() string {
+	msgs := make([]string, len(oe))
+	for i, err := range oe {
+		msgs[i] = err.Error()
+	}
+
+	return "autohttp: invalid router options: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As look through every collected error,
+// from Go 1.20
+func (oe OptionErrors) Unwrap() []error {
+	return oe
+}
+
+// conflicts reports combinations of options that can't work together, or
+// that would silently do nothing
+func (r *Router) conflicts() []error {
+	var errs []error
+
+	if r.cspNoncePolicy != "" && r.embeddedAssets == nil {
+		errs = append(errs, errors.New("WithCSPNonces requires WithEmbeddedAssets"))
+	}
+
+	if r.maxHeaderValueBytes != 0 && !r.strictRequests {
+		errs = append(errs, errors.New("WithMaxHeaderValueBytes requires EnableStrictRequests"))
+	}
+
+	if r.compression != nil && len(r.compression.compressors) == 0 {
+		if r.compression.cache != nil {
+			errs = append(errs, errors.New("WithCompressionCache requires WithCompression with at least one compressor"))
+		} else {
+			errs = append(errs, errors.New("WithCompressionMinSize and WithCompressionContentTypes require WithCompression with at least one compressor"))
+		}
+	}
+
+	if r.hideRequestIDsInErrors && !r.enableRequestIDs {
+		errs = append(errs, errors.New("HideRequestIDsInErrors has no effect without EnableRequestIDs"))
+	}
+
+	if r.requestErrorHandler != nil && r.defaultErrorHandler != nil {
+		errs = append(errs, errors.New("WithDefaultErrorHandler and WithRequestErrorHandler can't be combined"))
+	}
+
+	if (r.accessLogFormat != nil || r.accessLogOptIn) && r.accessLog == nil {
+		errs = append(errs, errors.New("WithAccessLogFormat and AccessLogOptIn require WithAccessLog"))
+	}
+
+	if r.mockModeConflict {
+		errs = append(errs, errors.New("EnableMockResponses and EnableMockResponsesOnHeader can't be combined"))
+	}
+
+	return errs
+}
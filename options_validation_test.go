@@ -0,0 +1,66 @@
+package autohttp
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestOptionConflicts(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("option failed")
+	failing := func(r *Router) error {
+		return errFailed
+	}
+
+	cases := []struct {
+		Name        string
+		Options     []RouterOption
+		ExpectErrs  int
+		ExpectInErr string
+	}{
+		{"none", nil, 0, ""},
+		{"compatible", []RouterOption{EnableStrictRequests, WithMaxHeaderValueBytes(128), EnableRequestIDs, HideRequestIDsInErrors}, 0, ""},
+		{"csp-without-assets", []RouterOption{WithCSPNonces(DefaultCSPNoncePolicy)}, 1, "WithCSPNonces requires WithEmbeddedAssets"},
+		{"header-limit-without-strict", []RouterOption{WithMaxHeaderValueBytes(128)}, 1, "requires EnableStrictRequests"},
+		{"cache-without-compression", []RouterOption{WithCompressionCache(10)}, 1, "WithCompressionCache requires WithCompression"},
+		{"min-size-without-compression", []RouterOption{WithCompressionMinSize(512)}, 1, "WithCompressionMinSize and WithCompressionContentTypes require WithCompression"},
+		{"hidden-ids-without-ids", []RouterOption{HideRequestIDsInErrors}, 1, "HideRequestIDsInErrors has no effect"},
+		{"mock-modes", []RouterOption{EnableMockResponses, EnableMockResponsesOnHeader(DefaultMockHeader)}, 1, "can't be combined"},
+		{"aggregated", []RouterOption{WithMaxHeaderValueBytes(128), HideRequestIDsInErrors}, 2, "HideRequestIDsInErrors has no effect"},
+	}
+
+	// options that fail stop NewRouter before conflicts are checked
+	_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), failing, HideRequestIDsInErrors)
+	if err != errFailed {
+		t.Errorf("expected the failing option's error got %v", err)
+	}
+
+	for _, c := range cases {
+		_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), c.Options...)
+		if c.ExpectErrs == 0 {
+			if err != nil {
+				t.Errorf("case[%s] failed unexpectedly: %s", c.Name, err)
+			}
+			continue
+		}
+
+		var oe OptionErrors
+		if !errors.As(err, &oe) {
+			t.Errorf("case[%s] expected OptionErrors got %v", c.Name, err)
+			continue
+		}
+
+		if len(oe) != c.ExpectErrs {
+			t.Errorf("case[%s] expected %d errors got %d: %s", c.Name, c.ExpectErrs, len(oe), err)
+		}
+
+		if !strings.Contains(err.Error(), c.ExpectInErr) {
+			t.Errorf("case[%s] expected %q in %q", c.Name, c.ExpectInErr, err)
+		}
+	}
+}
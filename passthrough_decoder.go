@@ -0,0 +1,78 @@
+package autohttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+var (
+	requestType = reflect.TypeOf((*http.Request)(nil))
+	readerType  = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// PassthroughDecoder hands the request to raw routes untouched. Functions may
+// take, in any order, a context.Context, a *http.Request, a Header and an
+// io.Reader of the body, each at most once
+type PassthroughDecoder struct{}
+
+func (pd PassthroughDecoder) ValidateType(fn interface{}) error {
+	fnType := reflect.TypeOf(fn)
+
+	seen := make(map[reflect.Type]bool)
+	for i := 0; i < fnType.NumIn(); i++ {
+		in := fnType.In(i)
+
+		var kind reflect.Type
+		switch {
+		case in == contextType:
+			kind = contextType
+		case in == requestType:
+			kind = requestType
+		case isHeaderType(in):
+			kind = headerType
+		case in == readerType:
+			kind = readerType
+		default:
+			return errTypeInvalidAtIndex(i, in)
+		}
+
+		if seen[kind] {
+			return ErrDuplicateType
+		}
+		seen[kind] = true
+	}
+
+	return nil
+}
+
+func (pd PassthroughDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	fnType := reflect.TypeOf(fn)
+
+	callValues := make([]reflect.Value, fnType.NumIn())
+	for i := range callValues {
+		switch in := fnType.In(i); {
+		case in == contextType:
+			callValues[i] = reflect.ValueOf(r.Context())
+		case in == requestType:
+			callValues[i] = reflect.ValueOf(r)
+		case isHeaderType(in):
+			header := make(Header)
+			for k := range r.Header {
+				header[http.CanonicalHeaderKey(k)] = r.Header.Get(k)
+			}
+			callValues[i] = reflect.ValueOf(header)
+		case in == readerType:
+			var body io.Reader = http.NoBody
+			if r.Body != nil {
+				body = r.Body
+			}
+			callValues[i] = reflect.ValueOf(&body).Elem()
+		default:
+			return nil, fmt.Errorf("autohttp: can't pass %s through", in)
+		}
+	}
+
+	return callValues, nil
+}
@@ -0,0 +1,94 @@
+package autohttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRegisterWithoutDefaults(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithDefaultDecoder(nil), WithDefaultEncoder(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	raw := func(ctx context.Context, req *http.Request, body io.Reader) error {
+		b, err := io.ReadAll(body)
+		got = req.Method + " " + string(b)
+		return err
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		Fn          interface{}
+		Opts        []RouteOption
+		ExpectInErr string
+	}{
+		{"typed-without-defaults", "/typed", func(ctx context.Context, in struct{ Name string }) {}, nil, "no default decoder or encoder"},
+		{"example-without-encoder", "/example", nil, []RouteOption{WithExample(map[string]string{})}, "examples need an encoder"},
+		{"nil-fn", "/nil", nil, []RouteOption{WithDecoder(PassthroughDecoder{}), WithEncoder(NoOpEncoder{})}, "must be functions"},
+		{"not-a-func", "/string", "hello", []RouteOption{WithDecoder(PassthroughDecoder{}), WithEncoder(NoOpEncoder{})}, "must be functions"},
+		{"raw", "/raw", raw, []RouteOption{WithDecoder(PassthroughDecoder{}), WithEncoder(NoOpEncoder{})}, ""},
+	}
+
+	for _, c := range cases {
+		err := r.Register(http.MethodPost, c.Path, c.Fn, nil, c.Opts...)
+		if c.ExpectInErr == "" {
+			if err != nil {
+				t.Errorf("case[%s] failed unexpectedly: %s", c.Name, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), c.ExpectInErr) {
+			t.Errorf("case[%s] expected an error containing %q got %v", c.Name, c.ExpectInErr, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/raw", strings.NewReader("payload")))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204 got %d", w.Code)
+	}
+
+	if got != "POST payload" {
+		t.Errorf("expected the raw request to be passed through, got %q", got)
+	}
+}
+
+func TestPassthroughDecoderValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		Fn        interface{}
+		ShouldErr bool
+	}{
+		{"none", func() {}, false},
+		{"all", func(h Header, body io.Reader, r *http.Request, ctx context.Context) {}, false},
+		{"duplicate", func(r *http.Request, r2 *http.Request) {}, true},
+		{"struct", func(in struct{ Name string }) {}, true},
+	}
+
+	for _, c := range cases {
+		err := PassthroughDecoder{}.ValidateType(c.Fn)
+		if err != nil && !c.ShouldErr {
+			t.Errorf("case[%s] failed unexpectedly", c.Name)
+		}
+
+		if err == nil && c.ShouldErr {
+			t.Errorf("case[%s] did not fail when it should have", c.Name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package autohttp
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// EnablePathNormalization cleans request paths before they are matched, collapsing
+// duplicate slashes and resolving "." and ".." segments. Percent-encoding is
+// resolved too, since routes and assets are matched against the decoded path
+func EnablePathNormalization(r *Router) error {
+	r.normalizePaths = true
+	return nil
+}
+
+// EnableCanonicalRedirects redirects requests for non-canonical paths to their
+// canonical form, instead of silently rewriting them. It implies EnablePathNormalization
+func EnableCanonicalRedirects(r *Router) error {
+	r.normalizePaths = true
+	r.redirectToCanonicalPath = true
+	return nil
+}
+
+// canonicalPath cleans p, keeping any trailing slash
+func canonicalPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// normalizePath returns req with a canonical path, or writes a redirect to the
+// canonical path and returns nil
+func (r *Router) normalizePath(w http.ResponseWriter, req *http.Request) *http.Request {
+	canonical := canonicalPath(req.URL.Path)
+	if canonical == req.URL.Path && req.URL.RawPath == "" {
+		return req
+	}
+
+	u := *req.URL
+	u.Path = canonical
+	u.RawPath = ""
+
+	if r.redirectToCanonicalPath && canonical != req.URL.Path {
+		code := http.StatusPermanentRedirect
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+
+		http.Redirect(w, req, u.RequestURI(), code)
+		return nil
+	}
+
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.URL = &u
+	return req2
+}
@@ -0,0 +1,81 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		In     string
+		Expect string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/a//b", "/a/b"},
+		{"/a/./b", "/a/b"},
+		{"/a/../b", "/b"},
+		{"/../../b", "/b"},
+		{"/a/b/", "/a/b/"},
+		{"a/b", "/a/b"},
+	}
+
+	for _, c := range cases {
+		if got := canonicalPath(c.In); got != c.Expect {
+			t.Errorf("canonicalPath(%q): expected %q got %q", c.In, c.Expect, got)
+		}
+	}
+}
+
+func TestPathNormalization(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Option         RouterOption
+		Target         string
+		ExpectStatus   int
+		ExpectLocation string
+	}{
+		{"disabled", nil, "/api//users", http.StatusNotFound, ""},
+		{"rewrite", EnablePathNormalization, "/api//users", http.StatusOK, ""},
+		{"rewrite-dot-dot", EnablePathNormalization, "/api/admin/../users", http.StatusOK, ""},
+		{"rewrite-encoded", EnablePathNormalization, "/api/%75sers", http.StatusOK, ""},
+		{"redirect", EnableCanonicalRedirects, "/api/./users?page=2", http.StatusMovedPermanently, "/api/users?page=2"},
+		{"canonical", EnableCanonicalRedirects, "/api/users", http.StatusOK, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			var opts []RouterOption
+			if c.Option != nil {
+				opts = append(opts, c.Option)
+			}
+
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Register(http.MethodGet, "/api/users", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Target, nil))
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("expected %d got %d", c.ExpectStatus, w.Code)
+			}
+
+			if w.Header().Get("Location") != c.ExpectLocation {
+				t.Errorf("expected location %q got %q", c.ExpectLocation, w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package autohttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jwfriese/autohttp/internal/httpsnoop"
+)
+
+// PrometheusContentType is the media type of the Prometheus text format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the request
+// duration histogram
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds, in bytes, of the response size histogram
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// unmatchedRoute labels requests that matched no route, so raw paths never
+// become label values
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests whose method has no registered routes, so
+// clients can't add label values by making up methods
+const otherMethod = "other"
+
+// WithPrometheusMetrics counts requests and records their durations and
+// response sizes, labeled by method and route pattern, and serves them in the
+// Prometheus text format at path. An empty path only collects them, for
+// serving elsewhere with Router.PrometheusHandler. Paths left out of route
+// metrics logging, such as health checks, aren't counted
+func WithPrometheusMetrics(path string) func(r *Router) error {
+	return func(r *Router) error {
+		r.prometheus = newPrometheusMetrics()
+		if path == "" {
+			return nil
+		}
+
+		return r.registerQuiet(path, r.prometheus)
+	}
+}
+
+// PrometheusHandler serves the metrics collected by WithPrometheusMetrics, or
+// nil without it
+func (r *Router) PrometheusHandler() http.Handler {
+	if r.prometheus == nil {
+		return nil
+	}
+
+	return r.prometheus
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += v
+	h.count++
+}
+
+type routeLabels struct {
+	method string
+	route  string
+}
+
+type statusLabels struct {
+	routeLabels
+	class string
+}
+
+type prometheusMetrics struct {
+	mu        sync.Mutex
+	requests  map[statusLabels]uint64
+	durations map[routeLabels]*histogram
+	sizes     map[routeLabels]*histogram
+}
+
+func newPrometheusMetrics() *prometheusMetrics {
+	return &prometheusMetrics{
+		requests:  make(map[statusLabels]uint64),
+		durations: make(map[routeLabels]*histogram),
+		sizes:     make(map[routeLabels]*histogram),
+	}
+}
+
+// metricsMethod returns the method label of requests made with method
+func (r *Router) metricsMethod(method string) string {
+	method = strings.ToUpper(method)
+	if _, ok := r.Routes[method]; ok {
+		return method
+	}
+
+	if _, ok := r.Routes[http.MethodGet]; ok && method == http.MethodHead {
+		// HEAD requests are answered by GET routes
+		return method
+	}
+
+	return otherMethod
+}
+
+func (pm *prometheusMetrics) record(req *http.Request, method string, m httpsnoop.Metrics) {
+	route := RoutePattern(req.Context())
+	if route == "" {
+		route = unmatchedRoute
+	}
+
+	rl := routeLabels{method: method, route: route}
+	sl := statusLabels{routeLabels: rl, class: strconv.Itoa(m.Code/100) + "xx"}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.requests[sl]++
+
+	d, ok := pm.durations[rl]
+	if !ok {
+		d = newHistogram(DefaultDurationBuckets)
+		pm.durations[rl] = d
+	}
+	d.observe(m.Duration.Seconds())
+
+	s, ok := pm.sizes[rl]
+	if !ok {
+		s = newHistogram(DefaultSizeBuckets)
+		pm.sizes[rl] = s
+	}
+	s.observe(float64(m.Written))
+}
+
+func (pm *prometheusMetrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", PrometheusContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	pm.write(w)
+}
+
+// write renders the metrics in the Prometheus text format, sorted so scrapes
+// are stable
+func (pm *prometheusMetrics) write(w io.Writer) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP autohttp_requests_total Requests served, by route and status class.")
+	fmt.Fprintln(w, "# TYPE autohttp_requests_total counter")
+
+	statuses := make([]statusLabels, 0, len(pm.requests))
+	for sl := range pm.requests {
+		statuses = append(statuses, sl)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].routeLabels != statuses[j].routeLabels {
+			return lessRouteLabels(statuses[i].routeLabels, statuses[j].routeLabels)
+		}
+		return statuses[i].class < statuses[j].class
+	})
+
+	for _, sl := range statuses {
+		fmt.Fprintf(w, "autohttp_requests_total{%s,status_class=%q} %d\n", sl.routeLabels, sl.class, pm.requests[sl])
+	}
+
+	writeHistograms(w, "autohttp_request_duration_seconds", "Time taken to serve requests, by route.", pm.durations)
+	writeHistograms(w, "autohttp_response_size_bytes", "Size of response bodies, by route.", pm.sizes)
+}
+
+func writeHistograms(w io.Writer, name, help string, hists map[routeLabels]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	labels := make([]routeLabels, 0, len(hists))
+	for rl := range hists {
+		labels = append(labels, rl)
+	}
+	sort.Slice(labels, func(i, j int) bool { return lessRouteLabels(labels[i], labels[j]) })
+
+	for _, rl := range labels {
+		h := hists[rl]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, rl, strconv.FormatFloat(upper, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, rl, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, rl, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, rl, h.count)
+	}
+}
+
+func lessRouteLabels(a, b routeLabels) bool {
+	if a.route != b.route {
+		return a.route < b.route
+	}
+
+	return a.method < b.method
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (rl routeLabels) String() string {
+	return `method="` + labelEscaper.Replace(rl.method) + `",route="` + labelEscaper.Replace(rl.route) + `"`
+}
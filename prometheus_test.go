@@ -0,0 +1,72 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithPrometheusMetrics("/metrics"),
+		WithHealth("/healthz", Health{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/pets/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if PathParam(r.Context(), "id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("rex"))
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/pets/1", "/pets/2", "/pets/missing", "/nowhere/1", "/healthz"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/pets/1", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Header().Get("Content-Type") != PrometheusContentType {
+		t.Errorf("expected %s got %s", PrometheusContentType, w.Header().Get("Content-Type"))
+	}
+
+	body := w.Body.String()
+	cases := []struct {
+		Name   string
+		Expect string
+		Absent bool
+	}{
+		{"ok-count", `autohttp_requests_total{method="GET",route="/pets/{id}",status_class="2xx"} 2`, false},
+		{"client-error-count", `autohttp_requests_total{method="GET",route="/pets/{id}",status_class="4xx"} 1`, false},
+		{"unmatched", `autohttp_requests_total{method="GET",route="unmatched",status_class="4xx"} 1`, false},
+		{"unregistered-method", `autohttp_requests_total{method="other",route="unmatched",status_class="4xx"} 1`, false},
+		{"raw-method", "BREW", true},
+		{"duration-count", `autohttp_request_duration_seconds_count{method="GET",route="/pets/{id}"} 3`, false},
+		{"duration-inf", `autohttp_request_duration_seconds_bucket{method="GET",route="/pets/{id}",le="+Inf"} 3`, false},
+		{"size-bucket", `autohttp_response_size_bytes_bucket{method="GET",route="/pets/{id}",le="100"} 3`, false},
+		{"size-sum", `autohttp_response_size_bytes_sum{method="GET",route="/pets/{id}"} 6`, false},
+		{"type", "# TYPE autohttp_request_duration_seconds histogram", false},
+		{"raw-path", "/nowhere/1", true},
+		{"quiet", "/healthz", true},
+	}
+
+	for _, c := range cases {
+		if strings.Contains(body, c.Expect) == c.Absent {
+			t.Errorf("case[%s] expected presence %t of %q in\n%s", c.Name, !c.Absent, c.Expect, body)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package autohttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ProtobufContentType is the media type of protobuf bodies
+const ProtobufContentType = "application/x-protobuf"
+
+// A ProtoMarshaler is a message that marshals itself, as gogo and vtprotobuf
+// generated messages do
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// A ProtoUnmarshaler is a message that unmarshals itself
+type ProtoUnmarshaler interface {
+	Unmarshal(b []byte) error
+}
+
+var (
+	protoMarshalerType   = reflect.TypeOf((*ProtoMarshaler)(nil)).Elem()
+	protoUnmarshalerType = reflect.TypeOf((*ProtoUnmarshaler)(nil)).Elem()
+)
+
+// ProtobufEncoder writes application/x-protobuf responses. autohttp doesn't
+// depend on a protobuf library, so Marshal plugs one in, e.g.
+//
+//	func(v interface{}) ([]byte, error) { return proto.Marshal(v.(proto.Message)) }
+//
+// If Marshal is nil, return values must implement ProtoMarshaler
+type ProtobufEncoder struct {
+	Marshal func(v interface{}) ([]byte, error)
+}
+
+func NewProtobufEncoder(marshal func(v interface{}) ([]byte, error)) *ProtobufEncoder {
+	return &ProtobufEncoder{Marshal: marshal}
+}
+
+func (pe *ProtobufEncoder) ValidateType(fn interface{}) error {
+	fnType := reflect.ValueOf(fn).Type()
+	for i := 0; i < fnType.NumOut(); i++ {
+		out := fnType.Out(i)
+		if isErrorType(out) {
+			continue
+		}
+
+		if out.Kind() != reflect.Ptr {
+			return fmt.Errorf("protobuf encoder needs messages returned by pointer, got %s", out)
+		}
+
+		if pe.Marshal == nil && !out.Implements(protoMarshalerType) {
+			return fmt.Errorf("protobuf encoder needs a Marshal func or %s to implement ProtoMarshaler", out)
+		}
+	}
+
+	return nil
+}
+
+func (pe *ProtobufEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	hw("Content-Type", ProtobufContentType)
+
+	if value == nil {
+		return http.StatusOK, nil, nil
+	}
+
+	var b []byte
+	var err error
+	if pe.Marshal != nil {
+		b, err = pe.Marshal(value)
+	} else if pm, ok := value.(ProtoMarshaler); ok {
+		b, err = pm.Marshal()
+	} else {
+		err = fmt.Errorf("protobuf encoder cannot marshal %T", value)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, bytes.NewReader(b), nil
+}
+
+// ProtobufDecoder reads application/x-protobuf request bodies into a message
+// argument, taken by pointer alongside the same context and Header arguments
+// as the JSONDecoder. Unmarshal plugs in a protobuf library, e.g.
+//
+//	func(b []byte, v interface{}) error { return proto.Unmarshal(b, v.(proto.Message)) }
+//
+// If Unmarshal is nil, messages must implement ProtoUnmarshaler
+type ProtobufDecoder struct {
+	MaxBytesToRead int64
+	Unmarshal      func(b []byte, v interface{}) error
+}
+
+func NewProtobufDecoder(unmarshal func(b []byte, v interface{}) error) *ProtobufDecoder {
+	return &ProtobufDecoder{
+		MaxBytesToRead: DefaultMaxBytesToRead,
+		Unmarshal:      unmarshal,
+	}
+}
+
+func (pd *ProtobufDecoder) isMessage(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
+}
+
+func (pd *ProtobufDecoder) ValidateType(fn interface{}) error {
+	_, _, decodeIdx, err := bodyInputIndices(fn, pd.isMessage)
+	if err != nil {
+		return err
+	}
+
+	if decodeIdx != uIdx && pd.Unmarshal == nil && !reflect.TypeOf(fn).In(decodeIdx).Implements(protoUnmarshalerType) {
+		return errors.New("protobuf decoder needs an Unmarshal func or messages implementing ProtoUnmarshaler")
+	}
+
+	return nil
+}
+
+// Decode returns the reflect values needed to call the fn
+// from the *http.Request
+func (pd *ProtobufDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	if !strings.Contains(r.Header.Get("Content-Type"), ProtobufContentType) {
+		return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	ctxIdx, hdrIdx, decodeIdx, err := bodyInputIndices(fn, pd.isMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	fnReflectType := reflect.ValueOf(fn).Type()
+	callValues := make([]reflect.Value, fnReflectType.NumIn())
+
+	if ctxIdx != uIdx {
+		callValues[ctxIdx] = reflect.ValueOf(r.Context())
+	}
+
+	if hdrIdx != uIdx {
+		callValues[hdrIdx] = reflect.ValueOf(requestHeader(r))
+	}
+
+	if decodeIdx != uIdx {
+		b, err := io.ReadAll(io.LimitReader(r.Body, pd.MaxBytesToRead+1))
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+		if int64(len(b)) > pd.MaxBytesToRead {
+			return nil, ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", pd.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+		}
+
+		msg := reflect.New(fnReflectType.In(decodeIdx).Elem())
+		if pd.Unmarshal != nil {
+			err = pd.Unmarshal(b, msg.Interface())
+		} else {
+			err = msg.Interface().(ProtoUnmarshaler).Unmarshal(b)
+		}
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		callValues[decodeIdx] = msg
+	}
+
+	return callValues, nil
+}
@@ -0,0 +1,114 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+// testMessage stands in for a generated message, marshaling to its name
+type testMessage struct {
+	Name string `json:"name"`
+}
+
+func (tm *testMessage) Marshal() ([]byte, error) {
+	return []byte("pb:" + tm.Name), nil
+}
+
+func (tm *testMessage) Unmarshal(b []byte) error {
+	if !bytes.HasPrefix(b, []byte("pb:")) {
+		return errors.New("not a message")
+	}
+	tm.Name = string(b[3:])
+	return nil
+}
+
+func TestProtobufCodec(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithEncoderFor(ProtobufContentType, NewProtobufEncoder(nil)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := ContentTypeDecoder{
+		"application/json":  NewJSONDecoder(),
+		ProtobufContentType: NewProtobufDecoder(nil),
+	}
+	err = r.Register(http.MethodPost, "/greet", func(ctx context.Context, in *testMessage) (*testMessage, error) {
+		return &testMessage{Name: "hello " + in.Name}, nil
+	}, nil, WithDecoder(decoder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		ContentType string
+		Accept      string
+		Body        string
+		ExpectCode  int
+		ExpectBody  string
+	}{
+		{"protobuf", ProtobufContentType, ProtobufContentType, "pb:ada", http.StatusOK, "pb:hello ada"},
+		{"json", "application/json", "application/json", `{"name":"ada"}`, http.StatusOK, `{"name":"hello ada"}`},
+		{"protobuf-in-json-out", ProtobufContentType, "", "pb:ada", http.StatusOK, `{"name":"hello ada"}`},
+		{"bad-message", ProtobufContentType, ProtobufContentType, "ada", http.StatusBadRequest, ""},
+		{"unsupported", "text/plain", "", "ada", http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", c.ContentType)
+		if c.Accept != "" {
+			req.Header.Set("Accept", c.Accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) {
+			t.Errorf("case[%s] expected %q in %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+}
+
+func TestProtobufValidation(t *testing.T) {
+	t.Parallel()
+
+	type plain struct{ Name string }
+
+	cases := []struct {
+		Name      string
+		Codec     interface{ ValidateType(interface{}) error }
+		Fn        interface{}
+		ShouldErr bool
+	}{
+		{"decoder-message", NewProtobufDecoder(nil), func(ctx context.Context, in *testMessage) {}, false},
+		{"decoder-by-value", NewProtobufDecoder(nil), func(in testMessage) {}, true},
+		{"decoder-no-unmarshal", NewProtobufDecoder(nil), func(in *plain) {}, true},
+		{"decoder-custom-unmarshal", NewProtobufDecoder(func(b []byte, v interface{}) error { return nil }), func(in *plain) {}, false},
+		{"encoder-message", NewProtobufEncoder(nil), func() (*testMessage, error) { return nil, nil }, false},
+		{"encoder-no-marshal", NewProtobufEncoder(nil), func() *plain { return nil }, true},
+		{"encoder-by-value", NewProtobufEncoder(func(v interface{}) ([]byte, error) { return nil, nil }), func() plain { return plain{} }, true},
+	}
+
+	for _, c := range cases {
+		err := c.Codec.ValidateType(c.Fn)
+		if (err != nil) != c.ShouldErr {
+			t.Errorf("case[%s] expected error %t got %v", c.Name, c.ShouldErr, err)
+		}
+	}
+}
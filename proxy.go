@@ -0,0 +1,76 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/fortytw2/lounge"
+)
+
+// Proxy forwards every request under prefix, for any method, to target, with
+// prefix replaced by target's path. Responses are flushed to the client as
+// the backend writes them, so server-sent events and chunked streams pass
+// through unbuffered, and upgraded connections such as WebSockets are
+// tunneled. When the client goes away the backend request is canceled.
+// Long-lived streams are still cut off by WithRequestTimeout, if the router
+// sets one
+func (r *Router) Proxy(prefix string, target *url.URL, opts ...RouteOption) error {
+	if target == nil || target.Scheme == "" || target.Host == "" {
+		return errors.New("autohttp: Proxy needs an absolute target URL")
+	}
+
+	prefix = "/" + strings.Trim(prefix, "/")
+	pattern := strings.TrimSuffix(prefix, "/") + "/*"
+
+	return r.Register(http.MethodGet, pattern, newStreamingProxy(r.log, prefix, target), nil, opts...)
+}
+
+func newStreamingProxy(log lounge.Log, prefix string, target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			path := strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(prefix, "/"))
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+
+			req.Header.Set("X-Forwarded-Host", req.Host)
+			if req.TLS != nil {
+				req.Header.Set("X-Forwarded-Proto", "https")
+			} else {
+				req.Header.Set("X-Forwarded-Proto", "http")
+			}
+
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = strings.TrimSuffix(target.Path, "/") + path
+			req.URL.RawPath = ""
+			if target.RawQuery != "" && req.URL.RawQuery != "" {
+				req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+			} else if target.RawQuery != "" {
+				req.URL.RawQuery = target.RawQuery
+			}
+			req.Host = target.Host
+
+			// the default user agent would be sent otherwise
+			if _, ok := req.Header["User-Agent"]; !ok {
+				req.Header.Set("User-Agent", "")
+			}
+		},
+		// flush every write, so streams reach the client as they're produced
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			// a client that hung up needs no response, and isn't a backend failure
+			if errors.Is(err, context.Canceled) && req.Context().Err() != nil {
+				return
+			}
+
+			log.Errorf("autohttp: proxying %s %s to %s failed: %s", req.Method, req.URL.Path, target.Host, err)
+			DefaultErrorHandler(w, ErrorWithCode{Err: fmt.Errorf("bad gateway"), StatusCode: http.StatusBadGateway})
+		},
+	}
+}
@@ -0,0 +1,158 @@
+package autohttp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func newProxyBackend(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 2; i++ {
+				fmt.Fprintf(w, "data: %d\n\n", i)
+				w.(http.Flusher).Flush()
+				time.Sleep(50 * time.Millisecond)
+			}
+
+		case "/v1/ws":
+			if r.Header.Get("Upgrade") != "websocket" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+
+			buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			buf.Flush()
+
+			line, _ := buf.ReadString('\n')
+			buf.WriteString("echo " + line)
+			buf.Flush()
+
+		default:
+			fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Forwarded-Host"))
+		}
+	}))
+}
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+
+	backend := newProxyBackend(t)
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL + "/v1")
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), EnableRouteMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Proxy("/api", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	front := httptest.NewServer(r)
+	defer front.Close()
+
+	t.Run("plain", func(t *testing.T) {
+		resp, err := http.Post(front.URL+"/api/pets?limit=1", "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		expect := "/v1/pets limit=1 " + strings.TrimPrefix(front.URL, "http://")
+		if string(body) != expect {
+			t.Errorf("expected %q got %q", expect, body)
+		}
+	})
+
+	t.Run("server-sent-events", func(t *testing.T) {
+		resp, err := http.Get(front.URL + "/api/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		// the first event arrives before the backend finishes
+		start := time.Now()
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line != "data: 0\n" || time.Since(start) > 40*time.Millisecond {
+			t.Errorf("expected the first event flushed immediately got %q after %s", line, time.Since(start))
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, front.URL+"/api/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("expected 101 got %d", resp.StatusCode)
+		}
+
+		conn, ok := resp.Body.(io.ReadWriter)
+		if !ok {
+			t.Fatal("expected a writable upgraded body")
+		}
+
+		io.WriteString(conn, "hello\n")
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line != "echo hello\n" {
+			t.Errorf("expected echo hello got %q", line)
+		}
+	})
+
+	t.Run("bad-gateway", func(t *testing.T) {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dead, _ := url.Parse("http://127.0.0.1:1")
+		err = r.Proxy("/api/", dead)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/pets", nil))
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("expected 502 got %d", w.Code)
+		}
+	})
+}
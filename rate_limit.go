@@ -0,0 +1,281 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A RateLimitKey extracts the key a request is rate limited under, or false
+// for requests that aren't limited
+type RateLimitKey func(r *http.Request) (string, bool)
+
+// RateLimitByIP limits requests by the address they came from
+func RateLimitByIP(r *http.Request) (string, bool) {
+	ip := remoteIP(r)
+	return ip, ip != ""
+}
+
+// RateLimitByHeader limits requests by the value of the header name, such as
+// an API key
+func RateLimitByHeader(name string) RateLimitKey {
+	return func(r *http.Request) (string, bool) {
+		v := r.Header.Get(name)
+		return v, v != ""
+	}
+}
+
+// RateLimitByPrincipal limits requests by the authenticated user, as set by
+// SetPrincipal, so it must come after the authentication middleware
+func RateLimitByPrincipal(r *http.Request) (string, bool) {
+	principal := PrincipalFromContext(r.Context())
+	return principal, principal != ""
+}
+
+// A RateLimitStore keeps the token buckets of a RateLimitMiddleware. Take
+// refills the bucket of key at rate tokens per second, up to burst, and takes
+// a token from it. When the bucket is empty it returns false and how long
+// until a token is available. Stores shared between instances, such as one
+// backed by Redis, must take tokens atomically
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+// A QuotaStore counts requests against quotas of a fixed number of requests
+// per window. TakeQuota counts a request for key, starting a window at the
+// first, and returns false and how long until the window ends once quota
+// requests have been counted in it. RateLimitStores shared between instances
+// should implement it too, so tenant quotas are shared
+type QuotaStore interface {
+	TakeQuota(ctx context.Context, key string, quota int, window time.Duration) (bool, time.Duration, error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled, and can be dropped
+	full time.Time
+}
+
+type quotaWindow struct {
+	end  time.Time
+	used int
+}
+
+// MemoryRateLimitStore keeps token buckets and quotas in memory, for a single
+// instance. Full buckets and ended quota windows are swept periodically.
+// It is safe for concurrent use
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	quotas    map[string]*quotaWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		quotas:  make(map[string]*quotaWindow),
+		now:     time.Now,
+	}
+}
+
+func (mrs *MemoryRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	now := mrs.now()
+	capacity := math.Max(float64(burst), 1)
+	mrs.sweep(now, time.Duration(capacity/rate*float64(time.Second)))
+
+	b, ok := mrs.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		mrs.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((capacity - b.tokens) / rate * float64(time.Second)))
+
+	if !allowed {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second)), nil
+	}
+
+	return true, 0, nil
+}
+
+func (mrs *MemoryRateLimitStore) TakeQuota(ctx context.Context, key string, quota int, window time.Duration) (bool, time.Duration, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	now := mrs.now()
+	mrs.sweep(now, window)
+
+	q, ok := mrs.quotas[key]
+	if !ok || !now.Before(q.end) {
+		q = &quotaWindow{end: now.Add(window)}
+		mrs.quotas[key] = q
+	}
+
+	if q.used >= quota {
+		return false, q.end.Sub(now), nil
+	}
+
+	q.used++
+	return true, 0, nil
+}
+
+// sweep drops buckets that have refilled and quota windows that have ended,
+// at most once per interval. Must be called with mu held
+func (mrs *MemoryRateLimitStore) sweep(now time.Time, interval time.Duration) {
+	if now.Sub(mrs.lastSweep) < interval {
+		return
+	}
+
+	for key, b := range mrs.buckets {
+		if !now.Before(b.full) {
+			delete(mrs.buckets, key)
+		}
+	}
+
+	for key, q := range mrs.quotas {
+		if !now.Before(q.end) {
+			delete(mrs.quotas, key)
+		}
+	}
+
+	mrs.lastSweep = now
+}
+
+// RateLimitedError is returned for requests over their rate limit
+type RateLimitedError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (rle *RateLimitedError) Error() string {
+	return "rate limit exceeded"
+}
+
+func (rle *RateLimitedError) ResponseHeaders() http.Header {
+	h := make(http.Header)
+	h.Set("Retry-After", strconv.Itoa(int(math.Ceil(rle.RetryAfter.Seconds()))))
+	return h
+}
+
+// A RateLimit allows Rate requests per second and bursts of up to Burst
+type RateLimit struct {
+	Rate  float64 `json:"rate" yaml:"rate"`
+	Burst int     `json:"burst" yaml:"burst"`
+}
+
+func (rl RateLimit) validate() error {
+	if rl.Rate <= 0 {
+		return errors.New("autohttp: rate limits need a positive rate")
+	}
+
+	return nil
+}
+
+// RateLimitMiddleware limits requests with a token bucket per key, allowing
+// rate requests per second and bursts of up to burst. Requests over the limit
+// get a 429 with a Retry-After header through the route's error handler.
+// Requests without a key aren't limited. The limit can be changed while
+// serving with SetLimit, or through ApplyConfig, see WithRateLimit
+type RateLimitMiddleware struct {
+	limit atomic.Value // RateLimit
+	key   RateLimitKey
+	store RateLimitStore
+}
+
+// NewRateLimitMiddleware limits requests by key, keeping buckets in store, or
+// a MemoryRateLimitStore when store is nil
+func NewRateLimitMiddleware(rate float64, burst int, key RateLimitKey, store RateLimitStore) (*RateLimitMiddleware, error) {
+	if key == nil {
+		return nil, errors.New("autohttp: rate limits need a key")
+	}
+
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+
+	rlm := &RateLimitMiddleware{
+		key:   key,
+		store: store,
+	}
+
+	err := rlm.SetLimit(RateLimit{Rate: rate, Burst: burst})
+	if err != nil {
+		return nil, err
+	}
+
+	return rlm, nil
+}
+
+// Limit returns the current limit
+func (rlm *RateLimitMiddleware) Limit() RateLimit {
+	return rlm.limit.Load().(RateLimit)
+}
+
+// SetLimit replaces the limit, taking effect for the next request
+func (rlm *RateLimitMiddleware) SetLimit(limit RateLimit) error {
+	err := limit.validate()
+	if err != nil {
+		return err
+	}
+
+	rlm.limit.Store(limit)
+	return nil
+}
+
+// WithRateLimit names rlm so its limit can be changed at runtime through the
+// RateLimits of a RuntimeConfig. The middleware still has to be added to
+// the routes it limits
+func WithRateLimit(name string, rlm *RateLimitMiddleware) func(r *Router) error {
+	return func(r *Router) error {
+		if rlm == nil {
+			return errors.New("autohttp: WithRateLimit needs a rate limit middleware")
+		}
+
+		if r.rateLimits == nil {
+			r.rateLimits = make(map[string]*RateLimitMiddleware)
+		}
+		r.rateLimits[name] = rlm
+		return nil
+	}
+}
+
+func (rlm *RateLimitMiddleware) Before(r *http.Request, h *Handler) error {
+	key, ok := rlm.key(r)
+	if !ok {
+		return nil
+	}
+
+	limit := rlm.Limit()
+	allowed, retryAfter, err := rlm.store.Take(r.Context(), key, limit.Rate, limit.Burst)
+	if err != nil {
+		return fmt.Errorf("autohttp: rate limiting %s: %w", key, err)
+	}
+
+	if !allowed {
+		return MiddlewareError{
+			StatusCode: http.StatusTooManyRequests,
+			Err:        &RateLimitedError{Key: key, RetryAfter: retryAfter},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	rlm, err := NewRateLimitMiddleware(2, 2, RateLimitByHeader("X-API-Key"), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHandler(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		NoOpDecoder{},
+		&JSONEncoder{},
+		[]Middleware{rlm},
+		DefaultErrorHandler,
+		func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name             string
+		Key              string
+		Advance          time.Duration
+		ExpectStatus     int
+		ExpectRetryAfter string
+	}{
+		{"burst-1", "a", 0, http.StatusOK, ""},
+		{"burst-2", "a", 0, http.StatusOK, ""},
+		{"limited", "a", 0, http.StatusTooManyRequests, "1"},
+		{"isolated", "b", 0, http.StatusOK, ""},
+		{"refilled", "a", 500 * time.Millisecond, http.StatusOK, ""},
+		{"limited-again", "a", 0, http.StatusTooManyRequests, "1"},
+		{"no-key", "", 0, http.StatusOK, ""},
+		{"swept", "a", time.Minute, http.StatusOK, ""},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.Key != "" {
+			r.Header.Set("X-API-Key", c.Key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+		}
+
+		if got := w.Header().Get("Retry-After"); got != c.ExpectRetryAfter {
+			t.Errorf("case[%s] expected Retry-After %q got %q", c.Name, c.ExpectRetryAfter, got)
+		}
+	}
+
+	if len(store.buckets) != 1 {
+		t.Errorf("expected refilled buckets to be swept, got %d", len(store.buckets))
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:4321"
+
+	if key, ok := RateLimitByIP(r); key != "10.0.0.1" || !ok {
+		t.Errorf("expected the remote IP, got %q %t", key, ok)
+	}
+
+	if key, ok := RateLimitByPrincipal(r); key != "" || ok {
+		t.Errorf("expected no principal outside a router, got %q %t", key, ok)
+	}
+
+	_, err := NewRateLimitMiddleware(0, 1, RateLimitByIP, nil)
+	if err == nil {
+		t.Error("expected a zero rate to be rejected")
+	}
+
+	rlm, err := NewRateLimitMiddleware(1, 1, RateLimitByIP, failingRateLimitStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rlm.Before(r, nil)
+	if err == nil || statusCodeForError(err) != http.StatusInternalServerError {
+		t.Errorf("expected store failures to be server errors, got %v", err)
+	}
+}
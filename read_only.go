@@ -0,0 +1,95 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrReadOnly is returned for write requests to routes in read-only mode
+var ErrReadOnly = errors.New("autohttp: route is temporarily read-only")
+
+// readOnlyPaths tracks path prefixes that currently reject writes.
+// It is safe to modify while the router is serving requests
+type readOnlyPaths struct {
+	mu       sync.RWMutex
+	prefixes map[string]bool
+}
+
+func newReadOnlyPaths() *readOnlyPaths {
+	return &readOnlyPaths{prefixes: make(map[string]bool)}
+}
+
+func (rop *readOnlyPaths) set(prefix string, readOnly bool) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	rop.mu.Lock()
+	defer rop.mu.Unlock()
+
+	if readOnly {
+		rop.prefixes[prefix] = true
+	} else {
+		delete(rop.prefixes, prefix)
+	}
+}
+
+// replace swaps every prefix for prefixes at once, returning the old ones
+func (rop *readOnlyPaths) replace(prefixes []string) []string {
+	updated := make(map[string]bool, len(prefixes))
+	for _, prefix := range prefixes {
+		updated[strings.TrimSuffix(prefix, "/")] = true
+	}
+
+	old := rop.list()
+
+	rop.mu.Lock()
+	rop.prefixes = updated
+	rop.mu.Unlock()
+
+	return old
+}
+
+// list returns the read-only prefixes, sorted
+func (rop *readOnlyPaths) list() []string {
+	rop.mu.RLock()
+	defer rop.mu.RUnlock()
+
+	prefixes := make([]string, 0, len(rop.prefixes))
+	for prefix := range rop.prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	return prefixes
+}
+
+func (rop *readOnlyPaths) matches(path string) bool {
+	rop.mu.RLock()
+	defer rop.mu.RUnlock()
+
+	for prefix := range rop.prefixes {
+		if hasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetReadOnly flips every route under prefix in or out of read-only mode.
+// While read-only, requests other than GET, HEAD and OPTIONS are rejected
+// with a 503. Use "/" to put the whole router into read-only mode
+func (r *Router) SetReadOnly(prefix string, readOnly bool) {
+	r.readOnly.set(prefix, readOnly)
+}
+
+// SetReadOnly flips every route in the group in or out of read-only mode
+func (g *Group) SetReadOnly(readOnly bool) {
+	g.router.SetReadOnly(g.prefix, readOnly)
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
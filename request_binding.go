@@ -0,0 +1,172 @@
+package autohttp
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Fields of a decoded struct can be bound from the request itself rather
+// than its body. GET and HEAD requests to routes whose input has bound fields
+// are decoded from them alone, other requests have them applied over the
+// decoded body
+const (
+	// QueryTag names the URL query parameter a field of a decoded struct is
+	// bound from, e.g. `query:"page"`
+	QueryTag = "query"
+	// HeaderTag names the request header a field is bound from, e.g.
+	// `header:"X-Request-ID"`. Slices collect every value of the header
+	HeaderTag = "header"
+	// CookieTag names the cookie a field is bound from, e.g. `cookie:"session"`
+	CookieTag = "cookie"
+)
+
+// boundTags are the request parts fields can be bound from, in binding order
+var boundTags = []string{QueryTag, HeaderTag, CookieTag}
+
+// A boundField is a field of a decoded struct filled from the request itself
+type boundField struct {
+	reflect.StructField
+	// tag is where the field is bound from, one of boundTags
+	tag  string
+	name string
+}
+
+// boundFields lists the fields of t, or of the struct t points to, that are
+// bound from the query, headers or cookies
+func boundFields(t reflect.Type) []boundField {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []boundField
+	for _, f := range formFields(t) {
+		for _, tag := range boundTags {
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name != "" && name != "-" {
+				fields = append(fields, boundField{StructField: f, tag: tag, name: name})
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// isBoundField reports whether f is filled from the request rather than a body
+func isBoundField(f reflect.StructField) bool {
+	for _, tag := range boundTags {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkBoundFields reports bound fields of t that can't be parsed from text
+func checkBoundFields(t reflect.Type) error {
+	for _, f := range boundFields(t) {
+		if !isFormValueType(f.Type) {
+			return fmt.Errorf("%s cannot be bound to field %s of type %s", f.describe(), f.Name, f.Type)
+		}
+	}
+
+	return nil
+}
+
+func (bf boundField) describe() string {
+	switch bf.tag {
+	case HeaderTag:
+		return fmt.Sprintf("header %q", bf.name)
+	case CookieTag:
+		return fmt.Sprintf("cookie %q", bf.name)
+	}
+
+	return fmt.Sprintf("query parameter %q", bf.name)
+}
+
+// values returns the request's values for the field, and whether it has any
+func (bf boundField) values(r *http.Request) ([]string, bool) {
+	switch bf.tag {
+	case HeaderTag:
+		vals := r.Header.Values(bf.name)
+		return vals, len(vals) > 0
+	case CookieTag:
+		var vals []string
+		for _, c := range r.Cookies() {
+			if c.Name == bf.name {
+				vals = append(vals, c.Value)
+			}
+		}
+		return vals, len(vals) > 0
+	}
+
+	vals, ok := r.URL.Query()[bf.name]
+	return vals, ok
+}
+
+// bindRequest sets the bound fields of v, a struct or pointer to one, from
+// the request. Fields the request has no value for are left alone
+func bindRequest(r *http.Request, v reflect.Value, fields []boundField) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	for _, f := range fields {
+		vals, ok := f.values(r)
+		if !ok {
+			continue
+		}
+
+		err := setFormValue(v.FieldByIndex(f.Index), vals)
+		if err != nil {
+			return ErrorWithCode{
+				Err:        fmt.Errorf("%s: invalid value %q, expected %s", f.describe(), strings.Join(vals, ","), describeBoundType(f.Type)),
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeBoundType names the kind of value a bound field expects, for errors
+func describeBoundType(t reflect.Type) string {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return "a list of " + strings.TrimPrefix(strings.TrimPrefix(describeBoundType(t.Elem()), "a "), "an ") + "s"
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return "an RFC 3339 time"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+
+	return "a " + t.String()
+}
+
+// isQueryMethod reports whether requests with method carry their input in
+// the query rather than a body
+func isQueryMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
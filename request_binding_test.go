@@ -0,0 +1,200 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+type listQuery struct {
+	Page    int       `query:"page" json:"-" validate:"min=1"`
+	Active  *bool     `query:"active" json:"-"`
+	IDs     []int64   `query:"id" json:"-"`
+	Since   time.Time `query:"since" json:"-"`
+	Filter  string    `json:"filter"`
+	Ignored string    `query:"-" json:"-"`
+}
+
+func TestQueryBinding(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got listQuery
+	fn := func(ctx context.Context, q listQuery) error {
+		got = q
+		return nil
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		err = r.Register(method, "/items", fn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = RegisterTyped(r, http.MethodGet, "/typed", func(ctx context.Context, q *listQuery) (*listQuery, error) {
+		got = *q
+		return q, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yes := true
+	cases := []struct {
+		Name        string
+		Method      string
+		Path        string
+		Body        string
+		ExpectCode  int
+		ExpectError string
+		Expect      listQuery
+	}{
+		{"get", http.MethodGet, "/items?page=2&active=true&id=1&id=2&since=2024-05-01T10:00:00Z&Ignored=x", "", http.StatusOK, "",
+			listQuery{Page: 2, Active: &yes, IDs: []int64{1, 2}, Since: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}},
+		{"typed", http.MethodGet, "/typed?page=3", "", http.StatusOK, "", listQuery{Page: 3}},
+		{"post-overlay", http.MethodPost, "/items?page=4", `{"filter":"new"}`, http.StatusOK, "", listQuery{Page: 4, Filter: "new"}},
+		{"bad-int", http.MethodGet, "/items?page=two", "", http.StatusBadRequest, `query parameter \"page\": invalid value \"two\", expected an integer`, listQuery{}},
+		{"bad-slice", http.MethodGet, "/items?page=1&id=1&id=x", "", http.StatusBadRequest, `expected a list of integers`, listQuery{}},
+		{"bad-bool", http.MethodGet, "/typed?page=1&active=maybe", "", http.StatusBadRequest, `expected a boolean`, listQuery{}},
+		{"bad-time", http.MethodGet, "/items?page=1&since=yesterday", "", http.StatusBadRequest, `expected an RFC 3339 time`, listQuery{}},
+		{"validation", http.MethodGet, "/items?page=0", "", http.StatusBadRequest, "", listQuery{}},
+	}
+
+	for _, c := range cases {
+		got = listQuery{}
+		req := httptest.NewRequest(c.Method, c.Path, strings.NewReader(c.Body))
+		if c.Body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+			continue
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectError) {
+			t.Errorf("case[%s] expected error %s got %s", c.Name, c.ExpectError, w.Body.String())
+		}
+
+		if !reflect.DeepEqual(got, c.Expect) {
+			t.Errorf("case[%s] expected %+v got %+v", c.Name, c.Expect, got)
+		}
+	}
+
+	err = r.Register(http.MethodGet, "/bad", func(ctx context.Context, q struct {
+		Filter map[string]string `query:"filter"`
+	}) error {
+		return nil
+	}, nil)
+	if err == nil {
+		t.Error("expected an unbindable query field to fail registration")
+	}
+}
+
+type sessionInput struct {
+	RequestID string   `header:"X-Request-ID" validate:"required"`
+	Retries   int      `header:"X-Retry-Count" json:"-"`
+	Forwarded []string `header:"X-Forwarded-For" json:"-"`
+	Session   string   `cookie:"session" json:"-"`
+	Theme     *string  `cookie:"theme" json:"-"`
+	Name      string   `json:"name" form:"name"`
+}
+
+func TestHeaderAndCookieBinding(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got sessionInput
+	fn := func(ctx context.Context, in sessionInput) error {
+		got = in
+		return nil
+	}
+	err = r.Register(http.MethodGet, "/session", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Register(http.MethodPost, "/session", fn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Register(http.MethodPut, "/session", fn, nil, WithDecoder(&FormDecoder{MaxBytesToRead: 256, DisallowUnknownFields: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dark := "dark"
+	cases := []struct {
+		Name        string
+		Method      string
+		ContentType string
+		Body        string
+		Headers     map[string][]string
+		Cookies     []*http.Cookie
+		ExpectCode  int
+		ExpectError string
+		Expect      sessionInput
+	}{
+		{"get", http.MethodGet, "", "", map[string][]string{
+			"X-Request-ID":    {"req-1"},
+			"X-Retry-Count":   {"2"},
+			"X-Forwarded-For": {"10.0.0.1", "10.0.0.2"},
+		}, []*http.Cookie{{Name: "session", Value: "s3cr3t"}, {Name: "theme", Value: "dark"}}, http.StatusOK, "",
+			sessionInput{RequestID: "req-1", Retries: 2, Forwarded: []string{"10.0.0.1", "10.0.0.2"}, Session: "s3cr3t", Theme: &dark}},
+		{"json-body", http.MethodPost, "application/json", `{"name":"ada"}`, map[string][]string{"X-Request-ID": {"req-2"}}, nil, http.StatusOK, "",
+			sessionInput{RequestID: "req-2", Name: "ada"}},
+		{"form-body", http.MethodPut, "application/x-www-form-urlencoded", "name=grace", map[string][]string{"X-Request-ID": {"req-3"}},
+			[]*http.Cookie{{Name: "session", Value: "abc"}}, http.StatusOK, "",
+			sessionInput{RequestID: "req-3", Session: "abc", Name: "grace"}},
+		{"bad-header", http.MethodGet, "", "", map[string][]string{"X-Request-ID": {"req-4"}, "X-Retry-Count": {"many"}}, nil,
+			http.StatusBadRequest, `header \"X-Retry-Count\": invalid value \"many\", expected an integer`, sessionInput{}},
+		{"missing-required", http.MethodGet, "", "", nil, nil, http.StatusBadRequest, "", sessionInput{}},
+	}
+
+	for _, c := range cases {
+		got = sessionInput{}
+		req := httptest.NewRequest(c.Method, "/session", strings.NewReader(c.Body))
+		if c.ContentType != "" {
+			req.Header.Set("Content-Type", c.ContentType)
+		}
+		for k, vals := range c.Headers {
+			for _, v := range vals {
+				req.Header.Add(k, v)
+			}
+		}
+		for _, cookie := range c.Cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+			continue
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectError) {
+			t.Errorf("case[%s] expected error %s got %s", c.Name, c.ExpectError, w.Body.String())
+		}
+
+		if !reflect.DeepEqual(got, c.Expect) {
+			t.Errorf("case[%s] expected %+v got %+v", c.Name, c.Expect, got)
+		}
+	}
+}
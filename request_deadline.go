@@ -0,0 +1,109 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// RequestDeadlineHeader carries an absolute RFC 3339 deadline
+	RequestDeadlineHeader = "X-Request-Deadline"
+	// GRPCTimeoutHeader carries a relative gRPC style timeout, such as 250m
+	GRPCTimeoutHeader = "Grpc-Timeout"
+)
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+type deadlinePropagation struct {
+	trusted func(req *http.Request) bool
+	max     time.Duration
+}
+
+// WithRequestTimeout bounds every request's context to d. Propagated
+// deadlines can shorten it, but never extend it
+func WithRequestTimeout(d time.Duration) func(r *Router) error {
+	return func(r *Router) error {
+		if d < 0 {
+			return errors.New("autohttp: request timeout can't be negative")
+		}
+
+		r.requestTimeout = d
+		return nil
+	}
+}
+
+// WithDeadlinePropagation honors deadlines sent by callers for which trusted
+// returns true, setting the request context deadline accordingly. Deadlines are
+// read from X-Request-Deadline or Grpc-Timeout, and are never further away than
+// max, zero leaving them uncapped
+func WithDeadlinePropagation(trusted func(req *http.Request) bool, max time.Duration) func(r *Router) error {
+	return func(r *Router) error {
+		if trusted == nil {
+			return errors.New("autohttp: deadline propagation requires a trust check")
+		}
+
+		if max < 0 {
+			return errors.New("autohttp: propagated deadline cap can't be negative")
+		}
+
+		r.deadlines = &deadlinePropagation{trusted: trusted, max: max}
+		return nil
+	}
+}
+
+// apply returns req with the propagated deadline, if there is one
+func (dp *deadlinePropagation) apply(req *http.Request) (*http.Request, context.CancelFunc) {
+	if !dp.trusted(req) {
+		return req, func() {}
+	}
+
+	timeout, ok := requestedTimeout(req, time.Now())
+	if !ok {
+		return req, func() {}
+	}
+
+	if dp.max > 0 && timeout > dp.max {
+		timeout = dp.max
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return req.WithContext(ctx), cancel
+}
+
+// requestedTimeout reads the timeout a caller asked for, relative to now
+func requestedTimeout(req *http.Request, now time.Time) (time.Duration, bool) {
+	if v := req.Header.Get(RequestDeadlineHeader); v != "" {
+		deadline, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return 0, false
+		}
+
+		return deadline.Sub(now), true
+	}
+
+	if v := req.Header.Get(GRPCTimeoutHeader); len(v) > 1 && len(v) <= 9 {
+		unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+		if !ok {
+			return 0, false
+		}
+
+		n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+
+		return time.Duration(n) * unit, true
+	}
+
+	return 0, false
+}
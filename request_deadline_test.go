@@ -0,0 +1,132 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestedTimeout(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 12, 22, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		Name     string
+		Header   string
+		Value    string
+		Expect   time.Duration
+		ExpectOK bool
+	}{
+		{"none", "", "", 0, false},
+		{"deadline", RequestDeadlineHeader, "2021-12-22T12:00:02Z", 2 * time.Second, true},
+		{"bad-deadline", RequestDeadlineHeader, "tomorrow", 0, false},
+		{"grpc-millis", GRPCTimeoutHeader, "250m", 250 * time.Millisecond, true},
+		{"grpc-seconds", GRPCTimeoutHeader, "3S", 3 * time.Second, true},
+		{"grpc-bad-unit", GRPCTimeoutHeader, "3x", 0, false},
+		{"grpc-too-long", GRPCTimeoutHeader, "123456789S", 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.Header != "" {
+				req.Header.Set(c.Header, c.Value)
+			}
+
+			got, ok := requestedTimeout(req, now)
+			if ok != c.ExpectOK || got != c.Expect {
+				t.Errorf("expected %s %t got %s %t", c.Expect, c.ExpectOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	trusted := func(req *http.Request) bool {
+		return req.Header.Get("X-Internal") == "true"
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithDeadlinePropagation(trusted, 5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining time.Duration
+	var hasDeadline bool
+	err = r.Register(http.MethodGet, "/work", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var deadline time.Time
+		deadline, hasDeadline = r.Context().Deadline()
+		remaining = time.Until(deadline)
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Internal     bool
+		Timeout      string
+		ExpectMax    time.Duration
+		ExpectBounds bool
+	}{
+		{"untrusted", false, "1S", 0, false},
+		{"trusted", true, "1S", time.Second, true},
+		{"bounded", true, "60S", 5 * time.Second, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/work", nil)
+			req.Header.Set(GRPCTimeoutHeader, c.Timeout)
+			if c.Internal {
+				req.Header.Set("X-Internal", "true")
+			}
+
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if hasDeadline != c.ExpectBounds {
+				t.Fatalf("expected deadline %t", c.ExpectBounds)
+			}
+
+			if c.ExpectBounds && (remaining > c.ExpectMax || remaining < c.ExpectMax-time.Second) {
+				t.Errorf("expected about %s remaining, got %s", c.ExpectMax, remaining)
+			}
+		})
+	}
+}
+
+func TestDeadlinePropagationUncapped(t *testing.T) {
+	trusted := func(req *http.Request) bool { return true }
+
+	_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithDeadlinePropagation(trusted, -time.Second))
+	if err == nil {
+		t.Error("expected a negative cap to be rejected")
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithDeadlinePropagation(trusted, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining time.Duration
+	err = r.Register(http.MethodGet, "/work", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		remaining = time.Until(deadline)
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/work", nil)
+	req.Header.Set(GRPCTimeoutHeader, "60S")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if remaining < 59*time.Second {
+		t.Errorf("expected the propagated deadline to be left uncapped, got %s remaining", remaining)
+	}
+}
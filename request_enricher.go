@@ -0,0 +1,38 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// A RequestEnricher returns the context a request should be served with,
+// typically ctx with org-wide values such as region, deployment or tenant
+type RequestEnricher func(ctx context.Context, r *http.Request) context.Context
+
+// WithRequestEnricher runs enrichers, in order, on every request before
+// routing and middleware. They see request IDs and propagated deadlines
+func WithRequestEnricher(enrichers ...RequestEnricher) func(r *Router) error {
+	return func(r *Router) error {
+		for _, e := range enrichers {
+			if e == nil {
+				return errors.New("autohttp: nil request enricher")
+			}
+		}
+
+		r.enrichers = append(r.enrichers, enrichers...)
+		return nil
+	}
+}
+
+// enrich applies the router's enrichers to req
+func (r *Router) enrich(req *http.Request) *http.Request {
+	ctx := req.Context()
+	for _, e := range r.enrichers {
+		if enriched := e(ctx, req); enriched != nil {
+			ctx = enriched
+		}
+	}
+
+	return req.WithContext(ctx)
+}
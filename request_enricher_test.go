@@ -0,0 +1,72 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type regionCtxKey struct{}
+type tenantCtxKey struct{}
+
+type beforeFunc func(r *http.Request) error
+
+func (bf beforeFunc) Before(r *http.Request, h *Handler) error {
+	return bf(r)
+}
+
+func TestRequestEnricher(t *testing.T) {
+	t.Parallel()
+
+	region := func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, regionCtxKey{}, "us-east-1")
+	}
+	tenant := func(ctx context.Context, r *http.Request) context.Context {
+		// enrichers run in order and see the request ID
+		if RequestIDFromContext(ctx) == "" {
+			return nil
+		}
+		return context.WithValue(ctx, tenantCtxKey{}, r.Header.Get("X-Tenant")+"@"+ctx.Value(regionCtxKey{}).(string))
+	}
+
+	var seen []string
+	mw := beforeFunc(func(r *http.Request) error {
+		seen = append(seen, "middleware:"+r.Context().Value(tenantCtxKey{}).(string))
+		return nil
+	})
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		EnableRequestIDs,
+		WithRequestEnricher(region, tenant),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/", func(ctx context.Context) {
+		seen = append(seen, "handler:"+ctx.Value(tenantCtxKey{}).(string))
+	}, []Middleware{mw})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "acme")
+	r.ServeHTTP(w, req)
+
+	expect := []string{"middleware:acme@us-east-1", "handler:acme@us-east-1"}
+	if len(seen) != len(expect) || seen[0] != expect[0] || seen[1] != expect[1] {
+		t.Errorf("expected %v got %v", expect, seen)
+	}
+
+	_, err = NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithRequestEnricher(nil))
+	if err == nil {
+		t.Error("expected a nil enricher to fail")
+	}
+}
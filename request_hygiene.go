@@ -0,0 +1,87 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxHeaderValueBytes is the longest single header value allowed in strict mode
+const DefaultMaxHeaderValueBytes = 8 << 10
+
+var (
+	ErrConflictingLength = errors.New("autohttp: conflicting Content-Length and Transfer-Encoding")
+	ErrMaliciousPath     = errors.New("autohttp: malformed request path")
+)
+
+// criticalHeaders may appear at most once in strict mode
+var criticalHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"Transfer-Encoding",
+	"Authorization",
+	"Host",
+}
+
+// EnableStrictRequests rejects requests that are commonly used for request
+// smuggling or to slip past path based rules: conflicting Content-Length and
+// Transfer-Encoding, duplicated critical headers, oversized header values,
+// and paths containing NUL bytes or ".." segments
+func EnableStrictRequests(r *Router) error {
+	r.strictRequests = true
+	if r.maxHeaderValueBytes == 0 {
+		r.maxHeaderValueBytes = DefaultMaxHeaderValueBytes
+	}
+
+	return nil
+}
+
+// WithMaxHeaderValueBytes sets the longest header value allowed in strict mode
+func WithMaxHeaderValueBytes(n int) func(r *Router) error {
+	return func(r *Router) error {
+		r.maxHeaderValueBytes = n
+		return nil
+	}
+}
+
+func checkRequestHygiene(req *http.Request, maxHeaderValueBytes int) error {
+	_, hasLength := req.Header["Content-Length"]
+	if hasLength && (len(req.TransferEncoding) > 0 || req.Header.Get("Transfer-Encoding") != "") {
+		return ErrorWithCode{Err: ErrConflictingLength, StatusCode: http.StatusBadRequest}
+	}
+
+	for _, h := range criticalHeaders {
+		if len(req.Header.Values(h)) > 1 {
+			return ErrorWithCode{Err: fmt.Errorf("autohttp: duplicate %s header", h), StatusCode: http.StatusBadRequest}
+		}
+	}
+
+	for k, vals := range req.Header {
+		for _, v := range vals {
+			if len(v) > maxHeaderValueBytes {
+				return ErrorWithCode{Err: fmt.Errorf("autohttp: %s header too large", k), StatusCode: http.StatusRequestHeaderFieldsTooLarge}
+			}
+		}
+	}
+
+	if isMaliciousPath(req.URL.Path) || isMaliciousPath(req.URL.RawPath) {
+		return ErrorWithCode{Err: ErrMaliciousPath, StatusCode: http.StatusBadRequest}
+	}
+
+	return nil
+}
+
+func isMaliciousPath(p string) bool {
+	if strings.Contains(p, "\x00") || strings.Contains(strings.ToLower(p), "%00") {
+		return true
+	}
+
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." || strings.EqualFold(segment, "%2e%2e") {
+			return true
+		}
+	}
+
+	return false
+}
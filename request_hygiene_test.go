@@ -0,0 +1,64 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestStrictRequests(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), EnableStrictRequests, WithMaxHeaderValueBytes(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	err = r.Register(http.MethodGet, "/files", ok, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Target       string
+		Header       http.Header
+		Chunked      bool
+		ExpectStatus int
+	}{
+		{"clean", "/files", nil, false, http.StatusOK},
+		{"length-and-chunked", "/files", http.Header{"Content-Length": {"4"}}, true, http.StatusBadRequest},
+		{"duplicate-content-type", "/files", http.Header{"Content-Type": {"a/b", "c/d"}}, false, http.StatusBadRequest},
+		{"duplicate-authorization", "/files", http.Header{"Authorization": {"a", "b"}}, false, http.StatusBadRequest},
+		{"oversized-header", "/files", http.Header{"X-Big": {strings.Repeat("a", 65)}}, false, http.StatusRequestHeaderFieldsTooLarge},
+		{"dot-dot", "/files/../etc/passwd", nil, false, http.StatusBadRequest},
+		{"encoded-dot-dot", "/files/%2e%2e/etc/passwd", nil, false, http.StatusBadRequest},
+		{"encoded-nul", "/files%00", nil, false, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.Target, nil)
+			for k, vals := range c.Header {
+				req.Header[k] = vals
+			}
+			if c.Chunked {
+				req.TransferEncoding = []string{"chunked"}
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("expected %d got %d", c.ExpectStatus, w.Code)
+			}
+
+			if c.ExpectStatus != http.StatusOK && w.Header().Get("Connection") != "close" {
+				t.Error("expected the connection to be closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package autohttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is read from incoming requests and written to error responses
+// so that user reports can be correlated with server logs
+const RequestIDHeader = "X-Request-ID"
+
+// requests are allowed to bring their own ID, up to a sane length
+const maxIncomingRequestIDLength = 128
+
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext returns the ID assigned to the request by the Router,
+// or an empty string if request IDs are not enabled
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+func withRequestID(req *http.Request) *http.Request {
+	id := req.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), requestIDCtxKey{}, id))
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxIncomingRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,104 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestIDsInErrors(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Options       []RouterOption
+		IncomingID    string
+		ExpectID      bool
+		ExpectIDValue string
+	}{
+		{
+			"disabled",
+			nil,
+			"",
+			false,
+			"",
+		},
+		{
+			"generated",
+			[]RouterOption{EnableRequestIDs},
+			"",
+			true,
+			"",
+		},
+		{
+			"incoming",
+			[]RouterOption{EnableRequestIDs},
+			"abc-123",
+			true,
+			"abc-123",
+		},
+		{
+			"hidden",
+			[]RouterOption{EnableRequestIDs, HideRequestIDsInErrors},
+			"abc-123",
+			false,
+			"",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), c.Options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Register(http.MethodPost, "/fail", func(ctx context.Context, in struct{ Name string }) error {
+				return errors.New("boom")
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/fail", strings.NewReader(`{"Name": "x"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if c.IncomingID != "" {
+				req.Header.Set(RequestIDHeader, c.IncomingID)
+			}
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("expected %d got %d", http.StatusInternalServerError, w.Code)
+			}
+
+			var body map[string]string
+			err = json.NewDecoder(w.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			headerID := w.Header().Get(RequestIDHeader)
+			if !c.ExpectID {
+				if headerID != "" || body["request_id"] != "" {
+					t.Fatalf("expected no request id, got header %q body %q", headerID, body["request_id"])
+				}
+				return
+			}
+
+			if headerID == "" || headerID != body["request_id"] {
+				t.Fatalf("expected matching request ids, got header %q body %q", headerID, body["request_id"])
+			}
+
+			if c.ExpectIDValue != "" && headerID != c.ExpectIDValue {
+				t.Fatalf("expected request id %q got %q", c.ExpectIDValue, headerID)
+			}
+		})
+	}
+}
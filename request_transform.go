@@ -0,0 +1,56 @@
+package autohttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A RequestTransformer rewrites an incoming request's body or headers before
+// the Decoder runs, e.g. to migrate a v1 payload to the v2 shape a handler expects
+type RequestTransformer func(r *http.Request) error
+
+// WithRequestTransform runs transformers, in order, after the route's
+// middleware and before decoding
+func WithRequestTransform(transformers ...RequestTransformer) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.requestTransformers = append(rc.requestTransformers, transformers...)
+		return nil
+	}
+}
+
+// JSONBodyTransformer returns a RequestTransformer that decodes the JSON body
+// into a generic value, hands it to fn, and re-encodes the result as the new body.
+// Requests without a JSON content type are left untouched
+func JSONBodyTransformer(fn func(body interface{}) (interface{}, error)) RequestTransformer {
+	return func(r *http.Request) error {
+		if r.Body == nil || !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+			return nil
+		}
+
+		var body interface{}
+		err := json.NewDecoder(io.LimitReader(r.Body, DefaultMaxBytesToRead)).Decode(&body)
+		if err != nil {
+			return ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+		r.Body.Close()
+
+		transformed, err := fn(body)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(transformed)
+		if err != nil {
+			return err
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(b))
+		r.ContentLength = int64(len(b))
+		r.Header.Set("Content-Length", strconv.Itoa(len(b)))
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestTransform(t *testing.T) {
+	// v1 clients send {"name": "..."}, v2 handlers expect {"FullName": "..."}
+	migrateV1 := JSONBodyTransformer(func(body interface{}) (interface{}, error) {
+		m, ok := body.(map[string]interface{})
+		if !ok {
+			return nil, ErrorWithCode{Err: errors.New("expected an object"), StatusCode: http.StatusBadRequest}
+		}
+
+		if name, ok := m["name"]; ok {
+			delete(m, "name")
+			m["FullName"] = name
+		}
+
+		return m, nil
+	})
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/users", func(ctx context.Context, in struct{ FullName string }) map[string]string {
+		return map[string]string{"name": in.FullName}
+	}, nil, WithRequestTransform(migrateV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Body         string
+		ExpectStatus int
+		ExpectRes    string
+	}{
+		{"v1", `{"name": "ada"}`, http.StatusOK, `{"name":"ada"}`},
+		{"v2", `{"FullName": "ada"}`, http.StatusOK, `{"name":"ada"}`},
+		{"not-an-object", `[1]`, http.StatusBadRequest, `{"error":"expected an object"}`},
+		{"invalid-json", `{`, http.StatusBadRequest, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(c.Body))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("expected %d got %d", c.ExpectStatus, w.Code)
+			}
+
+			if c.ExpectRes != "" && strings.TrimSpace(w.Body.String()) != c.ExpectRes {
+				t.Errorf("expected %q got %q", c.ExpectRes, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package autohttp
+
+import "net/http"
+
+// Response wraps a handler's return value to control how it's written while
+// still going through the route's Encoder. Zero fields leave the encoder's
+// choices alone, so a handler can return
+//
+//	autohttp.Response{Body: user, ContentType: "application/vnd.myapp.v2+json"}
+//
+// to version a JSON body without a custom encoder
+type Response struct {
+	Body interface{}
+	// ContentType replaces the Content-Type set by the encoder
+	ContentType string
+	// Status replaces the encoder's status code
+	Status int
+	// Header is added to the response
+	Header http.Header
+}
+
+// unwrapResponse splits a Response from the value it carries
+func unwrapResponse(value interface{}) (interface{}, *Response) {
+	switch resp := value.(type) {
+	case Response:
+		return resp.Body, &resp
+	case *Response:
+		if resp == nil {
+			return nil, nil
+		}
+		return resp.Body, resp
+	}
+
+	return value, nil
+}
+
+// apply writes the overrides to header, returning the status code to use
+func (resp *Response) apply(header http.Header, code int) int {
+	for k, vals := range resp.Header {
+		for _, v := range vals {
+			header.Add(k, v)
+		}
+	}
+
+	if resp.ContentType != "" {
+		header.Set("Content-Type", resp.ContentType)
+	}
+
+	if resp.Status != 0 {
+		return resp.Status
+	}
+
+	return code
+}
@@ -0,0 +1,261 @@
+package autohttp
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultResponseCacheEntries bounds the number of responses a route caches
+const DefaultResponseCacheEntries = 1024
+
+// A CacheKeyFunc derives a response cache key from a request and its decoded
+// input, letting semantically identical requests share an entry. input is nil
+// for handlers that take no decoded input. Returning "" leaves the request
+// uncached
+type CacheKeyFunc func(r *http.Request, input interface{}) string
+
+// DefaultCacheKey keys responses on the method, URL, Accept header and the
+// JSON encoding of input. Inputs that can't be encoded aren't cached. Routes
+// using it don't cache requests carrying credentials, which may get
+// responses for their user only, see WithCacheKey
+func DefaultCacheKey(r *http.Request, input interface{}) string {
+	key := r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("Accept")
+	if input == nil {
+		return key
+	}
+
+	b, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+
+	return key + " " + string(b)
+}
+
+// WithResponseCache caches the route's successful responses for ttl
+func WithResponseCache(ttl time.Duration) RouteOption {
+	return func(rc *routeConfig) error {
+		if ttl <= 0 {
+			return errors.New("autohttp: response caches need a positive ttl")
+		}
+
+		if rc.responseCache == nil {
+			rc.responseCache = newResponseCache(ttl, DefaultResponseCacheEntries, DefaultCacheKey)
+			return nil
+		}
+
+		rc.responseCache.ttl = ttl
+		return nil
+	}
+}
+
+// WithStaleWhileRevalidate keeps serving expired responses for up to window
+// while a single request regenerates them, so an entry expiring under load
+// doesn't send every request to the handler at once. Until the refresh
+// succeeds the stale copy is served, marked "X-Cache: STALE".
+// It must be used alongside WithResponseCache
+func WithStaleWhileRevalidate(window time.Duration) RouteOption {
+	return func(rc *routeConfig) error {
+		if rc.responseCache == nil {
+			rc.responseCache = newResponseCache(0, DefaultResponseCacheEntries, DefaultCacheKey)
+		}
+
+		rc.responseCache.stale = window
+		return nil
+	}
+}
+
+// WithCacheKey sets how the route's response cache keys requests.
+// It must be used alongside WithResponseCache
+func WithCacheKey(fn CacheKeyFunc) RouteOption {
+	return func(rc *routeConfig) error {
+		if rc.responseCache == nil {
+			rc.responseCache = newResponseCache(0, DefaultResponseCacheEntries, fn)
+			rc.responseCache.customKey = true
+			return nil
+		}
+
+		rc.responseCache.keyFn = fn
+		rc.responseCache.customKey = true
+		return nil
+	}
+}
+
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+
+	// refreshing is set while a request regenerates a stale entry
+	refreshing bool
+}
+
+// responseCache is a size bounded LRU of encoded responses
+type responseCache struct {
+	ttl   time.Duration
+	stale time.Duration
+	size  int
+	keyFn CacheKeyFunc
+	// customKey is set by WithCacheKey, trusting keyFn to tell users apart
+	customKey bool
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+func newResponseCache(ttl time.Duration, size int, keyFn CacheKeyFunc) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		size:    size,
+		keyFn:   keyFn,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// key derives the cache key from the request and the decoded call values
+func (rc *responseCache) key(r *http.Request, callValues []reflect.Value) string {
+	var input interface{}
+	for _, cv := range callValues {
+		if !cv.IsValid() || isContextType(cv.Type()) || isHeaderType(cv.Type()) {
+			continue
+		}
+
+		input = cv.Interface()
+		break
+	}
+
+	return rc.inputKey(r, input)
+}
+
+// inputKey derives the cache key from the request and its decoded input, or
+// "" if the response mustn't be cached
+func (rc *responseCache) inputKey(r *http.Request, input interface{}) string {
+	if !rc.customKey && hasCredentials(r) {
+		return ""
+	}
+
+	return rc.keyFn(r, input)
+}
+
+// hasCredentials reports whether r identifies its user
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+func (rc *responseCache) get(key string) (*cachedResponse, bool) {
+	cached, hit := rc.lookup(key)
+	return cached, hit != ""
+}
+
+// lookup returns the entry to serve for key and whether it is a "HIT" or
+// "STALE", or "" if the caller should regenerate the response. Of the
+// requests finding an entry stale, only the first is told to regenerate it
+func (rc *responseCache) lookup(key string) (*cachedResponse, string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	el, ok := rc.entries[key]
+	if !ok {
+		return nil, ""
+	}
+
+	cached := el.Value.(*cachedResponse)
+	now := rc.now()
+	if now.Before(cached.expires) {
+		rc.order.MoveToFront(el)
+		return cached, "HIT"
+	}
+
+	if now.Before(cached.expires.Add(rc.stale)) {
+		rc.order.MoveToFront(el)
+		if cached.refreshing {
+			return cached, "STALE"
+		}
+
+		cached.refreshing = true
+		return nil, ""
+	}
+
+	rc.order.Remove(el)
+	delete(rc.entries, key)
+	return nil, ""
+}
+
+func (rc *responseCache) put(cached *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	cached.expires = rc.now().Add(rc.ttl)
+	if el, ok := rc.entries[cached.key]; ok {
+		el.Value = cached
+		rc.order.MoveToFront(el)
+		return
+	}
+
+	rc.entries[cached.key] = rc.order.PushFront(cached)
+	if rc.order.Len() > rc.size {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// recordHeaders returns a HeaderWriter setting headers on header, and the
+// headers it set. Only those describe a cached response, headers set for the
+// request that generated it, such as CORS or cookies, must not be replayed
+func recordHeaders(header http.Header) (http.Header, HeaderWriter) {
+	recorded := http.Header{}
+	return recorded, func(key, val string) {
+		header.Set(key, val)
+		recorded.Set(key, val)
+	}
+}
+
+// store caches a successful encoded response with header, returning a reader
+// over the body
+func (rc *responseCache) store(key string, status int, header http.Header, body io.Reader) (io.Reader, error) {
+	if status < 200 || status >= 300 {
+		return body, nil
+	}
+
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rc.put(&cachedResponse{
+		key:    key,
+		status: status,
+		header: header.Clone(),
+		body:   b,
+	})
+
+	return bytes.NewReader(b), nil
+}
+
+// serveCached writes a cached response, hit being "HIT" or "STALE"
+func (h *Handler) serveCached(w http.ResponseWriter, r *http.Request, cached *cachedResponse, hit string) {
+	for k, vals := range cached.header {
+		w.Header()[k] = append([]string(nil), vals...)
+	}
+	w.Header().Set("X-Cache", hit)
+
+	h.writeResponse(w, r, cached.status, bytes.NewReader(cached.body))
+}
@@ -0,0 +1,294 @@
+package autohttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	type lookup struct {
+		IDs []int
+	}
+
+	calls := 0
+	fn := func(ctx context.Context, in lookup) map[string]int {
+		calls++
+		sum := 0
+		for _, id := range in.IDs {
+			sum += id
+		}
+		return map[string]int{"sum": sum}
+	}
+
+	// the order IDs are requested in doesn't change the response
+	sortedIDs := func(r *http.Request, input interface{}) string {
+		ids := append([]int(nil), input.(lookup).IDs...)
+		sort.Ints(ids)
+		return fmt.Sprint(ids)
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/lookup", fn, nil, WithResponseCache(time.Minute), WithCacheKey(sortedIDs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Body        string
+		ExpectCache string
+		ExpectCalls int
+	}{
+		{"miss", `{"IDs": [1, 2, 3]}`, "MISS", 1},
+		{"hit", `{"IDs": [1, 2, 3]}`, "HIT", 1},
+		{"reordered-hit", `{"IDs": [3, 1, 2]}`, "HIT", 1},
+		{"different-input", `{"IDs": [4]}`, "MISS", 2},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/lookup", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+
+		r.ServeHTTP(w, req)
+
+		if w.Header().Get("X-Cache") != c.ExpectCache {
+			t.Errorf("case[%s] expected cache %s got %s", c.Name, c.ExpectCache, w.Header().Get("X-Cache"))
+		}
+
+		if calls != c.ExpectCalls {
+			t.Errorf("case[%s] expected %d calls got %d", c.Name, c.ExpectCalls, calls)
+		}
+
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("case[%s] lost the content type", c.Name)
+		}
+	}
+}
+
+func TestDefaultCacheKey(t *testing.T) {
+	t.Parallel()
+
+	type pet struct {
+		Name string
+	}
+
+	calls := 0
+	fn := func(ctx context.Context, in pet) map[string]string {
+		calls++
+		return map[string]string{"name": in.Name}
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/pets", fn, nil, WithResponseCache(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Body        string
+		Header      string
+		ExpectCache string
+		ExpectCalls int
+		ExpectBody  string
+	}{
+		{"miss", `{"Name": "rex"}`, "", "MISS", 1, `"name":"rex"`},
+		{"hit", `{"Name": "rex"}`, "", "HIT", 1, `"name":"rex"`},
+		{"different-body", `{"Name": "fido"}`, "", "MISS", 2, `"name":"fido"`},
+		{"authorization", `{"Name": "rex"}`, "Authorization", "", 3, `"name":"rex"`},
+		{"cookie", `{"Name": "rex"}`, "Cookie", "", 4, `"name":"rex"`},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		if c.Header != "" {
+			req.Header.Set(c.Header, "secret")
+		}
+
+		r.ServeHTTP(w, req)
+
+		if w.Header().Get("X-Cache") != c.ExpectCache {
+			t.Errorf("case[%s] expected cache %q got %q", c.Name, c.ExpectCache, w.Header().Get("X-Cache"))
+		}
+
+		if calls != c.ExpectCalls {
+			t.Errorf("case[%s] expected %d calls got %d", c.Name, c.ExpectCalls, calls)
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) {
+			t.Errorf("case[%s] expected %s in %s", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	rc := newResponseCache(time.Minute, 2, DefaultCacheKey)
+	rc.now = func() time.Time { return now }
+
+	rc.put(&cachedResponse{key: "a", status: http.StatusOK})
+	if _, ok := rc.get("a"); !ok {
+		t.Fatal("expected a cache hit")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := rc.get("a"); ok {
+		t.Fatal("expected the entry to expire")
+	}
+
+	rc.put(&cachedResponse{key: "a", status: http.StatusOK})
+	rc.put(&cachedResponse{key: "b", status: http.StatusOK})
+	rc.put(&cachedResponse{key: "c", status: http.StatusOK})
+	if _, ok := rc.get("a"); ok {
+		t.Fatal("expected the oldest entry to be evicted")
+	}
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	rc := newResponseCache(time.Minute, 2, DefaultCacheKey)
+	rc.stale = 30 * time.Second
+	rc.now = func() time.Time { return now }
+	rc.put(&cachedResponse{key: "a", status: http.StatusOK})
+
+	cases := []struct {
+		Name      string
+		Advance   time.Duration
+		Refresh   bool
+		ExpectHit string
+	}{
+		{"fresh", 0, false, "HIT"},
+		{"first-stale-regenerates", time.Minute, false, ""},
+		{"others-get-stale", 0, false, "STALE"},
+		{"still-stale", 10 * time.Second, false, "STALE"},
+		{"refreshed", 0, true, "HIT"},
+		{"stale-again", time.Minute, false, ""},
+		{"past-window", time.Minute, false, ""},
+		{"evicted", 0, false, ""},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+		if c.Refresh {
+			rc.put(&cachedResponse{key: "a", status: http.StatusOK})
+		}
+
+		_, hit := rc.lookup("a")
+		if hit != c.ExpectHit {
+			t.Errorf("case[%s] expected %q got %q", c.Name, c.ExpectHit, hit)
+		}
+	}
+}
+
+func TestResponseCacheHeaders(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithCORS(CORSPolicy{AllowedOrigins: []string{"https://a.example", "https://b.example"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(ctx context.Context) (Response, error) {
+		return Response{Body: map[string]int{"count": 1}, Header: http.Header{"X-Version": {"2"}}}, nil
+	}
+
+	err = r.Register(http.MethodPost, "/count", fn, nil, WithResponseCache(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Origin      string
+		ExpectCache string
+	}{
+		{"miss", "https://a.example", "MISS"},
+		{"hit from another origin", "https://b.example", "HIT"},
+		{"hit without an origin", "", "HIT"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/count", nil)
+		req.Header.Set("Content-Type", "application/json")
+		if c.Origin != "" {
+			req.Header.Set("Origin", c.Origin)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Header().Get("X-Cache") != c.ExpectCache {
+			t.Errorf("case[%s] expected cache %s got %s", c.Name, c.ExpectCache, w.Header().Get("X-Cache"))
+		}
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != c.Origin {
+			t.Errorf("case[%s] expected the request's own origin %q got %q", c.Name, c.Origin, got)
+		}
+
+		if w.Header().Get("Content-Type") != "application/json" || w.Header().Get("X-Version") != "2" {
+			t.Errorf("case[%s] lost the encoded headers %v", c.Name, w.Header())
+		}
+	}
+}
+
+func TestResponseCacheNeedsTTL(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn := func(ctx context.Context) (map[string]int, error) {
+		return nil, nil
+	}
+
+	keyFn := func(r *http.Request, input interface{}) string { return "" }
+	cases := []struct {
+		Name string
+		Opts []RouteOption
+	}{
+		{"zero ttl", []RouteOption{WithResponseCache(0)}},
+		{"cache key alone", []RouteOption{WithCacheKey(keyFn)}},
+		{"stale alone", []RouteOption{WithStaleWhileRevalidate(time.Minute)}},
+	}
+
+	for _, c := range cases {
+		err = r.Register(http.MethodPost, "/"+strings.ReplaceAll(c.Name, " ", "-"), fn, nil, c.Opts...)
+		if err == nil {
+			t.Errorf("case[%s] expected registration to fail", c.Name)
+		}
+	}
+
+	err = r.Register(http.MethodPost, "/ordered", fn, nil, WithCacheKey(keyFn), WithResponseCache(time.Minute))
+	if err != nil {
+		t.Errorf("expected options in any order to register, got %s", err)
+	}
+}
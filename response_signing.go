@@ -0,0 +1,281 @@
+package autohttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultSignatureHeader carries HMAC response signatures
+const DefaultSignatureHeader = "X-Content-Signature"
+
+// DefaultJWSHeader carries detached JWS response signatures
+const DefaultJWSHeader = "X-JWS-Signature"
+
+// ErrInvalidSignature is returned when a signature doesn't verify
+var ErrInvalidSignature = errors.New("autohttp: invalid signature")
+
+// A SigningKey is a shared secret, identified so verifiers know which key
+// signed a payload while keys are being rotated
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// A KeyRing holds the key payloads are signed with and the keys they're
+// verified with. Rotating adds a new signing key while the previous ones keep
+// verifying until they're retired, so signers and verifiers can be updated
+// independently. It is safe for concurrent use
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+// NewKeyRing returns a KeyRing signing with primary, and also verifying
+// with previous
+func NewKeyRing(primary SigningKey, previous ...SigningKey) (*KeyRing, error) {
+	kr := &KeyRing{}
+	for _, k := range append([]SigningKey{primary}, previous...) {
+		if k.ID == "" || len(k.Secret) == 0 {
+			return nil, errors.New("autohttp: signing keys need an ID and a secret")
+		}
+
+		if _, ok := kr.key(k.ID); ok {
+			return nil, fmt.Errorf("autohttp: duplicate signing key %q", k.ID)
+		}
+
+		kr.keys = append(kr.keys, k)
+	}
+
+	return kr, nil
+}
+
+// Primary returns the key new signatures are made with
+func (kr *KeyRing) Primary() SigningKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	return kr.keys[0]
+}
+
+// Key returns the key with id, if it's still in the ring
+func (kr *KeyRing) Key(id string) (SigningKey, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	return kr.key(id)
+}
+
+func (kr *KeyRing) key(id string) (SigningKey, bool) {
+	for _, k := range kr.keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+
+	return SigningKey{}, false
+}
+
+// Rotate signs with next from now on, keeping the current keys for verification
+func (kr *KeyRing) Rotate(next SigningKey) error {
+	if next.ID == "" || len(next.Secret) == 0 {
+		return errors.New("autohttp: signing keys need an ID and a secret")
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if _, ok := kr.key(next.ID); ok {
+		return fmt.Errorf("autohttp: duplicate signing key %q", next.ID)
+	}
+
+	kr.keys = append([]SigningKey{next}, kr.keys...)
+	return nil
+}
+
+// Retire stops verifying with the key id. The primary key can't be retired
+func (kr *KeyRing) Retire(id string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	for i, k := range kr.keys {
+		if k.ID != id {
+			continue
+		}
+
+		if i == 0 {
+			return errors.New("autohttp: the primary signing key can't be retired")
+		}
+
+		kr.keys = append(kr.keys[:i], kr.keys[i+1:]...)
+		return nil
+	}
+
+	return fmt.Errorf("autohttp: unknown signing key %q", id)
+}
+
+// A ResponseSigner signs response bodies, setting the signature in header
+type ResponseSigner interface {
+	SignResponse(body []byte, header http.Header) error
+}
+
+// WithResponseSigning signs the route's successful response bodies with s,
+// so consumers can verify them end to end. Bodies are signed before they're
+// compressed, and error responses and streams aren't signed
+func WithResponseSigning(s ResponseSigner) RouteOption {
+	return func(rc *routeConfig) error {
+		if s == nil {
+			return errors.New("autohttp: WithResponseSigning needs a signer")
+		}
+
+		rc.responseSigner = s
+		return nil
+	}
+}
+
+// signBody buffers body and has signer sign it
+func signBody(signer ResponseSigner, header http.Header, body io.Reader) (io.Reader, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = signer.SignResponse(b, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(b), nil
+}
+
+func hmacSHA256(secret []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	for _, p := range parts {
+		mac.Write(p)
+	}
+
+	return mac.Sum(nil)
+}
+
+// HMACSigner signs bodies with HMAC-SHA256 under the key ring's primary key,
+// as `keyId="k1",alg="hmac-sha256",sig="<base64url>"` in Header, or
+// DefaultSignatureHeader
+type HMACSigner struct {
+	Keys   *KeyRing
+	Header string
+}
+
+func (hs HMACSigner) SignResponse(body []byte, header http.Header) error {
+	key := hs.Keys.Primary()
+	sig := base64.RawURLEncoding.EncodeToString(hmacSHA256(key.Secret, body))
+
+	name := hs.Header
+	if name == "" {
+		name = DefaultSignatureHeader
+	}
+
+	header.Set(name, fmt.Sprintf(`keyId=%q,alg="hmac-sha256",sig=%q`, key.ID, sig))
+	return nil
+}
+
+// VerifyHMACSignature checks a signature made by HMACSigner, or a webhook
+// sender using the same scheme, with any key still in keys
+func VerifyHMACSignature(keys *KeyRing, body []byte, signature string) error {
+	params := make(map[string]string)
+	for _, part := range strings.Split(signature, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[k] = strings.Trim(v, `"`)
+		}
+	}
+
+	if params["alg"] != "hmac-sha256" {
+		return ErrInvalidSignature
+	}
+
+	key, ok := keys.Key(params["keyId"])
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(params["sig"])
+	if err != nil || !hmac.Equal(sig, hmacSHA256(key.Secret, body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+type jwsHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	B64  bool     `json:"b64"`
+	Crit []string `json:"crit"`
+}
+
+// JWSSigner signs bodies as a detached, unencoded payload JWS (RFC 7797)
+// using HS256 and the key ring's primary key, set in Header, or DefaultJWSHeader
+type JWSSigner struct {
+	Keys   *KeyRing
+	Header string
+}
+
+func (js JWSSigner) SignResponse(body []byte, header http.Header) error {
+	key := js.Keys.Primary()
+
+	protected, err := json.Marshal(jwsHeader{Alg: "HS256", Kid: key.ID, B64: false, Crit: []string{"b64"}})
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(protected)
+	sig := hmacSHA256(key.Secret, []byte(encoded), []byte("."), body)
+
+	name := js.Header
+	if name == "" {
+		name = DefaultJWSHeader
+	}
+
+	header.Set(name, encoded+".."+base64.RawURLEncoding.EncodeToString(sig))
+	return nil
+}
+
+// VerifyDetachedJWS checks a JWS made by JWSSigner over body, with any key
+// still in keys
+func VerifyDetachedJWS(keys *KeyRing, body []byte, jws string) error {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return ErrInvalidSignature
+	}
+
+	protected, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	var h jwsHeader
+	err = json.Unmarshal(protected, &h)
+	if err != nil || h.Alg != "HS256" || h.B64 {
+		return ErrInvalidSignature
+	}
+
+	key, ok := keys.Key(h.Kid)
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, hmacSHA256(key.Secret, []byte(parts[0]), []byte("."), body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
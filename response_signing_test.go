@@ -0,0 +1,122 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestResponseSigning(t *testing.T) {
+	t.Parallel()
+
+	keys, err := NewKeyRing(SigningKey{ID: "k1", Secret: []byte("first secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithCompression(GzipCompressor{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []struct {
+		Path   string
+		Signer ResponseSigner
+	}{
+		{"/hmac", HMACSigner{Keys: keys}},
+		{"/jws", JWSSigner{Keys: keys}},
+	}
+
+	for _, route := range routes {
+		err = r.Register(http.MethodPost, route.Path, func(ctx context.Context, in struct{ Fail bool }) (map[string]string, error) {
+			if in.Fail {
+				return nil, ErrorWithCode{Err: ErrNotAcceptable, StatusCode: http.StatusBadRequest}
+			}
+			return map[string]string{"name": "rex"}, nil
+		}, nil, WithResponseSigning(route.Signer))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serve := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	hmacResp := serve("/hmac", `{}`)
+	jwsResp := serve("/jws", `{}`)
+
+	cases := []struct {
+		Name   string
+		Verify func() error
+		Expect error
+	}{
+		{"hmac", func() error {
+			return VerifyHMACSignature(keys, hmacResp.Body.Bytes(), hmacResp.Header().Get(DefaultSignatureHeader))
+		}, nil},
+		{"jws", func() error {
+			return VerifyDetachedJWS(keys, jwsResp.Body.Bytes(), jwsResp.Header().Get(DefaultJWSHeader))
+		}, nil},
+		{"hmac-tampered", func() error {
+			return VerifyHMACSignature(keys, []byte(`{"name":"max"}`), hmacResp.Header().Get(DefaultSignatureHeader))
+		}, ErrInvalidSignature},
+		{"jws-tampered", func() error {
+			return VerifyDetachedJWS(keys, []byte(`{"name":"max"}`), jwsResp.Header().Get(DefaultJWSHeader))
+		}, ErrInvalidSignature},
+		{"rotated", func() error {
+			err := keys.Rotate(SigningKey{ID: "k2", Secret: []byte("second secret")})
+			if err != nil {
+				return err
+			}
+
+			// signatures under the previous key still verify
+			err = VerifyHMACSignature(keys, hmacResp.Body.Bytes(), hmacResp.Header().Get(DefaultSignatureHeader))
+			if err != nil {
+				return err
+			}
+
+			resp := serve("/hmac", `{}`)
+			if !strings.Contains(resp.Header().Get(DefaultSignatureHeader), `keyId="k2"`) {
+				return ErrInvalidSignature
+			}
+
+			return VerifyHMACSignature(keys, resp.Body.Bytes(), resp.Header().Get(DefaultSignatureHeader))
+		}, nil},
+		{"retired", func() error {
+			err := keys.Retire("k1")
+			if err != nil {
+				return err
+			}
+
+			return VerifyDetachedJWS(keys, jwsResp.Body.Bytes(), jwsResp.Header().Get(DefaultJWSHeader))
+		}, ErrInvalidSignature},
+		{"error-unsigned", func() error {
+			resp := serve("/hmac", `{"Fail": true}`)
+			if resp.Header().Get(DefaultSignatureHeader) != "" {
+				return ErrInvalidSignature
+			}
+			return nil
+		}, nil},
+	}
+
+	for _, c := range cases {
+		err := c.Verify()
+		if err != c.Expect {
+			t.Errorf("case[%s] expected %v got %v", c.Name, c.Expect, err)
+		}
+	}
+
+	err = keys.Retire("k2")
+	if err == nil {
+		t.Error("expected the primary key to be kept")
+	}
+}
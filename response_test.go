@@ -0,0 +1,63 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestResponseOverrides(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name              string
+		Fn                interface{}
+		ExpectCode        int
+		ExpectContentType string
+		ExpectBody        string
+		ExpectHeader      string
+	}{
+		{"plain", func() map[string]int { return map[string]int{"v": 1} }, http.StatusOK, "application/json", `{"v":1}` + "\n", ""},
+		{"content-type", func() Response {
+			return Response{Body: map[string]int{"v": 2}, ContentType: "application/vnd.myapp.v2+json"}
+		}, http.StatusOK, "application/vnd.myapp.v2+json", `{"v":2}` + "\n", ""},
+		{"status-and-header", func() (*Response, error) {
+			return &Response{Body: map[string]int{"v": 3}, Status: http.StatusCreated, Header: http.Header{"Location": {"/things/3"}}}, nil
+		}, http.StatusCreated, "application/json", `{"v":3}` + "\n", "/things/3"},
+		{"nil", func() *Response { return nil }, http.StatusOK, "application/json", "null\n", ""},
+	}
+
+	for _, c := range cases {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.Register(http.MethodPost, "/things", c.Fn, nil, WithDecoder(NoOpDecoder{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/things", nil))
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if w.Header().Get("Content-Type") != c.ExpectContentType {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.ExpectContentType, w.Header().Get("Content-Type"))
+		}
+
+		if w.Body.String() != c.ExpectBody {
+			t.Errorf("case[%s] expected %q got %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		if w.Header().Get("Location") != c.ExpectHeader {
+			t.Errorf("case[%s] expected Location %q got %q", c.Name, c.ExpectHeader, w.Header().Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package autohttp
+
+import "net/http"
+
+// A ResponseTransformer adapts the value returned by a handler before it is
+// encoded, e.g. to downgrade the canonical response to an older API version's
+// wire format so one handler implementation can serve every version
+type ResponseTransformer func(r *http.Request, value interface{}) (interface{}, error)
+
+// WithResponseTransform runs transformers, in order, on the handler's
+// return value before encoding
+func WithResponseTransform(transformers ...ResponseTransformer) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.responseTransformers = append(rc.responseTransformers, transformers...)
+		return nil
+	}
+}
+
+// ResponseTransformsByVersion selects a transformer based on the API version
+// requested in header. Requests for any other version get the canonical response
+func ResponseTransformsByVersion(header string, transforms map[string]ResponseTransformer) ResponseTransformer {
+	return func(r *http.Request, value interface{}) (interface{}, error) {
+		transform, ok := transforms[r.Header.Get(header)]
+		if !ok {
+			return value, nil
+		}
+
+		return transform(r, value)
+	}
+}
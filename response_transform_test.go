@@ -0,0 +1,63 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestResponseTransformByVersion(t *testing.T) {
+	type user struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+
+	downgrades := ResponseTransformsByVersion("X-API-Version", map[string]ResponseTransformer{
+		"1": func(r *http.Request, value interface{}) (interface{}, error) {
+			u := value.(user)
+			return map[string]string{"name": u.FirstName + " " + u.LastName}, nil
+		},
+	})
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/user", func(ctx context.Context, in struct{ ID int }) user {
+		return user{FirstName: "Ada", LastName: "Lovelace"}
+	}, nil, WithResponseTransform(downgrades))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name      string
+		Version   string
+		ExpectRes string
+	}{
+		{"v1", "1", `{"name":"Ada Lovelace"}`},
+		{"v2", "2", `{"first_name":"Ada","last_name":"Lovelace"}`},
+		{"unversioned", "", `{"first_name":"Ada","last_name":"Lovelace"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(`{"ID": 1}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-API-Version", c.Version)
+
+			r.ServeHTTP(w, req)
+
+			if strings.TrimSpace(w.Body.String()) != c.ExpectRes {
+				t.Errorf("expected %q got %q", c.ExpectRes, w.Body.String())
+			}
+		})
+	}
+}
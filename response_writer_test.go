@@ -0,0 +1,100 @@
+package autohttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+// fullResponseWriter implements every optional ResponseWriter interface
+// a websocket or SSE handler relies on
+type fullResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (frw fullResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijacked")
+}
+
+func (frw fullResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return nil
+}
+
+func TestResponseWriterInterfacesPassThrough(t *testing.T) {
+	optionSets := map[string][]RouterOption{
+		"defaults":     nil,
+		"metrics":      {EnableRouteMetrics},
+		"request-ids":  {EnableRequestIDs},
+		"compression":  {WithCompression(GzipCompressor{}), WithCompressionCache(0)},
+		"drain-policy": {WithDefaultBodyDrainPolicy(CloseBody)},
+		"everything": {
+			EnableRouteMetrics,
+			EnableRequestIDs,
+			WithCompression(GzipCompressor{}),
+			WithDefaultBodyDrainPolicy(CloseBody),
+		},
+	}
+
+	for name, opts := range optionSets {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var checked int
+			check := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				checked++
+
+				if _, ok := w.(http.Hijacker); !ok {
+					t.Errorf("%s: lost http.Hijacker", req.URL.Path)
+				}
+
+				if _, ok := w.(http.Flusher); !ok {
+					t.Errorf("%s: lost http.Flusher", req.URL.Path)
+				}
+
+				if _, ok := w.(http.Pusher); !ok {
+					t.Errorf("%s: lost http.Pusher", req.URL.Path)
+				}
+			})
+
+			err = r.Register(http.MethodGet, "/plain", check, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Register(http.MethodGet, "/ws/*", check, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g := r.Group("/events")
+			g.SetHeader("Cache-Control", "no-store")
+			err = g.Register(http.MethodGet, "/stream", check, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Register(http.MethodGet, "/canary", NewCanary(check, check, 50), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, path := range []string{"/plain", "/ws/socket", "/events/stream", "/canary"} {
+				w := fullResponseWriter{httptest.NewRecorder()}
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			}
+
+			if checked != 4 {
+				t.Fatalf("expected 4 handlers to run, got %d", checked)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package autohttp
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader identifies a logical operation across client retries
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryStats counts the requests a route received, and how many were retries
+type RetryStats struct {
+	Requests uint64
+	Retries  uint64
+}
+
+// EnableRetryTracking counts, per route, requests that repeat an Idempotency-Key
+// or client-supplied X-Request-ID seen within window, so retry storms against an
+// endpoint show up in RetryStats
+func EnableRetryTracking(window time.Duration) func(r *Router) error {
+	return func(r *Router) error {
+		r.retries = &retryTracker{
+			window: window,
+			seen:   make(map[retryKey]time.Time),
+			stats:  make(map[string]*RetryStats),
+			now:    time.Now,
+		}
+		return nil
+	}
+}
+
+// RetryStats returns the retry counts of each route, keyed by "METHOD /path".
+// It returns nil if retry tracking is not enabled
+func (r *Router) RetryStats() map[string]RetryStats {
+	if r.retries == nil {
+		return nil
+	}
+
+	return r.retries.snapshot()
+}
+
+type retryKey struct {
+	route string
+	token string
+}
+
+type retryTracker struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[retryKey]time.Time
+	stats     map[string]*RetryStats
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func (rt *retryTracker) observe(route string, req *http.Request) {
+	token := req.Header.Get(IdempotencyKeyHeader)
+	if token == "" {
+		token = req.Header.Get(RequestIDHeader)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	stats, ok := rt.stats[route]
+	if !ok {
+		stats = &RetryStats{}
+		rt.stats[route] = stats
+	}
+	stats.Requests++
+
+	if token == "" {
+		return
+	}
+
+	now := rt.now()
+	rt.sweep(now)
+
+	key := retryKey{route: route, token: token}
+	if seenAt, ok := rt.seen[key]; ok && now.Sub(seenAt) < rt.window {
+		stats.Retries++
+	}
+	rt.seen[key] = now
+}
+
+// sweep drops expired tokens at most once per window. Must be called with mu held
+func (rt *retryTracker) sweep(now time.Time) {
+	if now.Sub(rt.lastSweep) < rt.window {
+		return
+	}
+
+	for key, seenAt := range rt.seen {
+		if now.Sub(seenAt) >= rt.window {
+			delete(rt.seen, key)
+		}
+	}
+
+	rt.lastSweep = now
+}
+
+func (rt *retryTracker) snapshot() map[string]RetryStats {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	snapshot := make(map[string]RetryStats, len(rt.stats))
+	for route, stats := range rt.stats {
+		snapshot[route] = *stats
+	}
+
+	return snapshot
+}
@@ -0,0 +1,67 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRetryTracking(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), EnableRetryTracking(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	r.retries.now = func() time.Time { return now }
+
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, path := range []string{"/charge", "/refund"} {
+		err = r.Register(http.MethodPost, path, noop, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests := []struct {
+		Path    string
+		Header  string
+		Token   string
+		Advance time.Duration
+	}{
+		{"/charge", IdempotencyKeyHeader, "a", 0},
+		{"/charge", IdempotencyKeyHeader, "a", time.Second},
+		{"/charge", RequestIDHeader, "req-1", 0},
+		{"/charge", RequestIDHeader, "req-1", 0},
+		{"/charge", "", "", 0},
+		{"/refund", IdempotencyKeyHeader, "a", 0},
+		{"/charge", IdempotencyKeyHeader, "a", time.Minute},
+	}
+
+	for _, req := range requests {
+		now = now.Add(req.Advance)
+
+		hr := httptest.NewRequest(http.MethodPost, req.Path, nil)
+		if req.Header != "" {
+			hr.Header.Set(req.Header, req.Token)
+		}
+
+		r.ServeHTTP(httptest.NewRecorder(), hr)
+	}
+
+	stats := r.RetryStats()
+	expected := map[string]RetryStats{
+		"POST /charge": {Requests: 6, Retries: 2},
+		"POST /refund": {Requests: 1, Retries: 0},
+	}
+
+	for route, expect := range expected {
+		if stats[route] != expect {
+			t.Errorf("%s: expected %+v got %+v", route, expect, stats[route])
+		}
+	}
+}
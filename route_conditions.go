@@ -0,0 +1,130 @@
+package autohttp
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// When only serves the route to requests matching every pred, so several
+// routes can share a method and path and be picked by request attributes,
+// e.g. webhook providers that all POST to /webhook. Conditional routes are
+// tried in registration order, then the route registered without conditions,
+// if any. Requests matching none of them get a 404
+func When(preds ...RequestPredicate) RouteOption {
+	return func(rc *routeConfig) error {
+		for _, pred := range preds {
+			if pred == nil {
+				return errors.New("autohttp: When needs non-nil predicates")
+			}
+		}
+
+		rc.conditions = append(rc.conditions, preds...)
+		return nil
+	}
+}
+
+// HeaderEquals matches requests whose header is value
+func HeaderEquals(header, value string) RequestPredicate {
+	return func(r *http.Request) bool {
+		return r.Header.Get(header) == value
+	}
+}
+
+// QueryEquals matches requests whose query parameter name is value
+func QueryEquals(name, value string) RequestPredicate {
+	return func(r *http.Request) bool {
+		return r.URL.Query().Get(name) == value
+	}
+}
+
+// ContentTypeIs matches requests whose body is any of mediaTypes, ignoring
+// parameters such as charset
+func ContentTypeIs(mediaTypes ...string) RequestPredicate {
+	return func(r *http.Request) bool {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+
+		for _, mt := range mediaTypes {
+			if strings.EqualFold(mediaType, mt) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+type routeVariant struct {
+	conditions []RequestPredicate
+	handler    http.Handler
+}
+
+func (rv routeVariant) matches(r *http.Request) bool {
+	for _, pred := range rv.conditions {
+		if !pred(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// routeVariants serves the routes sharing a method and path
+type routeVariants struct {
+	variants []routeVariant
+	fallback http.Handler
+}
+
+func (rvs *routeVariants) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, rv := range rvs.variants {
+		if rv.matches(r) {
+			rv.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if rvs.fallback != nil {
+		rvs.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// sharesRoute reports whether a route with conditions can be registered
+// alongside existing, the handler already at its method and path
+func sharesRoute(existing http.Handler, conditions []RequestPredicate) bool {
+	if len(conditions) > 0 {
+		return true
+	}
+
+	rvs, ok := existing.(*routeVariants)
+	return ok && rvs.fallback == nil
+}
+
+// addRouteVariant adds handler to the routes at pattern in tree, turning the
+// route already there into a set of variants, and returns the set
+func addRouteVariant(tree *routeNode, pattern string, existing, handler http.Handler, conditions []RequestPredicate) (*routeVariants, error) {
+	rvs, ok := existing.(*routeVariants)
+	if !ok {
+		entry := tree.find(pattern)
+		if entry == nil {
+			return nil, errors.New("autohttp: route " + pattern + " is missing from the route tree")
+		}
+
+		rvs = &routeVariants{fallback: existing}
+		entry.handler = rvs
+	}
+
+	if len(conditions) == 0 {
+		rvs.fallback = handler
+	} else {
+		rvs.variants = append(rvs.variants, routeVariant{conditions: conditions, handler: handler})
+	}
+
+	return rvs, nil
+}
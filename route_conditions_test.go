@@ -0,0 +1,85 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRouteConditions(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, body)
+		})
+	}
+
+	routes := []struct {
+		Path    string
+		Handler http.Handler
+		Options []RouteOption
+	}{
+		{"/webhook", reply("github"), []RouteOption{When(HasHeader("X-GitHub-Event"))}},
+		{"/webhook", reply("stripe"), []RouteOption{When(HasHeader("Stripe-Signature"), ContentTypeIs("application/json"))}},
+		{"/webhook", reply("legacy"), nil},
+		{"/hooks/{id}", reply("v1"), nil},
+		{"/hooks/{id}", reply("v2"), []RouteOption{When(QueryEquals("version", "2"))}},
+		{"/strict", reply("form"), []RouteOption{When(ContentTypeIs("application/x-www-form-urlencoded"))}},
+	}
+
+	for _, route := range routes {
+		err = r.Register(http.MethodPost, route.Path, route.Handler, nil, route.Options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Register(http.MethodPost, "/webhook", reply("dup"), nil)
+	if err == nil {
+		t.Error("expected a second unconditioned route to be refused")
+	}
+
+	cases := []struct {
+		Name       string
+		Path       string
+		Header     map[string]string
+		ExpectCode int
+		ExpectBody string
+	}{
+		{"github", "/webhook", map[string]string{"X-GitHub-Event": "push"}, http.StatusOK, "github"},
+		{"stripe", "/webhook", map[string]string{"Stripe-Signature": "t=1", "Content-Type": "application/json; charset=utf-8"}, http.StatusOK, "stripe"},
+		{"stripe-wrong-type", "/webhook", map[string]string{"Stripe-Signature": "t=1", "Content-Type": "text/plain"}, http.StatusOK, "legacy"},
+		{"fallback", "/webhook", nil, http.StatusOK, "legacy"},
+		{"param-fallback", "/hooks/1", nil, http.StatusOK, "v1"},
+		{"param-query", "/hooks/1?version=2", nil, http.StatusOK, "v2"},
+		{"no-match", "/strict", map[string]string{"Content-Type": "application/json"}, http.StatusNotFound, ""},
+		{"match", "/strict", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, http.StatusOK, "form"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.Path, nil)
+		for k, v := range c.Header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if w.Body.String() != c.ExpectBody {
+			t.Errorf("case[%s] expected %q got %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package autohttp
+
+import (
+	"net/http"
+	"time"
+)
+
+// routeConfig holds settings applied to a single route at registration time
+type routeConfig struct {
+	headers Header
+
+	decoder Decoder
+	encoder Encoder
+
+	drainPolicy    BodyDrainPolicy
+	drainPolicySet bool
+
+	requestTransformers  []RequestTransformer
+	responseTransformers []ResponseTransformer
+	bodySpooling         *bodySpooling
+	responseCache        *responseCache
+	memo                 *memo
+	responseSigner       ResponseSigner
+	requestDigest        *requestDigest
+	responseDigest       []string
+	workerPool           *WorkerPool
+	warmup               func() bool
+	timeout              time.Duration
+	timeoutSet           bool
+	maxBodyBytes         int64
+	maxBodyBytesSet      bool
+	cors                 *corsPolicy
+	contentTypes         []string
+	sampling             *sampling
+
+	example    interface{}
+	hasExample bool
+
+	hidden   bool
+	weakETag bool
+	tags     []string
+
+	metricBlind bool
+	accessLog   *bool
+
+	conditions []RequestPredicate
+}
+
+// A RouteOption configures a single route at registration time
+type RouteOption func(rc *routeConfig) error
+
+// WithDecoder decodes the route's requests with d instead of the router default
+func WithDecoder(d Decoder) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.decoder = d
+		return nil
+	}
+}
+
+// WithEncoder encodes the route's responses with e instead of the router default
+func WithEncoder(e Encoder) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.encoder = e
+		return nil
+	}
+}
+
+// configure applies the route level settings to a generated Handler
+func (rc routeConfig) configure(h *Handler) {
+	h.requestTransformers = rc.requestTransformers
+	h.responseTransformers = rc.responseTransformers
+	h.bodySpooling = rc.bodySpooling
+	h.responseCache = rc.responseCache
+	h.memo = rc.memo
+	h.responseSigner = rc.responseSigner
+	h.requestDigest = rc.requestDigest
+	h.responseDigest = rc.responseDigest
+	h.hideFromIntrospectors = rc.hidden
+	h.weakETag = rc.weakETag
+	h.tags = rc.tags
+}
+
+// wrap applies the route level settings around the final handler.
+// Wrappers that need to intercept the ResponseWriter must do so with
+// httpsnoop.Wrap, so http.Hijacker, http.Flusher and http.Pusher survive
+func (rc routeConfig) wrap(h http.Handler) http.Handler {
+	if len(rc.headers) > 0 {
+		h = &headersHandler{headers: rc.headers, next: h}
+	}
+
+	if rc.drainPolicy != SkipBody {
+		h = &drainingHandler{policy: rc.drainPolicy, next: h}
+	}
+
+	return h
+}
@@ -0,0 +1,212 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A Param is a path segment captured by a {name} or * route pattern
+type Param struct {
+	Key   string
+	Value string
+}
+
+type paramsCtxKey struct{}
+
+// PathParam returns the value captured for name by the matched route, or ""
+// if there is none. Trailing wildcards are captured as "*"
+func PathParam(ctx context.Context, name string) string {
+	params, _ := ctx.Value(paramsCtxKey{}).([]Param)
+	for _, p := range params {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+
+	return ""
+}
+
+// routeEntry is a registered route at the end of a tree path
+type routeEntry struct {
+	pattern string
+	handler http.Handler
+}
+
+// routeNode is a node of a per-method segment tree. At each segment a static
+// child wins over a {param}, which wins over a trailing wildcard, so matching
+// doesn't depend on registration order
+type routeNode struct {
+	static map[string]*routeNode
+
+	param     *routeNode
+	paramName string
+
+	wildcard *routeEntry
+	entry    *routeEntry
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{static: make(map[string]*routeNode)}
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimPrefix(p, "/"), "/")
+}
+
+// insert adds handler at pattern, which may contain {name} segments and end
+// with a * wildcard
+func (n *routeNode) insert(pattern string, handler http.Handler) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("autohttp: route %q must start with /", pattern)
+	}
+
+	segments := splitPath(pattern)
+	entry := &routeEntry{pattern: pattern, handler: handler}
+
+	for i, seg := range segments {
+		switch {
+		case seg == "*":
+			if i != len(segments)-1 {
+				return fmt.Errorf("autohttp: wildcard must be the last segment of %q", pattern)
+			}
+
+			if n.wildcard != nil {
+				return errors.New("route already registered")
+			}
+			n.wildcard = entry
+			return nil
+
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			name := seg[1 : len(seg)-1]
+			if name == "" {
+				return fmt.Errorf("autohttp: empty parameter name in %q", pattern)
+			}
+
+			if n.param == nil {
+				n.param = newRouteNode()
+				n.paramName = name
+			} else if n.paramName != name {
+				return fmt.Errorf("autohttp: parameter {%s} in %q conflicts with {%s}", name, pattern, n.paramName)
+			}
+			n = n.param
+
+		case strings.ContainsAny(seg, "{}*"):
+			return fmt.Errorf("autohttp: invalid segment %q in %q", seg, pattern)
+
+		default:
+			child, ok := n.static[seg]
+			if !ok {
+				child = newRouteNode()
+				n.static[seg] = child
+			}
+			n = child
+		}
+	}
+
+	if n.entry != nil {
+		return errors.New("route already registered")
+	}
+	n.entry = entry
+
+	return nil
+}
+
+// lookup finds the route for path, appending captured parameters to params
+func (n *routeNode) lookup(path string, params []Param) (*routeEntry, []Param) {
+	return n.match(strings.TrimPrefix(path, "/"), false, params)
+}
+
+// match walks the segments of rest without splitting it up front, done is
+// set once every segment has been consumed
+func (n *routeNode) match(rest string, done bool, params []Param) (*routeEntry, []Param) {
+	if done {
+		return n.entry, params
+	}
+
+	seg, next, nextDone := rest, "", true
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		seg, next, nextDone = rest[:i], rest[i+1:], false
+	}
+
+	if child, ok := n.static[seg]; ok {
+		if entry, p := child.match(next, nextDone, params); entry != nil {
+			return entry, p
+		}
+	}
+
+	if n.param != nil && seg != "" {
+		if entry, p := n.param.match(next, nextDone, append(params, Param{Key: n.paramName, Value: seg})); entry != nil {
+			return entry, p
+		}
+	}
+
+	if n.wildcard != nil {
+		return n.wildcard, append(params, Param{Key: "*", Value: rest})
+	}
+
+	return nil, params
+}
+
+// lookup matches path against the method's route tree, falling back to an
+// exact match for handlers added to Routes directly
+func (r *Router) lookup(method string, routes map[string]http.Handler, path string) (http.Handler, string, []Param, bool) {
+	if tree, ok := r.trees[method]; ok {
+		if entry, params := tree.lookup(path, nil); entry != nil {
+			return entry.handler, entry.pattern, params, true
+		}
+	}
+
+	if handler, ok := routes[path]; ok {
+		return handler, path, nil, true
+	}
+
+	return nil, "", nil, false
+}
+
+// starRoute is an http.Handler registered for every method under prefix
+type starRoute struct {
+	pattern string
+	prefix  string
+	handler http.Handler
+}
+
+// addStarRoute registers a star route, keeping the longest prefixes first
+// so overlapping star routes match deterministically
+func (r *Router) addStarRoute(path string, handler http.Handler) {
+	prefix := strings.ReplaceAll(path, "*", "")
+	for i, sr := range r.starRoutes {
+		if sr.prefix == prefix {
+			r.starRoutes[i].handler = handler
+			return
+		}
+	}
+
+	r.starRoutes = append(r.starRoutes, starRoute{pattern: path, prefix: prefix, handler: handler})
+	sort.SliceStable(r.starRoutes, func(i, j int) bool {
+		return len(r.starRoutes[i].prefix) > len(r.starRoutes[j].prefix)
+	})
+}
+
+// find returns the route registered at pattern, as passed to insert
+func (n *routeNode) find(pattern string) *routeEntry {
+	for _, seg := range splitPath(pattern) {
+		switch {
+		case seg == "*":
+			return n.wildcard
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			n = n.param
+		default:
+			n = n.static[seg]
+		}
+
+		if n == nil {
+			return nil
+		}
+	}
+
+	return n.entry
+}
@@ -0,0 +1,167 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRouteTreeMatching(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []string{
+		"/users/me",
+		"/users/{id}",
+		"/users/{id}/posts/{post}",
+		"/users/{id}/*",
+		"/files/*",
+		"/",
+	}
+
+	// register in reverse to show priority doesn't depend on order
+	for i := len(routes) - 1; i >= 0; i-- {
+		pattern := routes[i]
+		err = r.Register(http.MethodPost, pattern, func(ctx context.Context) map[string]string {
+			return map[string]string{
+				"pattern": pattern,
+				"id":      PathParam(ctx, "id"),
+				"post":    PathParam(ctx, "post"),
+				"rest":    PathParam(ctx, "*"),
+			}
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Name         string
+		Path         string
+		ExpectStatus int
+		Expect       map[string]string
+	}{
+		{"root", "/", http.StatusOK, map[string]string{"pattern": "/"}},
+		{"static-beats-param", "/users/me", http.StatusOK, map[string]string{"pattern": "/users/me"}},
+		{"param", "/users/42", http.StatusOK, map[string]string{"pattern": "/users/{id}", "id": "42"}},
+		{"two-params", "/users/42/posts/7", http.StatusOK, map[string]string{"pattern": "/users/{id}/posts/{post}", "id": "42", "post": "7"}},
+		{"backtrack-to-wildcard", "/users/42/posts/7/comments", http.StatusOK, map[string]string{"pattern": "/users/{id}/*", "id": "42", "rest": "posts/7/comments"}},
+		{"wildcard", "/files/a/b.txt", http.StatusOK, map[string]string{"pattern": "/files/*", "rest": "a/b.txt"}},
+		{"empty-param", "/users/", http.StatusNotFound, nil},
+		{"no-match", "/nope", http.StatusNotFound, nil},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, c.Path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+			continue
+		}
+
+		for k, v := range c.Expect {
+			if got := decodeStringMap(t, w)[k]; got != v {
+				t.Errorf("case[%s] expected %s=%q got %q", c.Name, k, v, got)
+			}
+		}
+	}
+}
+
+func TestRouteTreeConflicts(t *testing.T) {
+	t.Parallel()
+
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		Name     string
+		Existing string
+		Pattern  string
+	}{
+		{"duplicate", "/a/{id}", "/a/{id}"},
+		{"param-names", "/a/{id}", "/a/{name}/b"},
+		{"mid-wildcard", "", "/a/*/b"},
+		{"empty-param", "", "/a/{}"},
+		{"partial-param", "", "/a/x{id}"},
+		{"relative", "", "a/b"},
+	}
+
+	for _, c := range cases {
+		tree := newRouteNode()
+		if c.Existing != "" {
+			if err := tree.insert(c.Existing, noop); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := tree.insert(c.Pattern, noop); err == nil {
+			t.Errorf("case[%s] expected %s to be rejected", c.Name, c.Pattern)
+		}
+	}
+}
+
+func decodeStringMap(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+
+	var m map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestStarRoutePriority(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+
+	for _, prefix := range []string{"/api/*", "/api/v2/*", "/a*"} {
+		err = r.Register(http.MethodGet, prefix, named(prefix), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Path   string
+		Expect string
+	}{
+		{"/api/v2/users", "/api/v2/*"},
+		{"/api/v1/users", "/api/*"},
+		{"/assets", "/a*"},
+	}
+
+	for _, c := range cases {
+		// repeat to catch map iteration order leaking into matching
+		for i := 0; i < 10; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+			if w.Body.String() != c.Expect {
+				t.Fatalf("case[%s] expected %s got %s", c.Path, c.Expect, w.Body.String())
+			}
+		}
+	}
+}
@@ -1,50 +1,147 @@
 package autohttp
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fortytw2/lounge"
 	"github.com/jwfriese/autohttp/internal/httpsnoop"
 )
 
-type embeddedAssets struct {
-	staticDir fs.FS
-}
-
-func newEmbeddedAssets(assets fs.FS, distDir string) (*embeddedAssets, error) {
-	staticFS, err := fs.Sub(assets, distDir)
-	if err != nil {
-		return nil, err
-	}
-
-	return &embeddedAssets{
-		staticDir: staticFS,
-	}, nil
-}
-
 type Router struct {
 	Routes     map[string]map[string]http.Handler
-	starRoutes map[string]http.Handler
+	trees      map[string]*routeNode
+	starRoutes []starRoute
+	gone       *routeNode
 
 	embeddedAssets *embeddedAssets
+	assetMounts    []*assetMount
+	matcherMounts  []*matcherMount
+	cspNoncePolicy string
 
 	log lounge.Log
-
-	enableHSTS         bool
-	enableRouteMetrics bool
+	// logLevel is log, kept typed so its level can be changed while serving
+	logLevel *levelLog
+	port     string
+
+	enableHSTS          bool
+	enableRouteMetrics  bool
+	prometheus          *prometheusMetrics
+	rateLimits          map[string]*RateLimitMiddleware
+	tracer              Tracer
+	requestErrorHandler RequestErrorHandler
+	accessLogFormat     AccessLogFormatter
+	accessLogFields     []AccessLogField
+	accessLogOptIn      bool
+	accessLogRoutes     map[string]bool
+	cors                *corsPolicy
+	corsRoutes          map[string]*corsPolicy
+	methodNotAllowed    MethodNotAllowedHandler
+	warmups             map[string]*warmingHandler
+
+	lifecycleMu            sync.Mutex
+	subscribers            []LifecycleSubscriber
+	timeout                time.Duration
+	maxBodyBytes           int64
+	enableRequestIDs       bool
+	hideRequestIDsInErrors bool
+	strictRequests         bool
+	maxHeaderValueBytes    int
+
+	quietPaths    map[string]bool
+	quietPrefixes []string
+
+	normalizePaths          bool
+	redirectToCanonicalPath bool
+
+	accessLog      *accessLog
+	enrichers      []RequestEnricher
+	requestTimeout time.Duration
+
+	requestTimelines bool
+	deadlines        *deadlinePropagation
+	retries          *retryTracker
+
+	operations *operations
+	background background
+
+	openAPIInfo OpenAPIInfo
+	docs        []routeDoc
+
+	mockMode         mockMode
+	mockHeader       string
+	mockModeConflict bool
+
+	scheduleMu sync.Mutex
+	scheduled  []*scheduledTask
+
+	middlewares       []Middleware
+	tagMiddlewares    map[string][]Middleware
+	hasFunctionRoutes bool
+	routeTags         map[string][]string
+
+	metricBlindMu sync.Mutex
+	metricBlind   map[string]bool
 
 	defaultEncoder      Encoder
 	defaultDecoder      Decoder
+	encoderWrappers     []EncoderWrapper
+	decoderWrappers     []DecoderWrapper
 	defaultErrorHandler ErrorHandler
+
+	compression        *compression
+	negotiation        *negotiation
+	errorLogging       *errorLogging
+	metricsSink        MetricsSink
+	fieldScopes        ScopeFunc
+	defaultDrainPolicy BodyDrainPolicy
+
+	readOnly *readOnlyPaths
+	features *featureGates
+	rules    *ruleSet
+	sessions *sessionStore
+	configMu sync.Mutex
 }
 
 type RouterOption func(r *Router) error
 
+// DefaultPort is the port returned by Router.Port when none is configured
+const DefaultPort = "8080"
+
+// WithPort sets the port the router is meant to be served on
+func WithPort(port string) func(r *Router) error {
+	return func(r *Router) error {
+		_, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return fmt.Errorf("autohttp: invalid port %q", port)
+		}
+
+		r.port = port
+		return nil
+	}
+}
+
+// Port returns the configured port, or DefaultPort
+func (r *Router) Port() string {
+	if r.port == "" {
+		return DefaultPort
+	}
+
+	return r.port
+}
+
+// HSTSPolicy is the Strict-Transport-Security header sent when HSTS is enabled
+var HSTSPolicy = "max-age=63072000; includeSubDomains"
+
+// EnableHSTS tells browsers to only ever reach this host over HTTPS
 func EnableHSTS(r *Router) error {
 	r.enableHSTS = true
 	return nil
@@ -55,9 +152,23 @@ func EnableRouteMetrics(r *Router) error {
 	return nil
 }
 
-func WithEmbeddedAssets(assets fs.FS, path string) func(r *Router) error {
+// EnableRequestIDs assigns every request an ID, available through
+// RequestIDFromContext. Incoming X-Request-ID headers are reused when present
+func EnableRequestIDs(r *Router) error {
+	r.enableRequestIDs = true
+	return nil
+}
+
+// HideRequestIDsInErrors stops request IDs from being written to 5xx responses,
+// for deployments that don't want to leak them to clients
+func HideRequestIDsInErrors(r *Router) error {
+	r.hideRequestIDsInErrors = true
+	return nil
+}
+
+func WithEmbeddedAssets(assets fs.FS, path string, opts ...AssetOption) func(r *Router) error {
 	return func(r *Router) error {
-		ea, err := newEmbeddedAssets(assets, path)
+		ea, err := newEmbeddedAssets(assets, path, opts...)
 		if err != nil {
 			return err
 		}
@@ -94,7 +205,16 @@ var DefaultOptions = []RouterOption{
 }
 
 func NewRouter(log lounge.Log, routerOptions ...RouterOption) (*Router, error) {
-	r := &Router{log: log, Routes: make(map[string]map[string]http.Handler), starRoutes: make(map[string]http.Handler)}
+	ll := newLevelLog(log)
+	r := &Router{
+		log:      ll,
+		logLevel: ll,
+		Routes:   make(map[string]map[string]http.Handler),
+		trees:    make(map[string]*routeNode),
+		readOnly: newReadOnlyPaths(),
+
+		errorLogging: &errorLogging{level: LogError},
+	}
 	for _, ro := range append(DefaultOptions, routerOptions...) {
 		err := ro(r)
 		if err != nil {
@@ -102,22 +222,109 @@ func NewRouter(log lounge.Log, routerOptions ...RouterOption) (*Router, error) {
 		}
 	}
 
+	if errs := r.conflicts(); len(errs) > 0 {
+		return nil, OptionErrors(errs)
+	}
+
+	r.wrapNegotiatedEncoders()
+
+	if r.cspNoncePolicy != "" {
+		err := r.embeddedAssets.loadIndexTemplate(r.cspNoncePolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
+}
+
+// errorHandler returns the ErrorHandler used for errors raised by the router itself
+func (r *Router) errorHandler() ErrorHandler {
+	if r.defaultErrorHandler != nil {
+		return r.defaultErrorHandler
+	}
 
+	return DefaultErrorHandler
 }
 
 var validMethods = map[string]bool{
 	http.MethodGet:    true,
+	http.MethodHead:   true,
 	http.MethodDelete: true,
 	http.MethodPatch:  true,
 	http.MethodPost:   true,
 	http.MethodPut:    true,
 }
 
-func (r *Router) Register(method string, path string, fn interface{}, middlewares []Middleware) error {
+// Use adds middleware run on every route before group and per-route
+// middleware. It must be called before any routes are registered, so no
+// route silently misses it, and like all Middleware it only applies to
+// function routes, not http.Handlers
+func (r *Router) Use(mws ...Middleware) {
+	if r.hasFunctionRoutes {
+		panic("autohttp: Use must be called before routes are registered")
+	}
+
+	r.middlewares = append(r.middlewares, mws...)
+}
+
+func (r *Router) Register(method string, path string, fn interface{}, middlewares []Middleware, opts ...RouteOption) error {
+	return r.register(method, path, fn, middlewares, routeConfig{}, opts)
+}
+
+func (r *Router) register(method string, path string, fn interface{}, middlewares []Middleware, rc routeConfig, opts []RouteOption) error {
+	if !rc.drainPolicySet {
+		rc.drainPolicy = r.defaultDrainPolicy
+	}
+
+	for _, opt := range opts {
+		err := opt(&rc)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rc.memo != nil {
+		err := checkMemoization(fn)
+		if err != nil {
+			return fmt.Errorf("autohttp: %s %s: %w", method, path, err)
+		}
+	}
+
 	if strings.Contains(path, "*") {
 		if httpHandler, ok := fn.(http.Handler); ok {
-			r.starRoutes[path] = httpHandler
+			if len(rc.conditions) > 0 {
+				return fmt.Errorf("autohttp: %s: star routes can't have conditions", path)
+			}
+
+			if rc.accessLog != nil {
+				return fmt.Errorf("autohttp: %s: star routes can't switch access logging", path)
+			}
+
+			if rc.workerPool != nil {
+				return fmt.Errorf("autohttp: %s: star routes can't run on worker pools", path)
+			}
+
+			if rc.timeoutSet {
+				return fmt.Errorf("autohttp: %s: star routes can't time out", path)
+			}
+
+			if rc.cors != nil {
+				return fmt.Errorf("autohttp: %s: star routes can't override the CORS policy", path)
+			}
+
+			// star routes commonly proxy or stream, so by default their bodies are left alone
+			if !rc.drainPolicySet {
+				rc.drainPolicy = SkipBody
+			}
+
+			if rc.metricBlind {
+				r.markMetricBlind("* " + path)
+			}
+
+			limited := r.withBodyLimit(withContentTypes(httpHandler, rc, r.serveRouterError), rc, true, r.serveRouterError)
+			r.addStarRoute(path, r.warm("* "+path, rc.wrap(limited), rc))
+			r.emitLifecycle(func(s LifecycleSubscriber) { s.RouteRegistered(RouteEvent{Method: "*", Pattern: path, Tags: rc.tags}) })
 			return nil
 		}
 	}
@@ -131,30 +338,277 @@ func (r *Router) Register(method string, path string, fn interface{}, middleware
 		r.Routes[method] = make(map[string]http.Handler)
 	}
 
-	_, ok = r.Routes[method][path]
-	if ok {
+	existing, shared := r.Routes[method][path]
+	if shared && !sharesRoute(existing, rc.conditions) {
 		return errors.New("route already registered")
 	}
 
+	tree, ok := r.trees[method]
+	if !ok {
+		tree = newRouteNode()
+	}
+
+	decoder, encoder := rc.decoder, rc.encoder
+	if decoder == nil {
+		decoder = r.defaultDecoder
+	}
+	if encoder == nil {
+		encoder = r.defaultEncoder
+	}
+
+	var handler http.Handler
 	if httpHandler, ok := fn.(http.Handler); ok {
-		r.Routes[method][path] = httpHandler
-		return nil
+		handler = httpHandler
+	} else if fn != nil || !rc.hasExample {
+		if decoder == nil || encoder == nil {
+			return fmt.Errorf("autohttp: %s %s: the router has no default decoder or encoder, "+
+				"set them with WithDefaultDecoder and WithDefaultEncoder, per route with WithDecoder and WithEncoder, "+
+				"or register an http.Handler", method, path)
+		}
+
+		if len(r.middlewares) > 0 || len(r.tagMiddlewares) > 0 {
+			composed := append([]Middleware{}, r.middlewares...)
+			composed = append(composed, r.tagged(rc.tags)...)
+			middlewares = append(composed, middlewares...)
+		}
+
+		var h *Handler
+		var err error
+		if tc, ok := fn.(typedCaller); ok {
+			h, err = tc.handler(r.log, decoder, r.wrapEncoder(encoder), middlewares, r.defaultErrorHandler)
+		} else {
+			h, err = NewHandler(r.log, r.wrapDecoder(decoder), r.wrapEncoder(encoder), middlewares, r.defaultErrorHandler, fn)
+		}
+		if err != nil {
+			return fmt.Errorf("autohttp: %s %s: %w", method, path, err)
+		}
+		r.hasFunctionRoutes = true
+		h.hideRequestIDs = r.hideRequestIDsInErrors
+		h.compression = r.compression
+		if _, ok := encoder.(NoOpEncoder); !ok && !isStreamFunc(h.fn) {
+			// routes without a body, or streaming their own, have nothing to negotiate
+			h.negotiation = r.negotiation.forFunc(h.fn)
+			h.errorNegotiation = r.negotiation
+		}
+		h.errorLogging = r.errorLogging
+		h.requestErrorHandler = r.requestErrorHandler
+		if rc.responseCache != nil && rc.responseCache.ttl <= 0 {
+			return fmt.Errorf("autohttp: %s %s: WithCacheKey and WithStaleWhileRevalidate need WithResponseCache", method, path)
+		}
+		if r.fieldScopes != nil && scopedResponse(h.fn) {
+			if rc.responseCache != nil && reflect.ValueOf(rc.responseCache.keyFn).Pointer() == reflect.ValueOf(DefaultCacheKey).Pointer() {
+				return fmt.Errorf("autohttp: %s %s: responses with scoped fields can't be cached under DefaultCacheKey, see WithCacheKey", method, path)
+			}
+
+			rc.responseTransformers = append(rc.responseTransformers, FilterFieldsByScope(r.fieldScopes))
+		}
+		if r.embeddedAssets != nil {
+			h.errorPages = r.embeddedAssets.errorPages
+		}
+		rc.configure(h)
+		if rc.requestDigest != nil {
+			rd := *rc.requestDigest
+			rd.limit = r.bodyLimit(rc, false)
+			if rd.limit == 0 {
+				rd.limit = decoderBodyLimit(decoder)
+			}
+			if rd.limit <= 0 {
+				rd.limit = DefaultMaxBytesToRead
+			}
+			h.requestDigest = &rd
+		}
+
+		if rc.sampling != nil {
+			if r.metricsSink == nil {
+				return fmt.Errorf("autohttp: %s %s: sampling requires a metrics sink, see WithMetricsSink", method, path)
+			}
+
+			sampling := *rc.sampling
+			sampling.sink = r.metricsSink
+			sampling.tags = rc.tags
+			h.sampling = &sampling
+		}
+
+		handler = h
+	}
+
+	if rc.hasExample {
+		if encoder == nil {
+			return fmt.Errorf("autohttp: %s %s: examples need an encoder, see WithDefaultEncoder and WithEncoder", method, path)
+		}
+
+		handler = &mockHandler{
+			mode:                r.mockMode,
+			header:              r.mockHeader,
+			example:             rc.example,
+			encoder:             r.wrapEncoder(encoder),
+			errorHandler:        r.errorHandler(),
+			requestErrorHandler: r.requestErrorHandler,
+			errorShaping:        r.errorShaping(),
+			next:                handler,
+		}
+	}
+
+	// timeouts, rejected content types and oversized bodies are errors of the route itself
+	timeoutErrors, decodeErrors := r.routeErrorHandler(handler, StageHandler), r.routeErrorHandler(handler, StageDecode)
+	handler = r.withTimeout(handler, rc, timeoutErrors)
+	handler = r.withBodyLimit(withContentTypes(handler, rc, decodeErrors), rc, false, decodeErrors)
+	handler = rc.wrap(handler)
+	if rc.workerPool != nil {
+		handler = &pooledHandler{pool: rc.workerPool, next: handler, errorHandler: r.serveRouterError}
+	}
+	handler = r.warm(method+" "+path, handler, rc)
+
+	if shared {
+		rvs, err := addRouteVariant(tree, path, existing, handler, rc.conditions)
+		if err != nil {
+			return err
+		}
+		handler = rvs
+	} else {
+		if len(rc.conditions) > 0 {
+			handler = &routeVariants{variants: []routeVariant{{conditions: rc.conditions, handler: handler}}}
+		}
+
+		err := tree.insert(path, handler)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.trees[method] = tree
+	r.Routes[method][path] = handler
+
+	if rc.metricBlind {
+		r.markMetricBlind(method + " " + path)
+	}
+
+	if rc.accessLog != nil {
+		if r.accessLogRoutes == nil {
+			r.accessLogRoutes = make(map[string]bool)
+		}
+		r.accessLogRoutes[method+" "+path] = *rc.accessLog
 	}
 
-	h, err := NewHandler(r.log, r.defaultDecoder, r.defaultEncoder, middlewares, r.defaultErrorHandler, fn)
-	if err != nil {
-		return err
+	if rc.cors != nil {
+		if r.corsRoutes == nil {
+			r.corsRoutes = make(map[string]*corsPolicy)
+		}
+		r.corsRoutes[method+" "+path] = rc.cors
+	}
+
+	if len(rc.tags) > 0 {
+		if r.routeTags == nil {
+			r.routeTags = make(map[string][]string)
+		}
+		r.routeTags[method+" "+path] = rc.tags
+	}
+
+	if tc, ok := fn.(typedCaller); ok {
+		// typed routes always decode JSON, whatever the route's decoder
+		fn = tc.handlerFunc()
+		if _, ok := decoder.(*JSONDecoder); !ok {
+			decoder = NewJSONDecoder()
+		}
 	}
 
-	r.Routes[method][path] = h
+	// routes sharing a path are documented by the first one registered
+	if _, ok := fn.(http.Handler); !ok && !rc.hidden && !shared && !strings.HasSuffix(path, "*") {
+		r.docs = append(r.docs, routeDoc{
+			method:     method,
+			pattern:    path,
+			fn:         fn,
+			decoder:    decoder,
+			example:    rc.example,
+			hasExample: rc.hasExample,
+			tags:       rc.tags,
+
+			contentTypes: rc.contentTypes,
+		})
+	}
 
+	r.emitLifecycle(func(s LifecycleSubscriber) {
+		s.RouteRegistered(RouteEvent{Method: method, Pattern: path, Tags: rc.tags})
+	})
 	return nil
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if r.enableRouteMetrics {
-		m := httpsnoop.CaptureMetrics(http.HandlerFunc(r.internalServeHTTP), w, req)
-		r.log.Debugf("served %d bytes for %s %s in %s with code %d", m.Written, req.Method, req.URL.Path, m.Duration, m.Code)
+	if r.requestTimelines && r.debugEnabled() {
+		var tl *requestTimeline
+		req, tl = withTimeline(req)
+		defer r.logTimeline(req, tl)
+	}
+
+	if r.errorLogging != nil || r.accessLog != nil || r.prometheus != nil || r.tracer != nil || r.routeTags != nil {
+		req = withRequestMeta(req)
+	}
+
+	if r.enableRequestIDs {
+		req = withRequestID(req)
+	}
+
+	if r.operations != nil {
+		req = req.WithContext(context.WithValue(req.Context(), operationsCtxKey{}, r.operations))
+	}
+
+	if r.features != nil {
+		req = req.WithContext(context.WithValue(req.Context(), featureGatesCtxKey{}, r.features))
+	}
+
+	if r.sessions != nil {
+		var save func()
+		w, req, save = r.sessions.load(w, req)
+		defer save()
+	}
+
+	if r.requestTimeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), r.requestTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	if r.deadlines != nil {
+		var cancel context.CancelFunc
+		req, cancel = r.deadlines.apply(req)
+		defer cancel()
+	}
+
+	if r.enableHSTS {
+		w.Header().Set("Strict-Transport-Security", HSTSPolicy)
+	}
+
+	if len(r.enrichers) > 0 {
+		req = r.enrich(req)
+	}
+
+	if r.enableRouteMetrics || r.accessLog != nil || r.prometheus != nil || r.tracer != nil {
+		var span Span
+		if r.tracer != nil {
+			req, span = r.startSpan(req)
+		}
+
+		tracked, req, raw := withRawWriter(w, req)
+		m := httpsnoop.CaptureMetrics(http.HandlerFunc(r.internalServeHTTP), tracked, req)
+		if span != nil {
+			r.endSpan(span, req, m.Code)
+		}
+
+		if r.isQuiet(req.URL.Path) || raw.bypassed {
+			return
+		}
+
+		if r.prometheus != nil {
+			r.prometheus.record(req, r.metricsMethod(req.Method), m)
+		}
+
+		if r.enableRouteMetrics {
+			r.log.Debugf("served %d bytes for %s %s in %s with code %d", m.Written, req.Method, req.URL.Path, m.Duration, m.Code)
+		}
+
+		if r.accessLog != nil && r.accessLogEnabled(req) {
+			r.accessLog.record(r.log, r.metricsSink, req, m, r.accessLogFormat, r.accessLogFields)
+		}
 
 		return
 	}
@@ -163,23 +617,77 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 func (r *Router) internalServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.strictRequests {
+		err := checkRequestHygiene(req, r.maxHeaderValueBytes)
+		if err != nil {
+			// never reuse a connection that may have been desynced
+			w.Header().Set("Connection", "close")
+			r.serveRouterError(w, req, err)
+			return
+		}
+	}
+
+	if r.normalizePaths {
+		req = r.normalizePath(w, req)
+		if req == nil {
+			return
+		}
+	}
+
+	if r.rules != nil {
+		var err error
+		req, err = r.rules.apply(req)
+		if err != nil {
+			r.serveRouterError(w, req, err)
+			r.cleanLeftovers(req)
+			return
+		}
+	}
+
 	if req.Method == http.MethodOptions {
+		if r.cors != nil || len(r.corsRoutes) > 0 {
+			r.serveOptions(w, req)
+		}
+		return
+	}
+
+	if !isReadMethod(req.Method) && r.readOnly.matches(req.URL.Path) {
+		r.serveRouterError(w, req, ErrorWithCode{Err: ErrReadOnly, StatusCode: http.StatusServiceUnavailable})
+		r.cleanLeftovers(req)
+		return
+	}
+
+	if r.serveAssetMount(w, req) {
+		r.cleanLeftovers(req)
+		return
+	}
+
+	if handler, ok := r.matchMount(req); ok {
+		handler.ServeHTTP(w, req)
+		r.cleanLeftovers(req)
 		return
 	}
 
-	for path, handler := range r.starRoutes {
-		pathPrefix := strings.ReplaceAll(path, "*", "")
-		if strings.HasPrefix(req.URL.Path, pathPrefix) {
-			handler.ServeHTTP(w, req)
+	for _, sr := range r.starRoutes {
+		if strings.HasPrefix(req.URL.Path, sr.prefix) {
+			if r.cors != nil {
+				r.cors.applySimple(w, req)
+			}
+			r.serveRoute(w, req, "* "+sr.pattern, sr.handler)
 			return
 		}
 	}
 
 	method := strings.ToUpper(req.Method)
-	routes, ok := r.Routes[method]
+	route, pattern, params, ok := r.lookup(method, r.Routes[method], req.URL.Path)
+	head := false
+	if !ok && method == http.MethodHead {
+		// HEAD requests are answered by the GET route, unless one is registered
+		method, head = http.MethodGet, true
+		route, pattern, params, ok = r.lookup(method, r.Routes[method], req.URL.Path)
+	}
 	if !ok {
-		if method != http.MethodGet {
-			w.WriteHeader(http.StatusMethodNotAllowed)
+		if r.serveMethodNotAllowed(w, req) || r.serveGone(w, req) {
 			return
 		}
 
@@ -188,15 +696,35 @@ func (r *Router) internalServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	route, ok := routes[req.URL.Path]
-	if !ok {
-		r.serveNotFound(w, req)
-		r.cleanLeftovers(req)
+	if len(params) > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), paramsCtxKey{}, params))
+	}
+
+	timelineFromContext(req.Context()).routed(pattern)
+
+	if rm := requestMetaFromContext(req.Context()); rm != nil {
+		rm.pattern = pattern
+		if r.routeTags != nil {
+			rm.tags = r.routeTags[method+" "+pattern]
+		}
+	}
+
+	if r.retries != nil {
+		r.retries.observe(method+" "+pattern, req)
+	}
+
+	if cp := r.corsPolicyFor(method, pattern); cp != nil {
+		cp.applySimple(w, req)
+	}
+
+	if head {
+		serveHead(w, req, func(w http.ResponseWriter, req *http.Request) {
+			r.serveRoute(w, req, method+" "+pattern, route)
+		})
 		return
 	}
 
-	route.ServeHTTP(w, req)
-	r.cleanLeftovers(req)
+	r.serveRoute(w, req, method+" "+pattern, route)
 }
 
 // this is a bit of weirdness from production on Heroku
@@ -206,9 +734,7 @@ func (r *Router) cleanLeftovers(req *http.Request) {
 	if req.Body == nil || req.Body == http.NoBody {
 		// do nothing
 	} else {
-		// chew up the rest of the body
-		var buf bytes.Buffer
-		buf.ReadFrom(req.Body)
-		req.Body.Close()
+		// chew up the rest of the body, within reason
+		drainBody(req.Body)
 	}
 }
@@ -30,10 +30,5 @@ func (r *Router) serveNotFound(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Handling the FileServer Code Snippet was taken from here:
-	// https://golang.org/pkg/embed/#hdr-File_Systems
-	nfs := indexOnNotFoundFS{fs: r.embeddedAssets.staticDir}
-
-	embeddedFileServer := http.FileServer(http.FS(nfs))
-	embeddedFileServer.ServeHTTP(w, req)
+	r.embeddedAssets.ServeHTTP(w, req)
 }
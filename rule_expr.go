@@ -0,0 +1,398 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Rule expressions are a small, side effect free language evaluated against
+// each request. They have strings and booleans:
+//
+//	'literal' or "literal", true, false
+//	method, path, host, scheme, remote_ip
+//	header(name), query(name), cookie(name), lower(s), feature(name)
+//	has_prefix(s, prefix), has_suffix(s, suffix), contains(s, sub)
+//	matches(s, 'regexp')
+//	a + b, a == b, a != b, !a, a && b, a || b, (a)
+//
+// Expressions are type checked when they're compiled, so a rule that could
+// fail on a request is rejected up front
+type exprType string
+
+const (
+	exprString exprType = "string"
+	exprBool   exprType = "bool"
+)
+
+// compiledExpr evaluates to a string or a bool, as its type says
+type compiledExpr struct {
+	typ  exprType
+	eval func(r *http.Request) interface{}
+
+	// literal is set for string literals, which some functions require
+	literal *string
+}
+
+// ExprPredicate compiles a rule expression, such as
+// `header('X-Env') == 'canary' && has_prefix(path, '/api')`, into a
+// RequestPredicate, e.g. for When
+func ExprPredicate(expr string) (RequestPredicate, error) {
+	ce, err := compileExpr(expr, exprBool)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(r *http.Request) bool {
+		return ce.eval(r).(bool)
+	}, nil
+}
+
+// compileExpr parses expr, which must evaluate to want
+func compileExpr(expr string, want exprType) (compiledExpr, error) {
+	tokens, err := lexExpr(expr)
+	if err != nil {
+		return compiledExpr{}, fmt.Errorf("autohttp: rule expression %q: %w", expr, err)
+	}
+
+	p := &exprParser{tokens: tokens}
+	ce, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err == nil && ce.typ != want {
+		err = fmt.Errorf("evaluates to a %s, not a %s", ce.typ, want)
+	}
+	if err != nil {
+		return compiledExpr{}, fmt.Errorf("autohttp: rule expression %q: %w", expr, err)
+	}
+
+	return ce, nil
+}
+
+type exprTokenKind int
+
+const (
+	tokenIdent exprTokenKind = iota
+	tokenString
+	tokenOp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+func lexExpr(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				b.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: b.String()})
+			i = j + 1
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(expr) && (expr[j] == '_' || expr[j] >= 'a' && expr[j] <= 'z' || expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: expr[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "&&", "||", "!", "(", ")", ",", "+"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenOp, text: op})
+			i += len(op)
+		}
+	}
+
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// accept consumes the next token if it's the operator op
+func (p *exprParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOp && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *exprParser) parseOr() (compiledExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right compiledExpr
+		right, err = p.parseAnd()
+		if err == nil {
+			left, err = boolOp("||", left, right)
+		}
+	}
+
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (compiledExpr, error) {
+	left, err := p.parseComparison()
+	for err == nil && p.accept("&&") {
+		var right compiledExpr
+		right, err = p.parseComparison()
+		if err == nil {
+			left, err = boolOp("&&", left, right)
+		}
+	}
+
+	return left, err
+}
+
+func boolOp(op string, left, right compiledExpr) (compiledExpr, error) {
+	if left.typ != exprBool || right.typ != exprBool {
+		return compiledExpr{}, fmt.Errorf("%s needs bools", op)
+	}
+
+	l, r := left.eval, right.eval
+	if op == "&&" {
+		return compiledExpr{typ: exprBool, eval: func(req *http.Request) interface{} {
+			return l(req).(bool) && r(req).(bool)
+		}}, nil
+	}
+
+	return compiledExpr{typ: exprBool, eval: func(req *http.Request) interface{} {
+		return l(req).(bool) || r(req).(bool)
+	}}, nil
+}
+
+func (p *exprParser) parseComparison() (compiledExpr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return left, err
+	}
+
+	negate := false
+	switch {
+	case p.accept("=="):
+	case p.accept("!="):
+		negate = true
+	default:
+		return left, nil
+	}
+
+	right, err := p.parseConcat()
+	if err != nil {
+		return right, err
+	}
+
+	if left.typ != right.typ {
+		return compiledExpr{}, fmt.Errorf("can't compare a %s to a %s", left.typ, right.typ)
+	}
+
+	l, r := left.eval, right.eval
+	return compiledExpr{typ: exprBool, eval: func(req *http.Request) interface{} {
+		return (l(req) == r(req)) != negate
+	}}, nil
+}
+
+func (p *exprParser) parseConcat() (compiledExpr, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.accept("+") {
+		var right compiledExpr
+		right, err = p.parseUnary()
+		if err != nil {
+			break
+		}
+
+		if left.typ != exprString || right.typ != exprString {
+			return compiledExpr{}, errors.New("+ needs strings")
+		}
+
+		l, r := left.eval, right.eval
+		left = compiledExpr{typ: exprString, eval: func(req *http.Request) interface{} {
+			return l(req).(string) + r(req).(string)
+		}}
+	}
+
+	return left, err
+}
+
+func (p *exprParser) parseUnary() (compiledExpr, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return operand, err
+		}
+
+		if operand.typ != exprBool {
+			return compiledExpr{}, errors.New("! needs a bool")
+		}
+
+		eval := operand.eval
+		return compiledExpr{typ: exprBool, eval: func(req *http.Request) interface{} {
+			return !eval(req).(bool)
+		}}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (compiledExpr, error) {
+	if p.pos == len(p.tokens) {
+		return compiledExpr{}, errors.New("unexpected end")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case tok.kind == tokenString:
+		s := tok.text
+		return compiledExpr{typ: exprString, eval: func(*http.Request) interface{} { return s }, literal: &s}, nil
+	case tok.kind == tokenOp && tok.text == "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return inner, err
+		}
+		if !p.accept(")") {
+			return compiledExpr{}, errors.New("missing )")
+		}
+		return inner, nil
+	case tok.kind == tokenIdent && p.accept("("):
+		var args []compiledExpr
+		for !p.accept(")") {
+			if len(args) > 0 && !p.accept(",") {
+				return compiledExpr{}, fmt.Errorf("expected , or ) in %s()", tok.text)
+			}
+
+			arg, err := p.parseOr()
+			if err != nil {
+				return arg, err
+			}
+			args = append(args, arg)
+		}
+		return compileCall(tok.text, args)
+	case tok.kind == tokenIdent:
+		return compileIdent(tok.text)
+	}
+
+	return compiledExpr{}, fmt.Errorf("unexpected %q", tok.text)
+}
+
+func compileIdent(name string) (compiledExpr, error) {
+	var eval func(r *http.Request) interface{}
+	switch name {
+	case "true", "false":
+		b := name == "true"
+		return compiledExpr{typ: exprBool, eval: func(*http.Request) interface{} { return b }}, nil
+	case "method":
+		eval = func(r *http.Request) interface{} { return r.Method }
+	case "path":
+		eval = func(r *http.Request) interface{} { return r.URL.Path }
+	case "host":
+		eval = func(r *http.Request) interface{} { return r.Host }
+	case "scheme":
+		eval = func(r *http.Request) interface{} {
+			if r.TLS != nil {
+				return "https"
+			}
+			return "http"
+		}
+	case "remote_ip":
+		eval = func(r *http.Request) interface{} { return remoteIP(r) }
+	default:
+		return compiledExpr{}, fmt.Errorf("unknown variable %s", name)
+	}
+
+	return compiledExpr{typ: exprString, eval: eval}, nil
+}
+
+func compileCall(name string, args []compiledExpr) (compiledExpr, error) {
+	oneString := func(fn func(r *http.Request, a string) interface{}, typ exprType) (compiledExpr, error) {
+		if len(args) != 1 || args[0].typ != exprString {
+			return compiledExpr{}, fmt.Errorf("%s() takes a string", name)
+		}
+
+		a := args[0].eval
+		return compiledExpr{typ: typ, eval: func(r *http.Request) interface{} {
+			return fn(r, a(r).(string))
+		}}, nil
+	}
+
+	twoStrings := func(fn func(a, b string) bool) (compiledExpr, error) {
+		if len(args) != 2 || args[0].typ != exprString || args[1].typ != exprString {
+			return compiledExpr{}, fmt.Errorf("%s() takes two strings", name)
+		}
+
+		a, b := args[0].eval, args[1].eval
+		return compiledExpr{typ: exprBool, eval: func(r *http.Request) interface{} {
+			return fn(a(r).(string), b(r).(string))
+		}}, nil
+	}
+
+	switch name {
+	case "header":
+		return oneString(func(r *http.Request, a string) interface{} { return r.Header.Get(a) }, exprString)
+	case "query":
+		return oneString(func(r *http.Request, a string) interface{} { return r.URL.Query().Get(a) }, exprString)
+	case "cookie":
+		return oneString(func(r *http.Request, a string) interface{} {
+			c, err := r.Cookie(a)
+			if err != nil {
+				return ""
+			}
+			return c.Value
+		}, exprString)
+	case "lower":
+		return oneString(func(r *http.Request, a string) interface{} { return strings.ToLower(a) }, exprString)
+	case "feature":
+		return oneString(func(r *http.Request, a string) interface{} { return FeatureEnabled(r.Context(), a) }, exprBool)
+	case "has_prefix":
+		return twoStrings(strings.HasPrefix)
+	case "has_suffix":
+		return twoStrings(strings.HasSuffix)
+	case "contains":
+		return twoStrings(strings.Contains)
+	case "matches":
+		if len(args) != 2 || args[0].typ != exprString || args[1].literal == nil {
+			return compiledExpr{}, errors.New("matches() takes a string and a regexp literal")
+		}
+
+		re, err := regexp.Compile(*args[1].literal)
+		if err != nil {
+			return compiledExpr{}, err
+		}
+
+		a := args[0].eval
+		return compiledExpr{typ: exprBool, eval: func(r *http.Request) interface{} {
+			return re.MatchString(a(r).(string))
+		}}, nil
+	}
+
+	return compiledExpr{}, fmt.Errorf("unknown function %s()", name)
+}
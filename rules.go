@@ -0,0 +1,219 @@
+package autohttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// A RuleAction is what a Rule does to the requests it matches
+type RuleAction string
+
+const (
+	// RuleTransform rewrites the request and carries on with the next rule
+	RuleTransform RuleAction = "transform"
+	// RuleMatch rewrites the request and serves it, skipping the rules after it
+	RuleMatch RuleAction = "match"
+	// RuleDeny answers the request with an error
+	RuleDeny RuleAction = "deny"
+)
+
+// A Rule matches requests with an expression, see ExprPredicate for the
+// expression language, and transforms, routes or denies them. Rules let small
+// API gateways built on a Router change their routing without recompiling,
+// see WithRules and RuntimeConfig
+type Rule struct {
+	Name string `json:"name" yaml:"name"`
+	// When must evaluate to a bool, an empty When matches every request
+	When   string     `json:"when,omitempty" yaml:"when,omitempty"`
+	Action RuleAction `json:"action" yaml:"action"`
+
+	// SetHeaders sets request headers to the values of string expressions,
+	// e.g. {"X-Upstream": "'billing-' + header('X-Region')"}
+	SetHeaders    map[string]string `json:"set_headers,omitempty" yaml:"set_headers,omitempty"`
+	RemoveHeaders []string          `json:"remove_headers,omitempty" yaml:"remove_headers,omitempty"`
+	// RewritePath replaces the path with the value of a string expression,
+	// before the request is routed
+	RewritePath string `json:"rewrite_path,omitempty" yaml:"rewrite_path,omitempty"`
+
+	// Status and Message answer denied requests, with a 403 by default
+	Status  int    `json:"status,omitempty" yaml:"status,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// RuleDeniedError is returned for requests denied by a Rule
+type RuleDeniedError struct {
+	Rule    string
+	Message string
+}
+
+func (rde *RuleDeniedError) Error() string {
+	if rde.Message != "" {
+		return rde.Message
+	}
+
+	return fmt.Sprintf("autohttp: denied by rule %s", rde.Rule)
+}
+
+// LoadRules reads a JSON encoded list of rules, rejecting unknown fields so
+// typos don't silently change what a rule does
+func LoadRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("autohttp: invalid rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// WithRules applies rules, in order, to every request before it's routed.
+// They can be replaced while the router is serving with ApplyConfig
+func WithRules(rules ...Rule) func(r *Router) error {
+	return func(r *Router) error {
+		compiled, err := compileRules(rules)
+		if err != nil {
+			return err
+		}
+
+		r.rules = &ruleSet{}
+		r.rules.rules.Store(compiled)
+		return nil
+	}
+}
+
+// ruleSet is swapped as a whole, so requests never see half an update
+type ruleSet struct {
+	rules atomic.Value // []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+
+	when       *compiledExpr
+	setHeaders map[string]compiledExpr
+	rewrite    *compiledExpr
+}
+
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		cr, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("autohttp: rule %s: %w", rule.Name, err)
+		}
+
+		compiled = append(compiled, cr)
+	}
+
+	return compiled, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: rule}
+	if rule.Name == "" {
+		return cr, errors.New("rules need a name")
+	}
+
+	switch rule.Action {
+	case RuleTransform, RuleMatch:
+		if rule.Status != 0 || rule.Message != "" {
+			return cr, fmt.Errorf("only %s rules have a status and message", RuleDeny)
+		}
+	case RuleDeny:
+		if len(rule.SetHeaders) > 0 || len(rule.RemoveHeaders) > 0 || rule.RewritePath != "" {
+			return cr, fmt.Errorf("%s rules can't rewrite requests", RuleDeny)
+		}
+
+		if rule.Status != 0 && (rule.Status < http.StatusBadRequest || rule.Status > 599) {
+			return cr, fmt.Errorf("%s rules need an error status, got %d", RuleDeny, rule.Status)
+		}
+	default:
+		return cr, fmt.Errorf("unknown action %q", rule.Action)
+	}
+
+	if rule.When != "" {
+		when, err := compileExpr(rule.When, exprBool)
+		if err != nil {
+			return cr, err
+		}
+		cr.when = &when
+	}
+
+	if len(rule.SetHeaders) > 0 {
+		cr.setHeaders = make(map[string]compiledExpr, len(rule.SetHeaders))
+		for name, expr := range rule.SetHeaders {
+			value, err := compileExpr(expr, exprString)
+			if err != nil {
+				return cr, err
+			}
+			cr.setHeaders[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	if rule.RewritePath != "" {
+		rewrite, err := compileExpr(rule.RewritePath, exprString)
+		if err != nil {
+			return cr, err
+		}
+		cr.rewrite = &rewrite
+	}
+
+	return cr, nil
+}
+
+// apply runs the rules against req, returning the request to serve or an
+// error if a rule denied it
+func (rs *ruleSet) apply(req *http.Request) (*http.Request, error) {
+	for _, rule := range rs.rules.Load().([]compiledRule) {
+		if rule.when != nil && !rule.when.eval(req).(bool) {
+			continue
+		}
+
+		if rule.Action == RuleDeny {
+			status := rule.Status
+			if status == 0 {
+				status = http.StatusForbidden
+			}
+
+			return req, ErrorWithCode{Err: &RuleDeniedError{Rule: rule.Name, Message: rule.Message}, StatusCode: status}
+		}
+
+		req = rule.rewriteRequest(req)
+		if rule.Action == RuleMatch {
+			break
+		}
+	}
+
+	return req, nil
+}
+
+// rewriteRequest applies the rule's header and path changes to a copy of req.
+// Every expression sees the request as it came into the rule
+func (cr compiledRule) rewriteRequest(req *http.Request) *http.Request {
+	if len(cr.setHeaders) == 0 && len(cr.RemoveHeaders) == 0 && cr.rewrite == nil {
+		return req
+	}
+
+	rewritten := req.Clone(req.Context())
+	for name, value := range cr.setHeaders {
+		rewritten.Header.Set(name, value.eval(req).(string))
+	}
+
+	for _, name := range cr.RemoveHeaders {
+		rewritten.Header.Del(name)
+	}
+
+	if cr.rewrite != nil {
+		rewritten.URL.Path = cr.rewrite.eval(req).(string)
+		rewritten.URL.RawPath = ""
+	}
+
+	return rewritten
+}
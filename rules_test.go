@@ -0,0 +1,188 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestExprPredicate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Expr   string
+		Expect bool
+		Err    string
+	}{
+		{Expr: `method == 'GET'`, Expect: true},
+		{Expr: `method != "GET"`, Expect: false},
+		{Expr: `has_prefix(path, '/api/') && header('X-Env') == 'canary'`, Expect: true},
+		{Expr: `query('v') == '2' || false`, Expect: true},
+		{Expr: `!(scheme == 'https')`, Expect: true},
+		{Expr: `lower(header('X-Mixed')) + '!' == 'shout!'`, Expect: true},
+		{Expr: `matches(path, '^/api/v[0-9]+/')`, Expect: true},
+		{Expr: `contains(host, 'example') && has_suffix(path, 'pets')`, Expect: true},
+		{Expr: `cookie('session') == 'abc' && remote_ip == '192.0.2.1'`, Expect: true},
+		{Expr: `feature('beta')`, Expect: false},
+		{Expr: `'it\'s' == "it's"`, Expect: true},
+		{Expr: `method`, Err: "evaluates to a string, not a bool"},
+		{Expr: `method == true`, Err: "can't compare a string to a bool"},
+		{Expr: `!method`, Err: "! needs a bool"},
+		{Expr: `header('a', 'b')`, Err: "header() takes a string"},
+		{Expr: `matches(path, header('X-Re'))`, Err: "regexp literal"},
+		{Expr: `matches(path, '(')`, Err: "missing closing )"},
+		{Expr: `nope(path)`, Err: "unknown function nope()"},
+		{Expr: `user == 'a'`, Err: "unknown variable user"},
+		{Expr: `(method == 'GET'`, Err: "missing )"},
+		{Expr: `method == 'GET`, Err: "unterminated string"},
+		{Expr: `method == 'GET' method`, Err: `unexpected "method"`},
+		{Expr: `path > 'a'`, Err: `unexpected '>'`},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com/api/v2/pets?v=2", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Env", "canary")
+	req.Header.Set("X-Mixed", "ShOuT")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	for _, c := range cases {
+		pred, err := ExprPredicate(c.Expr)
+		if c.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.Err) {
+				t.Errorf("case[%s] expected error containing %q, got %v", c.Expr, c.Err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("case[%s] %s", c.Expr, err)
+			continue
+		}
+
+		if got := pred(req); got != c.Expect {
+			t.Errorf("case[%s] expected %t, got %t", c.Expr, c.Expect, got)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithRules(
+			Rule{Name: "internal", When: `has_prefix(path, '/internal')`, Action: RuleDeny, Status: http.StatusNotFound, Message: "not here"},
+			Rule{Name: "tag", Action: RuleTransform, SetHeaders: map[string]string{"x-gateway": `'gw-' + method`}, RemoveHeaders: []string{"X-Debug"}},
+			Rule{Name: "v1", When: `has_prefix(path, '/v1/')`, Action: RuleMatch, RewritePath: `'/legacy'`},
+			Rule{Name: "no-bots", When: `contains(header('User-Agent'), 'bot')`, Action: RuleDeny},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(name + " " + req.Header.Get("X-Gateway") + " " + req.Header.Get("X-Debug")))
+		})
+	}
+
+	for _, path := range []string{"/pets", "/legacy"} {
+		err = r.Register(http.MethodGet, path, echo(path), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		path      string
+		userAgent string
+		code      int
+		body      string
+	}{
+		{name: "transformed", path: "/pets", code: http.StatusOK, body: "/pets gw-GET "},
+		{name: "denied", path: "/internal/stats", code: http.StatusNotFound, body: "not here"},
+		{name: "denied by default", path: "/pets", userAgent: "crawlbot", code: http.StatusForbidden, body: "autohttp: denied by rule no-bots"},
+		{name: "matched and rewritten", path: "/v1/anything", userAgent: "crawlbot", code: http.StatusOK, body: "/legacy gw-GET "},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Header.Set("X-Debug", "true")
+		if c.userAgent != "" {
+			req.Header.Set("User-Agent", c.userAgent)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if !strings.Contains(w.Body.String(), c.body) {
+			t.Errorf("case[%s] expected body containing %q, got %q", c.name, c.body, w.Body.String())
+		}
+	}
+
+	rules, err := LoadRules(strings.NewReader(`[{"name": "closed", "action": "deny", "status": 503}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.ApplyConfig(RuntimeConfig{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pets", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the replaced rules to deny with a 503, got %d", w.Code)
+	}
+
+	err = r.ApplyConfig(RuntimeConfig{Rules: []Rule{{Name: "broken", Action: RuleMatch, When: "path =="}}})
+	if err == nil {
+		t.Error("expected bad rules to be rejected")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pets", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected rejected rules to leave the current ones, got %d", w.Code)
+	}
+}
+
+func TestRuleValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name string
+		Rule Rule
+		Err  string
+	}{
+		{"unnamed", Rule{Action: RuleDeny}, "rules need a name"},
+		{"unknown action", Rule{Name: "r", Action: "allow"}, `unknown action "allow"`},
+		{"deny rewriting", Rule{Name: "r", Action: RuleDeny, RewritePath: "'/'"}, "can't rewrite requests"},
+		{"deny success", Rule{Name: "r", Action: RuleDeny, Status: http.StatusOK}, "need an error status"},
+		{"transform status", Rule{Name: "r", Action: RuleTransform, Status: http.StatusForbidden}, "only deny rules"},
+		{"bool header", Rule{Name: "r", Action: RuleTransform, SetHeaders: map[string]string{"X-A": "true"}}, "not a string"},
+	}
+
+	for _, c := range cases {
+		_, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithRules(c.Rule))
+		if err == nil || !strings.Contains(err.Error(), c.Err) {
+			t.Errorf("case[%s] expected error containing %q, got %v", c.Name, c.Err, err)
+		}
+	}
+
+	_, err := LoadRules(strings.NewReader(`[{"name": "r", "action": "deny", "stauts": 503}]`))
+	if err == nil {
+		t.Error("expected unknown fields to be rejected")
+	}
+}
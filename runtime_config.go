@@ -0,0 +1,254 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// RuntimeConfig holds the settings that can change while the router is
+// serving. Empty fields leave the current setting alone
+type RuntimeConfig struct {
+	// LogLevel is one of debug, info or error
+	LogLevel string `json:"log_level,omitempty" yaml:"log_level,omitempty"`
+
+	// ReadOnly lists the path prefixes in read-only (maintenance) mode,
+	// replacing the current list. An empty list takes every route out of it
+	ReadOnly []string `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+
+	// Features replaces the feature gates, see WithFeatureGates
+	Features map[string]bool `json:"features,omitempty" yaml:"features,omitempty"`
+
+	// Rules replaces the rules, see WithRules. An empty list removes them all
+	Rules []Rule `json:"rules,omitempty" yaml:"rules,omitempty"`
+
+	// RateLimits changes the limits of rate limits named with WithRateLimit.
+	// Limits left out keep their current value
+	RateLimits map[string]RateLimit `json:"rate_limits,omitempty" yaml:"rate_limits,omitempty"`
+}
+
+type featureGatesCtxKey struct{}
+
+// featureGates is swapped as a whole, so readers never see half an update
+type featureGates struct {
+	gates atomic.Value // map[string]bool
+}
+
+func (fg *featureGates) enabled(name string) bool {
+	return fg.gates.Load().(map[string]bool)[name]
+}
+
+// WithFeatureGates enables feature gates with their initial values. Handlers
+// check them with FeatureEnabled, and ApplyConfig can flip them at runtime
+func WithFeatureGates(defaults map[string]bool) func(r *Router) error {
+	return func(r *Router) error {
+		r.features = &featureGates{}
+		r.features.gates.Store(copyGates(defaults))
+		return nil
+	}
+}
+
+// FeatureEnabled reports whether the named feature gate is on for the
+// router serving ctx. Unknown gates are off
+func FeatureEnabled(ctx context.Context, name string) bool {
+	fg, ok := ctx.Value(featureGatesCtxKey{}).(*featureGates)
+	if !ok {
+		return false
+	}
+
+	return fg.enabled(name)
+}
+
+// ApplyConfig updates the runtime settings. Every field is validated before
+// anything changes, so a bad config is rejected as a whole, and each change
+// is logged
+func (r *Router) ApplyConfig(cfg RuntimeConfig) error {
+	var level LogLevel
+	if cfg.LogLevel != "" {
+		var err error
+		level, err = ParseLogLevel(cfg.LogLevel)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.Features != nil && r.features == nil {
+		return errors.New("autohttp: feature gates require WithFeatureGates")
+	}
+
+	var rules []compiledRule
+	if cfg.Rules != nil {
+		if r.rules == nil {
+			return errors.New("autohttp: rules require WithRules")
+		}
+
+		var err error
+		rules, err = compileRules(cfg.Rules)
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, limit := range cfg.RateLimits {
+		if _, ok := r.rateLimits[name]; !ok {
+			return fmt.Errorf("autohttp: no rate limit named %q, see WithRateLimit", name)
+		}
+
+		err := limit.validate()
+		if err != nil {
+			return fmt.Errorf("autohttp: rate limit %s: %w", name, err)
+		}
+	}
+
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+
+	if cfg.LogLevel != "" {
+		if old := LogLevel(atomic.LoadInt32(r.logLevel.level)); old != level {
+			r.log.Infof("autohttp: log level changed from %s to %s", old, level)
+		}
+		r.logLevel.set(level)
+	}
+
+	if cfg.ReadOnly != nil {
+		old := r.readOnly.replace(cfg.ReadOnly)
+		if !equalStrings(old, r.readOnly.list()) {
+			r.log.Infof("autohttp: read-only paths changed from %v to %v", old, r.readOnly.list())
+		}
+	}
+
+	if cfg.Features != nil {
+		old := r.features.gates.Load().(map[string]bool)
+		for _, change := range gateChanges(old, cfg.Features) {
+			r.log.Infof("autohttp: feature %s", change)
+		}
+		r.features.gates.Store(copyGates(cfg.Features))
+	}
+
+	if cfg.Rules != nil {
+		r.rules.rules.Store(rules)
+		r.log.Infof("autohttp: %d rules applied", len(rules))
+	}
+
+	names := make([]string, 0, len(cfg.RateLimits))
+	for name := range cfg.RateLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rlm, limit := r.rateLimits[name], cfg.RateLimits[name]
+		if old := rlm.Limit(); old != limit {
+			r.log.Infof("autohttp: rate limit %s changed from %g/s burst %d to %g/s burst %d", name, old.Rate, old.Burst, limit.Rate, limit.Burst)
+		}
+		rlm.SetLimit(limit)
+	}
+
+	return nil
+}
+
+// WatchConfig applies the JSON encoded RuntimeConfig at path when the router
+// starts, then again whenever the file changes, polling every interval.
+// Bad configs are logged and skipped, keeping the last good one
+func (r *Router) WatchConfig(path string, interval time.Duration) {
+	r.Go("config-watcher "+path, func(ctx context.Context) error {
+		var lastMod time.Time
+		var lastSize int64 = -1
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			info, err := os.Stat(path)
+			if err != nil {
+				r.log.Errorf("autohttp: watching config: %s", err)
+			} else if !info.ModTime().Equal(lastMod) || info.Size() != lastSize {
+				lastMod, lastSize = info.ModTime(), info.Size()
+
+				err = r.applyConfigFile(path)
+				if err != nil {
+					r.log.Errorf("autohttp: config %s not applied: %s", path, err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func (r *Router) applyConfigFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var cfg RuntimeConfig
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&cfg)
+	if err != nil {
+		return err
+	}
+
+	return r.ApplyConfig(cfg)
+}
+
+func copyGates(gates map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(gates))
+	for k, v := range gates {
+		copied[k] = v
+	}
+
+	return copied
+}
+
+// gateChanges describes the differences between two sets of gates, sorted
+func gateChanges(old, updated map[string]bool) []string {
+	var changes []string
+	for name, on := range updated {
+		if old[name] != on {
+			changes = append(changes, fmt.Sprintf("%s turned %s", name, onOff(on)))
+		}
+	}
+
+	for name, on := range old {
+		if _, ok := updated[name]; !ok && on {
+			changes = append(changes, fmt.Sprintf("%s turned off", name))
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
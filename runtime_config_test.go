@@ -0,0 +1,269 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+// syncBuffer is a bytes.Buffer safe to log to from background tasks
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
+
+func TestApplyConfig(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)), WithFeatureGates(map[string]bool{"beta": false}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/orders", func(ctx context.Context) map[string]bool {
+		return map[string]bool{"beta": FeatureEnabled(ctx, "beta")}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		Name         string
+		Config       RuntimeConfig
+		ShouldErr    bool
+		ExpectStatus int
+		ExpectBody   string
+		ExpectLog    string
+	}{
+		{"flip-feature", RuntimeConfig{Features: map[string]bool{"beta": true}}, false, http.StatusOK, `{"beta":true}`, "feature beta turned on"},
+		{"maintenance", RuntimeConfig{ReadOnly: []string{"/"}}, false, http.StatusServiceUnavailable, "", "read-only paths changed"},
+		{"bad-level-rejected-whole", RuntimeConfig{LogLevel: "loud", ReadOnly: []string{}}, true, http.StatusServiceUnavailable, "", ""},
+		{"out-of-maintenance", RuntimeConfig{ReadOnly: []string{}}, false, http.StatusOK, `{"beta":true}`, ""},
+		{"quiet", RuntimeConfig{LogLevel: "error"}, false, http.StatusOK, "", "log level changed from debug to error"},
+	}
+
+	for _, c := range cases {
+		err := r.ApplyConfig(c.Config)
+		if err != nil && !c.ShouldErr {
+			t.Errorf("case[%s] failed unexpectedly: %s", c.Name, err)
+		}
+
+		if err == nil && c.ShouldErr {
+			t.Errorf("case[%s] did not fail when it should have", c.Name)
+		}
+
+		w := serve()
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+		}
+
+		if c.ExpectBody != "" && strings.TrimSpace(w.Body.String()) != c.ExpectBody {
+			t.Errorf("case[%s] expected body %s got %s", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		if !strings.Contains(logs.String(), c.ExpectLog) {
+			t.Errorf("case[%s] expected %q to be logged, got %q", c.Name, c.ExpectLog, logs.String())
+		}
+	}
+
+	r2, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r2.ApplyConfig(RuntimeConfig{Features: map[string]bool{"beta": true}})
+	if err == nil {
+		t.Error("expected features without WithFeatureGates to fail")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "runtime.json")
+	err := os.WriteFile(path, []byte(`{"features": {"beta": true}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs syncBuffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)), WithFeatureGates(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.WatchConfig(path, 5*time.Millisecond)
+	r.StartBackground()
+	defer r.StopBackground(context.Background())
+
+	waitFor := func(what string, cond func() bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s, logs: %s", what, logs.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitFor("the initial config", func() bool { return r.features.enabled("beta") })
+
+	err = os.WriteFile(path, []byte(`{"features": {"beta": false, "gamma": true}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the updated config", func() bool { return r.features.enabled("gamma") })
+
+	err = os.WriteFile(path, []byte(`{"features": `), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the bad config to be logged", func() bool { return strings.Contains(logs.String(), "not applied") })
+
+	if !r.features.enabled("gamma") {
+		t.Error("expected the last good config to be kept")
+	}
+}
+
+func TestApplyConfigLogLevelWhileServing(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs), lounge.WithDebugEnabled()), EnableRouteMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/orders", func(ctx context.Context) map[string]bool {
+		return map[string]bool{"ok": true}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func() {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				serve()
+			}
+		}()
+	}
+
+	err = r.ApplyConfig(RuntimeConfig{LogLevel: "error"})
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := strings.Count(logs.String(), "served ")
+	serve()
+	if after := strings.Count(logs.String(), "served "); after != before {
+		t.Errorf("expected debug lines to be dropped once the level is error, got %d more", after-before)
+	}
+}
+
+func TestApplyConfigRateLimits(t *testing.T) {
+	t.Parallel()
+
+	rlm, err := NewRateLimitMiddleware(1, 1, RateLimitByHeader("X-API-Key"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs syncBuffer
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs)), WithRateLimit("api", rlm))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/orders", func(ctx context.Context) map[string]bool {
+		return map[string]bool{"ok": true}
+	}, []Middleware{rlm})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(key string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if serve("a") != http.StatusOK || serve("a") != http.StatusTooManyRequests {
+		t.Fatal("expected a burst of one before the config change")
+	}
+
+	cases := []struct {
+		Name      string
+		Config    RuntimeConfig
+		ShouldErr bool
+		Expect    RateLimit
+	}{
+		{"unknown-name", RuntimeConfig{RateLimits: map[string]RateLimit{"nope": {Rate: 5, Burst: 5}}}, true, RateLimit{Rate: 1, Burst: 1}},
+		{"bad-rate-rejected-whole", RuntimeConfig{LogLevel: "error", RateLimits: map[string]RateLimit{"api": {Rate: 0, Burst: 5}}}, true, RateLimit{Rate: 1, Burst: 1}},
+		{"raised", RuntimeConfig{RateLimits: map[string]RateLimit{"api": {Rate: 1000, Burst: 10}}}, false, RateLimit{Rate: 1000, Burst: 10}},
+	}
+
+	for _, c := range cases {
+		err := r.ApplyConfig(c.Config)
+		if (err != nil) != c.ShouldErr {
+			t.Errorf("case[%s] expected error %t got %v", c.Name, c.ShouldErr, err)
+		}
+
+		if got := rlm.Limit(); got != c.Expect {
+			t.Errorf("case[%s] expected limit %+v got %+v", c.Name, c.Expect, got)
+		}
+	}
+
+	if !strings.Contains(logs.String(), "rate limit api changed") {
+		t.Errorf("expected the change to be logged, got %q", logs.String())
+	}
+
+	for i := 0; i < 10; i++ {
+		if code := serve("b"); code != http.StatusOK {
+			t.Errorf("request %d expected the raised burst to let it through, got %d", i, code)
+		}
+	}
+}
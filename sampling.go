@@ -0,0 +1,222 @@
+package autohttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"runtime/trace"
+	"time"
+)
+
+// A RequestSample is the detailed timing of a single sampled request
+type RequestSample struct {
+	Method string
+	Path   string
+	Tags   []string
+	Start  time.Time
+
+	Decode  time.Duration
+	Handler time.Duration
+	Encode  time.Duration
+	Total   time.Duration
+
+	// Trace holds a runtime/trace capture of the request when tracing is
+	// enabled and no other trace was already running
+	Trace []byte
+}
+
+// A MetricsSink receives the samples collected by routes with WithSampling
+type MetricsSink interface {
+	RecordSample(s RequestSample)
+}
+
+// MetricsSinkFunc adapts a function to a MetricsSink
+type MetricsSinkFunc func(s RequestSample)
+
+func (f MetricsSinkFunc) RecordSample(s RequestSample) {
+	f(s)
+}
+
+// WithMetricsSink sets where sampled request timings are exported
+func WithMetricsSink(sink MetricsSink) func(r *Router) error {
+	return func(r *Router) error {
+		r.metricsSink = sink
+		return nil
+	}
+}
+
+// WithSampling captures detailed timings for fraction (0 to 1) of the
+// requests to a route and exports them to the router's MetricsSink
+func WithSampling(fraction float64) RouteOption {
+	return func(rc *routeConfig) error {
+		if fraction <= 0 || fraction > 1 {
+			return errors.New("autohttp: sampling fraction must be in (0, 1]")
+		}
+
+		if rc.sampling == nil {
+			rc.sampling = &sampling{}
+		}
+		rc.sampling.fraction = fraction
+		return nil
+	}
+}
+
+// WithSampleTracing also records an execution trace for sampled requests.
+// runtime/trace is process wide, so the trace covers everything running
+// while the request is served and is skipped when a trace is already active
+func WithSampleTracing() RouteOption {
+	return func(rc *routeConfig) error {
+		if rc.sampling == nil {
+			rc.sampling = &sampling{fraction: 1}
+		}
+		rc.sampling.trace = true
+		return nil
+	}
+}
+
+type sampling struct {
+	fraction float64
+	trace    bool
+	sink     MetricsSink
+	tags     []string
+
+	// random is swapped out in tests
+	random func() float64
+}
+
+// start returns a sampleTimer if this request was picked for sampling, or
+// is having its timeline recorded, see EnableRequestTimelines
+func (s *sampling) start(r *http.Request) *sampleTimer {
+	tl := timelineFromContext(r.Context())
+	if !s.picked() {
+		if tl == nil {
+			return nil
+		}
+
+		return &sampleTimer{timeline: tl, last: time.Now()}
+	}
+
+	st := &sampleTimer{
+		sink:     s.sink,
+		timeline: tl,
+		sample: RequestSample{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Tags:   s.tags,
+			Start:  time.Now(),
+		},
+	}
+	st.last = st.sample.Start
+
+	if s.trace {
+		st.traceBuf = &bytes.Buffer{}
+		if trace.Start(st.traceBuf) != nil {
+			st.traceBuf = nil
+		}
+	}
+
+	return st
+}
+
+// picked reports whether to sample this request
+func (s *sampling) picked() bool {
+	if s == nil {
+		return false
+	}
+
+	random := s.random
+	if random == nil {
+		random = rand.Float64
+	}
+
+	return random() < s.fraction
+}
+
+type samplePhase int
+
+const (
+	phaseDecode samplePhase = iota
+	phaseHandler
+	phaseEncode
+)
+
+func (p samplePhase) String() string {
+	switch p {
+	case phaseDecode:
+		return "decode"
+	case phaseHandler:
+		return "handler"
+	case phaseEncode:
+		return "encode"
+	}
+
+	return fmt.Sprintf("samplePhase(%d)", int(p))
+}
+
+// sampleTimer methods are safe to call on a nil timer, so unsampled
+// requests pay for nothing but the nil checks. A timer without a sink only
+// feeds the request's timeline
+type sampleTimer struct {
+	sink     MetricsSink
+	sample   RequestSample
+	last     time.Time
+	traceBuf *bytes.Buffer
+	timeline *requestTimeline
+}
+
+// mark starts timing the next phase
+func (st *sampleTimer) mark() {
+	if st == nil {
+		return
+	}
+
+	st.last = time.Now()
+}
+
+// lap attributes the time since the last mark to p
+func (st *sampleTimer) lap(p samplePhase) {
+	if st == nil {
+		return
+	}
+
+	now := time.Now()
+	d := now.Sub(st.last)
+	st.timeline.span(p.String(), st.last, now)
+	st.last = now
+
+	switch p {
+	case phaseDecode:
+		st.sample.Decode += d
+	case phaseHandler:
+		st.sample.Handler += d
+	case phaseEncode:
+		st.sample.Encode += d
+	}
+}
+
+// lapMiddleware adds the time since the last mark to the timeline as mw
+func (st *sampleTimer) lapMiddleware(mw Middleware) {
+	if st == nil || st.timeline == nil {
+		return
+	}
+
+	now := time.Now()
+	st.timeline.span(middlewareSpanName(mw), st.last, now)
+	st.last = now
+}
+
+func (st *sampleTimer) finish() {
+	if st == nil || st.sink == nil {
+		return
+	}
+
+	st.sample.Total = time.Since(st.sample.Start)
+	if st.traceBuf != nil {
+		trace.Stop()
+		st.sample.Trace = st.traceBuf.Bytes()
+	}
+
+	st.sink.RecordSample(st.sample)
+}
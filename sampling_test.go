@@ -0,0 +1,121 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestSampling(t *testing.T) {
+	var mu sync.Mutex
+	var samples []RequestSample
+	sink := MetricsSinkFunc(func(s RequestSample) {
+		mu.Lock()
+		samples = append(samples, s)
+		mu.Unlock()
+	})
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithMetricsSink(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slow := func(ctx context.Context, in struct{ Name string }) map[string]string {
+		time.Sleep(10 * time.Millisecond)
+		return map[string]string{"name": in.Name}
+	}
+
+	err = r.Register(http.MethodPost, "/sampled", slow, nil, WithSampling(1), WithSampleTracing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/unsampled", slow, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/sampled", "/unsampled"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"Name": "a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s got %d", path, w.Code)
+		}
+	}
+
+	if len(samples) != 1 {
+		t.Fatalf("expected 1 sample got %d", len(samples))
+	}
+
+	s := samples[0]
+	if s.Method != http.MethodPost || s.Path != "/sampled" {
+		t.Errorf("sample recorded the wrong request: %s %s", s.Method, s.Path)
+	}
+
+	if s.Handler < 10*time.Millisecond {
+		t.Errorf("expected the handler phase to include the sleep, got %s", s.Handler)
+	}
+
+	if s.Total < s.Decode+s.Handler+s.Encode {
+		t.Errorf("expected the total %s to cover every phase", s.Total)
+	}
+
+	if len(s.Trace) == 0 {
+		t.Error("expected an execution trace")
+	}
+}
+
+func TestSamplingFraction(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name         string
+		Fraction     float64
+		Random       float64
+		ExpectSample bool
+	}{
+		{"below", 0.25, 0.1, true},
+		{"above", 0.25, 0.5, false},
+		{"boundary", 0.25, 0.25, false},
+		{"always", 1, 0.999, true},
+	}
+
+	for _, c := range cases {
+		random := c.Random
+		s := &sampling{fraction: c.Fraction, sink: MetricsSinkFunc(func(RequestSample) {}), random: func() float64 { return random }}
+
+		st := s.start(httptest.NewRequest(http.MethodGet, "/", nil))
+		if (st != nil) != c.ExpectSample {
+			t.Errorf("case[%s] expected sampled=%v", c.Name, c.ExpectSample)
+		}
+	}
+}
+
+func TestSamplingRequiresSink(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/", func(ctx context.Context) {}, nil, WithSampling(0.1))
+	if err == nil || !strings.Contains(err.Error(), "POST /: sampling requires a metrics sink") {
+		t.Errorf("expected sampling without a metrics sink to fail naming the route, got %v", err)
+	}
+
+	err = r.Register(http.MethodPost, "/", func(ctx context.Context) {}, nil, WithSampling(2))
+	if err == nil {
+		t.Error("expected an out of range fraction to fail")
+	}
+}
@@ -0,0 +1,142 @@
+package autohttp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jwfriese/autohttp/internal/cron"
+)
+
+// ScheduledTaskStats records the runs of a scheduled task
+type ScheduledTaskStats struct {
+	Runs     uint64
+	Failures uint64
+	// Skipped counts runs that were due while the previous run was still going
+	Skipped uint64
+
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    string
+}
+
+type scheduledTask struct {
+	name     string
+	schedule *cron.Schedule
+	fn       func(ctx context.Context) error
+
+	mu      sync.Mutex
+	running bool
+	stats   ScheduledTaskStats
+}
+
+// Schedule runs fn on a cron expression, such as "*/5 * * * *" or "@daily", as a
+// background task of the router. A run that is still going when the next is due
+// causes that run to be skipped rather than overlap
+func (r *Router) Schedule(name string, spec string, fn func(ctx context.Context) error) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	st := &scheduledTask{name: name, schedule: schedule, fn: fn}
+
+	r.scheduleMu.Lock()
+	r.scheduled = append(r.scheduled, st)
+	r.scheduleMu.Unlock()
+
+	r.Go(name, func(ctx context.Context) error {
+		return r.runSchedule(ctx, st)
+	})
+
+	return nil
+}
+
+// ScheduleStats returns the run statistics of each scheduled task, keyed by name
+func (r *Router) ScheduleStats() map[string]ScheduledTaskStats {
+	r.scheduleMu.Lock()
+	defer r.scheduleMu.Unlock()
+
+	stats := make(map[string]ScheduledTaskStats, len(r.scheduled))
+	for _, st := range r.scheduled {
+		st.mu.Lock()
+		stats[st.name] = st.stats
+		st.mu.Unlock()
+	}
+
+	return stats
+}
+
+func (r *Router) runSchedule(ctx context.Context, st *scheduledTask) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		next, err := st.schedule.Next(time.Now())
+		if err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		if !st.tryStart() {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.runScheduledTask(ctx, st)
+		}()
+	}
+}
+
+// tryStart marks the task as running, unless it already is
+func (st *scheduledTask) tryStart() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.running {
+		st.stats.Skipped++
+		return false
+	}
+
+	st.running = true
+	return true
+}
+
+func (r *Router) runScheduledTask(ctx context.Context, st *scheduledTask) {
+	start := time.Now()
+
+	var err error
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("panic: %v", rec)
+			}
+		}()
+
+		err = st.fn(ctx)
+	}()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.running = false
+	st.stats.Runs++
+	st.stats.LastRun = start
+	st.stats.LastDuration = time.Since(start)
+	st.stats.LastError = ""
+	if err != nil {
+		st.stats.Failures++
+		st.stats.LastError = err.Error()
+		r.log.Errorf("scheduled task %s failed: %s", st.name, err)
+	}
+}
@@ -0,0 +1,68 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestScheduleInvalidSpec(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Schedule("bad", "every tuesday", func(ctx context.Context) error { return nil })
+	if err == nil {
+		t.Fatal("expected an invalid spec to fail")
+	}
+}
+
+func TestScheduledTaskRuns(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []func() error{
+		func() error { return nil },
+		func() error { return errors.New("failed") },
+		func() error { panic("boom") },
+		func() error { return nil },
+	}
+
+	run := 0
+	err = r.Schedule("cleanup", "@hourly", func(ctx context.Context) error {
+		defer func() { run++ }()
+		return results[run]()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := r.scheduled[0]
+	for range results {
+		if !st.tryStart() {
+			t.Fatal("expected the task to start")
+		}
+
+		// overlapping runs are skipped
+		if st.tryStart() {
+			t.Fatal("expected an overlapping run to be skipped")
+		}
+
+		r.runScheduledTask(context.Background(), st)
+	}
+
+	stats := r.ScheduleStats()["cleanup"]
+	if stats.Runs != 4 || stats.Failures != 2 || stats.Skipped != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if stats.LastError != "" {
+		t.Errorf("expected the last error to be cleared, got %q", stats.LastError)
+	}
+}
@@ -0,0 +1,212 @@
+package autohttp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long Server.Run waits for in-flight
+// requests to drain once its context ends
+const DefaultShutdownTimeout = 30 * time.Second
+
+// DefaultReadHeaderTimeout is the ReadHeaderTimeout of servers made by NewServer
+const DefaultReadHeaderTimeout = 10 * time.Second
+
+// Server serves a Router and manages its lifecycle: background tasks start
+// with the server, and Shutdown runs the pre-shutdown hooks, drains in-flight
+// requests and then stops the background tasks. The embedded http.Server can
+// be configured before serving
+type Server struct {
+	*http.Server
+
+	// ShutdownTimeout bounds the graceful shutdown done by Run
+	ShutdownTimeout time.Duration
+
+	router *Router
+
+	// httpServer answers ACME challenges and redirects to HTTPS when
+	// serving with WithAutoTLS
+	httpServer    *http.Server
+	autoTLSConfig *tls.Config
+	h2c           bool
+
+	mu          sync.Mutex
+	preShutdown []func(ctx context.Context) error
+}
+
+// A ServerOption configures a Server
+type ServerOption func(s *Server) error
+
+// NewServer returns a Server for r, listening on r.Port()
+func NewServer(r *Router, opts ...ServerOption) (*Server, error) {
+	s := &Server{
+		Server: &http.Server{
+			Addr:              ":" + r.Port(),
+			Handler:           r,
+			ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		},
+		ShutdownTimeout: DefaultShutdownTimeout,
+		router:          r,
+	}
+
+	for _, opt := range opts {
+		err := opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.httpServer != nil && s.TLSConfig != s.autoTLSConfig {
+		return nil, errTLSConfigConflict
+	}
+
+	if s.h2c {
+		if s.httpServer != nil {
+			return nil, errors.New("autohttp: EnableH2C and WithAutoTLS can't be combined")
+		}
+
+		err := enableH2C(s.Server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// EnableH2C serves HTTP/2 over cleartext connections (h2c), alongside HTTP/1,
+// for load balancers that speak HTTP/2 to their backends and terminate TLS
+// themselves. Clients must use prior knowledge, the h2c upgrade from HTTP/1
+// isn't supported
+func EnableH2C(s *Server) error {
+	s.h2c = true
+	return nil
+}
+
+// BeforeShutdown registers fn to run when Shutdown is called, before the
+// server stops accepting connections, e.g. to fail readiness checks so load
+// balancers stop sending traffic. Hooks run in registration order, and an
+// error from one doesn't stop the shutdown
+func (s *Server) BeforeShutdown(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.preShutdown = append(s.preShutdown, fn)
+}
+
+// ListenAndServe starts the router's background tasks and serves on Addr.
+// With WithAutoTLS it serves HTTPS, and answers ACME challenges and redirects
+// to HTTPS on the challenge address. It returns nil once the server has been
+// shut down
+func (s *Server) ListenAndServe() error {
+	s.router.StartBackground()
+	s.router.emitLifecycle(func(ls LifecycleSubscriber) { ls.ServerStarted(s.serverEvent(s.Addr)) })
+	if s.httpServer == nil {
+		return serverClosed(s.Server.ListenAndServe())
+	}
+
+	go func() {
+		err := serverClosed(s.httpServer.ListenAndServe())
+		if err != nil {
+			s.router.log.Errorf("autohttp: serving ACME challenges on %s failed: %s", s.httpServer.Addr, err)
+		}
+	}()
+
+	return serverClosed(s.Server.ListenAndServeTLS("", ""))
+}
+
+// Serve starts the router's background tasks and serves connections from l,
+// over TLS with WithAutoTLS. It returns nil once the server has been shut down
+func (s *Server) Serve(l net.Listener) error {
+	s.router.StartBackground()
+	s.router.emitLifecycle(func(ls LifecycleSubscriber) { ls.ServerStarted(s.serverEvent(l.Addr().String())) })
+	if s.httpServer != nil {
+		return serverClosed(s.Server.ServeTLS(l, "", ""))
+	}
+
+	return serverClosed(s.Server.Serve(l))
+}
+
+// Shutdown runs the pre-shutdown hooks, stops accepting connections, waits
+// for in-flight requests to finish and then stops the background tasks, all
+// within ctx. It returns the first error encountered
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.router.emitLifecycle(func(ls LifecycleSubscriber) { ls.DrainBegun(s.serverEvent(s.Addr)) })
+
+	s.mu.Lock()
+	hooks := append([]func(ctx context.Context) error(nil), s.preShutdown...)
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, hook := range hooks {
+		err := hook(ctx)
+		if err != nil {
+			s.router.log.Errorf("autohttp: pre-shutdown hook failed: %s", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if s.httpServer != nil {
+		err := s.httpServer.Shutdown(ctx)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	err := s.Server.Shutdown(ctx)
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	err = s.router.StopBackground(ctx)
+	if err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
+}
+
+// Run serves until ctx ends, such as one from signal.NotifyContext, then
+// shuts down gracefully within ShutdownTimeout
+func (s *Server) Run(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := s.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.Shutdown(shutdownCtx)
+	if err != nil {
+		return err
+	}
+
+	return <-errs
+}
+
+func serverClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,38 @@
+package autohttp
+
+import "net/http"
+
+// WithCriticalAssets declares assets, by URL path relative to the mount,
+// that every page load needs. They are recorded in the AssetManifest
+func WithCriticalAssets(paths ...string) AssetOption {
+	return func(ea *embeddedAssets) error {
+		ea.manifest.Critical = append(ea.manifest.Critical, paths...)
+		return nil
+	}
+}
+
+// EnableServerPush pushes the manifest's critical assets alongside index.html
+// to HTTP/2 clients. It is a no-op for HTTP/1.1 clients
+func EnableServerPush(ea *embeddedAssets) error {
+	ea.serverPush = true
+	return nil
+}
+
+func (ea *embeddedAssets) pushCriticalAssets(w http.ResponseWriter, req *http.Request) {
+	if req.ProtoMajor < 2 {
+		return
+	}
+
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+
+	for _, p := range ea.manifest.Critical {
+		// clients may disable push, in which case there is nothing to do
+		err := pusher.Push(ea.urlPrefix+p, nil)
+		if err != nil {
+			return
+		}
+	}
+}
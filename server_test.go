@@ -0,0 +1,111 @@
+package autohttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestServerGracefulShutdown(t *testing.T) {
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err = r.Register(http.MethodGet, "/slow", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var taskStopped int32
+	r.Go("task", func(ctx context.Context) error {
+		<-ctx.Done()
+		atomic.StoreInt32(&taskStopped, 1)
+		return nil
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServer(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hookRan int32
+	srv.BeforeShutdown(func(ctx context.Context) error {
+		atomic.StoreInt32(&hookRan, 1)
+		return nil
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(l)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
+	// shutdown waits for the in-flight request
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned %v before the request drained", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if atomic.LoadInt32(&hookRan) != 1 {
+		t.Error("expected the pre-shutdown hook to run")
+	}
+
+	close(release)
+
+	if got := <-body; got != "done" {
+		t.Errorf("expected the in-flight request to finish, got %q", got)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-served; err != nil {
+		t.Fatalf("expected nil from Serve after shutdown got %v", err)
+	}
+
+	if atomic.LoadInt32(&taskStopped) != 1 {
+		t.Error("expected background tasks to be stopped")
+	}
+}
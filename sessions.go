@@ -0,0 +1,222 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jwfriese/autohttp/internal/httpsnoop"
+	"github.com/jwfriese/autohttp/internal/keysigner"
+)
+
+// DefaultSessionCookieName is the cookie sessions are stored in
+const DefaultSessionCookieName = "autohttp_session"
+
+// DefaultSessionMaxAge keeps a session for two weeks after its last change.
+// The expiry is signed into the cookie, so copies of it expire too
+var DefaultSessionMaxAge = 14 * 24 * time.Hour
+
+// ErrNoSession is returned when session data is used outside a router with
+// WithSessions
+var ErrNoSession = errors.New("autohttp: sessions aren't enabled")
+
+// WithSessions keeps a small key/value session for each client in a cookie
+// signed with key, for server rendered apps. The cookie is only rewritten
+// when a handler changes the session, see SessionFromContext
+func WithSessions(key string) func(r *Router) error {
+	return func(r *Router) error {
+		if key == "" {
+			return errors.New("autohttp: sessions need a signing key")
+		}
+
+		r.sessions = &sessionStore{
+			ks:         keysigner.NewKeySigner(key),
+			cookieName: DefaultSessionCookieName,
+			maxAge:     DefaultSessionMaxAge,
+			now:        time.Now,
+		}
+		return nil
+	}
+}
+
+// A Session holds the values stored for a client. Changes are saved to the
+// session cookie before the response headers are written, so they must be
+// made before the handler starts its response
+type Session struct {
+	mu     sync.Mutex
+	values map[string]string
+	dirty  bool
+}
+
+type sessionCtxKey struct{}
+
+// SessionFromContext returns the session for the request, or nil if the
+// router doesn't have WithSessions
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionCtxKey{}).(*Session)
+	return s
+}
+
+// Get returns the value stored under key, or ""
+func (s *Session) Get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set stores value under key
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.dirty = true
+}
+
+// Delete removes key from the session
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+type sessionStore struct {
+	ks         *keysigner.KeySigner
+	cookieName string
+	maxAge     time.Duration
+	now        func() time.Time
+}
+
+// load puts the client's session into the request context. The returned
+// writer saves a changed session as the response starts, and save catches
+// handlers that never write
+func (ss *sessionStore) load(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	session := &Session{values: ss.decode(req)}
+	req = req.WithContext(context.WithValue(req.Context(), sessionCtxKey{}, session))
+
+	saved := false
+	save := func() {
+		if saved {
+			return
+		}
+		saved = true
+		ss.save(w, session)
+	}
+
+	wrapped := httpsnoop.Wrap(w, httpsnoop.Hooks{
+		WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+			return func(code int) {
+				if code >= http.StatusOK {
+					save()
+				}
+				next(code)
+			}
+		},
+		Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+			return func(b []byte) (int, error) {
+				save()
+				return next(b)
+			}
+		},
+		ReadFrom: func(next httpsnoop.ReadFromFunc) httpsnoop.ReadFromFunc {
+			return func(src io.Reader) (int64, error) {
+				save()
+				return next(src)
+			}
+		},
+		Flush: func(next httpsnoop.FlushFunc) httpsnoop.FlushFunc {
+			return func() {
+				save()
+				next()
+			}
+		},
+	})
+
+	return wrapped, req, save
+}
+
+// decode reads the session cookie, starting a new session if it's missing,
+// expired or its signature doesn't verify. Cookies hold the signed expiry, as
+// Unix seconds, and the encoded values, separated by a colon
+func (ss *sessionStore) decode(req *http.Request) map[string]string {
+	values := map[string]string{}
+
+	cookie, err := req.Cookie(ss.cookieName)
+	if err != nil {
+		return values
+	}
+
+	verified, err := ss.ks.Verify(cookie.Value)
+	if err != nil {
+		return values
+	}
+
+	expiry, encoded, ok := strings.Cut(verified, ":")
+	if !ok {
+		return values
+	}
+
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !ss.now().Before(time.Unix(expires, 0)) {
+		return values
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return values
+	}
+
+	err = json.Unmarshal(b, &values)
+	if err != nil {
+		return map[string]string{}
+	}
+
+	return values
+}
+
+func (ss *sessionStore) save(w http.ResponseWriter, session *Session) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if !session.dirty {
+		return
+	}
+	session.dirty = false
+
+	cookie := &http.Cookie{
+		Name:     ss.cookieName,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	if len(session.values) == 0 {
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		return
+	}
+
+	b, err := json.Marshal(session.values)
+	if err != nil {
+		return
+	}
+
+	expires := ss.now().Add(ss.maxAge).Unix()
+	signed, err := ss.ks.Sign(strconv.FormatInt(expires, 10) + ":" + base64.RawURLEncoding.EncodeToString(b))
+	if err != nil {
+		return
+	}
+
+	cookie.Value = signed
+	cookie.MaxAge = int(ss.maxAge.Seconds())
+	http.SetCookie(w, cookie)
+}
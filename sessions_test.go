@@ -0,0 +1,105 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithSessions("s3cr3t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	r.sessions.now = func() time.Time { return now }
+
+	err = r.Register(http.MethodGet, "/set", func(ctx context.Context) (map[string]string, error) {
+		SessionFromContext(ctx).Set("user", "rex")
+		return nil, nil
+	}, nil, WithDecoder(NewFormDecoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/get", func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"user": SessionFromContext(ctx).Get("user")}, nil
+	}, nil, WithDecoder(NewFormDecoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/clear", func(ctx context.Context) (map[string]string, error) {
+		SessionFromContext(ctx).Delete("user")
+		return nil, nil
+	}, nil, WithDecoder(NewFormDecoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	cookies := serve("/set").Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge != int(DefaultSessionMaxAge.Seconds()) {
+		t.Fatalf("expected a session cookie, got %v", cookies)
+	}
+	session := cookies[0]
+
+	tampered := *session
+	tampered.Value = strings.Replace(tampered.Value, ":", "0:", 1)
+
+	cases := []struct {
+		Name       string
+		Advance    time.Duration
+		Cookie     *http.Cookie
+		ExpectUser string
+	}{
+		{"signed", 0, session, "rex"},
+		{"no cookie", 0, nil, ""},
+		{"tampered", 0, &tampered, ""},
+		{"before expiry", DefaultSessionMaxAge - time.Minute, session, "rex"},
+		{"expired", time.Minute, session, ""},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+
+		var w *httptest.ResponseRecorder
+		if c.Cookie != nil {
+			w = serve("/get", c.Cookie)
+		} else {
+			w = serve("/get")
+		}
+
+		if !strings.Contains(w.Body.String(), `"user":"`+c.ExpectUser+`"`) {
+			t.Errorf("case[%s] expected user %q got %s", c.Name, c.ExpectUser, w.Body.String())
+		}
+
+		if len(w.Result().Cookies()) != 0 {
+			t.Errorf("case[%s] expected an unchanged session not to be rewritten", c.Name)
+		}
+	}
+
+	cookies = serve("/clear", serve("/set").Result().Cookies()...).Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected clearing the session to delete its cookie, got %v", cookies)
+	}
+}
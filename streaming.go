@@ -0,0 +1,71 @@
+package autohttp
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// StreamContentType is sent for streamed responses that don't set their own,
+// see Response.ContentType
+const StreamContentType = "application/octet-stream"
+
+// isStreamFunc reports whether fn returns an io.Reader, which the handler
+// streams to the client instead of encoding
+func isStreamFunc(fn interface{}) bool {
+	fnType := reflect.TypeOf(fn)
+	for i := 0; i < fnType.NumOut(); i++ {
+		out := fnType.Out(i)
+		if !isErrorType(out) && out.Kind() == reflect.Interface && out.Implements(readerType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stream copies a reader returned by the handler to the client as it's read,
+// rather than buffering it through the Encoder, so large exports can be
+// served from function routes. Return a Response to set the content type,
+// status or headers such as Content-Disposition. Readers that are also
+// io.Closers are closed once copied. Streams skip the response cache,
+// weak ETags and compression, which all need the whole body
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, body io.Reader, resp *Response) {
+	if closer, ok := body.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	w.Header().Set("Content-Type", StreamContentType)
+	code := http.StatusOK
+	if resp != nil {
+		code = resp.apply(w.Header(), code)
+	}
+
+	w.WriteHeader(code)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	var dst io.Writer = w
+	if f, ok := w.(http.Flusher); ok {
+		dst = flushWriter{w: w, f: f}
+	}
+
+	_, err := io.Copy(dst, body)
+	if err != nil {
+		h.log.Errorf("error streaming response body to writer: %s", err)
+	}
+}
+
+// flushWriter flushes after every write so streamed chunks reach the client
+// as they're produced
+type flushWriter struct {
+	w io.Writer
+	f http.Flusher
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.f.Flush()
+	return n, err
+}
@@ -0,0 +1,116 @@
+package autohttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (ct *closeTracker) Close() error {
+	ct.closed = true
+	return nil
+}
+
+func TestStreamingResponses(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithCompression(GzipCompressor{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	export := strings.Repeat("id,name\n1,ann\n", 1024)
+	tracker := &closeTracker{}
+
+	err = r.Register(http.MethodPost, "/reader", func(ctx context.Context) (io.Reader, error) {
+		return strings.NewReader(export), nil
+	}, nil, WithEncoder(TextEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/read-closer", func(ctx context.Context) (io.ReadCloser, error) {
+		tracker.Reader = strings.NewReader(export)
+		return tracker, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/response", func(ctx context.Context) (Response, error) {
+		return Response{
+			Body:        strings.NewReader(export),
+			ContentType: "text/csv",
+			Status:      http.StatusCreated,
+			Header:      http.Header{"Content-Disposition": {`attachment; filename="export.csv"`}},
+		}, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RegisterTyped(r, http.MethodPost, "/typed", func(ctx context.Context, in struct{}) (io.Reader, error) {
+		return strings.NewReader(export), nil
+	}, WithEncoder(TextEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name              string
+		Path              string
+		Body              string
+		ExpectCode        int
+		ExpectContentType string
+		ExpectDisposition string
+	}{
+		{"reader", "/reader", "", http.StatusOK, StreamContentType, ""},
+		{"read-closer", "/read-closer", "", http.StatusOK, StreamContentType, ""},
+		{"response", "/response", "", http.StatusCreated, "text/csv", `attachment; filename="export.csv"`},
+		{"typed", "/typed", "{}", http.StatusOK, StreamContentType, ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+			continue
+		}
+
+		if got := w.Header().Get("Content-Type"); got != c.ExpectContentType {
+			t.Errorf("case[%s] expected Content-Type %q got %q", c.Name, c.ExpectContentType, got)
+		}
+
+		if got := w.Header().Get("Content-Disposition"); got != c.ExpectDisposition {
+			t.Errorf("case[%s] expected Content-Disposition %q got %q", c.Name, c.ExpectDisposition, got)
+		}
+
+		if w.Body.String() != export {
+			t.Errorf("case[%s] expected the export to be streamed as is, got %d bytes", c.Name, w.Body.Len())
+		}
+
+		if !w.Flushed {
+			t.Errorf("case[%s] expected the stream to be flushed", c.Name)
+		}
+	}
+
+	if !tracker.closed {
+		t.Error("expected the returned io.ReadCloser to be closed")
+	}
+}
@@ -0,0 +1,63 @@
+package autohttp
+
+import "context"
+
+// WithTags labels the route, letting policies such as WithTagMiddleware,
+// metrics and the OpenAPI document treat logical groups of routes alike
+// wherever they sit in the path structure
+func WithTags(tags ...string) RouteOption {
+	return func(rc *routeConfig) error {
+		rc.tags = append(rc.tags, tags...)
+		return nil
+	}
+}
+
+// WithTagMiddleware runs mws on every function route tagged tag, after any
+// global middleware and before the route's own. Like Use, it only applies
+// to routes registered after it, so pass it to NewRouter
+func WithTagMiddleware(tag string, mws ...Middleware) func(r *Router) error {
+	return func(r *Router) error {
+		if r.tagMiddlewares == nil {
+			r.tagMiddlewares = make(map[string][]Middleware)
+		}
+
+		r.tagMiddlewares[tag] = append(r.tagMiddlewares[tag], mws...)
+		return nil
+	}
+}
+
+// tagged returns the middleware tags apply to a route, in tag order
+func (r *Router) tagged(tags []string) []Middleware {
+	var mws []Middleware
+	for _, tag := range tags {
+		mws = append(mws, r.tagMiddlewares[tag]...)
+	}
+
+	return mws
+}
+
+// RouteTags returns the tags of the route serving the request
+func RouteTags(ctx context.Context) []string {
+	if rm := requestMetaFromContext(ctx); rm != nil {
+		return rm.tags
+	}
+
+	return nil
+}
+
+// Tags returns the tags the handler's route was registered with
+func (h *Handler) Tags() []string {
+	return h.tags
+}
+
+// HasTag reports whether the handler's route is tagged tag, for middleware
+// that only applies to some routes
+func (h *Handler) HasTag(tag string) bool {
+	for _, t := range h.tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+// tagCheck rejects requests to routes tagged "billing" without an account header
+type tagCheck struct{}
+
+func (tagCheck) Before(r *http.Request, h *Handler) error {
+	if h.HasTag("billing") && r.Header.Get("X-Account") == "" {
+		return ErrorWithCode{Err: errors.New("account required"), StatusCode: http.StatusForbidden}
+	}
+	return nil
+}
+
+func TestRouteTags(t *testing.T) {
+	t.Parallel()
+
+	var samples []RequestSample
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithTagMiddleware("public", beforeFunc(func(r *http.Request) error {
+			r.Header.Set("X-Seen-By", "public")
+			return nil
+		})),
+		WithMetricsSink(MetricsSinkFunc(func(s RequestSample) { samples = append(samples, s) })),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Use(tagCheck{})
+
+	tagsOf := func(ctx context.Context, h Header) map[string]string {
+		return map[string]string{"tags": strings.Join(RouteTags(ctx), ","), "seen": h["X-Seen-By"]}
+	}
+
+	routes := []struct {
+		Path string
+		Tags []string
+	}{
+		{"/invoices/{id}", []string{"public", "billing"}},
+		{"/status", []string{"public"}},
+		{"/admin", nil},
+	}
+	for _, rt := range routes {
+		err = r.Register(http.MethodPost, rt.Path, tagsOf, nil, WithTags(rt.Tags...), WithSampling(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		Name       string
+		Path       string
+		Account    string
+		ExpectCode int
+		ExpectBody string
+	}{
+		{"billing-without-account", "/invoices/4", "", http.StatusForbidden, ""},
+		{"billing", "/invoices/4", "acme", http.StatusOK, `{"seen":"public","tags":"public,billing"}`},
+		{"public", "/status", "", http.StatusOK, `{"seen":"public","tags":"public"}`},
+		{"untagged", "/admin", "", http.StatusOK, `{"seen":"","tags":""}`},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.Path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		if c.Account != "" {
+			req.Header.Set("X-Account", c.Account)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if c.ExpectBody != "" && strings.TrimSpace(w.Body.String()) != c.ExpectBody {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+
+	if len(samples) != len(cases) || !reflect.DeepEqual(samples[0].Tags, []string{"public", "billing"}) {
+		t.Errorf("expected tagged samples, got %+v", samples)
+	}
+
+	op := r.OpenAPISpec().Paths["/invoices/{id}"]["post"]
+	if op == nil || !reflect.DeepEqual(op.Tags, []string{"public", "billing"}) {
+		t.Errorf("expected tags in the OpenAPI document, got %+v", op)
+	}
+}
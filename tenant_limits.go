@@ -0,0 +1,182 @@
+package autohttp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultQuotaWindow is the window of a TenantLimit quota that doesn't set one
+const DefaultQuotaWindow = 24 * time.Hour
+
+// A TenantResolver extracts the tenant a request is made on behalf of, or
+// false for requests that aren't tied to a tenant
+type TenantResolver func(r *http.Request) (string, bool)
+
+// TenantFromSubdomain takes the tenant from the first label of hosts under
+// domain, e.g. acme for acme.example.com under example.com
+func TenantFromSubdomain(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.TrimPrefix(domain, "."))
+	return func(r *http.Request) (string, bool) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		host = strings.ToLower(host)
+		if !strings.HasSuffix(host, suffix) {
+			return "", false
+		}
+
+		sub := strings.TrimSuffix(host, suffix)
+		if i := strings.LastIndexByte(sub, '.'); i >= 0 {
+			sub = sub[i+1:]
+		}
+
+		return sub, sub != ""
+	}
+}
+
+// TenantFromHeader takes the tenant from the request header name
+func TenantFromHeader(name string) TenantResolver {
+	return func(r *http.Request) (string, bool) {
+		tenant := r.Header.Get(name)
+		return tenant, tenant != ""
+	}
+}
+
+// TenantLimit caps the traffic of a tenant. Zero values leave that limit off
+type TenantLimit struct {
+	// Rate is the sustained requests per second allowed
+	Rate float64
+	// Burst is how many requests may arrive at once, at least 1
+	Burst int
+
+	// Quota is the number of requests allowed per QuotaWindow
+	Quota       int
+	QuotaWindow time.Duration
+}
+
+// A TenantLimitResolver returns the limits of tenant, e.g. from its plan
+type TenantLimitResolver func(ctx context.Context, tenant string) (TenantLimit, error)
+
+// TenantTiers resolves limits by tier, where tierOf looks up the tenant's tier.
+// Tenants in tiers missing from limits are unlimited
+func TenantTiers(tierOf func(ctx context.Context, tenant string) (string, error), limits map[string]TenantLimit) TenantLimitResolver {
+	return func(ctx context.Context, tenant string) (TenantLimit, error) {
+		tier, err := tierOf(ctx, tenant)
+		if err != nil {
+			return TenantLimit{}, err
+		}
+
+		return limits[tier], nil
+	}
+}
+
+// TenantLimitedError is returned for requests over their tenant's rate or quota
+type TenantLimitedError struct {
+	Tenant string
+	// Quota is true when the tenant's quota ran out, rather than its rate
+	Quota      bool
+	RetryAfter time.Duration
+}
+
+func (tle *TenantLimitedError) Error() string {
+	if tle.Quota {
+		return fmt.Sprintf("quota exceeded for tenant %s", tle.Tenant)
+	}
+
+	return fmt.Sprintf("rate limit exceeded for tenant %s", tle.Tenant)
+}
+
+func (tle *TenantLimitedError) ResponseHeaders() http.Header {
+	h := make(http.Header)
+	h.Set("Retry-After", strconv.Itoa(int(math.Ceil(tle.RetryAfter.Seconds()))))
+	return h
+}
+
+// TenantLimitMiddleware rate limits and enforces quotas per tenant, with each
+// tenant's limits coming from a TenantLimitResolver so they can follow its
+// plan or tier. Requests over a limit get a 429 with a Retry-After header.
+// Requests without a tenant aren't limited
+type TenantLimitMiddleware struct {
+	tenants TenantResolver
+	limits  TenantLimitResolver
+	store   RateLimitStore
+	quotas  QuotaStore
+}
+
+// NewTenantLimitMiddleware keeps tenant usage in store, or a
+// MemoryRateLimitStore when store is nil. Quotas are kept in store too when it
+// implements QuotaStore, and in memory otherwise
+func NewTenantLimitMiddleware(tenants TenantResolver, limits TenantLimitResolver, store RateLimitStore) *TenantLimitMiddleware {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+
+	quotas, ok := store.(QuotaStore)
+	if !ok {
+		quotas = NewMemoryRateLimitStore()
+	}
+
+	return &TenantLimitMiddleware{
+		tenants: tenants,
+		limits:  limits,
+		store:   store,
+		quotas:  quotas,
+	}
+}
+
+func (tlm *TenantLimitMiddleware) Before(r *http.Request, h *Handler) error {
+	tenant, ok := tlm.tenants(r)
+	if !ok {
+		return nil
+	}
+
+	limit, err := tlm.limits(r.Context(), tenant)
+	if err != nil {
+		return err
+	}
+
+	key := "tenant:" + tenant
+
+	if limit.Rate > 0 {
+		allowed, retryAfter, err := tlm.store.Take(r.Context(), key, limit.Rate, limit.Burst)
+		if err != nil {
+			return fmt.Errorf("autohttp: rate limiting tenant %s: %w", tenant, err)
+		}
+
+		if !allowed {
+			return MiddlewareError{
+				StatusCode: http.StatusTooManyRequests,
+				Err:        &TenantLimitedError{Tenant: tenant, RetryAfter: retryAfter},
+			}
+		}
+	}
+
+	if limit.Quota > 0 {
+		window := limit.QuotaWindow
+		if window <= 0 {
+			window = DefaultQuotaWindow
+		}
+
+		allowed, retryAfter, err := tlm.quotas.TakeQuota(r.Context(), key, limit.Quota, window)
+		if err != nil {
+			return fmt.Errorf("autohttp: counting quota of tenant %s: %w", tenant, err)
+		}
+
+		if !allowed {
+			return MiddlewareError{
+				StatusCode: http.StatusTooManyRequests,
+				Err:        &TenantLimitedError{Tenant: tenant, Quota: true, RetryAfter: retryAfter},
+			}
+		}
+	}
+
+	return nil
+}
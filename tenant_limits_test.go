@@ -0,0 +1,141 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestTenantFromSubdomain(t *testing.T) {
+	t.Parallel()
+
+	resolve := TenantFromSubdomain("example.com")
+
+	cases := []struct {
+		Host         string
+		ExpectTenant string
+		ExpectOK     bool
+	}{
+		{"acme.example.com", "acme", true},
+		{"ACME.Example.com:8080", "acme", true},
+		{"api.acme.example.com", "acme", true},
+		{"example.com", "", false},
+		{"acme.other.com", "", false},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = c.Host
+
+		tenant, ok := resolve(r)
+		if tenant != c.ExpectTenant || ok != c.ExpectOK {
+			t.Errorf("case[%s] expected %q %t got %q %t", c.Host, c.ExpectTenant, c.ExpectOK, tenant, ok)
+		}
+	}
+}
+
+func TestTenantLimitMiddleware(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	tiers := map[string]string{"acme": "free", "globex": "pro", "initech": "internal"}
+	tlm := NewTenantLimitMiddleware(TenantFromHeader("X-Tenant"), TenantTiers(
+		func(ctx context.Context, tenant string) (string, error) {
+			return tiers[tenant], nil
+		},
+		map[string]TenantLimit{
+			"free": {Rate: 1, Burst: 2, Quota: 3, QuotaWindow: time.Hour},
+			"pro":  {Rate: 10, Burst: 1},
+		},
+	), store)
+
+	h, err := NewHandler(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		NoOpDecoder{},
+		&JSONEncoder{},
+		[]Middleware{tlm},
+		DefaultErrorHandler,
+		func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name             string
+		Tenant           string
+		Advance          time.Duration
+		ExpectStatus     int
+		ExpectRetryAfter string
+	}{
+		{"free-burst-1", "acme", 0, http.StatusOK, ""},
+		{"free-burst-2", "acme", 0, http.StatusOK, ""},
+		{"free-rate", "acme", 0, http.StatusTooManyRequests, "1"},
+		{"free-refilled", "acme", time.Second, http.StatusOK, ""},
+		{"free-quota", "acme", 10 * time.Second, http.StatusTooManyRequests, "3589"},
+		{"pro-isolated", "globex", 0, http.StatusOK, ""},
+		{"pro-rate", "globex", 0, http.StatusTooManyRequests, "1"},
+		{"pro-refilled", "globex", 100 * time.Millisecond, http.StatusOK, ""},
+		{"unlimited-tier", "initech", 0, http.StatusOK, ""},
+		{"no-tenant", "", 0, http.StatusOK, ""},
+		{"free-next-window", "acme", time.Hour, http.StatusOK, ""},
+	}
+
+	for _, c := range cases {
+		now = now.Add(c.Advance)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.Tenant != "" {
+			r.Header.Set("X-Tenant", c.Tenant)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+		}
+
+		if got := w.Header().Get("Retry-After"); got != c.ExpectRetryAfter {
+			t.Errorf("case[%s] expected Retry-After %q got %q", c.Name, c.ExpectRetryAfter, got)
+		}
+	}
+}
+
+func TestTenantLimitMiddlewareSweepsIdleTenants(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	tlm := NewTenantLimitMiddleware(TenantFromHeader("X-Tenant"), func(ctx context.Context, tenant string) (TenantLimit, error) {
+		return TenantLimit{Rate: 10, Burst: 1, Quota: 100, QuotaWindow: time.Minute}, nil
+	}, store)
+
+	for i := 0; i < 100; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Tenant", strconv.Itoa(i))
+
+		err := tlm.Before(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = now.Add(time.Minute)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "active")
+	err := tlm.Before(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.buckets) != 1 || len(store.quotas) != 1 {
+		t.Errorf("expected only the active tenant to be kept, got %d buckets and %d quotas", len(store.buckets), len(store.quotas))
+	}
+}
@@ -0,0 +1,52 @@
+package autohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Text is rendered as text/plain by the JSONEncoder and TextEncoder, use it
+// to make plain text responses explicit in a handler's signature
+type Text string
+
+var textType = reflect.TypeOf(Text(""))
+
+// TextEncoder renders string and Text return values as text/plain. Routes
+// returning plain strings opt in with WithEncoder(TextEncoder{})
+type TextEncoder struct{}
+
+func (te TextEncoder) ValidateType(fn interface{}) error {
+	fnType := reflect.ValueOf(fn).Type()
+	for i := 0; i < fnType.NumOut(); i++ {
+		out := fnType.Out(i)
+		if !isErrorType(out) && !isTextType(out) {
+			return errors.New("text encoder only works for functions returning a string or Text")
+		}
+	}
+
+	return nil
+}
+
+func (te TextEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	hw("Content-Type", "text/plain; charset=utf-8")
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case Text:
+		s = string(v)
+	case nil:
+	default:
+		return http.StatusInternalServerError, nil, errors.New("text encoder can only encode a string or Text")
+	}
+
+	return http.StatusOK, strings.NewReader(s), nil
+}
+
+func isTextType(t reflect.Type) bool {
+	return t == textType || t.Kind() == reflect.String
+}
@@ -0,0 +1,96 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestTextResponses(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := map[string]interface{}{
+		"/string": func(ctx context.Context) string { return "ok" },
+		"/text":   func(ctx context.Context) (Text, error) { return "User-agent: *\n", nil },
+		"/json":   func(ctx context.Context) map[string]string { return map[string]string{"status": "ok"} },
+	}
+	for path, fn := range routes {
+		err = r.Register(http.MethodPost, path, fn, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Register(http.MethodPost, "/plain", func(ctx context.Context) string { return "ok" }, nil, WithEncoder(TextEncoder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name              string
+		Path              string
+		ExpectContentType string
+		ExpectBody        string
+	}{
+		{"string", "/string", "application/json", `"ok"` + "\n"},
+		{"plain", "/plain", "text/plain; charset=utf-8", "ok"},
+		{"text", "/text", "text/plain; charset=utf-8", "User-agent: *\n"},
+		{"json", "/json", "application/json", `{"status":"ok"}` + "\n"},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s] expected 200 got %d", c.Name, w.Code)
+		}
+
+		if w.Header().Get("Content-Type") != c.ExpectContentType {
+			t.Errorf("case[%s] expected content type %q got %q", c.Name, c.ExpectContentType, w.Header().Get("Content-Type"))
+		}
+
+		if w.Body.String() != c.ExpectBody {
+			t.Errorf("case[%s] expected body %q got %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+}
+
+func TestTextEncoderValidation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		Fn        interface{}
+		ShouldErr bool
+	}{
+		{"string", func() string { return "" }, false},
+		{"text-error", func() (Text, error) { return "", nil }, false},
+		{"no-output", func() {}, false},
+		{"struct", func() struct{} { return struct{}{} }, true},
+	}
+
+	for _, c := range cases {
+		err := TextEncoder{}.ValidateType(c.Fn)
+		if err != nil && !c.ShouldErr {
+			t.Errorf("case[%s] failed unexpectedly", c.Name)
+		}
+
+		if err == nil && c.ShouldErr {
+			t.Errorf("case[%s] did not fail when it should have", c.Name)
+		}
+	}
+}
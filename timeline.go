@@ -0,0 +1,105 @@
+package autohttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EnableRequestTimelines logs, at debug level, when each stage of a request
+// started and ended: route matching, every middleware, decoding, the handler
+// and encoding, to help find which stage is slow. Nothing is recorded while
+// the router's log level, see WithLogLevel, is above debug
+func EnableRequestTimelines(r *Router) error {
+	r.requestTimelines = true
+	return nil
+}
+
+type timelineSpan struct {
+	name       string
+	start, end time.Duration
+}
+
+// requestTimeline collects the stages of one request, as offsets from when
+// the router received it
+type requestTimeline struct {
+	start   time.Time
+	pattern string
+	spans   []timelineSpan
+}
+
+type timelineCtxKey struct{}
+
+func withTimeline(req *http.Request) (*http.Request, *requestTimeline) {
+	tl := &requestTimeline{start: time.Now()}
+	return req.WithContext(context.WithValue(req.Context(), timelineCtxKey{}, tl)), tl
+}
+
+func timelineFromContext(ctx context.Context) *requestTimeline {
+	tl, _ := ctx.Value(timelineCtxKey{}).(*requestTimeline)
+	return tl
+}
+
+// span records a stage running from start to end. It is safe to call on a
+// nil timeline
+func (tl *requestTimeline) span(name string, start, end time.Time) {
+	if tl == nil {
+		return
+	}
+
+	tl.spans = append(tl.spans, timelineSpan{name: name, start: start.Sub(tl.start), end: end.Sub(tl.start)})
+}
+
+// routed records the route being matched
+func (tl *requestTimeline) routed(pattern string) {
+	if tl == nil {
+		return
+	}
+
+	tl.pattern = pattern
+	now := time.Now()
+	tl.span("route", now, now)
+}
+
+// String renders the timeline compactly, e.g.
+// "route@12µs middleware(*autohttp.BasicAuthMiddleware)@15µs-18µs decode@20µs-41µs ..."
+func (tl *requestTimeline) String() string {
+	var b strings.Builder
+	for i, s := range tl.spans {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(s.name)
+		b.WriteByte('@')
+		b.WriteString(s.start.Round(time.Microsecond).String())
+		if s.end != s.start {
+			b.WriteByte('-')
+			b.WriteString(s.end.Round(time.Microsecond).String())
+		}
+	}
+
+	return b.String()
+}
+
+// logTimeline writes the request's timeline to the debug log
+func (r *Router) logTimeline(req *http.Request, tl *requestTimeline) {
+	route := tl.pattern
+	if route == "" {
+		route = req.URL.Path
+	}
+
+	r.log.Debugf("timeline method=%s route=%s total=%s: %s", req.Method, route, time.Since(tl.start).Round(time.Microsecond), tl)
+}
+
+// debugEnabled reports whether debug lines would be logged
+func (r *Router) debugEnabled() bool {
+	return r.logLevel.enabled(LogDebug)
+}
+
+// middlewareSpanName names a middleware in timelines by its type
+func middlewareSpanName(mw Middleware) string {
+	return fmt.Sprintf("middleware(%T)", mw)
+}
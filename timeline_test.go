@@ -0,0 +1,67 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestRequestTimelines(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name       string
+		Options    []RouterOption
+		ExpectLine *regexp.Regexp
+	}{
+		{
+			"enabled",
+			[]RouterOption{EnableRequestTimelines},
+			regexp.MustCompile(`timeline method=POST route=/users/\{id\} total=\S+: route@\S+ middleware\(\*autohttp\.BasicAuthMiddleware\)@\S+-\S+ decode@\S+-\S+ handler@\S+-\S+ encode@\S+-\S+`),
+		},
+		{"disabled", nil, nil},
+		{"above-debug", []RouterOption{EnableRequestTimelines, WithLogLevel(LogInfo)}, nil},
+	}
+
+	for _, c := range cases {
+		var logs syncBuffer
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs), lounge.WithDebugEnabled()), c.Options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.Register(http.MethodPost, "/users/{id}", func(ctx context.Context, in struct{ Name string }) (string, error) {
+			return PathParam(ctx, "id"), nil
+		}, []Middleware{NewBasicAuthMiddleware("user", "pass")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/users/7", strings.NewReader(`{"Name":"ann"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("user", "pass")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("case[%s] expected 200 got %d: %s", c.Name, w.Code, w.Body.String())
+		}
+
+		logged := logs.String()
+		if c.ExpectLine == nil {
+			if strings.Contains(logged, "timeline") {
+				t.Errorf("case[%s] expected no timeline got %s", c.Name, logged)
+			}
+			continue
+		}
+
+		if !c.ExpectLine.MatchString(logged) {
+			t.Errorf("case[%s] expected a timeline matching %s got %s", c.Name, c.ExpectLine, logged)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned for requests whose handler outlives its timeout
+var ErrTimeout = errors.New("autohttp: request timed out")
+
+// WithTimeout cancels the context of requests still being handled after d and
+// answers them with a 503 through the route's error handler, instead of
+// wrapping routes in http.TimeoutHandler, which bypasses it. Responses are
+// held back until the handler returns, so star routes, which commonly stream
+// or proxy, and functions returning an io.Reader are left out. Informational
+// responses, such as early hints, are passed straight through. Routes can
+// override it with WithRouteTimeout
+func WithTimeout(d time.Duration) func(r *Router) error {
+	return func(r *Router) error {
+		if d < 0 {
+			return errors.New("autohttp: timeout can't be negative")
+		}
+
+		r.timeout = d
+		return nil
+	}
+}
+
+// WithRouteTimeout times the route out after d instead of the router's
+// WithTimeout, zero serving it without a timeout. Streamed responses are
+// held back in memory like any other
+func WithRouteTimeout(d time.Duration) RouteOption {
+	return func(rc *routeConfig) error {
+		if d < 0 {
+			return errors.New("autohttp: timeout can't be negative")
+		}
+
+		rc.timeout = d
+		rc.timeoutSet = true
+		return nil
+	}
+}
+
+// withTimeout wraps handler in the route's timeout, if it has one. Timeouts
+// are handled by errorHandler
+func (r *Router) withTimeout(handler http.Handler, rc routeConfig, errorHandler func(w http.ResponseWriter, req *http.Request, err error)) http.Handler {
+	timeout := r.timeout
+	if rc.timeoutSet {
+		timeout = rc.timeout
+	} else if h, ok := handler.(*Handler); ok && isStreamFunc(h.fn) {
+		// buffering would hold the whole stream in memory
+		return handler
+	}
+
+	if timeout == 0 {
+		return handler
+	}
+
+	return &timeoutHandler{timeout: timeout, next: handler, errorHandler: errorHandler}
+}
+
+type timeoutHandler struct {
+	timeout      time.Duration
+	next         http.Handler
+	errorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+func (th *timeoutHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), th.timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+		}()
+
+		th.next.ServeHTTP(tw, req)
+		close(done)
+	}()
+
+	select {
+	case p := <-panicked:
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		for k, vals := range tw.header {
+			w.Header()[k] = vals
+		}
+
+		if tw.code == 0 {
+			tw.code = http.StatusOK
+		}
+		w.WriteHeader(tw.code)
+		w.Write(tw.body.Bytes())
+	case <-ctx.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		// clients that went away get nothing
+		tw.timedOut = true
+		if ctx.Err() == context.DeadlineExceeded {
+			th.errorHandler(w, req, ErrorWithCode{Err: ErrTimeout, StatusCode: http.StatusServiceUnavailable})
+		}
+	}
+}
+
+// timeoutWriter holds a response back until its handler returns in time
+type timeoutWriter struct {
+	// w only receives informational responses until the handler returns
+	w        http.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	code     int
+	body     bytes.Buffer
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+
+	if code < http.StatusOK {
+		// informational responses carry the headers set so far
+		for k, vals := range tw.header {
+			tw.w.Header()[k] = append([]string(nil), vals...)
+		}
+		tw.w.WriteHeader(code)
+		return
+	}
+	tw.code = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
@@ -0,0 +1,195 @@
+package autohttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(
+		lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithTimeout(20*time.Millisecond),
+		WithDefaultErrorHandler(func(w http.ResponseWriter, err error) {
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte("custom: " + err.Error()))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slow waits out its context, or 50ms if it has none
+	slow := func(ctx context.Context) (map[string]string, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return map[string]string{"done": "true"}, nil
+		}
+	}
+
+	err = r.Register(http.MethodPost, "/slow", slow, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/patient", slow, nil, WithRouteTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/unbounded", slow, nil, WithRouteTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/raw", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		w.Write([]byte("too late"))
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/fast", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Fast", "true")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("made it"))
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{name: "timed out", method: http.MethodPost, path: "/slow", code: http.StatusServiceUnavailable, body: "custom: autohttp: request timed out"},
+		{name: "longer route timeout", method: http.MethodPost, path: "/patient", code: http.StatusOK, body: `"done":"true"`},
+		{name: "route without a timeout", method: http.MethodPost, path: "/unbounded", code: http.StatusOK, body: `"done":"true"`},
+		{name: "http.Handler timed out", method: http.MethodGet, path: "/raw", code: http.StatusServiceUnavailable, body: "custom: autohttp: request timed out"},
+		{name: "in time", method: http.MethodGet, path: "/fast", code: http.StatusCreated, body: "made it"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if !strings.Contains(w.Body.String(), c.body) {
+			t.Errorf("case[%s] expected body containing %q, got %q", c.name, c.body, w.Body.String())
+		}
+
+		if c.path == "/fast" && w.Header().Get("X-Fast") != "true" {
+			t.Errorf("case[%s] expected the handler's headers", c.name)
+		}
+	}
+
+	err = r.Register(http.MethodGet, "/files/*", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}), nil, WithRouteTimeout(time.Second))
+	if err == nil {
+		t.Error("expected star routes to reject timeouts")
+	}
+}
+
+func TestTimeoutPassesStreamsAndEarlyHints(t *testing.T) {
+	t.Parallel()
+
+	if !earlyHintsSupported {
+		t.Skip("early hints need Go 1.19")
+	}
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the stream only finishes once the client has read its first chunk
+	firstRead := make(chan struct{})
+	err = r.Register(http.MethodPost, "/export", func(ctx context.Context) (io.Reader, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write([]byte("id,name\n"))
+			<-firstRead
+			pw.Write([]byte("1,ann\n"))
+			pw.Close()
+		}()
+		return pr, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/page", func(ctx context.Context) (map[string]string, error) {
+		err := EarlyHints(ctx, PreloadLink("/app.js", "script"))
+		return map[string]string{"page": "ok"}, err
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/export", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	first := make([]byte, len("id,name\n"))
+	_, err = io.ReadFull(res.Body, first)
+	if err != nil {
+		t.Fatalf("expected the first chunk before the stream ended, got %s", err)
+	}
+	close(firstRead)
+
+	rest, err := io.ReadAll(res.Body)
+	if err != nil || string(first)+string(rest) != "id,name\n1,ann\n" {
+		t.Errorf("expected the whole export, got %q %q %v", first, rest, err)
+	}
+
+	var hints []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			hints = append(hints, header.Values("Link")...)
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodPost, srv.URL+"/page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || len(hints) != 1 {
+		t.Errorf("expected early hints through the timeout, got %d %v", res.StatusCode, hints)
+	}
+}
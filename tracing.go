@@ -0,0 +1,163 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TraceParentHeader and TraceStateHeader carry W3C trace context
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// ErrInvalidTraceParent is returned for malformed traceparent headers
+var ErrInvalidTraceParent = errors.New("autohttp: invalid traceparent")
+
+// A TraceParent is the W3C trace context a request was sent with, see
+// https://www.w3.org/TR/trace-context/
+type TraceParent struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+	// State is the vendor specific tracestate header, passed on as is
+	State string
+}
+
+// String formats tp as a traceparent header value
+func (tp TraceParent) String() string {
+	flags := "00"
+	if tp.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + hex.EncodeToString(tp.TraceID[:]) + "-" + hex.EncodeToString(tp.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceParent parses a traceparent header value
+func ParseTraceParent(header string) (TraceParent, error) {
+	var tp TraceParent
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return tp, ErrInvalidTraceParent
+	}
+
+	// later versions may append fields, version 00 may not
+	if parts[0] == "00" && len(parts) != 4 {
+		return tp, ErrInvalidTraceParent
+	}
+
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return tp, ErrInvalidTraceParent
+	}
+
+	var flags [1]byte
+	for _, field := range []struct {
+		dst []byte
+		src string
+	}{{tp.TraceID[:], parts[1]}, {tp.SpanID[:], parts[2]}, {flags[:], parts[3]}} {
+		// hex accepts upper case, the spec doesn't
+		if strings.ToLower(field.src) != field.src {
+			return tp, ErrInvalidTraceParent
+		}
+
+		_, err := hex.Decode(field.dst, []byte(field.src))
+		if err != nil {
+			return tp, ErrInvalidTraceParent
+		}
+	}
+
+	if tp.TraceID == [16]byte{} || tp.SpanID == [8]byte{} {
+		return tp, ErrInvalidTraceParent
+	}
+
+	tp.Sampled = flags[0]&1 == 1
+	return tp, nil
+}
+
+type traceParentCtxKey struct{}
+
+// TraceParentFromContext returns the trace context the request was sent with,
+// if it carried a valid one and the router has tracing enabled
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	tp, ok := ctx.Value(traceParentCtxKey{}).(TraceParent)
+	return tp, ok
+}
+
+// A Span is one traced request
+type Span interface {
+	// SetName renames the span, once the route is known
+	SetName(name string)
+	// SetStatusCode records the response status
+	SetStatusCode(code int)
+	// RecordError records an error handed to the ErrorHandler
+	RecordError(err error)
+	End()
+}
+
+// A Tracer starts a server span per request. The request's remote parent,
+// if any, is available from ctx through TraceParentFromContext. Wrapping an
+// OpenTelemetry tracer takes a few lines:
+//
+//	func (ot otelTracer) Start(ctx context.Context, name string) (context.Context, autohttp.Span) {
+//		if tp, ok := autohttp.TraceParentFromContext(ctx); ok {
+//			ctx = trace.ContextWithRemoteSpanContext(ctx, spanContextOf(tp))
+//		}
+//		ctx, span := ot.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// WithTracing starts a span with t for every request, named after the matched
+// route pattern rather than the raw path, e.g. "GET /users/{id}". Incoming
+// traceparent headers are propagated into the handler's context, and the
+// response status and errors handed to the ErrorHandler are recorded
+func WithTracing(t Tracer) func(r *Router) error {
+	return func(r *Router) error {
+		if t == nil {
+			return errors.New("autohttp: WithTracing needs a Tracer")
+		}
+
+		r.tracer = t
+		return nil
+	}
+}
+
+type spanCtxKey struct{}
+
+func spanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanCtxKey{}).(Span)
+	return span
+}
+
+// startSpan starts req's span, with its remote parent, if it has one
+func (r *Router) startSpan(req *http.Request) (*http.Request, Span) {
+	ctx := req.Context()
+	if tp, err := ParseTraceParent(req.Header.Get(TraceParentHeader)); err == nil {
+		tp.State = req.Header.Get(TraceStateHeader)
+		ctx = context.WithValue(ctx, traceParentCtxKey{}, tp)
+	}
+
+	ctx, span := r.tracer.Start(ctx, "HTTP "+req.Method)
+	ctx = context.WithValue(ctx, spanCtxKey{}, span)
+	return req.WithContext(ctx), span
+}
+
+// endSpan names span after the route req matched and ends it
+func (r *Router) endSpan(span Span, req *http.Request, code int) {
+	if pattern := RoutePattern(req.Context()); pattern != "" {
+		span.SetName(req.Method + " " + pattern)
+	}
+
+	if code != 0 {
+		span.SetStatusCode(code)
+	}
+
+	span.End()
+}
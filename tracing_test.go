@@ -0,0 +1,146 @@
+package autohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name          string
+		Header        string
+		ExpectErr     bool
+		ExpectSampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"not-sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"future-version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, true},
+		{"extra-field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, false},
+		{"invalid-version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, false},
+		{"zero-trace", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true, false},
+		{"zero-span", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true, false},
+		{"upper-case", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true, false},
+		{"short", "00-4bf92f35-00f067aa0ba902b7-01", true, false},
+		{"empty", "", true, false},
+	}
+
+	for _, c := range cases {
+		tp, err := ParseTraceParent(c.Header)
+		if (err != nil) != c.ExpectErr {
+			t.Errorf("case[%s] expected error %t got %v", c.Name, c.ExpectErr, err)
+			continue
+		}
+
+		if err == nil && tp.Sampled != c.ExpectSampled {
+			t.Errorf("case[%s] expected sampled %t got %t", c.Name, c.ExpectSampled, tp.Sampled)
+		}
+
+		if c.Name == "sampled" && tp.String() != c.Header {
+			t.Errorf("case[%s] expected %s got %s", c.Name, c.Header, tp.String())
+		}
+	}
+}
+
+type recordedSpan struct {
+	name   string
+	parent TraceParent
+	code   int
+	errs   []error
+	ended  bool
+}
+
+func (rs *recordedSpan) SetName(name string)    { rs.name = name }
+func (rs *recordedSpan) SetStatusCode(code int) { rs.code = code }
+func (rs *recordedSpan) RecordError(err error)  { rs.errs = append(rs.errs, err) }
+func (rs *recordedSpan) End()                   { rs.ended = true }
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordedSpan{name: name}
+	span.parent, _ = TraceParentFromContext(ctx)
+
+	rt.mu.Lock()
+	rt.spans = append(rt.spans, span)
+	rt.mu.Unlock()
+
+	return ctx, span
+}
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	tracer := &recordingTracer{}
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)), WithTracing(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handlerParent TraceParent
+	err = r.Register(http.MethodPost, "/users/{id}", func(ctx context.Context, in struct{}) error {
+		handlerParent, _ = TraceParentFromContext(ctx)
+		if PathParam(ctx, "id") == "broken" {
+			return errors.New("broken")
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	cases := []struct {
+		Name        string
+		Path        string
+		TraceParent string
+		ExpectName  string
+		ExpectCode  int
+		ExpectErrs  int
+	}{
+		{"propagated", "/users/1", traceparent, "POST /users/{id}", http.StatusOK, 0},
+		{"error", "/users/broken", "", "POST /users/{id}", http.StatusInternalServerError, 1},
+		{"unmatched", "/nowhere", traceparent, "HTTP POST", http.StatusNotFound, 0},
+	}
+
+	for i, c := range cases {
+		handlerParent = TraceParent{}
+		req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		if c.TraceParent != "" {
+			req.Header.Set(TraceParentHeader, c.TraceParent)
+			req.Header.Set(TraceStateHeader, "vendor=1")
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(tracer.spans) != i+1 {
+			t.Fatalf("case[%s] expected %d spans got %d", c.Name, i+1, len(tracer.spans))
+		}
+
+		span := tracer.spans[i]
+		if span.name != c.ExpectName || span.code != c.ExpectCode || len(span.errs) != c.ExpectErrs || !span.ended {
+			t.Errorf("case[%s] unexpected span %+v", c.Name, span)
+		}
+
+		if c.TraceParent != "" && (span.parent.String() != c.TraceParent || span.parent.State != "vendor=1") {
+			t.Errorf("case[%s] expected parent %s got %s", c.Name, c.TraceParent, span.parent)
+		}
+
+		if c.Name == "propagated" && handlerParent.String() != traceparent {
+			t.Errorf("case[%s] expected the handler to see parent %s got %s", c.Name, traceparent, handlerParent)
+		}
+	}
+}
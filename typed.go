@@ -0,0 +1,153 @@
+package autohttp
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/fortytw2/lounge"
+)
+
+// RegisterTyped registers fn at method and path without reflecting over it
+// per request, catching signature mistakes at compile time. Requests are
+// decoded as JSON into Req using the route's JSONDecoder settings, or
+// NewJSONDecoder's if the route uses another decoder, and Resp is encoded with
+// the route's Encoder. Go methods can't take type parameters, hence the function
+func RegisterTyped[Req, Resp any](r *Router, method, path string, fn func(context.Context, Req) (Resp, error), opts ...RouteOption) error {
+	if fn == nil {
+		return r.register(method, path, nil, nil, routeConfig{}, opts)
+	}
+
+	return r.register(method, path, &typedRoute[Req, Resp]{fn: fn}, nil, routeConfig{}, opts)
+}
+
+// typedCaller decodes and calls a typed route, the typed counterpart of
+// Handler.callReflect
+type typedCaller interface {
+	call(h *Handler, w http.ResponseWriter, r *http.Request, sample *sampleTimer) (interface{}, string, bool)
+	handlerFunc() interface{}
+	handler(log lounge.Log, decoder Decoder, encoder Encoder, middlewares []Middleware, errorHandler ErrorHandler) (*Handler, error)
+}
+
+type typedRoute[Req, Resp any] struct {
+	fn       func(context.Context, Req) (Resp, error)
+	decoder  *JSONDecoder
+	validate bool
+	bound    []boundField
+}
+
+func (tr *typedRoute[Req, Resp]) handlerFunc() interface{} {
+	return tr.fn
+}
+
+func (tr *typedRoute[Req, Resp]) handler(log lounge.Log, decoder Decoder, encoder Encoder, middlewares []Middleware, errorHandler ErrorHandler) (*Handler, error) {
+	jsd, ok := decoder.(*JSONDecoder)
+	if !ok {
+		jsd = NewJSONDecoder()
+	}
+
+	if !isStreamFunc(tr.fn) {
+		err := encoder.ValidateType(tr.fn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	err := checkValidationTags(reqType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkBoundFields(reqType)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &typedRoute[Req, Resp]{
+		fn:       tr.fn,
+		decoder:  jsd,
+		validate: hasValidationTags(reqType) || isValidatorType(reqType),
+		bound:    boundFields(reqType),
+	}
+
+	h := newHandler(log, jsd, encoder, middlewares, errorHandler, tr.fn)
+	h.typed = route
+	return h, nil
+}
+
+func (tr *typedRoute[Req, Resp]) call(h *Handler, w http.ResponseWriter, r *http.Request, sample *sampleTimer) (interface{}, string, bool) {
+	sample.mark()
+	var in Req
+	err := tr.decode(r, &in)
+	sample.lap(phaseDecode)
+	if err != nil {
+		h.handleError(w, r, StageDecode, err)
+		return nil, "", false
+	}
+
+	var cacheKey string
+	if h.responseCache != nil {
+		cacheKey = h.responseCache.inputKey(r, in)
+	}
+	if cacheKey != "" {
+		if cached, hit := h.responseCache.lookup(cacheKey); hit != "" {
+			h.serveCached(w, r, cached, hit)
+			return nil, "", false
+		}
+	}
+
+	var memoKey interface{}
+	if h.memo != nil {
+		memoKey = h.memo.key(r.Context(), in)
+		if result, ok := h.memo.get(memoKey); ok {
+			return result, cacheKey, true
+		}
+	}
+
+	sample.mark()
+	out, err := tr.fn(r.Context(), in)
+	sample.lap(phaseHandler)
+	if err != nil {
+		h.handleError(w, r, StageHandler, err)
+		return nil, "", false
+	}
+
+	if h.memo != nil {
+		h.memo.put(memoKey, out)
+	}
+
+	return out, cacheKey, true
+}
+
+func (tr *typedRoute[Req, Resp]) decode(r *http.Request, in *Req) error {
+	// GET requests to routes with bound fields have no body to decode
+	if len(tr.bound) == 0 || !isQueryMethod(r.Method) {
+		err := tr.decoder.checkRequest(r)
+		if err != nil {
+			return err
+		}
+
+		err = tr.decoder.decodeBody(r, in)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(tr.bound) > 0 {
+		err := bindRequest(r, reflect.ValueOf(in).Elem(), tr.bound)
+		if err != nil {
+			return err
+		}
+	}
+
+	// only types with validate tags or a Validate method pay for reflection
+	if tr.validate {
+		err := validateValue(reflect.ValueOf(in))
+		if err != nil {
+			return ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package autohttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type typedPet struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestRegisterTyped(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RegisterTyped(r, http.MethodPost, "/pets", func(ctx context.Context, p typedPet) (*typedPet, error) {
+		if p.Name == "boom" {
+			return nil, errors.New("boom")
+		}
+		return &typedPet{Name: strings.ToUpper(p.Name)}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RegisterTyped(r, http.MethodPost, "/echo", func(ctx context.Context, s string) (Text, error) {
+		return Text(s), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Path        string
+		ContentType string
+		Body        string
+		ExpectCode  int
+		ExpectBody  string
+	}{
+		{"ok", "/pets", "application/json", `{"name":"rex"}`, http.StatusOK, `{"name":"REX"}` + "\n"},
+		{"non-struct", "/echo", "application/json", `"hello"`, http.StatusOK, "hello"},
+		{"invalid", "/pets", "application/json", `{}`, http.StatusBadRequest, ""},
+		{"unknown-field", "/pets", "application/json", `{"name":"rex","age":2}`, http.StatusBadRequest, ""},
+		{"mime-type", "/pets", "text/plain", `{"name":"rex"}`, http.StatusUnsupportedMediaType, ""},
+		{"handler-error", "/pets", "application/json", `{"name":"boom"}`, http.StatusInternalServerError, ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.Path, strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", c.ContentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+		}
+
+		if c.ExpectBody != "" && w.Body.String() != c.ExpectBody {
+			t.Errorf("case[%s] expected %q got %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+
+	if op := r.OpenAPISpec().Paths["/pets"]["post"]; op == nil || op.RequestBody == nil {
+		t.Errorf("expected typed routes to be documented, got %+v", op)
+	}
+}
+
+func TestRegisterTypedAllocations(t *testing.T) {
+	fn := func(ctx context.Context, in benchInput) (benchInput, error) { return in, nil }
+	payload := []byte(`{"id":1,"name":"autohttp","tags":["a","b"],"owner":{"login":"x","admin":true},"stars":3}`)
+
+	allocs := func(register func(r *Router) error) float64 {
+		r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(io.Discard)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = register(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return testing.AllocsPerRun(100, func() {
+			req := httptest.NewRequest(http.MethodPost, "/bench", bytes.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		})
+	}
+
+	reflective := allocs(func(r *Router) error { return r.Register(http.MethodPost, "/bench", fn, nil) })
+	typed := allocs(func(r *Router) error { return RegisterTyped(r, http.MethodPost, "/bench", fn) })
+
+	if typed >= reflective {
+		t.Errorf("expected typed routes to allocate less, got %.0f typed and %.0f reflective", typed, reflective)
+	}
+}
@@ -0,0 +1,373 @@
+package autohttp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValidateTag is the struct tag the JSONDecoder reads constraints from, e.g.
+//
+//	Name string `validate:"required,min=2,max=64,pattern=^[a-z]+$"`
+//
+// min and max bound numbers by value and strings, slices and maps by length,
+// len requires an exact length and pattern must be the last rule in the tag
+const ValidateTag = "validate"
+
+// FieldError describes a single failed constraint
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Msg   string `json:"message"`
+}
+
+func (fe FieldError) Error() string {
+	return fe.Field + ": " + fe.Msg
+}
+
+// ValidationErrors is returned (wrapped in an ErrorWithCode) when a decoded
+// request body fails its validate tags
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, fe := range ve {
+		msgs[i] = fe.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+type validationRule struct {
+	name  string
+	arg   string
+	num   float64
+	regex *regexp.Regexp
+}
+
+var validationRuleCache sync.Map // map[string][]validationRule
+
+func parseValidationTag(tag string) ([]validationRule, error) {
+	if cached, ok := validationRuleCache.Load(tag); ok {
+		return cached.([]validationRule), nil
+	}
+
+	var rules []validationRule
+	rest := tag
+	for rest != "" {
+		var part string
+		if strings.HasPrefix(rest, "pattern=") {
+			// patterns can contain commas, so they take the rest of the tag
+			part, rest = rest, ""
+		} else if idx := strings.IndexByte(rest, ','); idx >= 0 {
+			part, rest = rest[:idx], rest[idx+1:]
+		} else {
+			part, rest = rest, ""
+		}
+
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		rule := validationRule{name: part}
+		if idx := strings.IndexByte(part, '='); idx >= 0 {
+			rule.name, rule.arg = part[:idx], part[idx+1:]
+		}
+
+		switch rule.name {
+		case "required":
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(rule.arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("autohttp: invalid %s value %q", rule.name, rule.arg)
+			}
+			rule.num = n
+		case "pattern":
+			re, err := regexp.Compile(rule.arg)
+			if err != nil {
+				return nil, fmt.Errorf("autohttp: invalid pattern %q: %w", rule.arg, err)
+			}
+			rule.regex = re
+		default:
+			return nil, fmt.Errorf("autohttp: unknown validation rule %q", rule.name)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	validationRuleCache.Store(tag, rules)
+	return rules, nil
+}
+
+// checkValidationTags parses every validate tag reachable from t so bad tags
+// fail at registration instead of on the first request
+func checkValidationTags(t reflect.Type) error {
+	return walkValidationTags(t, map[reflect.Type]bool{})
+}
+
+func walkValidationTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		if tag, ok := f.Tag.Lookup(ValidateTag); ok {
+			if _, err := parseValidationTag(tag); err != nil {
+				return fmt.Errorf("%w (field %s.%s)", err, t.Name(), f.Name)
+			}
+		}
+
+		if err := walkValidationTags(f.Type, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasValidationTags reports whether any field reachable from t has a validate tag
+func hasValidationTags(t reflect.Type) bool {
+	return findValidationTag(t, map[reflect.Type]bool{})
+}
+
+func findValidationTag(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		if _, ok := f.Tag.Lookup(ValidateTag); ok || findValidationTag(f.Type, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A Validator is a decoded request that checks itself, for rules that don't
+// fit in validate tags such as comparing fields. Validate is called after
+// the validate tags pass. Returning a FieldError or ValidationErrors reports
+// which fields were at fault
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// isValidatorType reports whether t, or what it points to, is a Validator
+func isValidatorType(t reflect.Type) bool {
+	for {
+		if t.Implements(validatorType) || reflect.PtrTo(t).Implements(validatorType) {
+			return true
+		}
+
+		if t.Kind() != reflect.Ptr {
+			return false
+		}
+		t = t.Elem()
+	}
+}
+
+// validateValue checks v against the validate tags of its fields, then its
+// Validate method if it has one, returning ValidationErrors if any fails
+func validateValue(v reflect.Value) error {
+	var errs ValidationErrors
+	validateInto(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return runValidator(v)
+}
+
+// runValidator calls Validate on v, or whatever v points to
+func runValidator(v reflect.Value) error {
+	for v.IsValid() {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+
+		if val, ok := v.Interface().(Validator); ok {
+			err := val.Validate()
+			if err == nil {
+				return nil
+			}
+
+			var fe FieldError
+			if errors.As(err, &fe) {
+				return ValidationErrors{fe}
+			}
+			return err
+		}
+
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	return nil
+}
+
+func validateInto(v reflect.Value, prefix string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateInto(v.Index(i), prefix+"["+strconv.Itoa(i)+"]", errs)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		name := fieldName(f)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if f.Anonymous {
+			name = prefix
+		}
+
+		fv := v.Field(i)
+		if tag, ok := f.Tag.Lookup(ValidateTag); ok {
+			// tags are checked at registration, so an error here can't happen
+			// for handlers built through NewHandler
+			rules, err := parseValidationTag(tag)
+			if err != nil {
+				*errs = append(*errs, FieldError{Field: name, Rule: "tag", Msg: err.Error()})
+				continue
+			}
+
+			if !applyRules(fv, name, rules, errs) {
+				continue
+			}
+		}
+
+		validateInto(fv, name, errs)
+	}
+}
+
+// applyRules reports whether validation should continue into fv
+func applyRules(fv reflect.Value, name string, rules []validationRule, errs *ValidationErrors) bool {
+	for _, rule := range rules {
+		if rule.name == "required" && fv.IsZero() {
+			*errs = append(*errs, FieldError{Field: name, Rule: rule.name, Msg: "is required"})
+			return false
+		}
+	}
+
+	v := fv
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			// optional and absent
+			return false
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range rules {
+		var msg string
+		switch rule.name {
+		case "min":
+			if n, isLen, ok := measure(v); ok && n < rule.num {
+				msg = boundMsg("at least", rule.arg, isLen)
+			}
+		case "max":
+			if n, isLen, ok := measure(v); ok && n > rule.num {
+				msg = boundMsg("at most", rule.arg, isLen)
+			}
+		case "len":
+			if n, isLen, ok := measure(v); ok && isLen && n != rule.num {
+				msg = "must have length " + rule.arg
+			}
+		case "pattern":
+			if v.Kind() == reflect.String && !rule.regex.MatchString(v.String()) {
+				msg = "must match " + rule.arg
+			}
+		}
+
+		if msg != "" {
+			*errs = append(*errs, FieldError{Field: name, Rule: rule.name, Msg: msg})
+		}
+	}
+
+	return true
+}
+
+// measure returns the value of numbers or the length of strings and
+// collections, and whether it was a length
+func measure(v reflect.Value) (float64, bool, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+
+	return 0, false, false
+}
+
+func boundMsg(bound, arg string, isLen bool) string {
+	if isLen {
+		return "must have length " + bound + " " + arg
+	}
+
+	return "must be " + bound + " " + arg
+}
+
+// fieldName prefers the JSON name so errors match what the client sent
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return f.Name
+}
@@ -0,0 +1,212 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type signup struct {
+	Name  string   `json:"name" validate:"required,min=2,max=8"`
+	Code  string   `json:"code" validate:"len=4,pattern=^[A-Z]{2,4}$"`
+	Age   int      `json:"age" validate:"min=18,max=130"`
+	Tags  []string `json:"tags" validate:"max=2"`
+	Email *string  `json:"email" validate:"pattern=@"`
+	Pets  []struct {
+		Name string `json:"name" validate:"required"`
+	} `json:"pets"`
+}
+
+func TestTagValidation(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/signup", func(ctx context.Context, in signup) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Body         string
+		ExpectStatus int
+		ExpectError  string
+	}{
+		{"valid", `{"name": "ann", "code": "ABCD", "age": 30}`, http.StatusOK, ""},
+		{"valid-optional-pointer", `{"name": "ann", "code": "ABCD", "age": 30, "email": "a@b.c"}`, http.StatusOK, ""},
+		{"missing-required", `{"code": "ABCD", "age": 30}`, http.StatusBadRequest, "name: is required"},
+		{"too-short", `{"name": "a", "code": "ABCD", "age": 30}`, http.StatusBadRequest, "name: must have length at least 2"},
+		{"too-young", `{"name": "ann", "code": "ABCD", "age": 3}`, http.StatusBadRequest, "age: must be at least 18"},
+		{"wrong-length", `{"name": "ann", "code": "ABC", "age": 30}`, http.StatusBadRequest, "code: must have length 4"},
+		{"pattern", `{"name": "ann", "code": "abcd", "age": 30}`, http.StatusBadRequest, "code: must match ^[A-Z]{2,4}$"},
+		{"too-many-tags", `{"name": "ann", "code": "ABCD", "age": 30, "tags": ["a", "b", "c"]}`, http.StatusBadRequest, "tags: must have length at most 2"},
+		{"bad-pointer", `{"name": "ann", "code": "ABCD", "age": 30, "email": "nope"}`, http.StatusBadRequest, "email: must match @"},
+		{"nested", `{"name": "ann", "code": "ABCD", "age": 30, "pets": [{"name": "rex"}, {}]}`, http.StatusBadRequest, "pets[1].name: is required"},
+		{"multiple", `{"name": "a", "code": "ABCD", "age": 3}`, http.StatusBadRequest, "name: must have length at least 2; age: must be at least 18"},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", "application/json")
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+			continue
+		}
+
+		if c.ExpectError == "" {
+			continue
+		}
+
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Error != c.ExpectError {
+			t.Errorf("case[%s] expected error %q got %q", c.Name, c.ExpectError, body.Error)
+		}
+	}
+}
+
+func TestTagValidationAtRegistration(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Name      string
+		Fn        interface{}
+		ShouldErr bool
+	}{
+		{"valid", func(in struct {
+			X int `validate:"min=1"`
+		}) {
+		}, false},
+		{"unknown-rule", func(in struct {
+			X int `validate:"positive"`
+		}) {
+		}, true},
+		{"bad-number", func(in struct {
+			X int `validate:"min=one"`
+		}) {
+		}, true},
+		{"bad-pattern", func(in []struct {
+			X string `validate:"pattern=("`
+		}) {
+		}, true},
+	}
+
+	for _, c := range cases {
+		err := NewJSONDecoder().ValidateType(c.Fn)
+		if err != nil && !c.ShouldErr {
+			t.Errorf("case[%s] failed unexpectedly: %s", c.Name, err)
+		}
+
+		if err == nil && c.ShouldErr {
+			t.Errorf("case[%s] did not fail when it should have", c.Name)
+		}
+	}
+}
+
+type booking struct {
+	From  string `json:"from" validate:"required"`
+	To    string `json:"to" validate:"required"`
+	Seats int    `json:"seats"`
+}
+
+func (b booking) Validate() error {
+	if b.From == b.To {
+		return FieldError{Field: "to", Rule: "distinct", Msg: "must differ from from"}
+	}
+
+	if b.Seats > 9 {
+		return errors.New("too many seats for one booking")
+	}
+
+	return nil
+}
+
+func TestValidateMethod(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/bookings", func(ctx context.Context, in *booking) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RegisterTyped(r, http.MethodPost, "/typed", func(ctx context.Context, in booking) (booking, error) {
+		return in, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Body         string
+		ExpectStatus int
+		ExpectError  string
+		ExpectFields []FieldError
+	}{
+		{"valid", `{"from": "AMS", "to": "LIS", "seats": 2}`, http.StatusOK, "", nil},
+		{"tags-first", `{"from": "AMS"}`, http.StatusBadRequest, "to: is required", []FieldError{{Field: "to", Rule: "required", Msg: "is required"}}},
+		{"field-error", `{"from": "AMS", "to": "AMS"}`, http.StatusBadRequest, "to: must differ from from", []FieldError{{Field: "to", Rule: "distinct", Msg: "must differ from from"}}},
+		{"plain-error", `{"from": "AMS", "to": "LIS", "seats": 12}`, http.StatusBadRequest, "too many seats for one booking", nil},
+	}
+
+	for _, path := range []string{"/bookings", "/typed"} {
+		for _, c := range cases {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(c.Body))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != c.ExpectStatus {
+				t.Errorf("case[%s %s] expected %d got %d", path, c.Name, c.ExpectStatus, w.Code)
+				continue
+			}
+
+			if c.ExpectError == "" {
+				continue
+			}
+
+			var body struct {
+				Error  string       `json:"error"`
+				Fields []FieldError `json:"fields"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if body.Error != c.ExpectError {
+				t.Errorf("case[%s %s] expected error %q got %q", path, c.Name, c.ExpectError, body.Error)
+			}
+
+			if !reflect.DeepEqual(body.Fields, c.ExpectFields) {
+				t.Errorf("case[%s %s] expected fields %+v got %+v", path, c.Name, c.ExpectFields, body.Fields)
+			}
+		}
+	}
+}
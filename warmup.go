@@ -0,0 +1,86 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync/atomic"
+)
+
+// ErrWarmingUp is returned for requests to routes that aren't ready yet
+var ErrWarmingUp = errors.New("autohttp: warming up, try again later")
+
+// WithWarmup registers the route in a warming state, answering 503s until
+// ready reports true, for routes that would otherwise serve partial data just
+// after a deploy, such as while a cache loads. The route then goes live for
+// good and ready isn't called again. Until then ready is called once per
+// request, so it should be cheap, such as loading an atomic flag
+func WithWarmup(ready func() bool) RouteOption {
+	return func(rc *routeConfig) error {
+		if ready == nil {
+			return errors.New("autohttp: WithWarmup needs a readiness func")
+		}
+
+		rc.warmup = ready
+		return nil
+	}
+}
+
+// Warming returns the routes, as "METHOD pattern", still warming up
+func (r *Router) Warming() []string {
+	var warming []string
+	for route, wh := range r.warmups {
+		if !wh.isLive() {
+			warming = append(warming, route)
+		}
+	}
+	sort.Strings(warming)
+
+	return warming
+}
+
+type warmingHandler struct {
+	ready        func() bool
+	live         int32
+	next         http.Handler
+	errorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+// isLive reports whether the route is live, flipping it if ready
+func (wh *warmingHandler) isLive() bool {
+	if atomic.LoadInt32(&wh.live) == 1 {
+		return true
+	}
+
+	if !wh.ready() {
+		return false
+	}
+
+	atomic.StoreInt32(&wh.live, 1)
+	return true
+}
+
+func (wh *warmingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !wh.isLive() {
+		w.Header().Set("Retry-After", "1")
+		wh.errorHandler(w, req, ErrorWithCode{Err: ErrWarmingUp, StatusCode: http.StatusServiceUnavailable})
+		return
+	}
+
+	wh.next.ServeHTTP(w, req)
+}
+
+// warm wraps handler, served as route, in a warmingHandler if rc has a warmup
+func (r *Router) warm(route string, handler http.Handler, rc routeConfig) http.Handler {
+	if rc.warmup == nil {
+		return handler
+	}
+
+	wh := &warmingHandler{ready: rc.warmup, next: handler, errorHandler: r.serveRouterError}
+	if r.warmups == nil {
+		r.warmups = make(map[string]*warmingHandler)
+	}
+	r.warmups[route] = wh
+
+	return wh
+}
@@ -0,0 +1,87 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded int32
+	ready := func() bool { return atomic.LoadInt32(&loaded) == 1 }
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	err = r.Register(http.MethodGet, "/catalog", ok, nil, WithWarmup(ready))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/files/*", ok, nil, WithWarmup(ready))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodGet, "/status", ok, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if got := r.Warming(); !reflect.DeepEqual(got, []string{"* /files/*", "GET /catalog"}) {
+		t.Errorf("unexpected warming routes %v", got)
+	}
+
+	cases := []struct {
+		name  string
+		path  string
+		code  int
+		retry bool
+	}{
+		{name: "warming", path: "/catalog", code: http.StatusServiceUnavailable, retry: true},
+		{name: "warming star route", path: "/files/a.txt", code: http.StatusServiceUnavailable, retry: true},
+		{name: "other routes", path: "/status", code: http.StatusOK},
+	}
+
+	for _, c := range cases {
+		w := serve(c.path)
+		if w.Code != c.code {
+			t.Errorf("case[%s] expected code %d, got %d", c.name, c.code, w.Code)
+		}
+
+		if c.retry && w.Header().Get("Retry-After") == "" {
+			t.Errorf("case[%s] expected a Retry-After header", c.name)
+		}
+	}
+
+	atomic.StoreInt32(&loaded, 1)
+	if w := serve("/catalog"); w.Code != http.StatusOK {
+		t.Errorf("expected the route to go live, got %d", w.Code)
+	}
+
+	// once live a route stays live
+	atomic.StoreInt32(&loaded, 0)
+	if w := serve("/catalog"); w.Code != http.StatusOK {
+		t.Errorf("expected the route to stay live, got %d", w.Code)
+	}
+
+	if got := r.Warming(); !reflect.DeepEqual(got, []string{"* /files/*"}) {
+		t.Errorf("unexpected warming routes %v", got)
+	}
+}
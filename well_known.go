@@ -0,0 +1,109 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DefaultRobotsTxt allows every crawler everywhere
+const DefaultRobotsTxt = "User-agent: *\nDisallow:\n"
+
+const wellKnownPrefix = "/.well-known/"
+
+// WithRobotsTxt serves body at /robots.txt, or DefaultRobotsTxt if body is empty
+func WithRobotsTxt(body string) func(r *Router) error {
+	if body == "" {
+		body = DefaultRobotsTxt
+	}
+
+	return func(r *Router) error {
+		return r.registerQuiet("/robots.txt", staticTextHandler(body))
+	}
+}
+
+// WithFavicon serves icon at /favicon.ico. A nil icon answers with a
+// cacheable 204 so browsers stop asking and the requests stop 404ing
+func WithFavicon(icon []byte) func(r *Router) error {
+	return func(r *Router) error {
+		return r.registerQuiet("/favicon.ico", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Cache-Control", "public, max-age=86400")
+			if icon == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			w.Header().Set("Content-Type", http.DetectContentType(icon))
+			w.Write(icon)
+		}))
+	}
+}
+
+// WithSecurityTxt serves body at /.well-known/security.txt, see RFC 9116
+func WithSecurityTxt(body string) func(r *Router) error {
+	return func(r *Router) error {
+		if body == "" {
+			return errors.New("autohttp: security.txt requires at least a Contact field")
+		}
+
+		return r.registerQuiet(wellKnownPrefix+"security.txt", staticTextHandler(body))
+	}
+}
+
+// WithChangePasswordURL redirects /.well-known/change-password to the page
+// where users change their password, so password managers can link to it
+func WithChangePasswordURL(url string) func(r *Router) error {
+	return func(r *Router) error {
+		return r.registerQuiet(wellKnownPrefix+"change-password", http.RedirectHandler(url, http.StatusFound))
+	}
+}
+
+// WithWellKnown serves h at /.well-known/name. Names ending in a slash match
+// every path below them, e.g. "acme-challenge/" for HTTP-01 challenges
+func WithWellKnown(name string, h http.Handler) func(r *Router) error {
+	return func(r *Router) error {
+		name = strings.TrimPrefix(name, "/")
+		if name == "" {
+			return errors.New("autohttp: well-known routes need a name")
+		}
+
+		if strings.HasSuffix(name, "/") {
+			r.quietPrefixes = append(r.quietPrefixes, wellKnownPrefix+name)
+			return r.register(http.MethodGet, wellKnownPrefix+name+"*", h, nil, routeConfig{}, nil)
+		}
+
+		return r.registerQuiet(wellKnownPrefix+name, h)
+	}
+}
+
+// registerQuiet registers a GET route that is left out of route metrics logging
+func (r *Router) registerQuiet(path string, h http.Handler) error {
+	if r.quietPaths == nil {
+		r.quietPaths = make(map[string]bool)
+	}
+	r.quietPaths[path] = true
+
+	return r.register(http.MethodGet, path, h, nil, routeConfig{}, nil)
+}
+
+// isQuiet reports whether requests for p are too noisy to log
+func (r *Router) isQuiet(p string) bool {
+	if r.quietPaths[p] {
+		return true
+	}
+
+	for _, prefix := range r.quietPrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func staticTextHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(body))
+	})
+}
@@ -0,0 +1,108 @@
+package autohttp
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestWellKnownRoutes(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	acme := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(strings.TrimPrefix(req.URL.Path, "/.well-known/acme-challenge/") + ".key"))
+	})
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(&logs), lounge.WithDebugEnabled()),
+		EnableRouteMetrics,
+		WithRobotsTxt(""),
+		WithFavicon(nil),
+		WithSecurityTxt("Contact: mailto:security@example.com\n"),
+		WithChangePasswordURL("/account/password"),
+		WithWellKnown("acme-challenge/", acme),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name         string
+		Path         string
+		ExpectStatus int
+		ExpectBody   string
+		ExpectHeader map[string]string
+	}{
+		{"robots", "/robots.txt", http.StatusOK, DefaultRobotsTxt, map[string]string{"Content-Type": "text/plain; charset=utf-8"}},
+		{"favicon", "/favicon.ico", http.StatusNoContent, "", map[string]string{"Cache-Control": "public, max-age=86400"}},
+		{"security", "/.well-known/security.txt", http.StatusOK, "Contact: mailto:security@example.com\n", nil},
+		{"change-password", "/.well-known/change-password", http.StatusFound, "", map[string]string{"Location": "/account/password"}},
+		{"acme", "/.well-known/acme-challenge/abc", http.StatusOK, "abc.key", nil},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+
+		if w.Code != c.ExpectStatus {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectStatus, w.Code)
+		}
+
+		if c.ExpectBody != "" && w.Body.String() != c.ExpectBody {
+			t.Errorf("case[%s] expected body %q got %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+
+		for k, v := range c.ExpectHeader {
+			if w.Header().Get(k) != v {
+				t.Errorf("case[%s] expected %s %q got %q", c.Name, k, v, w.Header().Get(k))
+			}
+		}
+	}
+
+	if logs.Len() != 0 {
+		t.Errorf("expected well-known routes to stay out of the logs, got %q", logs.String())
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if !strings.Contains(logs.String(), "/missing") {
+		t.Errorf("expected other routes to still be logged, got %q", logs.String())
+	}
+}
+
+func TestWellKnownOverrides(t *testing.T) {
+	t.Parallel()
+
+	icon := []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00}
+	r, err := NewRouter(lounge.NewDefaultLog(),
+		WithRobotsTxt("User-agent: *\nDisallow: /\n"),
+		WithFavicon(icon),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	if w.Body.String() != "User-agent: *\nDisallow: /\n" {
+		t.Errorf("expected the custom robots.txt got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), icon) {
+		t.Errorf("expected the icon to be served, got %d", w.Code)
+	}
+
+	if w.Header().Get("Content-Type") != "image/x-icon" {
+		t.Errorf("expected an icon content type got %q", w.Header().Get("Content-Type"))
+	}
+
+	_, err = NewRouter(lounge.NewDefaultLog(), WithSecurityTxt(""))
+	if err == nil {
+		t.Error("expected an empty security.txt to be rejected")
+	}
+}
@@ -0,0 +1,99 @@
+package autohttp
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+)
+
+// ErrWorkerPoolSaturated is returned when a worker pool's queue is full
+var ErrWorkerPoolSaturated = errors.New("autohttp: server busy, try again later")
+
+// A WorkerPool bounds how many requests of the routes sharing it run at once,
+// so CPU-heavy endpoints such as image processing or reports can't starve the
+// rest of the server. Requests beyond the pool's workers wait in a bounded
+// queue, and requests arriving to a full queue get a 503
+type WorkerPool struct {
+	workers chan struct{}
+	queue   chan struct{}
+
+	running int64
+	waiting int64
+}
+
+// NewWorkerPool returns a pool running up to workers requests at once, or
+// GOMAXPROCS if workers isn't positive, with up to queue more waiting
+func NewWorkerPool(workers, queue int) *WorkerPool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	if queue < 0 {
+		queue = 0
+	}
+
+	return &WorkerPool{
+		workers: make(chan struct{}, workers),
+		queue:   make(chan struct{}, workers+queue),
+	}
+}
+
+// Running is the number of requests being served by the pool
+func (wp *WorkerPool) Running() int {
+	return int(atomic.LoadInt64(&wp.running))
+}
+
+// Waiting is the number of requests queued for a worker
+func (wp *WorkerPool) Waiting() int {
+	return int(atomic.LoadInt64(&wp.waiting))
+}
+
+// WithWorkerPool runs the route on pool. Routes can share a pool to bound
+// their combined load
+func WithWorkerPool(pool *WorkerPool) RouteOption {
+	return func(rc *routeConfig) error {
+		if pool == nil {
+			return errors.New("autohttp: WithWorkerPool needs a pool")
+		}
+
+		rc.workerPool = pool
+		return nil
+	}
+}
+
+type pooledHandler struct {
+	pool         *WorkerPool
+	next         http.Handler
+	errorHandler func(w http.ResponseWriter, req *http.Request, err error)
+}
+
+func (ph *pooledHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// the queue holds every admitted request, running or waiting
+	select {
+	case ph.pool.queue <- struct{}{}:
+	default:
+		w.Header().Set("Retry-After", "1")
+		ph.errorHandler(w, req, ErrorWithCode{Err: ErrWorkerPoolSaturated, StatusCode: http.StatusServiceUnavailable})
+		return
+	}
+	defer func() { <-ph.pool.queue }()
+
+	atomic.AddInt64(&ph.pool.waiting, 1)
+	select {
+	case ph.pool.workers <- struct{}{}:
+		atomic.AddInt64(&ph.pool.waiting, -1)
+	case <-req.Context().Done():
+		// the client gave up while queued
+		atomic.AddInt64(&ph.pool.waiting, -1)
+		return
+	}
+
+	atomic.AddInt64(&ph.pool.running, 1)
+	defer func() {
+		atomic.AddInt64(&ph.pool.running, -1)
+		<-ph.pool.workers
+	}()
+
+	ph.next.ServeHTTP(w, req)
+}
@@ -0,0 +1,94 @@
+package autohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/lounge"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Parallel()
+
+	pool := NewWorkerPool(1, 1)
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	for _, path := range []string{"/reports", "/thumbnails"} {
+		err = r.Register(http.MethodGet, path, slow, nil, WithWorkerPool(pool))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Register(http.MethodGet, "/fast", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := make(chan int, 2)
+	serve := func(path string) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		codes <- w.Code
+	}
+
+	waitFor := func(name string, cond func() bool) {
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s, running %d waiting %d", name, pool.Running(), pool.Waiting())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	go serve("/reports")
+	waitFor("a running request", func() bool { return pool.Running() == 1 })
+
+	go serve("/thumbnails")
+	waitFor("a queued request", func() bool { return pool.Waiting() == 1 })
+
+	cases := []struct {
+		Name       string
+		Path       string
+		ExpectCode int
+	}{
+		{"saturated", "/reports", http.StatusServiceUnavailable},
+		{"shared-pool-saturated", "/thumbnails", http.StatusServiceUnavailable},
+		{"unpooled", "/fast", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.Path, nil))
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d", c.Name, c.ExpectCode, w.Code)
+		}
+
+		if c.ExpectCode == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+			t.Errorf("case[%s] expected a Retry-After header", c.Name)
+		}
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("expected pooled requests to finish with 200 got %d", code)
+		}
+	}
+
+	if pool.Running() != 0 || pool.Waiting() != 0 {
+		t.Errorf("expected an idle pool, running %d waiting %d", pool.Running(), pool.Waiting())
+	}
+}
@@ -0,0 +1,108 @@
+package autohttp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// XMLDecoder decodes application/xml and text/xml request bodies with
+// encoding/xml, taking the same arguments as the JSONDecoder
+type XMLDecoder struct {
+	MaxBytesToRead int64
+}
+
+func NewXMLDecoder() *XMLDecoder {
+	return &XMLDecoder{
+		MaxBytesToRead: DefaultMaxBytesToRead,
+	}
+}
+
+func (xd *XMLDecoder) ValidateType(fn interface{}) error {
+	_, _, decodeIdx, err := bodyInputIndices(fn, xd.isXMLDecodable)
+	if err != nil {
+		return err
+	}
+
+	if decodeIdx != uIdx {
+		return checkValidationTags(reflect.TypeOf(fn).In(decodeIdx))
+	}
+
+	return nil
+}
+
+// isXMLDecodable excludes maps, which encoding/xml can't decode into
+func (xd *XMLDecoder) isXMLDecodable(t reflect.Type) bool {
+	kind := t.Kind()
+
+	return !isHeaderType(t) && (kind == reflect.Ptr || kind == reflect.Slice || kind == reflect.Struct)
+}
+
+// Decode returns the reflect values needed to call the fn
+// from the *http.Request
+func (xd *XMLDecoder) Decode(fn interface{}, r *http.Request) ([]reflect.Value, error) {
+	ct := r.Header.Get("Content-Type")
+	if !strings.Contains(ct, "application/xml") && !strings.Contains(ct, "text/xml") {
+		return nil, ErrorWithCode{Err: errors.New("invalid mime type"), StatusCode: http.StatusUnsupportedMediaType}
+	}
+
+	if isQueryMethod(r.Method) {
+		return nil, ErrorWithCode{Err: errors.New("GET requests prohibited for this endpoint"), StatusCode: http.StatusMethodNotAllowed}
+	}
+
+	ctxIdx, hdrIdx, decodeIdx, err := bodyInputIndices(fn, xd.isXMLDecodable)
+	if err != nil {
+		return nil, err
+	}
+
+	fnReflectType := reflect.ValueOf(fn).Type()
+	callValues := make([]reflect.Value, fnReflectType.NumIn())
+
+	if ctxIdx != uIdx {
+		callValues[ctxIdx] = reflect.ValueOf(r.Context())
+	}
+
+	if hdrIdx != uIdx {
+		callValues[hdrIdx] = reflect.ValueOf(requestHeader(r))
+	}
+
+	if decodeIdx != uIdx {
+		inArg := fnReflectType.In(decodeIdx)
+
+		var object reflect.Value
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			object = reflect.New(inArg.Elem())
+		default:
+			object = reflect.New(inArg)
+		}
+
+		// read one byte past the limit to tell a large body from a short one
+		limitedReader := &io.LimitedReader{R: r.Body, N: xd.MaxBytesToRead + 1}
+		err = xml.NewDecoder(limitedReader).Decode(object.Interface())
+		if limitedReader.N <= 0 {
+			return nil, ErrorWithCode{Err: fmt.Errorf("maximum body size exceeded (%d bytes)", xd.MaxBytesToRead), StatusCode: http.StatusRequestEntityTooLarge}
+		}
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		err = validateValue(object)
+		if err != nil {
+			return nil, ErrorWithCode{Err: err, StatusCode: http.StatusBadRequest}
+		}
+
+		switch inArg.Kind() {
+		case reflect.Ptr:
+			callValues[decodeIdx] = object
+		default:
+			callValues[decodeIdx] = object.Elem()
+		}
+	}
+
+	return callValues, nil
+}
@@ -0,0 +1,100 @@
+package autohttp
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fortytw2/lounge"
+)
+
+type xmlOrder struct {
+	XMLName xml.Name `xml:"order"`
+	ID      string   `xml:"id,attr"`
+	Items   []string `xml:"item" validate:"min=1"`
+}
+
+func TestXMLDecoderValidation(t *testing.T) {
+	t.Parallel()
+
+	xd := NewXMLDecoder()
+
+	cases := []struct {
+		Name      string
+		Fn        interface{}
+		ShouldErr bool
+	}{
+		{"ctx-struct", func(ctx context.Context, in xmlOrder) {}, false},
+		{"hdr-ptr", func(h Header, in *xmlOrder) {}, false},
+		{"map", func(in map[string]string) {}, true},
+		{"two-bodies", func(a xmlOrder, b xmlOrder) {}, true},
+	}
+
+	for _, c := range cases {
+		err := xd.ValidateType(c.Fn)
+		if (err != nil) != c.ShouldErr {
+			t.Errorf("case[%s] expected error %t got %v", c.Name, c.ShouldErr, err)
+		}
+	}
+
+	err := (&XMLEncoder{}).ValidateType(func() map[string]int { return nil })
+	if err == nil {
+		t.Errorf("expected the xml encoder to reject maps")
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRouter(lounge.NewDefaultLog(lounge.WithOutput(os.Stderr)),
+		WithDefaultDecoder(&XMLDecoder{MaxBytesToRead: 256}),
+		WithDefaultEncoder(&XMLEncoder{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register(http.MethodPost, "/orders", func(ctx context.Context, o *xmlOrder) (*xmlOrder, error) {
+		o.Items = append(o.Items, "receipt")
+		return o, nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name        string
+		Method      string
+		ContentType string
+		Body        string
+		ExpectCode  int
+		ExpectBody  string
+	}{
+		{"ok", http.MethodPost, "application/xml", `<order id="7"><item>tea</item></order>`, http.StatusOK,
+			xml.Header + `<order id="7"><item>tea</item><item>receipt</item></order>`},
+		{"text-xml", http.MethodPost, "text/xml; charset=utf-8", `<order id="8"><item>tea</item></order>`, http.StatusOK, `<order id="8">`},
+		{"mime-type", http.MethodPost, "application/json", `{}`, http.StatusUnsupportedMediaType, ""},
+		{"invalid", http.MethodPost, "application/xml", `<order>`, http.StatusBadRequest, ""},
+		{"validation", http.MethodPost, "application/xml", `<order id="9"></order>`, http.StatusBadRequest, ""},
+		{"too-large", http.MethodPost, "application/xml", `<order id="10">` + strings.Repeat("<item>tea</item>", 20) + `</order>`, http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.Method, "/orders", strings.NewReader(c.Body))
+		req.Header.Set("Content-Type", c.ContentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.ExpectCode {
+			t.Errorf("case[%s] expected %d got %d: %s", c.Name, c.ExpectCode, w.Code, w.Body.String())
+		}
+
+		if !strings.Contains(w.Body.String(), c.ExpectBody) {
+			t.Errorf("case[%s] expected %q in %q", c.Name, c.ExpectBody, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package autohttp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// XMLEncoder renders return values with encoding/xml
+type XMLEncoder struct{}
+
+func (xe *XMLEncoder) ValidateType(fn interface{}) error {
+	fnType := reflect.ValueOf(fn).Type()
+	for i := 0; i < fnType.NumOut(); i++ {
+		if fnType.Out(i).Kind() == reflect.Map {
+			return errors.New("xml encoder cannot encode maps")
+		}
+	}
+
+	return nil
+}
+
+func (xe *XMLEncoder) Encode(value interface{}, hw HeaderWriter) (int, io.Reader, error) {
+	hw("Content-Type", "application/xml; charset=utf-8")
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	err := xml.NewEncoder(&b).Encode(value)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	return http.StatusOK, &b, nil
+}